@@ -0,0 +1,86 @@
+package anonymize
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"auxa/canvas"
+	"auxa/store"
+)
+
+// Pseudonyms are derived from a keyed hash, so they are stable across sessions but cannot
+// be reversed without the local key and the Canvas roster.
+var pseudonymEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Pseudonymizer assigns stable pseudonyms to students
+type Pseudonymizer struct {
+	key []byte
+}
+
+// NewPseudonymizer creates a pseudonymizer from a secret key
+func NewPseudonymizer(key []byte) *Pseudonymizer {
+	return &Pseudonymizer{key: key}
+}
+
+// LoadPseudonymizer reads the secret key at path, generating and saving one on first use
+func LoadPseudonymizer(path string) (*Pseudonymizer, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse pseudonym key: %w", err)
+		}
+		return NewPseudonymizer(key), nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read pseudonym key: %w", err)
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate pseudonym key: %w", err)
+	}
+	if err := store.WriteFile(path, []byte(hex.EncodeToString(key))); err != nil {
+		return nil, err
+	}
+
+	return NewPseudonymizer(key), nil
+}
+
+func (p *Pseudonymizer) pseudonym(courseID, identity string) string {
+	mac := hmac.New(sha256.New, p.key)
+	mac.Write([]byte(courseID + ":" + identity))
+	return "Student-" + pseudonymEncoding.EncodeToString(mac.Sum(nil))[:6]
+}
+
+// ForUser returns the pseudonym of a student within a course
+func (p *Pseudonymizer) ForUser(courseID string, userID int) string {
+	return p.pseudonym(courseID, "user:"+strconv.Itoa(userID))
+}
+
+// ForSubmission returns the pseudonym of a submission's author, the same one ForUser gives
+// them, so names scrubbed from text match. Only when Canvas hides the author, as on
+// anonymously graded assignments, is it keyed by the anonymous ID instead.
+func (p *Pseudonymizer) ForSubmission(courseID string, submission canvas.Submission) string {
+	if submission.UserID == 0 && submission.AnonymousID != "" {
+		return p.pseudonym(courseID, "anonymous:"+submission.AnonymousID)
+	}
+	return p.ForUser(courseID, submission.UserID)
+}
+
+// Resolve re-identifies the submission behind a pseudonym
+func (p *Pseudonymizer) Resolve(courseID, pseudonym string, submissions []canvas.Submission) (*canvas.Submission, bool) {
+	for i := range submissions {
+		if p.ForSubmission(courseID, submissions[i]) == pseudonym {
+			return &submissions[i], true
+		}
+	}
+	return nil, false
+}
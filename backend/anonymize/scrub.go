@@ -0,0 +1,60 @@
+package anonymize
+
+import (
+	"strings"
+
+	"auxa/canvas"
+	"auxa/redact"
+)
+
+// scrubConfig masks roster names, logins, SIS IDs and emails; pseudonyms replace anything
+// attributable to a student
+var scrubConfig = redact.Config{
+	Enabled:    true,
+	Names:      true,
+	Emails:     true,
+	StudentIDs: true,
+}
+
+// Scrubber replaces student names, logins and emails in free text with pseudonyms
+type Scrubber struct {
+	redactor *redact.Redactor
+}
+
+// NewScrubber builds a scrubber for a roster, replacing each student's identifiers with
+// the pseudonym returned by pseudonymFor
+func NewScrubber(users []canvas.User, pseudonymFor func(canvas.User) string) *Scrubber {
+	people := make([]redact.Person, len(users))
+	for i, user := range users {
+		people[i] = redact.Person{
+			Alias:  pseudonymFor(user),
+			Names:  []string{user.Name, user.SortableName, user.ShortName},
+			IDs:    []string{user.LoginID, user.SISUserID},
+			Emails: []string{user.Email},
+		}
+	}
+
+	// scrubConfig has no ID pattern, so New cannot fail
+	redactor, _ := redact.New(people, scrubConfig)
+	return &Scrubber{redactor: redactor}
+}
+
+// Scrub returns text with roster identifiers replaced by pseudonyms and any other email
+// address or student number masked. A nil scrubber returns text unchanged.
+func (s *Scrubber) Scrub(text string) string {
+	if s == nil {
+		return text
+	}
+	return s.redactor.Mask(text, nil)
+}
+
+// HiddenAuthor stands in for roster names when submissions cannot be tied to students
+const HiddenAuthor = "[Student]"
+
+// Reidentify replaces pseudonyms in text with the names they stand for
+func Reidentify(text string, names map[string]string) string {
+	for pseudonym, name := range names {
+		text = strings.ReplaceAll(text, pseudonym, name)
+	}
+	return text
+}
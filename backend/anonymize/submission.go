@@ -0,0 +1,46 @@
+package anonymize
+
+import "auxa/canvas"
+
+// Submission is a Canvas submission stripped of student identity and addressed by pseudonym
+type Submission struct {
+	canvas.Submission
+	Pseudonym string `json:"pseudonym"`
+}
+
+// AnonymizeSubmission strips user data from a submission, replaces it with a stable
+// pseudonym and scrubs roster names and emails from its free text
+func (p *Pseudonymizer) AnonymizeSubmission(courseID string, submission canvas.Submission, scrubber *Scrubber) Submission {
+	pseudonym := p.ForSubmission(courseID, submission)
+	authorID := submission.UserID
+
+	anonymized := submission
+	anonymized.UserID = 0
+	anonymized.AnonymousID = ""
+	anonymized.User = nil
+	anonymized.HTMLURL = ""
+	anonymized.PreviewURL = ""
+	anonymized.Body = scrubber.Scrub(submission.Body)
+
+	if len(submission.Attachments) > 0 {
+		anonymized.Attachments = make([]canvas.Attachment, len(submission.Attachments))
+		for i, attachment := range submission.Attachments {
+			attachment.Filename = scrubber.Scrub(attachment.Filename)
+			attachment.DisplayName = scrubber.Scrub(attachment.DisplayName)
+			anonymized.Attachments[i] = attachment
+		}
+	}
+
+	if len(submission.SubmissionComments) > 0 {
+		anonymized.SubmissionComments = make([]canvas.SubmissionComment, len(submission.SubmissionComments))
+		for i, comment := range submission.SubmissionComments {
+			if authorID != 0 && comment.AuthorID == authorID {
+				comment.AuthorID = 0
+			}
+			comment.Comment = scrubber.Scrub(comment.Comment)
+			anonymized.SubmissionComments[i] = comment
+		}
+	}
+
+	return Submission{Submission: anonymized, Pseudonym: pseudonym}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"auxa/anonymize"
+	"auxa/canvas"
+	"auxa/search"
+
+	"github.com/gin-gonic/gin"
+)
+
+// pseudonyms assigns stable student pseudonyms; initialised in main
+var pseudonyms *anonymize.Pseudonymizer
+
+// rosterScrubber builds a scrubber from the course's student enrollments
+func rosterScrubber(client *canvas.Client, courseID string) (*anonymize.Scrubber, []canvas.Enrollment, error) {
+	return scrubberWithAlias(client, courseID, func(user canvas.User) string {
+		return pseudonyms.ForUser(courseID, user.ID)
+	})
+}
+
+// hiddenAuthorScrubber builds a roster scrubber that replaces every student with the same
+// placeholder, for anonymously graded assignments whose submissions cannot be tied to users
+func hiddenAuthorScrubber(client *canvas.Client, courseID string) (*anonymize.Scrubber, error) {
+	scrubber, _, err := scrubberWithAlias(client, courseID, func(canvas.User) string {
+		return anonymize.HiddenAuthor
+	})
+	return scrubber, err
+}
+
+// scrubberWithAlias builds a scrubber that replaces each enrolled student with aliasFor
+func scrubberWithAlias(client *canvas.Client, courseID string, aliasFor func(canvas.User) string) (*anonymize.Scrubber, []canvas.Enrollment, error) {
+	enrollments, err := client.GetCourseEnrollments(courseID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load roster for anonymization: %w", err)
+	}
+
+	users := make([]canvas.User, len(enrollments))
+	for i, enrollment := range enrollments {
+		users[i] = enrollment.User
+	}
+
+	return anonymize.NewScrubber(users, aliasFor), enrollments, nil
+}
+
+// scrubForProvider scrubs roster names and emails from text bound for an LLM provider.
+// It writes an error response and returns false when anonymization cannot be guaranteed.
+func scrubForProvider(c *gin.Context, courseID string, texts ...*string) bool {
+	scrubber, ok := providerScrubber(c, courseID)
+	if !ok {
+		return false
+	}
+
+	for _, text := range texts {
+		*text = scrubber.Scrub(*text)
+	}
+	return true
+}
+
+// providerScrubber loads the roster scrubber for text bound for an LLM provider, for callers
+// that scrub many texts from one request. It writes an error response and returns false
+// when anonymization cannot be guaranteed.
+func providerScrubber(c *gin.Context, courseID string) (*anonymize.Scrubber, bool) {
+	if courseID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "course_id is required when anonymize is set"})
+		return nil, false
+	}
+
+	client, ok := canvasClientFromRequest(c)
+	if !ok {
+		return nil, false
+	}
+
+	scrubber, _, err := rosterScrubber(client, courseID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return scrubber, true
+}
+
+// Get submissions with student identity replaced by stable pseudonyms
+func getAnonymousSubmissions(c *gin.Context) {
+	courseID := c.Param("course_id")
+	assignmentID := c.Param("assignment_id")
+
+	client, ok := canvasClientFromRequest(c)
+	if !ok {
+		return
+	}
+
+	assignment, err := client.GetAssignment(courseID, assignmentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	submissions, err := client.GetAnonymousAssignmentSubmissions(courseID, assignmentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Names in anonymously graded work become one placeholder, since the pseudonyms of
+	// their authors cannot be known and any other pseudonym would point at a student
+	var scrubber *anonymize.Scrubber
+	if assignment.AnonymousGrading {
+		scrubber, err = hiddenAuthorScrubber(client, courseID)
+	} else {
+		scrubber, _, err = rosterScrubber(client, courseID)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Initialize as empty slice to ensure JSON returns [] instead of null
+	anonymized := make([]anonymize.Submission, 0, len(submissions))
+	for _, submission := range submissions {
+		anonymized = append(anonymized, pseudonyms.AnonymizeSubmission(courseID, submission, scrubber))
+	}
+
+	c.JSON(http.StatusOK, anonymized)
+}
+
+// Post a grade for a pseudonymous submission, re-identifying the student only at posting time
+func gradeAnonymousSubmission(c *gin.Context) {
+	courseID := c.Param("course_id")
+	assignmentID := c.Param("assignment_id")
+	pseudonym := c.Param("pseudonym")
+
+	var req canvas.SubmissionGrade
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	client, ok := canvasClientFromRequest(c)
+	if !ok {
+		return
+	}
+
+	assignment, err := client.GetAssignment(courseID, assignmentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	submissions, err := client.GetAnonymousAssignmentSubmissions(courseID, assignmentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	submission, found := pseudonyms.Resolve(courseID, pseudonym, submissions)
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No submission matches this pseudonym"})
+		return
+	}
+
+	// Index the comment as the grader wrote it, with pseudonyms rather than names
+	indexed := search.Entry{Kind: search.KindComment, CourseID: courseID, AssignmentID: assignmentID, Text: req.TextComment}
+
+	if assignment.AnonymousGrading {
+		// Graders of anonymous assignments never learn names, so pseudonyms stay in the comment
+		if _, err := client.GradeAnonymousSubmission(courseID, assignmentID, submission.AnonymousID, req); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	} else {
+		_, enrollments, err := rosterScrubber(client, courseID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		names := make(map[string]string, len(enrollments))
+		for _, enrollment := range enrollments {
+			name := enrollment.User.ShortName
+			if name == "" {
+				name = enrollment.User.Name
+			}
+			names[pseudonyms.ForUser(courseID, enrollment.User.ID)] = name
+		}
+		req.TextComment = anonymize.Reidentify(req.TextComment, names)
+
+		if _, err := client.GradeSubmission(courseID, assignmentID, strconv.Itoa(submission.UserID), req); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		indexed.UserID = submission.UserID
+	}
+	indexFeedback(client, indexed)
+
+	// Respond with the pseudonym only so the renderer never sees the real identity
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Grade posted",
+		"pseudonym": pseudonym,
+	})
+}
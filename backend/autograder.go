@@ -0,0 +1,390 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"auxa/autograder"
+	"auxa/canvas"
+	"auxa/llm"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	autograderJobKind = "autograde"
+	// Largest attachment downloaded for testing; archives may unpack to more
+	maxAutograderDownloadBytes = 50 * 1024 * 1024
+)
+
+// autograderSuites and autograderResults hold test suites and each student's latest run;
+// initialised in main
+var (
+	autograderSuites  *autograder.SuiteStore
+	autograderResults *autograder.ResultStore
+)
+
+// autograderJobState records which suite an assignment-wide run used
+type autograderJobState struct {
+	CourseID     string `json:"course_id"`
+	AssignmentID string `json:"assignment_id"`
+	SuiteID      string `json:"suite_id"`
+}
+
+// List test suites, optionally for a course and assignment
+func getAutograderSuites(c *gin.Context) {
+	c.JSON(http.StatusOK, autograderSuites.List(c.Query("course_id"), c.Query("assignment_id")))
+}
+
+// Get a test suite
+func getAutograderSuite(c *gin.Context) {
+	suite, found := autograderSuites.Get(c.Param("suite_id"))
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Suite not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, suite)
+}
+
+// Create a test suite; commands, format and limits default by language
+func createAutograderSuite(c *gin.Context) {
+	var req autograder.Suite
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	created, err := autograderSuites.Create(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, created)
+}
+
+// Replace a test suite
+func updateAutograderSuite(c *gin.Context) {
+	var req autograder.Suite
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	id := c.Param("suite_id")
+	if _, found := autograderSuites.Get(id); !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Suite not found"})
+		return
+	}
+
+	updated, err := autograderSuites.Update(id, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// Delete a test suite
+func deleteAutograderSuite(c *gin.Context) {
+	if err := autograderSuites.Delete(c.Param("suite_id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Suite deleted"})
+}
+
+// resolveSuite returns the named suite, or the assignment's only suite when none is named.
+// It writes an error response and returns false on failure.
+func resolveSuite(c *gin.Context, suiteID, courseID, assignmentID string) (autograder.Suite, bool) {
+	if suiteID != "" {
+		suite, found := autograderSuites.Get(suiteID)
+		if !found {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Suite not found"})
+			return autograder.Suite{}, false
+		}
+		return suite, true
+	}
+
+	suites := autograderSuites.List(courseID, assignmentID)
+	if len(suites) != 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("suite_id is required; the assignment has %d test suites", len(suites))})
+		return autograder.Suite{}, false
+	}
+	return suites[0], true
+}
+
+// autograderSources downloads a submission's attachments for testing
+func autograderSources(client *canvas.Client, submission canvas.Submission) ([]autograder.Source, error) {
+	if len(submission.Attachments) == 0 {
+		return nil, errors.New("submission has no files to test")
+	}
+
+	sources := make([]autograder.Source, 0, len(submission.Attachments))
+	for _, attachment := range submission.Attachments {
+		name := attachment.Filename
+		if name == "" {
+			name = attachment.DisplayName
+		}
+
+		data, err := client.DownloadFile(attachment.URL, maxAutograderDownloadBytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download %s: %w", name, err)
+		}
+		sources = append(sources, autograder.Source{Name: name, Data: data})
+	}
+	return sources, nil
+}
+
+// runAutograder tests one submission and stores the result as the student's latest
+func runAutograder(ctx context.Context, client *canvas.Client, suite autograder.Suite, courseID string, assignment *canvas.Assignment, submission canvas.Submission) (autograder.Result, error) {
+	sources, err := autograderSources(client, submission)
+	if err != nil {
+		return autograder.Result{}, err
+	}
+
+	result, err := autograder.Run(ctx, suite, sources, assignment.PointsPossible)
+	result.CourseID = courseID
+	result.AssignmentID = strconv.Itoa(assignment.ID)
+	result.UserID = submission.UserID
+	result.SubmissionID = submission.ID
+	result.Attempt = submission.Attempt
+	if err != nil {
+		result.Error = err.Error()
+		return result, err
+	}
+
+	if err := autograderResults.Save(result); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// Run a test suite against one student's submission and wait for the result
+func autogradeSubmission(c *gin.Context) {
+	courseID := c.Param("course_id")
+	assignmentID := c.Param("assignment_id")
+
+	userID, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id must be a number"})
+		return
+	}
+
+	var req struct {
+		SuiteID string `json:"suite_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	suite, ok := resolveSuite(c, req.SuiteID, courseID, assignmentID)
+	if !ok {
+		return
+	}
+
+	client, ok := canvasClientFromRequest(c)
+	if !ok {
+		return
+	}
+
+	assignment, err := client.GetAssignment(courseID, assignmentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	submission, err := findSubmission(client, courseID, assignmentID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if submission == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Submission not found"})
+		return
+	}
+
+	result, err := runAutograder(c.Request.Context(), client, suite, courseID, assignment, *submission)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "result": result})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"result":   result,
+		"evidence": autograder.Evidence(result),
+	})
+}
+
+// Run a test suite against every submitted attempt of an assignment as a background job
+func startAutograde(c *gin.Context) {
+	courseID := c.Param("course_id")
+	assignmentID := c.Param("assignment_id")
+
+	var req struct {
+		SuiteID string `json:"suite_id"`
+		UserIDs []int  `json:"user_ids"` // Limit the run to these students
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	suite, ok := resolveSuite(c, req.SuiteID, courseID, assignmentID)
+	if !ok {
+		return
+	}
+
+	client, ok := canvasClientFromRequest(c)
+	if !ok {
+		return
+	}
+
+	assignment, err := client.GetAssignment(courseID, assignmentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	submissions, err := client.GetAssignmentSubmissions(courseID, assignmentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	wanted := map[int]bool{}
+	for _, userID := range req.UserIDs {
+		wanted[userID] = true
+	}
+	var targets []canvas.Submission
+	for _, submission := range submissions {
+		if submission.SubmittedAt == nil || len(submission.Attachments) == 0 {
+			continue
+		}
+		if len(wanted) > 0 && !wanted[submission.UserID] {
+			continue
+		}
+		targets = append(targets, submission)
+	}
+	if len(targets) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No submitted files to test"})
+		return
+	}
+
+	state := autograderJobState{CourseID: courseID, AssignmentID: assignmentID, SuiteID: suite.ID}
+	job, err := jobManager.Create(autograderJobKind, len(targets), state)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	jobManager.Run(job.ID, func() error {
+		// Initialize as empty slice to ensure JSON returns [] instead of null
+		summaries := make([]gin.H, 0, len(targets))
+		for i, submission := range targets {
+			if err := jobManager.Progress(job.ID, i, len(targets), fmt.Sprintf("Testing submission %d of %d", i+1, len(targets))); err != nil {
+				return err
+			}
+
+			result, err := runAutograder(context.Background(), client, suite, courseID, assignment, submission)
+			if errors.Is(err, autograder.ErrNoIsolation) {
+				return err
+			}
+			entry := gin.H{"user_id": submission.UserID, "summary": result.Summary, "suggested_score": result.Score}
+			if err != nil {
+				entry["error"] = err.Error()
+			}
+			summaries = append(summaries, entry)
+		}
+
+		return jobManager.Complete(job.ID, summaries)
+	})
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+// List the latest test results for an assignment's suites
+func getAutogradeResults(c *gin.Context) {
+	courseID := c.Param("course_id")
+	assignmentID := c.Param("assignment_id")
+
+	suiteIDs := []string{c.Query("suite_id")}
+	if suiteIDs[0] == "" {
+		suiteIDs = suiteIDs[:0]
+		for _, suite := range autograderSuites.List(courseID, assignmentID) {
+			suiteIDs = append(suiteIDs, suite.ID)
+		}
+	}
+
+	// Initialize as empty slice to ensure JSON returns [] instead of null
+	results := make([]autograder.Result, 0)
+	for _, suiteID := range suiteIDs {
+		for _, result := range autograderResults.List(suiteID) {
+			if result.CourseID == courseID && result.AssignmentID == assignmentID {
+				results = append(results, result)
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, results)
+}
+
+// applyAutograder adds test results for the student's current attempt to the request's
+// prompt, running the suite unless a result for that attempt is stored. It writes an error
+// response and returns false on failure.
+func applyAutograder(c *gin.Context, req *llm.GradingRequest) bool {
+	if req.Autograder == nil {
+		return true
+	}
+
+	if req.CourseID == "" || req.AssignmentID == "" || req.Autograder.UserID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "course_id, assignment_id and autograder.user_id are required for autograded feedback"})
+		return false
+	}
+
+	suite, ok := resolveSuite(c, req.Autograder.SuiteID, req.CourseID, req.AssignmentID)
+	if !ok {
+		return false
+	}
+
+	client, ok := canvasClientFromRequest(c)
+	if !ok {
+		return false
+	}
+
+	submission, err := findSubmission(client, req.CourseID, req.AssignmentID, req.Autograder.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	if submission == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Submission not found"})
+		return false
+	}
+
+	result, found := autograderResults.Get(suite.ID, submission.UserID)
+	if !found || result.Attempt != submission.Attempt || req.Autograder.Rerun || result.FinishedAt.Before(suite.UpdatedAt) {
+		assignment, err := client.GetAssignment(req.CourseID, req.AssignmentID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return false
+		}
+
+		result, err = runAutograder(c.Request.Context(), client, suite, req.CourseID, assignment, *submission)
+		if err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Autograder could not run: " + err.Error(), "result": result})
+			return false
+		}
+	}
+
+	req.Prompt = req.Prompt + "\n\n" + autograder.Evidence(result)
+	req.Autograder = nil
+	return true
+}
@@ -0,0 +1,231 @@
+package autograder
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	javaPackagePattern = regexp.MustCompile(`(?m)^\s*package\s+([\w.]+)\s*;`)
+	goTestPattern      = regexp.MustCompile(`(?m)^func\s+(Test\w*)\s*\(`)
+)
+
+// testSet is the tests a suite declares. Results for other tests, such as ones a student
+// added to their submission, are not counted.
+type testSet struct {
+	all     bool            // Everything the suite's own runner reports counts
+	names   map[string]bool // Listed in the suite's Tests
+	modules []string        // Python test modules
+	classes []string        // Java classes
+	goTests []string        // Go test functions
+}
+
+// declaredTests works out which tests count for a suite
+func declaredTests(suite Suite) testSet {
+	set := testSet{}
+	if len(suite.Tests) > 0 {
+		set.names = map[string]bool{}
+		for _, name := range suite.Tests {
+			set.names[name] = true
+		}
+		return set
+	}
+
+	names := make([]string, 0, len(suite.Files))
+	for name := range suite.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		clean, err := cleanRelativePath(name)
+		if err != nil {
+			continue
+		}
+		base := path.Base(clean)
+		switch {
+		case suite.Language == LanguagePython && strings.HasPrefix(base, "test") && strings.HasSuffix(base, ".py"):
+			set.modules = append(set.modules, strings.ReplaceAll(strings.TrimSuffix(clean, ".py"), "/", "."))
+		case suite.Language == LanguageJava && strings.HasSuffix(base, ".java"):
+			class := strings.TrimSuffix(base, ".java")
+			if match := javaPackagePattern.FindStringSubmatch(suite.Files[name]); match != nil {
+				class = match[1] + "." + class
+			}
+			set.classes = append(set.classes, class)
+		case suite.Language == LanguageGo && strings.HasSuffix(base, "_test.go"):
+			for _, match := range goTestPattern.FindAllStringSubmatch(suite.Files[name], -1) {
+				if match[1] != "TestMain" {
+					set.goTests = append(set.goTests, match[1])
+				}
+			}
+		}
+	}
+
+	switch suite.Language {
+	case LanguagePython, LanguageJava, LanguageGo:
+	default:
+		set.all = true
+	}
+	return set
+}
+
+// counts reports whether a test's result belongs to the suite
+func (set testSet) counts(test TestCase) bool {
+	if set.all {
+		return true
+	}
+
+	topLevel, _, _ := strings.Cut(test.Name, "/")
+	if set.names != nil {
+		return set.names[test.Name] || set.names[topLevel] || set.names[test.Group+"."+test.Name]
+	}
+
+	for _, module := range set.modules {
+		if test.Name == module || strings.HasPrefix(test.Name, module+".") {
+			return true
+		}
+	}
+	for _, class := range set.classes {
+		if test.Group == class || strings.HasPrefix(test.Group, class+"$") {
+			return true
+		}
+	}
+	for _, name := range set.goTests {
+		if topLevel == name {
+			return true
+		}
+	}
+	// A Go package that failed to build is reported under its own name
+	return len(set.goTests) > 0 && test.Name == test.Group && test.Status == StatusError
+}
+
+// filter keeps the results that count and warns about the rest
+func (set testSet) filter(tests []TestCase, result *Result) []TestCase {
+	kept := make([]TestCase, 0, len(tests))
+	var ignored []string
+	for _, test := range tests {
+		if set.counts(test) {
+			kept = append(kept, test)
+		} else {
+			ignored = append(ignored, test.Name)
+		}
+	}
+	if len(ignored) > 0 {
+		listed := ignored
+		if len(listed) > 5 {
+			listed = append(listed[:5:5], "...")
+		}
+		result.Warnings = append(result.Warnings, fmt.Sprintf("Ignored %d results for tests the suite does not declare: %s", len(ignored), strings.Join(listed, ", ")))
+	}
+	return kept
+}
+
+// prepareHarness writes the suite's runners into dir, which steps see read-only as
+// $AUXA_HARNESS, and returns the variables that select the suite's tests
+func prepareHarness(dir string, suite Suite, set testSet) ([]string, error) {
+	switch suite.Language {
+	case LanguagePython:
+		modules, err := json.Marshal(append([]string{}, set.modules...))
+		if err != nil {
+			return nil, err
+		}
+		source := strings.Replace(pythonRunnerSource, "__MODULES__", string(modules), 1)
+		return nil, os.WriteFile(filepath.Join(dir, pythonRunnerName), []byte(source), 0o644)
+
+	case LanguageJava:
+		selectors := make([]string, 0, len(set.classes))
+		for _, class := range set.classes {
+			selectors = append(selectors, "--select-class="+class)
+		}
+		return []string{"AUXA_JUNIT_SELECTORS=" + strings.Join(selectors, " ")}, nil
+
+	case LanguageGo:
+		pattern := "^$"
+		if len(set.goTests) > 0 {
+			pattern = "^(" + strings.Join(set.goTests, "|") + ")$"
+		}
+		return []string{"AUXA_GO_TESTS=" + pattern}, nil
+	}
+	return nil, nil
+}
+
+// pythonRunnerName is the default Python test runner. It loads only the suite's test
+// modules and reports each test in TAP as it finishes, to a results file opened before any
+// student code is imported.
+const pythonRunnerName = "unittest_runner.py"
+
+const pythonRunnerSource = `import importlib
+import os
+import sys
+import traceback
+import unittest
+
+MODULES = __MODULES__
+
+results = open(os.path.join(os.environ["AUXA_RESULTS"], "results.tap"), "w")
+
+
+class TAPResult(unittest.TestResult):
+    def __init__(self):
+        super().__init__()
+        self.count = 0
+
+    def report(self, name, ok, directive="", trace=""):
+        self.count += 1
+        line = "%s %d - %s" % ("ok" if ok else "not ok", self.count, name.replace("#", ""))
+        if directive:
+            line += " # " + directive
+        results.write(line + "\n")
+        for text in trace.splitlines():
+            results.write("# " + text + "\n")
+        results.flush()
+
+    def addSuccess(self, test):
+        super().addSuccess(test)
+        self.report(test.id(), True)
+
+    def addFailure(self, test, err):
+        super().addFailure(test, err)
+        self.report(test.id(), False, trace=self.failures[-1][1])
+
+    def addError(self, test, err):
+        super().addError(test, err)
+        self.report(test.id(), False, trace=self.errors[-1][1])
+
+    def addSubTest(self, test, subtest, err):
+        super().addSubTest(test, subtest, err)
+        if err is not None:
+            self.report(subtest.id(), False, trace=self._exc_info_to_string(err, test))
+
+    def addSkip(self, test, reason):
+        super().addSkip(test, reason)
+        self.report(test.id(), True, "SKIP " + reason)
+
+    def addExpectedFailure(self, test, err):
+        super().addExpectedFailure(test, err)
+        self.report(test.id(), True)
+
+    def addUnexpectedSuccess(self, test):
+        super().addUnexpectedSuccess(test)
+        self.report(test.id(), False, trace="Unexpected success")
+
+
+# The workspace joins the path only now, after everything the runner needs is imported
+sys.path.insert(0, os.getcwd())
+result = TAPResult()
+for name in MODULES:
+    try:
+        tests = unittest.defaultTestLoader.loadTestsFromModule(importlib.import_module(name))
+    except BaseException:
+        result.report(name, False, trace=traceback.format_exc(limit=5))
+        continue
+    tests.run(result)
+results.write("1..%d\n" % result.count)
+results.close()
+`
@@ -0,0 +1,310 @@
+package autograder
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Test statuses
+const (
+	StatusPassed  = "passed"
+	StatusFailed  = "failed"
+	StatusError   = "error"
+	StatusSkipped = "skipped"
+)
+
+// Longest failure message kept per test
+const maxMessageBytes = 2000
+
+// TestCase is the outcome of one test
+type TestCase struct {
+	Name     string  `json:"name"`
+	Group    string  `json:"group,omitempty"` // Class, package or file the test belongs to
+	Status   string  `json:"status"`
+	Message  string  `json:"message,omitempty"`
+	Duration float64 `json:"duration_seconds,omitempty"`
+}
+
+// Summary counts test outcomes
+type Summary struct {
+	Total   int `json:"total"`
+	Passed  int `json:"passed"`
+	Failed  int `json:"failed"`
+	Errors  int `json:"errors"`
+	Skipped int `json:"skipped"`
+}
+
+// Summarize counts the tests by status
+func Summarize(tests []TestCase) Summary {
+	summary := Summary{Total: len(tests)}
+	for _, test := range tests {
+		switch test.Status {
+		case StatusPassed:
+			summary.Passed++
+		case StatusFailed:
+			summary.Failed++
+		case StatusError:
+			summary.Errors++
+		case StatusSkipped:
+			summary.Skipped++
+		}
+	}
+	return summary
+}
+
+var (
+	tapPlanPattern = regexp.MustCompile(`^1\.\.(\d+)`)
+	tapTestPattern = regexp.MustCompile(`^(not ok|ok)\b\s*(\d+)?\s*(?:-\s*)?([^#]*)(?:#\s*(\w+)\s*(.*))?$`)
+)
+
+// ParseTAP reads Test Anything Protocol output. Diagnostics after a failing test become its
+// message, and tests the plan promised but never reported count as errors.
+func ParseTAP(output []byte) []TestCase {
+	var tests []TestCase
+	planned := -1
+	inYAML := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+
+		// YAML diagnostic blocks belong to the test before them
+		if inYAML {
+			if trimmed == "..." {
+				inYAML = false
+			} else if len(tests) > 0 {
+				appendMessage(&tests[len(tests)-1], trimmed)
+			}
+			continue
+		}
+		if trimmed == "---" && len(tests) > 0 {
+			inYAML = true
+			continue
+		}
+
+		if match := tapPlanPattern.FindStringSubmatch(trimmed); match != nil {
+			planned, _ = strconv.Atoi(match[1])
+			continue
+		}
+
+		if match := tapTestPattern.FindStringSubmatch(trimmed); match != nil && !strings.HasPrefix(line, " ") {
+			name := strings.TrimSpace(match[3])
+			if name == "" {
+				name = "test " + match[2]
+			}
+			test := TestCase{Name: name, Status: StatusPassed}
+			if match[1] == "not ok" {
+				test.Status = StatusFailed
+			}
+			switch strings.ToUpper(match[4]) {
+			case "SKIP":
+				test.Status = StatusSkipped
+				test.Message = strings.TrimSpace(match[5])
+			case "TODO":
+				// TODO tests are expected to fail and do not count against the student
+				test.Status = StatusSkipped
+				test.Message = "TODO " + strings.TrimSpace(match[5])
+			}
+			tests = append(tests, test)
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, "#") && len(tests) > 0 && tests[len(tests)-1].Status == StatusFailed {
+			appendMessage(&tests[len(tests)-1], strings.TrimSpace(strings.TrimPrefix(trimmed, "#")))
+		}
+	}
+
+	for i := len(tests); i < planned; i++ {
+		tests = append(tests, TestCase{
+			Name:    fmt.Sprintf("test %d", i+1),
+			Status:  StatusError,
+			Message: "Planned test did not report; the test run may have crashed or timed out",
+		})
+	}
+	return tests
+}
+
+// junitFailure is a failure, error or skip element of a JUnit test case
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure"`
+	Error     *junitFailure `xml:"error"`
+	Skipped   *junitFailure `xml:"skipped"`
+}
+
+type junitSuite struct {
+	Name   string       `xml:"name,attr"`
+	Cases  []junitCase  `xml:"testcase"`
+	Suites []junitSuite `xml:"testsuite"`
+}
+
+// ParseJUnit reads a JUnit XML report with either a testsuites or a testsuite root
+func ParseJUnit(report []byte) ([]TestCase, error) {
+	var root struct {
+		XMLName xml.Name
+		junitSuite
+	}
+	if err := xml.Unmarshal(report, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse JUnit report: %w", err)
+	}
+
+	var tests []TestCase
+	var walk func(suite junitSuite)
+	walk = func(suite junitSuite) {
+		for _, c := range suite.Cases {
+			test := TestCase{Name: c.Name, Group: c.ClassName, Status: StatusPassed}
+			if test.Group == "" {
+				test.Group = suite.Name
+			}
+			test.Duration, _ = strconv.ParseFloat(c.Time, 64)
+
+			switch {
+			case c.Failure != nil:
+				test.Status = StatusFailed
+				test.Message = failureText(c.Failure)
+			case c.Error != nil:
+				test.Status = StatusError
+				test.Message = failureText(c.Error)
+			case c.Skipped != nil:
+				test.Status = StatusSkipped
+				test.Message = failureText(c.Skipped)
+			}
+			tests = append(tests, test)
+		}
+		for _, child := range suite.Suites {
+			walk(child)
+		}
+	}
+	walk(root.junitSuite)
+
+	return tests, nil
+}
+
+func failureText(f *junitFailure) string {
+	text := strings.TrimSpace(f.Message)
+	if detail := strings.TrimSpace(f.Text); detail != "" {
+		if text != "" {
+			text += "\n"
+		}
+		text += detail
+	}
+	return truncate(text, maxMessageBytes)
+}
+
+// goTestEvent is one line of go test -json output
+type goTestEvent struct {
+	Action  string  `json:"Action"`
+	Package string  `json:"Package"`
+	Test    string  `json:"Test"`
+	Output  string  `json:"Output"`
+	Elapsed float64 `json:"Elapsed"`
+}
+
+// ParseGoTest reads go test -json output. A package that fails without reporting any test,
+// such as one that does not compile, is recorded as a single error.
+func ParseGoTest(output []byte) []TestCase {
+	var tests []TestCase
+	outputs := map[string]*strings.Builder{}
+	packageTests := map[string]int{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var event goTestEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			continue // go test prints build errors as plain text before the JSON
+		}
+
+		key := event.Package + "\x00" + event.Test
+		switch event.Action {
+		case "output":
+			if outputs[key] == nil {
+				outputs[key] = &strings.Builder{}
+			}
+			if outputs[key].Len() < maxMessageBytes {
+				outputs[key].WriteString(event.Output)
+			}
+		case "pass", "fail", "skip":
+			if event.Test == "" {
+				if event.Action == "fail" && packageTests[event.Package] == 0 {
+					tests = append(tests, TestCase{
+						Name:    event.Package,
+						Group:   event.Package,
+						Status:  StatusError,
+						Message: truncate(strings.TrimSpace(outputText(outputs[key])), maxMessageBytes),
+					})
+				}
+				continue
+			}
+
+			packageTests[event.Package]++
+			test := TestCase{Name: event.Test, Group: event.Package, Duration: event.Elapsed}
+			switch event.Action {
+			case "pass":
+				test.Status = StatusPassed
+			case "fail":
+				test.Status = StatusFailed
+				test.Message = truncate(strings.TrimSpace(outputText(outputs[key])), maxMessageBytes)
+			case "skip":
+				test.Status = StatusSkipped
+			}
+			tests = append(tests, test)
+		}
+	}
+
+	// A test with subtests passes or fails with them, so only the subtests are counted
+	parents := map[string]bool{}
+	for _, test := range tests {
+		if i := strings.LastIndex(test.Name, "/"); i > 0 {
+			parents[test.Group+"\x00"+test.Name[:i]] = true
+		}
+	}
+	leaves := tests[:0]
+	for _, test := range tests {
+		if !parents[test.Group+"\x00"+test.Name] {
+			leaves = append(leaves, test)
+		}
+	}
+	return leaves
+}
+
+func outputText(b *strings.Builder) string {
+	if b == nil {
+		return ""
+	}
+	return b.String()
+}
+
+func appendMessage(test *TestCase, line string) {
+	if line == "" || len(test.Message) >= maxMessageBytes {
+		return
+	}
+	if test.Message != "" {
+		test.Message += "\n"
+	}
+	test.Message = truncate(test.Message+line, maxMessageBytes)
+}
+
+// truncate cuts text to limit bytes without splitting a character
+func truncate(text string, limit int) string {
+	if len(text) <= limit {
+		return text
+	}
+	return strings.ToValidUTF8(text[:limit], "") + "..."
+}
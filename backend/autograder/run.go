@@ -0,0 +1,405 @@
+package autograder
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Result is the outcome of running a suite against one submission
+type Result struct {
+	SuiteID      string `json:"suite_id"`
+	SuiteName    string `json:"suite_name"`
+	Language     string `json:"language"`
+	CourseID     string `json:"course_id,omitempty"`
+	AssignmentID string `json:"assignment_id,omitempty"`
+	UserID       int    `json:"user_id"`
+	SubmissionID int    `json:"submission_id,omitempty"`
+	Attempt      int    `json:"attempt"`
+
+	Files []string    `json:"files"` // Student files placed in the workspace
+	Build *StepResult `json:"build,omitempty"`
+	Test  *StepResult `json:"test,omitempty"`
+
+	Tests          []TestCase `json:"tests"`
+	Summary        Summary    `json:"summary"`
+	Score          *float64   `json:"suggested_score"` // Nil when there were no tests to score
+	PointsPossible float64    `json:"points_possible"`
+
+	Warnings   []string  `json:"warnings,omitempty"`
+	Error      string    `json:"error,omitempty"` // Why the suite could not run at all
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+}
+
+// BuildFailed reports whether the build step ran and failed
+func (r Result) BuildFailed() bool {
+	return r.Build != nil && !r.Build.Succeeded()
+}
+
+// Sandboxed runs are CPU-bound, so only a few run at once
+var runSlots = make(chan struct{}, max(1, runtime.NumCPU()/2))
+
+// Run builds and tests a submission in a temporary workspace that is removed afterwards.
+// pointsPossible is used when the suite does not set its own points. Failures of the
+// student's code are part of the result; an error means the suite could not be run.
+func Run(ctx context.Context, suite Suite, sources []Source, pointsPossible float64) (Result, error) {
+	result := Result{
+		SuiteID:        suite.ID,
+		SuiteName:      suite.Name,
+		Language:       suite.Language,
+		PointsPossible: pointsPossible,
+		Tests:          []TestCase{},
+		StartedAt:      time.Now(),
+	}
+	if suite.Points > 0 {
+		result.PointsPossible = suite.Points
+	}
+
+	select {
+	case runSlots <- struct{}{}:
+		defer func() { <-runSlots }()
+	case <-ctx.Done():
+		return result, ctx.Err()
+	}
+
+	dir, err := newRunDir("auxa-autograder-")
+	if err != nil {
+		return result, err
+	}
+	defer os.RemoveAll(dir)
+
+	files, warnings, err := prepareWorkspace(filepath.Join(dir, workDir), suite, sources)
+	result.Files = files
+	result.Warnings = append(result.Warnings, warnings...)
+	if err != nil {
+		return finish(result, suite), err
+	}
+
+	declared := declaredTests(suite)
+	env, err := prepareHarness(filepath.Join(dir, harnessDir), suite, declared)
+	if err != nil {
+		return finish(result, suite), fmt.Errorf("failed to prepare test runner: %w", err)
+	}
+
+	base := step{Dir: dir, Language: suite.Language, Limits: suite.Limits, Env: env}
+	for name := range suite.Files {
+		clean, _ := cleanRelativePath(name)
+		base.Protect = append(base.Protect, clean)
+	}
+
+	if suite.BuildCommand != "-" {
+		buildStep := base
+		buildStep.Command = suite.BuildCommand
+		build, err := runStep(ctx, buildStep)
+		result.Build = &build
+		if err != nil {
+			return finish(result, suite), err
+		}
+		if !build.Succeeded() {
+			return finish(result, suite), nil
+		}
+
+		if vet := defaults[suite.Language].Vet; vet != "" {
+			vetStep := base
+			vetStep.Command = vet
+			checked, err := runStep(ctx, vetStep)
+			if err != nil {
+				return finish(result, suite), err
+			}
+			if !checked.Succeeded() {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("%s reported problems, which do not fail the build:\n%s",
+					vet, truncate(strings.TrimSpace(checked.Stderr+"\n"+checked.Stdout), maxMessageBytes)))
+			}
+		}
+	}
+
+	// Only the test step can write results, into a directory made after the build
+	if err := os.Mkdir(filepath.Join(dir, resultsDir), 0o755); err != nil {
+		return finish(result, suite), fmt.Errorf("failed to create results directory: %w", err)
+	}
+	testStep := base
+	testStep.Command = suite.TestCommand
+	testStep.Results = true
+	test, err := runStep(ctx, testStep)
+	result.Test = &test
+	if err != nil {
+		return finish(result, suite), err
+	}
+
+	reports := readResults(filepath.Join(dir, resultsDir), &result)
+	var tests []TestCase
+	switch suite.Format {
+	case FormatTAP:
+		tests = ParseTAP(reports[resultsTAP])
+	case FormatGoTest:
+		tests = ParseGoTest(reports[resultsGoTest])
+	case FormatJUnit:
+		tests = parseJUnitReports(reports, &result)
+	}
+	result.Tests = declared.filter(tests, &result)
+
+	if test.TimedOut {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("Tests were stopped after %d seconds", suite.Limits.TimeSeconds))
+	}
+	if len(result.Tests) == 0 && !test.Succeeded() {
+		result.Tests = []TestCase{{
+			Name:    "test run",
+			Status:  StatusError,
+			Message: truncate(strings.TrimSpace(test.Stderr+"\n"+test.Stdout), maxMessageBytes),
+		}}
+	}
+
+	return finish(result, suite), nil
+}
+
+// Command is a single command for callers that bring their own runner
+type Command struct {
+	Language string
+	Command  string
+	Files    map[string][]byte // Workspace files by relative path
+	Harness  map[string][]byte // Read-only runner files under $AUXA_HARNESS, by name
+	Limits   Limits            // Zero values take the language's defaults
+}
+
+// RunCommand runs one command in a fresh sandboxed workspace. It returns the files the
+// command wrote to $AUXA_RESULTS, by name.
+func RunCommand(ctx context.Context, command Command) (StepResult, map[string][]byte, error) {
+	failed := StepResult{Command: command.Command}
+	d, ok := defaults[command.Language]
+	if !ok {
+		return failed, nil, fmt.Errorf("unsupported language: %q", command.Language)
+	}
+
+	select {
+	case runSlots <- struct{}{}:
+		defer func() { <-runSlots }()
+	case <-ctx.Done():
+		return failed, nil, ctx.Err()
+	}
+
+	dir, err := newRunDir("auxa-sandbox-")
+	if err != nil {
+		return failed, nil, err
+	}
+	defer os.RemoveAll(dir)
+	if err := os.Mkdir(filepath.Join(dir, resultsDir), 0o755); err != nil {
+		return failed, nil, fmt.Errorf("failed to create results directory: %w", err)
+	}
+
+	w := &workspace{dir: filepath.Join(dir, workDir), files: map[string]bool{}}
+	for name, data := range command.Files {
+		clean, err := cleanRelativePath(name)
+		if err != nil {
+			return failed, nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if err := w.write(clean, bytes.NewReader(data)); err != nil {
+			return failed, nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	for name, data := range command.Harness {
+		if strings.ContainsAny(name, "/\\") {
+			return failed, nil, fmt.Errorf("harness file %q must not be in a subdirectory", name)
+		}
+		if err := os.WriteFile(filepath.Join(dir, harnessDir, name), data, 0o644); err != nil {
+			return failed, nil, err
+		}
+	}
+
+	result, err := runStep(ctx, step{
+		Command:  command.Command,
+		Dir:      dir,
+		Language: command.Language,
+		Limits:   command.Limits.withDefaults(d.Limits),
+		Results:  true,
+	})
+	if err != nil {
+		return result, nil, err
+	}
+	return result, readResults(filepath.Join(dir, resultsDir), nil), nil
+}
+
+// newRunDir creates a run's temporary directory with its workspace and harness
+func newRunDir(prefix string) (string, error) {
+	dir, err := os.MkdirTemp("", prefix)
+	if err != nil {
+		return "", fmt.Errorf("failed to create workspace: %w", err)
+	}
+	for _, sub := range []string{workDir, harnessDir, "home", "tmp"} {
+		if err := os.Mkdir(filepath.Join(dir, sub), 0o755); err != nil {
+			os.RemoveAll(dir)
+			return "", fmt.Errorf("failed to create workspace: %w", err)
+		}
+	}
+	return dir, nil
+}
+
+// finish summarizes and scores a result
+func finish(result Result, suite Suite) Result {
+	if result.Tests == nil {
+		result.Tests = []TestCase{}
+	}
+	result.Summary = Summarize(result.Tests)
+	result.Score = suggestScore(result, suite.Weights)
+	result.FinishedAt = time.Now()
+	return result
+}
+
+// suggestScore awards points in proportion to the weight of passing tests; skipped tests do
+// not count. A failed build scores zero.
+func suggestScore(result Result, weights map[string]float64) *float64 {
+	if result.BuildFailed() {
+		zero := 0.0
+		return &zero
+	}
+
+	var earned, total float64
+	for _, test := range result.Tests {
+		if test.Status == StatusSkipped {
+			continue
+		}
+		weight := 1.0
+		if w, ok := weights[test.Name]; ok {
+			weight = w
+		}
+		total += weight
+		if test.Status == StatusPassed {
+			earned += weight
+		}
+	}
+	if total == 0 {
+		return nil
+	}
+
+	score := math.Round(result.PointsPossible*earned/total*100) / 100
+	return &score
+}
+
+// readResults reads the regular files a step wrote to its results directory. Links are
+// ignored so results cannot point elsewhere.
+func readResults(dir string, result *Result) map[string][]byte {
+	reports := map[string][]byte{}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return reports
+	}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			if result != nil {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("Could not read %s: %v", entry.Name(), err))
+			}
+			continue
+		}
+		reports[entry.Name()] = data
+	}
+	return reports
+}
+
+func parseJUnitReports(reports map[string][]byte, result *Result) []TestCase {
+	names := make([]string, 0, len(reports))
+	for name := range reports {
+		if strings.HasSuffix(name, ".xml") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var tests []TestCase
+	for _, name := range names {
+		parsed, err := ParseJUnit(reports[name])
+		if err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		tests = append(tests, parsed...)
+	}
+	return tests
+}
+
+// languageEnv adds the variables each language's tools need. Go gets a build cache of its
+// own in the step's home directory, so one submission cannot poison another's builds.
+func languageEnv(language string, paths sandboxPaths) []string {
+	var env []string
+	switch language {
+	case LanguageJava:
+		env = append(env, "AUXA_JUNIT_JAR="+os.Getenv("AUXA_JUNIT_JAR"))
+		if javaHome := os.Getenv("JAVA_HOME"); javaHome != "" {
+			env = append(env, "JAVA_HOME="+javaHome)
+		}
+	case LanguageGo:
+		env = append(env,
+			"GOCACHE="+filepath.Join(paths.Home, ".cache", "go-build"),
+			"GOPATH="+filepath.Join(paths.Home, "go"),
+			"GOPROXY=off",
+			"GOFLAGS=-mod=mod",
+			"GOTOOLCHAIN=local",
+			"CGO_ENABLED=0",
+		)
+	}
+	return env
+}
+
+// Evidence renders a result for a grading prompt
+func Evidence(result Result) string {
+	var b strings.Builder
+
+	b.WriteString("AUTOGRADER RESULTS:\n")
+	fmt.Fprintf(&b, "The instructor's test suite %q (%s) was run against this submission.\n", result.SuiteName, result.Language)
+
+	if result.Build != nil {
+		if result.Build.Succeeded() {
+			b.WriteString("Build: succeeded\n")
+		} else {
+			status := fmt.Sprintf("exit code %d", result.Build.ExitCode)
+			if result.Build.TimedOut {
+				status = "timed out"
+			}
+			fmt.Fprintf(&b, "Build: FAILED (%s)\n%s\n", status, truncate(strings.TrimSpace(result.Build.Stderr+"\n"+result.Build.Stdout), maxMessageBytes))
+		}
+	}
+
+	s := result.Summary
+	fmt.Fprintf(&b, "Tests: %d of %d passed (%d failed, %d errors, %d skipped)\n", s.Passed, s.Total, s.Failed, s.Errors, s.Skipped)
+	if result.Score != nil {
+		fmt.Fprintf(&b, "Suggested score from tests: %g/%g\n", *result.Score, result.PointsPossible)
+	}
+	for _, warning := range result.Warnings {
+		b.WriteString("Note: " + warning + "\n")
+	}
+
+	var passed []string
+	failedHeader := false
+	for _, test := range result.Tests {
+		switch test.Status {
+		case StatusPassed:
+			passed = append(passed, test.Name)
+		case StatusFailed, StatusError:
+			if !failedHeader {
+				b.WriteString("\nFAILING TESTS:\n")
+				failedHeader = true
+			}
+			fmt.Fprintf(&b, "- %s (%s)", test.Name, test.Status)
+			if test.Message != "" {
+				fmt.Fprintf(&b, ": %s", truncate(test.Message, 500))
+			}
+			b.WriteString("\n")
+		}
+	}
+	if len(passed) > 0 {
+		b.WriteString("\nPASSING TESTS: " + strings.Join(passed, ", ") + "\n")
+	}
+
+	b.WriteString("\nTreat these results as evidence of correctness. Explain failing tests in terms the student can act on, and still assess the parts of the rubric that tests cannot measure.")
+	return b.String()
+}
@@ -0,0 +1,220 @@
+package autograder
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Output kept per stream of each step
+const maxStepOutputBytes = 256 * 1024
+
+// allowUnsandboxedEnv lets steps run without the sandbox on hosts that cannot provide it.
+// Student code then runs as the backend's user with only resource limits around it.
+const allowUnsandboxedEnv = "AUXA_AUTOGRADER_ALLOW_UNSANDBOXED"
+
+// extraMountsEnv lists further host paths, separated by colons, that steps may read, such
+// as a toolchain installed under the home directory
+const extraMountsEnv = "AUXA_AUTOGRADER_MOUNTS"
+
+// ErrNoIsolation is returned when the host cannot run steps in the sandbox
+var ErrNoIsolation = errors.New("the autograder sandbox is unavailable on this host (it needs unprivileged user, mount and PID namespaces); set " + allowUnsandboxedEnv + "=1 to run tests without it")
+
+// Paths steps see inside the sandbox
+const (
+	sandboxWork    = "/work"
+	sandboxHome    = "/home/student"
+	sandboxTmp     = "/tmp"
+	sandboxHarness = "/harness"
+	sandboxResults = "/results"
+)
+
+// Subdirectories of a run's temporary directory
+const (
+	workDir    = "work"
+	harnessDir = "harness"
+	resultsDir = "results"
+)
+
+// StepResult is the outcome of running one command in the sandbox
+type StepResult struct {
+	Command  string  `json:"command"`
+	ExitCode int     `json:"exit_code"`
+	Stdout   string  `json:"stdout"`
+	Stderr   string  `json:"stderr"`
+	TimedOut bool    `json:"timed_out"`
+	Duration float64 `json:"duration_seconds"`
+	Isolated bool    `json:"sandboxed"`
+}
+
+// Succeeded reports whether the step exited cleanly in time
+func (s StepResult) Succeeded() bool {
+	return s.ExitCode == 0 && !s.TimedOut
+}
+
+// step is one command to run against a run directory
+type step struct {
+	Command  string
+	Dir      string // The run's temporary directory
+	Language string
+	Limits   Limits
+	Env      []string // Variables on top of the sandbox's own
+	// Workspace paths mounted read-only, so the suite's files cannot be rewritten
+	Protect []string
+	// Whether the results directory is mounted; only the test step reports results
+	Results bool
+}
+
+// sandboxPaths are where a step finds its directories
+type sandboxPaths struct {
+	Work, Home, Tmp, Harness, Results string
+}
+
+func isolatedPaths() sandboxPaths {
+	return sandboxPaths{
+		Work:    sandboxWork,
+		Home:    sandboxHome,
+		Tmp:     sandboxTmp,
+		Harness: sandboxHarness,
+		Results: sandboxResults,
+	}
+}
+
+// hostPaths are used when steps run without the sandbox
+func hostPaths(dir string) sandboxPaths {
+	return sandboxPaths{
+		Work:    filepath.Join(dir, workDir),
+		Home:    filepath.Join(dir, "home"),
+		Tmp:     filepath.Join(dir, "tmp"),
+		Harness: filepath.Join(dir, harnessDir),
+		Results: filepath.Join(dir, resultsDir),
+	}
+}
+
+// baseEnv is the environment every step sees; nothing else is inherited from the backend
+func baseEnv(paths sandboxPaths) []string {
+	return []string{
+		"PATH=" + os.Getenv("PATH"),
+		"HOME=" + paths.Home,
+		"TMPDIR=" + paths.Tmp,
+		"LANG=C.UTF-8",
+		"AUXA_HARNESS=" + paths.Harness,
+		"AUXA_RESULTS=" + paths.Results,
+	}
+}
+
+// readOnlyMounts lists the host paths steps may read: the system directories, everything on
+// PATH and the toolchains the languages need. Home directories and Auxa's own data are not
+// among them.
+func readOnlyMounts() []string {
+	candidates := []string{"/usr", "/bin", "/sbin", "/lib", "/lib32", "/lib64", "/libx32", "/etc", "/opt"}
+	candidates = append(candidates, filepath.SplitList(os.Getenv("PATH"))...)
+	if goBin, err := exec.LookPath("go"); err == nil {
+		if resolved, err := filepath.EvalSymlinks(goBin); err == nil {
+			candidates = append(candidates, filepath.Dir(filepath.Dir(resolved)))
+		}
+	}
+	for _, name := range []string{"JAVA_HOME", "AUXA_JUNIT_JAR"} {
+		if value := os.Getenv(name); value != "" {
+			candidates = append(candidates, value)
+		}
+	}
+	candidates = append(candidates, filepath.SplitList(os.Getenv(extraMountsEnv))...)
+
+	var mounts []string
+	for _, candidate := range candidates {
+		if !filepath.IsAbs(candidate) {
+			continue
+		}
+		// Keep symlinked top-level directories such as /bin -> usr/bin as links
+		if info, err := os.Lstat(candidate); err != nil || (info.Mode()&os.ModeSymlink != 0 && filepath.Dir(candidate) != "/") {
+			resolved, err := filepath.EvalSymlinks(candidate)
+			if err != nil {
+				continue
+			}
+			candidate = resolved
+		}
+		mounts = append(mounts, filepath.Clean(candidate))
+	}
+
+	// Drop paths already covered by a parent mount
+	var kept []string
+	for _, mount := range mounts {
+		covered := false
+		for _, other := range mounts {
+			if other != mount && strings.HasPrefix(mount, strings.TrimSuffix(other, "/")+"/") {
+				covered = true
+				break
+			}
+		}
+		if !covered && !contains(kept, mount) {
+			kept = append(kept, mount)
+		}
+	}
+	return kept
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// RunSandboxHelper sets up the sandbox and runs a step's command when the backend was
+// re-executed as the sandbox helper, and returns straight away otherwise. Call it first in
+// main.
+func RunSandboxHelper() {
+	if len(os.Args) < 3 || os.Args[1] != sandboxHelperArg {
+		return
+	}
+	err := enterSandbox(os.Args[2])
+	fmt.Fprintf(os.Stderr, "autograder sandbox: %v\n", err)
+	os.Exit(126)
+}
+
+// sandboxHelperArg marks the backend's re-execution as the sandbox helper
+const sandboxHelperArg = "__auxa_autograder_sandbox"
+
+// cappedBuffer keeps the first max bytes written to it and drops the rest
+type cappedBuffer struct {
+	buf       bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.buf.Len(); room > 0 {
+		if len(p) > room {
+			b.buf.Write(p[:room])
+			b.truncated = true
+		} else {
+			b.buf.Write(p)
+		}
+	} else if len(p) > 0 {
+		b.truncated = true
+	}
+	return len(p), nil
+}
+
+func (b *cappedBuffer) String() string {
+	if b.truncated {
+		return b.buf.String() + "\n... (output truncated)"
+	}
+	return b.buf.String()
+}
+
+func allowUnisolated() bool {
+	return os.Getenv(allowUnsandboxedEnv) == "1"
+}
+
+func seconds(d time.Duration) float64 {
+	return float64(d.Milliseconds()) / 1000
+}
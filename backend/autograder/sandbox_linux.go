@@ -0,0 +1,348 @@
+//go:build linux
+
+package autograder
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// Constants the syscall package does not define
+const (
+	rlimitNproc       = 6
+	prSetNoNewPrivs   = 38
+	capabilityVersion = 0x20080522
+)
+
+// Mount flags that must be kept when remounting a bind read-only inside a user namespace
+const lockedMountFlags = syscall.MS_NOSUID | syscall.MS_NODEV | syscall.MS_NOEXEC |
+	syscall.MS_NOATIME | syscall.MS_NODIRATIME | syscall.MS_RELATIME
+
+// Sizes of the private tmpfs mounts: the new root only holds mount points, while /tmp and
+// the home directory hold build caches
+const (
+	rootTmpfsOptions = "mode=0755,size=16m"
+	tmpTmpfsMB       = 512
+)
+
+// sandboxSpec tells the helper what to mount and run
+type sandboxSpec struct {
+	Dir     string   `json:"dir"`
+	Command string   `json:"command"`
+	Limits  Limits   `json:"limits"`
+	Mounts  []string `json:"mounts"`
+	Protect []string `json:"protect"`
+	Results bool     `json:"results"`
+}
+
+var (
+	sandboxOnce  sync.Once
+	sandboxError error
+)
+
+// sandboxAvailable reports whether the sandbox works on this host by running an empty step
+// in it once
+func sandboxAvailable() error {
+	sandboxOnce.Do(func() {
+		dir, err := newRunDir("auxa-sandbox-check-")
+		if err != nil {
+			sandboxError = err
+			return
+		}
+		defer os.RemoveAll(dir)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		cmd, err := isolatedCommand(ctx, step{Command: "true", Dir: dir, Limits: defaults[LanguageC].Limits})
+		if err != nil {
+			sandboxError = fmt.Errorf("%w: %v", ErrNoIsolation, err)
+			return
+		}
+		if output, err := cmd.CombinedOutput(); err != nil {
+			sandboxError = fmt.Errorf("%w: %v %s", ErrNoIsolation, err, output)
+		}
+	})
+	return sandboxError
+}
+
+// isolatedCommand re-executes the backend as the sandbox helper in new user, mount, PID,
+// network, IPC and UTS namespaces. The step runs as root inside them, which maps to the
+// backend's own user, with no capabilities.
+func isolatedCommand(ctx context.Context, s step) (*exec.Cmd, error) {
+	self, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	spec, err := json.Marshal(sandboxSpec{
+		Dir:     s.Dir,
+		Command: s.Command,
+		Limits:  s.Limits,
+		Mounts:  readOnlyMounts(),
+		Protect: s.Protect,
+		Results: s.Results,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	paths := isolatedPaths()
+	cmd := exec.CommandContext(ctx, self, sandboxHelperArg, string(spec))
+	cmd.Dir = s.Dir
+	cmd.Env = append(append(baseEnv(paths), languageEnv(s.Language, paths)...), s.Env...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags: syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWPID |
+			syscall.CLONE_NEWNET | syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS,
+		UidMappings: []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getuid(), Size: 1}},
+		GidMappings: []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getgid(), Size: 1}},
+		Setpgid:     true,
+		Pdeathsig:   syscall.SIGKILL,
+	}
+	return cmd, nil
+}
+
+// enterSandbox runs in the helper. It builds a new root from read-only binds of the host's
+// system directories, the workspace and private tmpfs mounts, pivots into it, applies the
+// limits, drops every capability and execs the step's command, which becomes PID 1 so that
+// nothing it starts outlives it. It only returns on failure.
+func enterSandbox(encoded string) error {
+	var spec sandboxSpec
+	if err := json.Unmarshal([]byte(encoded), &spec); err != nil {
+		return err
+	}
+
+	// Capabilities belong to threads, so everything through exec happens on this one
+	runtime.LockOSThread()
+
+	// Keep the mounts below from propagating back to the host
+	if err := syscall.Mount("", "/", "", syscall.MS_REC|syscall.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("make mounts private: %w", err)
+	}
+
+	// Each step's mounts live in its own namespace, so steps can share the mount point
+	root := filepath.Join(spec.Dir, "root")
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return err
+	}
+	if err := syscall.Mount("tmpfs", root, "tmpfs", syscall.MS_NOSUID|syscall.MS_NODEV, rootTmpfsOptions); err != nil {
+		return fmt.Errorf("mount root: %w", err)
+	}
+
+	for _, source := range spec.Mounts {
+		info, err := os.Lstat(source)
+		if err != nil {
+			continue
+		}
+		target := filepath.Join(root, source)
+		if info.Mode()&os.ModeSymlink != 0 {
+			link, err := os.Readlink(source)
+			if err != nil {
+				return err
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			if err := os.Symlink(link, target); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := bindMount(source, target, info.IsDir(), true); err != nil {
+			return err
+		}
+	}
+
+	if err := mountDevices(filepath.Join(root, "dev")); err != nil {
+		return err
+	}
+	if err := os.Mkdir(filepath.Join(root, "proc"), 0o555); err != nil {
+		return err
+	}
+	if err := syscall.Mount("proc", filepath.Join(root, "proc"), "proc", syscall.MS_NOSUID|syscall.MS_NODEV|syscall.MS_NOEXEC, ""); err != nil {
+		return fmt.Errorf("mount /proc: %w", err)
+	}
+
+	work := filepath.Join(root, sandboxWork)
+	if err := bindMount(filepath.Join(spec.Dir, workDir), work, true, false); err != nil {
+		return err
+	}
+	for _, name := range spec.Protect {
+		protected := filepath.Join(work, filepath.FromSlash(name))
+		if err := bindMount(protected, protected, false, true); err != nil {
+			return err
+		}
+	}
+	if err := bindMount(filepath.Join(spec.Dir, harnessDir), filepath.Join(root, sandboxHarness), true, true); err != nil {
+		return err
+	}
+	if spec.Results {
+		if err := bindMount(filepath.Join(spec.Dir, resultsDir), filepath.Join(root, sandboxResults), true, false); err != nil {
+			return err
+		}
+	}
+	for _, private := range []string{sandboxHome, sandboxTmp} {
+		target := filepath.Join(root, private)
+		if err := os.MkdirAll(target, 0o755); err != nil {
+			return err
+		}
+		options := fmt.Sprintf("mode=1777,size=%dm", tmpTmpfsMB)
+		if err := syscall.Mount("tmpfs", target, "tmpfs", syscall.MS_NOSUID|syscall.MS_NODEV, options); err != nil {
+			return fmt.Errorf("mount %s: %w", private, err)
+		}
+	}
+
+	// Swap the host's root for the new one and detach the old
+	oldRoot := filepath.Join(root, ".oldroot")
+	if err := os.Mkdir(oldRoot, 0o700); err != nil {
+		return err
+	}
+	if err := syscall.PivotRoot(root, oldRoot); err != nil {
+		return fmt.Errorf("pivot_root: %w", err)
+	}
+	if err := os.Chdir("/"); err != nil {
+		return err
+	}
+	if err := syscall.Unmount("/.oldroot", syscall.MNT_DETACH); err != nil {
+		return fmt.Errorf("detach host root: %w", err)
+	}
+	if err := os.Remove("/.oldroot"); err != nil {
+		return err
+	}
+	if err := syscall.Mount("", "/", "", syscall.MS_REMOUNT|syscall.MS_BIND|syscall.MS_RDONLY|syscall.MS_NOSUID|syscall.MS_NODEV, ""); err != nil {
+		return fmt.Errorf("make root read-only: %w", err)
+	}
+	if err := os.Chdir(sandboxWork); err != nil {
+		return err
+	}
+	if err := syscall.Sethostname([]byte("sandbox")); err != nil {
+		return err
+	}
+
+	if err := setLimits(spec.Limits); err != nil {
+		return err
+	}
+	if err := dropCapabilities(); err != nil {
+		return err
+	}
+	return syscall.Exec("/bin/sh", []string{"sh", "-c", spec.Command}, os.Environ())
+}
+
+// bindMount binds source onto target, creating target as a directory or empty file
+func bindMount(source, target string, dir, readOnly bool) error {
+	if dir {
+		if err := os.MkdirAll(target, 0o755); err != nil {
+			return err
+		}
+	} else {
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(target, os.O_CREATE|os.O_RDONLY, 0o644)
+		if err != nil {
+			return err
+		}
+		f.Close()
+	}
+
+	if err := syscall.Mount(source, target, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+		return fmt.Errorf("bind %s: %w", source, err)
+	}
+	if !readOnly {
+		return nil
+	}
+
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(target, &stat); err != nil {
+		return err
+	}
+	flags := uintptr(syscall.MS_BIND|syscall.MS_REMOUNT|syscall.MS_RDONLY) | uintptr(stat.Flags)&lockedMountFlags
+	if err := syscall.Mount("", target, "", flags, ""); err != nil {
+		return fmt.Errorf("make %s read-only: %w", source, err)
+	}
+	return nil
+}
+
+// mountDevices gives the sandbox a minimal /dev of the host's harmless devices
+func mountDevices(dev string) error {
+	if err := os.Mkdir(dev, 0o755); err != nil {
+		return err
+	}
+	if err := syscall.Mount("tmpfs", dev, "tmpfs", syscall.MS_NOSUID|syscall.MS_NOEXEC, "mode=0755,size=64k"); err != nil {
+		return fmt.Errorf("mount /dev: %w", err)
+	}
+	for _, name := range []string{"null", "zero", "full", "random", "urandom"} {
+		if err := bindMount("/dev/"+name, filepath.Join(dev, name), false, false); err != nil {
+			return err
+		}
+	}
+	links := map[string]string{"fd": "/proc/self/fd", "stdin": "/proc/self/fd/0", "stdout": "/proc/self/fd/1", "stderr": "/proc/self/fd/2"}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(dev, name)); err != nil {
+			return err
+		}
+	}
+
+	shm := filepath.Join(dev, "shm")
+	if err := os.Mkdir(shm, 0o1777); err != nil {
+		return err
+	}
+	if err := syscall.Mount("tmpfs", shm, "tmpfs", syscall.MS_NOSUID|syscall.MS_NODEV, "mode=1777,size=64m"); err != nil {
+		return fmt.Errorf("mount /dev/shm: %w", err)
+	}
+	return nil
+}
+
+// setLimits applies the step's limits. The process limit only counts processes inside the
+// sandbox's user namespace, so a fork bomb stops there.
+func setLimits(l Limits) error {
+	limits := []struct {
+		resource int
+		value    uint64
+	}{
+		{syscall.RLIMIT_CPU, uint64(l.CPUSeconds)},
+		{syscall.RLIMIT_AS, uint64(l.MemoryMB) << 20},
+		{syscall.RLIMIT_FSIZE, uint64(l.FileSizeMB) << 20},
+		{rlimitNproc, uint64(l.Processes)},
+	}
+	for _, limit := range limits {
+		if err := syscall.Setrlimit(limit.resource, &syscall.Rlimit{Cur: limit.value, Max: limit.value}); err != nil {
+			return fmt.Errorf("set limit %d: %w", limit.resource, err)
+		}
+	}
+	return nil
+}
+
+// dropCapabilities clears the bounding set and every capability so the command cannot undo
+// the read-only mounts, even though it runs as root inside the namespace
+func dropCapabilities() error {
+	for capability := 0; capability <= 63; capability++ {
+		_, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, syscall.PR_CAPBSET_DROP, uintptr(capability), 0)
+		if errno == syscall.EINVAL {
+			break // Past the last capability this kernel knows
+		}
+		if errno != 0 {
+			return fmt.Errorf("drop capability %d: %w", capability, errno)
+		}
+	}
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prSetNoNewPrivs, 1, 0); errno != 0 {
+		return fmt.Errorf("set no_new_privs: %w", errno)
+	}
+
+	header := struct {
+		version uint32
+		pid     int32
+	}{version: capabilityVersion}
+	var data [2]struct{ effective, permitted, inheritable uint32 }
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_CAPSET, uintptr(unsafe.Pointer(&header)), uintptr(unsafe.Pointer(&data[0])), 0); errno != 0 {
+		return fmt.Errorf("clear capabilities: %w", errno)
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+//go:build !unix
+
+package autograder
+
+import (
+	"context"
+	"errors"
+)
+
+// runStep is unavailable without Unix process groups and resource limits
+func runStep(ctx context.Context, s step) (StepResult, error) {
+	return StepResult{Command: s.Command}, errors.New("the autograder sandbox needs a Unix host")
+}
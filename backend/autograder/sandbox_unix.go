@@ -0,0 +1,89 @@
+//go:build unix
+
+package autograder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// runStep runs a step's shell command in the sandbox, or, where the host cannot provide one
+// and running without it is allowed, under ulimit alone. The whole process group is killed
+// when the wall-clock limit passes.
+func runStep(ctx context.Context, s step) (StepResult, error) {
+	result := StepResult{Command: s.Command}
+	if err := sandboxAvailable(); err == nil {
+		result.Isolated = true
+	} else if !allowUnisolated() {
+		return result, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(s.Limits.TimeSeconds)*time.Second)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if result.Isolated {
+		var err error
+		if cmd, err = isolatedCommand(ctx, s); err != nil {
+			return result, err
+		}
+	} else {
+		cmd = unisolatedCommand(ctx, s)
+	}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = 5 * time.Second
+
+	stdout := &cappedBuffer{max: maxStepOutputBytes}
+	stderr := &cappedBuffer{max: maxStepOutputBytes}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	started := time.Now()
+	err := cmd.Run()
+	result.Duration = seconds(time.Since(started))
+	result.Stdout = stdout.String()
+	result.Stderr = stderr.String()
+	result.TimedOut = errors.Is(ctx.Err(), context.DeadlineExceeded)
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+		if result.ExitCode < 0 {
+			result.ExitCode = 128 + int(exitErr.Sys().(syscall.WaitStatus).Signal())
+		}
+	case result.TimedOut:
+		result.ExitCode = -1
+	default:
+		return result, fmt.Errorf("failed to run %q: %w", s.Command, err)
+	}
+
+	// The parent context being cancelled is not a step failure; report it to the caller
+	if ctx.Err() != nil && !result.TimedOut {
+		return result, ctx.Err()
+	}
+	return result, nil
+}
+
+// unisolatedCommand runs a step as the backend's own user with only ulimit around it. There
+// is no process limit, since ulimit -u would count every process the user runs.
+func unisolatedCommand(ctx context.Context, s step) *exec.Cmd {
+	paths := hostPaths(s.Dir)
+
+	// ulimit -f counts 512-byte blocks and -v kilobytes
+	script := fmt.Sprintf("ulimit -t %d && ulimit -v %d && ulimit -f %d && exec sh -c \"$1\"",
+		s.Limits.CPUSeconds, s.Limits.MemoryMB*1024, s.Limits.FileSizeMB*2048)
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", script, "autograder", s.Command)
+	cmd.Dir = paths.Work
+	cmd.Env = append(append(baseEnv(paths), languageEnv(s.Language, paths)...), s.Env...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	return cmd
+}
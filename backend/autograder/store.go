@@ -0,0 +1,210 @@
+package autograder
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"auxa/store"
+)
+
+// SuiteStore persists test suites
+type SuiteStore struct {
+	mu     sync.RWMutex
+	path   string
+	suites map[string]Suite
+}
+
+// LoadSuiteStore reads suites from path
+func LoadSuiteStore(path string) (*SuiteStore, error) {
+	s := &SuiteStore{path: path, suites: make(map[string]Suite)}
+	if err := store.LoadJSON(path, &s.suites); err != nil {
+		return nil, err
+	}
+	if s.suites == nil {
+		s.suites = make(map[string]Suite)
+	}
+	return s, nil
+}
+
+func (s *SuiteStore) save() error {
+	return store.SaveJSON(s.path, s.suites)
+}
+
+// List returns suites, optionally filtered by course and assignment, sorted by name
+func (s *SuiteStore) List(courseID, assignmentID string) []Suite {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// Initialize as empty slice to ensure JSON returns [] instead of null
+	list := make([]Suite, 0, len(s.suites))
+	for _, suite := range s.suites {
+		if courseID != "" && suite.CourseID != courseID {
+			continue
+		}
+		if assignmentID != "" && suite.AssignmentID != assignmentID {
+			continue
+		}
+		list = append(list, suite)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Name != list[j].Name {
+			return list[i].Name < list[j].Name
+		}
+		return list[i].ID < list[j].ID
+	})
+	return list
+}
+
+// Get returns a suite by ID
+func (s *SuiteStore) Get(id string) (Suite, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	suite, ok := s.suites[id]
+	return suite, ok
+}
+
+// Create validates and stores a new suite
+func (s *SuiteStore) Create(suite Suite) (Suite, error) {
+	if err := suite.Validate(); err != nil {
+		return Suite{}, err
+	}
+
+	idBytes := make([]byte, 8)
+	if _, err := rand.Read(idBytes); err != nil {
+		return Suite{}, fmt.Errorf("failed to generate suite ID: %w", err)
+	}
+	suite.ID = hex.EncodeToString(idBytes)
+	suite.CreatedAt = time.Now()
+	suite.UpdatedAt = suite.CreatedAt
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.suites[suite.ID] = suite
+	if err := s.save(); err != nil {
+		delete(s.suites, suite.ID)
+		return Suite{}, err
+	}
+	return suite, nil
+}
+
+// Update validates and replaces an existing suite
+func (s *SuiteStore) Update(id string, suite Suite) (Suite, error) {
+	if err := suite.Validate(); err != nil {
+		return Suite{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.suites[id]
+	if !ok {
+		return Suite{}, fmt.Errorf("suite %s not found", id)
+	}
+
+	suite.ID = id
+	suite.CreatedAt = existing.CreatedAt
+	suite.UpdatedAt = time.Now()
+
+	s.suites[id] = suite
+	if err := s.save(); err != nil {
+		s.suites[id] = existing
+		return Suite{}, err
+	}
+	return suite, nil
+}
+
+// Delete removes a suite
+func (s *SuiteStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.suites[id]
+	if !ok {
+		return fmt.Errorf("suite %s not found", id)
+	}
+
+	delete(s.suites, id)
+	if err := s.save(); err != nil {
+		s.suites[id] = existing
+		return err
+	}
+	return nil
+}
+
+// ResultStore keeps the latest result of each suite for each student
+type ResultStore struct {
+	mu      sync.RWMutex
+	path    string
+	results map[string]Result
+}
+
+// LoadResultStore reads results from path
+func LoadResultStore(path string) (*ResultStore, error) {
+	s := &ResultStore{path: path, results: make(map[string]Result)}
+	if err := store.LoadJSON(path, &s.results); err != nil {
+		return nil, err
+	}
+	if s.results == nil {
+		s.results = make(map[string]Result)
+	}
+	return s, nil
+}
+
+func resultKey(suiteID string, userID int) string {
+	return fmt.Sprintf("%s/%d", suiteID, userID)
+}
+
+// Get returns a student's latest result for a suite
+func (s *ResultStore) Get(suiteID string, userID int) (Result, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result, ok := s.results[resultKey(suiteID, userID)]
+	return result, ok
+}
+
+// List returns a suite's results ordered by user ID
+func (s *ResultStore) List(suiteID string) []Result {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// Initialize as empty slice to ensure JSON returns [] instead of null
+	list := make([]Result, 0)
+	for _, result := range s.results {
+		if result.SuiteID == suiteID {
+			list = append(list, result)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].UserID < list[j].UserID })
+	return list
+}
+
+// Save records a result, replacing the student's previous one for the suite
+func (s *ResultStore) Save(result Result) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := resultKey(result.SuiteID, result.UserID)
+	previous, existed := s.results[key]
+	s.results[key] = result
+	if err := s.save(); err != nil {
+		if existed {
+			s.results[key] = previous
+		} else {
+			delete(s.results, key)
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *ResultStore) save() error {
+	return store.SaveJSON(s.path, s.results)
+}
@@ -0,0 +1,196 @@
+// Package autograder runs instructor test suites against programming submissions in a
+// resource-limited subprocess sandbox and turns the results into grading evidence.
+package autograder
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+)
+
+// Supported languages
+const (
+	LanguagePython = "python"
+	LanguageJava   = "java"
+	LanguageC      = "c"
+	LanguageCPP    = "cpp"
+	LanguageGo     = "go"
+)
+
+// Test result formats, read from files the test command writes under $AUXA_RESULTS
+const (
+	FormatTAP    = "tap"    // Test Anything Protocol in results.tap
+	FormatJUnit  = "junit"  // JUnit XML reports, *.xml
+	FormatGoTest = "gotest" // go test -json output in results.json
+)
+
+// Result files for the TAP and go test formats
+const (
+	resultsTAP    = "results.tap"
+	resultsGoTest = "results.json"
+)
+
+// Suite is an instructor's test suite for an assignment. Commands run with sh -c in the
+// workspace, which holds the student's files overlaid with the suite's Files. The suite's
+// files are read-only to both steps, and only the test step can write to $AUXA_RESULTS, so
+// results are never taken from what student code prints.
+type Suite struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	CourseID     string `json:"course_id,omitempty"`
+	AssignmentID string `json:"assignment_id,omitempty"`
+	Language     string `json:"language"`
+
+	// Instructor test files by relative path; they replace student files with the same path
+	Files map[string]string `json:"files"`
+
+	BuildCommand string `json:"build_command,omitempty"` // Defaults by language; "-" skips the build
+	TestCommand  string `json:"test_command,omitempty"`  // Defaults by language
+	Format       string `json:"format,omitempty"`        // Defaults by language
+
+	// Names of the tests that count towards the score. When empty they are taken from the
+	// suite's files for Python, Java and Go; TAP from a suite's own script counts in full.
+	Tests []string `json:"tests,omitempty"`
+
+	Limits Limits `json:"limits"`
+
+	// Points the suite is worth; 0 uses the assignment's points possible
+	Points float64 `json:"points,omitempty"`
+	// Relative weight of individual tests by name; unlisted tests weigh 1
+	Weights map[string]float64 `json:"weights,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Limits bound each build and test step. Zero values take the language's defaults.
+type Limits struct {
+	TimeSeconds int `json:"time_seconds"` // Wall-clock time
+	CPUSeconds  int `json:"cpu_seconds"`
+	MemoryMB    int `json:"memory_mb"`    // Virtual memory per process
+	FileSizeMB  int `json:"file_size_mb"` // Largest file a step may write
+	Processes   int `json:"processes"`    // Processes and threads running at once
+}
+
+// languageDefaults are the commands and limits used when a suite leaves them out
+type languageDefaults struct {
+	Build  string
+	Vet    string // Run after a successful build; problems become warnings
+	Test   string
+	Format string
+	Limits Limits
+}
+
+var defaults = map[string]languageDefaults{
+	LanguagePython: {
+		Build: "python3 -m compileall -q .",
+		// Isolated mode keeps student modules from shadowing the standard library
+		Test:   `python3 -I "$AUXA_HARNESS/` + pythonRunnerName + `"`,
+		Format: FormatTAP,
+		Limits: Limits{TimeSeconds: 60, CPUSeconds: 30, MemoryMB: 512, FileSizeMB: 16, Processes: 128},
+	},
+	LanguageJava: {
+		// JUnit's console launcher jar is supplied by the host as $AUXA_JUNIT_JAR; only the
+		// suite's classes are selected
+		Build:  `mkdir -p build && javac -proc:none -encoding UTF-8 -cp "$AUXA_JUNIT_JAR" -d build $(find . -name '*.java')`,
+		Test:   `java -Xmx512m -jar "$AUXA_JUNIT_JAR" execute --class-path build --disable-banner --reports-dir "$AUXA_RESULTS" $AUXA_JUNIT_SELECTORS`,
+		Format: FormatJUnit,
+		// The JVM reserves far more address space than it uses
+		Limits: Limits{TimeSeconds: 120, CPUSeconds: 60, MemoryMB: 4096, FileSizeMB: 16, Processes: 1024},
+	},
+	LanguageC: {
+		// A student's Makefile is never run; suites that want make set the build command
+		Build:  "cc -std=c11 -O2 -Wall -o solution $(find . -name '*.c') -lm",
+		Test:   `sh run_tests.sh > "$AUXA_RESULTS/` + resultsTAP + `"`,
+		Format: FormatTAP,
+		Limits: Limits{TimeSeconds: 60, CPUSeconds: 30, MemoryMB: 512, FileSizeMB: 16, Processes: 128},
+	},
+	LanguageCPP: {
+		Build:  "c++ -std=c++17 -O2 -Wall -o solution $(find . -name '*.cpp') -lm",
+		Test:   `sh run_tests.sh > "$AUXA_RESULTS/` + resultsTAP + `"`,
+		Format: FormatTAP,
+		Limits: Limits{TimeSeconds: 60, CPUSeconds: 30, MemoryMB: 1024, FileSizeMB: 16, Processes: 128},
+	},
+	LanguageGo: {
+		Build: "go build ./...",
+		Vet:   "go vet ./...",
+		// Vet already ran; only the suite's tests run
+		Test:   `go test -vet=off -json -run "$AUXA_GO_TESTS" ./... > "$AUXA_RESULTS/` + resultsGoTest + `"`,
+		Format: FormatGoTest,
+		Limits: Limits{TimeSeconds: 180, CPUSeconds: 120, MemoryMB: 4096, FileSizeMB: 64, Processes: 1024},
+	},
+}
+
+// Validate checks the suite and fills in defaults for its language
+func (s *Suite) Validate() error {
+	if s.Name == "" {
+		return fmt.Errorf("suite name is required")
+	}
+
+	d, ok := defaults[s.Language]
+	if !ok {
+		return fmt.Errorf("unsupported language: %q", s.Language)
+	}
+
+	for name := range s.Files {
+		if _, err := cleanRelativePath(name); err != nil {
+			return fmt.Errorf("test file %q: %w", name, err)
+		}
+	}
+
+	if s.BuildCommand == "" {
+		s.BuildCommand = d.Build
+	}
+	if s.TestCommand == "" {
+		s.TestCommand = d.Test
+	}
+	if s.Format == "" {
+		s.Format = d.Format
+	}
+	switch s.Format {
+	case FormatTAP, FormatJUnit, FormatGoTest:
+	default:
+		return fmt.Errorf("unsupported result format: %q", s.Format)
+	}
+
+	s.Limits = s.Limits.withDefaults(d.Limits)
+	if s.Points < 0 {
+		return fmt.Errorf("points cannot be negative")
+	}
+	for name, weight := range s.Weights {
+		if weight < 0 {
+			return fmt.Errorf("weight for %q cannot be negative", name)
+		}
+	}
+	return nil
+}
+
+func (l Limits) withDefaults(d Limits) Limits {
+	if l.TimeSeconds <= 0 {
+		l.TimeSeconds = d.TimeSeconds
+	}
+	if l.CPUSeconds <= 0 {
+		l.CPUSeconds = d.CPUSeconds
+	}
+	if l.MemoryMB <= 0 {
+		l.MemoryMB = d.MemoryMB
+	}
+	if l.FileSizeMB <= 0 {
+		l.FileSizeMB = d.FileSizeMB
+	}
+	if l.Processes <= 0 {
+		l.Processes = d.Processes
+	}
+	return l
+}
+
+// cleanRelativePath rejects paths that would escape the workspace
+func cleanRelativePath(name string) (string, error) {
+	name = strings.ReplaceAll(name, "\\", "/")
+	clean := path.Clean(name)
+	if name == "" || path.IsAbs(clean) || clean == "." || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("path must stay inside the workspace")
+	}
+	return clean, nil
+}
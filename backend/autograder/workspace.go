@@ -0,0 +1,174 @@
+package autograder
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Bounds on what a submission may unpack into its workspace
+const (
+	maxWorkspaceBytes = 100 * 1024 * 1024
+	maxWorkspaceFiles = 2000
+)
+
+// Source is a file submitted by the student; zip archives are unpacked
+type Source struct {
+	Name string
+	Data []byte
+}
+
+// workspace tracks what has been written so far against the limits
+type workspace struct {
+	dir   string
+	bytes int64
+	files map[string]bool
+}
+
+// prepareWorkspace unpacks the student's files into dir and overlays the suite's test files.
+// It returns the student's files by relative path, and warnings about files it dropped.
+func prepareWorkspace(dir string, suite Suite, sources []Source) ([]string, []string, error) {
+	w := &workspace{dir: dir, files: map[string]bool{}}
+	var warnings []string
+
+	for _, source := range sources {
+		if strings.EqualFold(path.Ext(source.Name), ".zip") {
+			skipped, err := w.unzip(source)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", source.Name, err)
+			}
+			warnings = append(warnings, skipped...)
+			continue
+		}
+		if err := w.write(path.Base(strings.ReplaceAll(source.Name, "\\", "/")), bytes.NewReader(source.Data)); err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", source.Name, err)
+		}
+	}
+
+	studentFiles := make([]string, 0, len(w.files))
+	for name := range w.files {
+		// A student's Go tests would be compiled into the suite's test binary, where a
+		// TestMain or init could interfere with it
+		if suite.Language == LanguageGo && strings.HasSuffix(name, "_test.go") {
+			if err := os.Remove(filepath.Join(dir, filepath.FromSlash(name))); err != nil {
+				return nil, nil, err
+			}
+			delete(w.files, name)
+			warnings = append(warnings, fmt.Sprintf("Ignored the student's test file %s", name))
+			continue
+		}
+		studentFiles = append(studentFiles, name)
+	}
+	sort.Strings(studentFiles)
+
+	for name, content := range suite.Files {
+		clean, _ := cleanRelativePath(name)
+		if w.files[clean] {
+			warnings = append(warnings, fmt.Sprintf("The suite's %s replaced the student's file of the same name", clean))
+		}
+		if err := w.write(clean, strings.NewReader(content)); err != nil {
+			return nil, nil, fmt.Errorf("test file %s: %w", clean, err)
+		}
+	}
+
+	if suite.Language == LanguageGo && !w.files["go.mod"] {
+		if err := w.write("go.mod", strings.NewReader("module submission\n\ngo 1.21\n")); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return studentFiles, warnings, nil
+}
+
+// unzip extracts an archive, dropping a single top-level folder so sources land where the
+// tests expect them. Entries that are not regular files or that would escape the workspace
+// are skipped with a warning.
+func (w *workspace) unzip(source Source) ([]string, error) {
+	reader, err := zip.NewReader(bytes.NewReader(source.Data), int64(len(source.Data)))
+	if err != nil {
+		return nil, fmt.Errorf("not a readable zip archive: %w", err)
+	}
+
+	var entries []*zip.File
+	for _, f := range reader.File {
+		name := strings.ReplaceAll(f.Name, "\\", "/")
+		if f.FileInfo().IsDir() || strings.HasPrefix(name, "__MACOSX/") || path.Base(name) == ".DS_Store" {
+			continue
+		}
+		entries = append(entries, f)
+	}
+
+	prefix := commonFolder(entries)
+	var warnings []string
+	for _, f := range entries {
+		name := strings.TrimPrefix(strings.ReplaceAll(f.Name, "\\", "/"), prefix)
+		clean, err := cleanRelativePath(name)
+		if err != nil || !f.Mode().IsRegular() {
+			warnings = append(warnings, fmt.Sprintf("Skipped %s from %s", f.Name, source.Name))
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", f.Name, err)
+		}
+		err = w.write(clean, rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Name, err)
+		}
+	}
+	return warnings, nil
+}
+
+// commonFolder returns "folder/" when every entry sits in the same top-level folder
+func commonFolder(entries []*zip.File) string {
+	prefix := ""
+	for _, f := range entries {
+		name := strings.ReplaceAll(f.Name, "\\", "/")
+		i := strings.Index(name, "/")
+		if i <= 0 {
+			return ""
+		}
+		if prefix == "" {
+			prefix = name[:i+1]
+		} else if !strings.HasPrefix(name, prefix) {
+			return ""
+		}
+	}
+	return prefix
+}
+
+// write stores a file in the workspace, enforcing the size and count limits
+func (w *workspace) write(name string, r io.Reader) error {
+	if !w.files[name] && len(w.files) >= maxWorkspaceFiles {
+		return fmt.Errorf("submission has more than %d files", maxWorkspaceFiles)
+	}
+
+	target := filepath.Join(w.dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	n, err := io.Copy(f, io.LimitReader(r, maxWorkspaceBytes-w.bytes+1))
+	w.bytes += n
+	if err != nil {
+		return err
+	}
+	if w.bytes > maxWorkspaceBytes {
+		return fmt.Errorf("submission unpacks to more than %d MB", maxWorkspaceBytes/(1024*1024))
+	}
+	w.files[name] = true
+	return nil
+}
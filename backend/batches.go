@@ -0,0 +1,388 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"auxa/anonymize"
+	"auxa/jobs"
+	"auxa/llm"
+	"auxa/redact"
+	"auxa/usage"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	batchJobKind             = "batch_grading"
+	defaultBatchPollInterval = time.Minute
+	// Consecutive failed status checks after which a batch job gives up
+	maxBatchRefreshFailures = 10
+)
+
+// jobManager tracks long-running backend jobs; initialised in main
+var jobManager *jobs.Manager
+
+// batchJobState is persisted with the job so polling can resume after a restart
+type batchJobState struct {
+	Platform         string            `json:"platform"`
+	CourseID         string            `json:"course_id,omitempty"`
+	AssignmentID     string            `json:"assignment_id,omitempty"`
+	GraderID         string            `json:"grader_id,omitempty"`
+	SubmissionIDs    []string          `json:"submission_ids"` // Indexed by batch item, see batchCustomID
+	CacheKeys        []string          `json:"cache_keys"`
+	Batch            *llm.Batch        `json:"batch,omitempty"`
+	CachedResults    []batchItemResult `json:"cached_results,omitempty"`
+	PollIntervalSecs int               `json:"poll_interval_seconds"`
+}
+
+// batchItemResult is one submission's feedback from a batch job
+type batchItemResult struct {
+	SubmissionID string     `json:"submission_id"`
+	Feedback     string     `json:"feedback"`
+	Usage        *llm.Usage `json:"usage,omitempty"`
+	Cached       bool       `json:"cached"`
+	Error        string     `json:"error,omitempty"`
+}
+
+// batchRun holds what must never be written to disk: the API key and the PII mappings
+type batchRun struct {
+	apiKey       string
+	mappings     []*redact.Mapping
+	reservations []*usage.Reservation
+}
+
+func (r *batchRun) release() {
+	for _, reservation := range r.reservations {
+		reservation.Release()
+	}
+	r.reservations = nil
+}
+
+// batchCustomID names batch items by index; Anthropic restricts the characters allowed
+func batchCustomID(index int) string {
+	return "item-" + strconv.Itoa(index)
+}
+
+func batchIndex(customID string) (int, bool) {
+	index, err := strconv.Atoi(strings.TrimPrefix(customID, "item-"))
+	return index, err == nil
+}
+
+// Submit a list of grading prompts to a provider batch API as a background job
+func createGradingBatch(c *gin.Context) {
+	var req struct {
+		llm.GradingRequest
+		Items []struct {
+			SubmissionID string `json:"submission_id"`
+			UserID       int    `json:"user_id"` // Required with exemplars, so no student sees their own work
+			Prompt       string `json:"prompt"`
+		} `json:"items"`
+		PollIntervalSeconds int `json:"poll_interval_seconds"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Platform != "openai" && req.Platform != "anthropic" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Batch grading supports the openai and anthropic platforms"})
+		return
+	}
+
+	if req.APIKey == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "API key is required"})
+		return
+	}
+
+	if req.Consensus != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Consensus grading is not supported for batches"})
+		return
+	}
+
+	if req.Resubmission != nil || req.Autograder != nil || req.Notebook != nil || req.Templates != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Resubmission, autograded, notebook-checked and templated feedback are not supported for batches"})
+		return
+	}
+
+	if len(req.Items) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one item is required"})
+		return
+	}
+
+	for i, item := range req.Items {
+		if item.SubmissionID == "" || item.Prompt == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Item %d needs a submission_id and prompt", i)})
+			return
+		}
+	}
+
+	template := req.GradingRequest
+	template.Prompt = ""
+	gradedUserIDs := make([]int, len(req.Items))
+	for i, item := range req.Items {
+		gradedUserIDs[i] = item.UserID
+	}
+	if !applyExemplars(c, &template, gradedUserIDs...) {
+		return
+	}
+
+	// The roster is loaded once and every item is scrubbed with it
+	var scrubber *anonymize.Scrubber
+	if template.Anonymize {
+		var ok bool
+		if scrubber, ok = providerScrubber(c, template.CourseID); !ok {
+			return
+		}
+		template.SystemPrompt = scrubber.Scrub(template.SystemPrompt)
+		template.SharedContext = scrubber.Scrub(template.SharedContext)
+	}
+
+	graderID, ok := graderFromRequest(c)
+	if !ok {
+		return
+	}
+	template.GraderID = graderID
+
+	redactor, ok := redactorForCourse(c, template.CourseID)
+	if !ok {
+		return
+	}
+
+	pollInterval := req.PollIntervalSeconds
+	if pollInterval <= 0 {
+		pollInterval = int(defaultBatchPollInterval.Seconds())
+	}
+
+	state := batchJobState{
+		Platform:         template.Platform,
+		CourseID:         template.CourseID,
+		AssignmentID:     template.AssignmentID,
+		GraderID:         template.GraderID,
+		SubmissionIDs:    make([]string, len(req.Items)),
+		CacheKeys:        make([]string, len(req.Items)),
+		PollIntervalSecs: pollInterval,
+	}
+	run := &batchRun{apiKey: template.APIKey, mappings: make([]*redact.Mapping, len(req.Items))}
+
+	var items []llm.BatchItem
+	for i, item := range req.Items {
+		itemReq := template
+		itemReq.Prompt = item.Prompt
+		if scrubber != nil {
+			itemReq.Prompt = scrubber.Scrub(itemReq.Prompt)
+		}
+
+		// Mask each item separately so tokens map back to the right student
+		mapping := redact.NewMapping()
+		if redactor != nil {
+			itemReq.SystemPrompt = redactor.Mask(itemReq.SystemPrompt, mapping)
+			itemReq.SharedContext = redactor.Mask(itemReq.SharedContext, mapping)
+			itemReq.Prompt = redactor.Mask(itemReq.Prompt, mapping)
+		}
+		run.mappings[i] = mapping
+		state.SubmissionIDs[i] = item.SubmissionID
+		state.CacheKeys[i] = llm.GradingCacheKey(itemReq)
+
+		var cached llm.GradingResponse
+		if !itemReq.BypassCache && responseCache.Get(state.CacheKeys[i], &cached) {
+			state.CachedResults = append(state.CachedResults, batchItemResult{
+				SubmissionID: item.SubmissionID,
+				Feedback:     mapping.Unmask(cached.Feedback),
+				Cached:       true,
+			})
+			continue
+		}
+
+		// Hold a quota slot per uncached item so one batch cannot drain the caps
+		reservation, _, err := quotas.Reserve(template.CourseID, template.GraderID, time.Now())
+		if err != nil {
+			run.release()
+			writeGenerationError(c, err, "feedback")
+			return
+		}
+		run.reservations = append(run.reservations, reservation)
+
+		items = append(items, llm.BatchItem{CustomID: batchCustomID(i), Request: itemReq})
+	}
+
+	job, err := jobManager.Create(batchJobKind, len(req.Items), state)
+	if err != nil {
+		run.release()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	jobManager.Run(job.ID, func() error {
+		defer run.release()
+
+		if len(items) > 0 {
+			batch, err := llm.SubmitBatch(state.Platform, run.apiKey, items)
+			if err != nil {
+				return err
+			}
+			state.Batch = batch
+			if err := jobManager.SetState(job.ID, state); err != nil {
+				return err
+			}
+		}
+
+		return pollGradingBatch(job.ID, state, run)
+	})
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+// pollGradingBatch waits for the provider batch to finish, then maps results back to submissions
+func pollGradingBatch(jobID string, state batchJobState, run *batchRun) error {
+	results := append([]batchItemResult{}, state.CachedResults...)
+	total := len(state.SubmissionIDs)
+
+	if state.Batch != nil {
+		interval := time.Duration(state.PollIntervalSecs) * time.Second
+		batch := state.Batch
+		failures := 0
+		for !batch.Done() {
+			if err := jobManager.Progress(jobID, len(results)+batch.Completed+batch.Failed, total, "Waiting for provider batch "+batch.ID); err != nil {
+				return err
+			}
+			time.Sleep(interval)
+
+			refreshed, err := llm.GetBatch(run.apiKey, batch)
+			if err != nil {
+				// Transient provider errors should not abandon an overnight batch, but a
+				// revoked key or deleted batch fails every time
+				failures++
+				fmt.Printf("[Batch] job=%s failed to refresh batch %s (%d of %d): %v\n", jobID, batch.ID, failures, maxBatchRefreshFailures, err)
+				if failures >= maxBatchRefreshFailures {
+					return fmt.Errorf("failed to check provider batch %s %d times in a row: %w", batch.ID, failures, err)
+				}
+				continue
+			}
+			failures = 0
+			batch = refreshed
+		}
+
+		state.Batch = batch
+		if err := jobManager.SetState(jobID, state); err != nil {
+			return err
+		}
+		if batch.Status != llm.BatchCompleted {
+			return fmt.Errorf("provider batch %s ended with status %s", batch.ID, batch.Status)
+		}
+
+		batchResults, err := llm.GetBatchResults(run.apiKey, batch)
+		if err != nil {
+			return err
+		}
+
+		for _, result := range batchResults {
+			index, ok := batchIndex(result.CustomID)
+			if !ok || index >= total {
+				fmt.Printf("[Batch] job=%s ignoring unknown result %s\n", jobID, result.CustomID)
+				continue
+			}
+
+			mapping := run.mappings[index]
+			item := batchItemResult{
+				SubmissionID: state.SubmissionIDs[index],
+				Feedback:     mapping.Unmask(result.Feedback),
+				Usage:        result.Usage,
+				Error:        mapping.Unmask(result.Error),
+			}
+			results = append(results, item)
+
+			if result.Usage != nil {
+				recordUsage(usage.Entry{
+					Kind:             usage.KindBatch,
+					CourseID:         state.CourseID,
+					AssignmentID:     state.AssignmentID,
+					GraderID:         state.GraderID,
+					Platform:         batch.Platform,
+					Model:            batch.Model,
+					InputTokens:      result.Usage.InputTokens,
+					OutputTokens:     result.Usage.OutputTokens,
+					CacheReadTokens:  result.Usage.CacheReadTokens,
+					CacheWriteTokens: result.Usage.CacheWriteTokens,
+					EstimatedCost:    result.Usage.EstimatedCost,
+				})
+			}
+
+			if result.Error == "" {
+				// Cache the masked feedback, as the interactive path does
+				cached := llm.GradingResponse{Feedback: result.Feedback, Model: batch.Model}
+				if err := responseCache.Put(state.CacheKeys[index], cached); err != nil {
+					fmt.Printf("[Cache] failed to store batch response: %v\n", err)
+				}
+			}
+		}
+	}
+
+	return jobManager.Complete(jobID, results)
+}
+
+// List batch grading jobs
+func getGradingBatches(c *gin.Context) {
+	c.JSON(http.StatusOK, jobManager.List(batchJobKind))
+}
+
+// Resume polling a batch job interrupted by a backend restart. PII mappings are never
+// persisted, so resumed results keep their mask tokens.
+func resumeGradingBatch(c *gin.Context) {
+	var req struct {
+		APIKey string `json:"api_key" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	job, found := jobManager.Get(c.Param("job_id"))
+	if !found || job.Kind != batchJobKind {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Batch job not found"})
+		return
+	}
+	if job.Status != jobs.StatusInterrupted {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only interrupted batch jobs can be resumed"})
+		return
+	}
+
+	var state batchJobState
+	if err := json.Unmarshal(job.State, &state); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if state.Batch == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "The batch was never submitted to the provider; create a new batch instead"})
+		return
+	}
+
+	run := &batchRun{apiKey: req.APIKey, mappings: make([]*redact.Mapping, len(state.SubmissionIDs))}
+	jobManager.Run(job.ID, func() error {
+		return pollGradingBatch(job.ID, state, run)
+	})
+
+	job, _ = jobManager.Get(job.ID)
+	c.JSON(http.StatusAccepted, job)
+}
+
+// Get a background job's status and result
+func getJob(c *gin.Context) {
+	job, found := jobManager.Get(c.Param("job_id"))
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// List background jobs, optionally of one kind
+func getJobs(c *gin.Context) {
+	c.JSON(http.StatusOK, jobManager.List(c.Query("kind")))
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"auxa/anonymize"
+	"auxa/calibration"
+	"auxa/canvas"
+	"auxa/exemplars"
+	"auxa/jobs"
+	"auxa/llm"
+	"auxa/usage"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	calibrationJobKind           = "calibration"
+	defaultCalibrationSampleSize = 10
+)
+
+// calibrationJobState records what a calibration run sampled so it can be repeated
+type calibrationJobState struct {
+	CourseID     string `json:"course_id"`
+	AssignmentID string `json:"assignment_id"`
+	Platform     string `json:"platform"`
+	Model        string `json:"model,omitempty"`
+	SampleSize   int    `json:"sample_size"`
+	Seed         int64  `json:"seed"`
+}
+
+// Replay the AI grader over human-graded submissions and report how closely it agrees
+func startCalibration(c *gin.Context) {
+	courseID := c.Param("course_id")
+	assignmentID := c.Param("assignment_id")
+
+	var req struct {
+		llm.GradingRequest
+		SampleSize int                     `json:"sample_size"`
+		Seed       *int64                  `json:"seed"`
+		Thresholds *calibration.Thresholds `json:"thresholds"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Platform == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Platform is required"})
+		return
+	}
+
+	if req.APIKey == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "API key is required"})
+		return
+	}
+
+	if err := llm.ValidateConsensus(req.GradingRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	client, ok := canvasClientFromRequest(c)
+	if !ok {
+		return
+	}
+
+	assignment, err := client.GetAssignment(courseID, assignmentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	submissions, err := client.GetAssignmentSubmissions(courseID, assignmentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	sampleSize := req.SampleSize
+	if sampleSize <= 0 {
+		sampleSize = defaultCalibrationSampleSize
+	}
+	seed := time.Now().UnixNano()
+	if req.Seed != nil {
+		seed = *req.Seed
+	}
+
+	sample := calibration.SelectSample(submissions, sampleSize, seed)
+	if len(sample) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No human-graded submissions to calibrate against"})
+		return
+	}
+
+	thresholds := calibration.DefaultThresholds(assignment.PointsPossible)
+	if req.Thresholds != nil {
+		thresholds = *req.Thresholds
+	}
+
+	template := req.GradingRequest
+	template.CourseID = courseID
+	template.AssignmentID = assignmentID
+
+	if template.Exemplars != nil {
+		// Never show the model the human grades it is being measured against
+		graded := make([]int, len(sample))
+		for i, submission := range sample {
+			graded[i] = submission.UserID
+		}
+		selected, err := loadExemplars(client, courseID, submissions, *template.Exemplars, graded)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to load exemplars: " + err.Error()})
+			return
+		}
+		template.SharedContext = appendContext(template.SharedContext, exemplars.Format(selected, assignment.PointsPossible))
+		template.Exemplars = nil
+	}
+
+	var scrubber *anonymize.Scrubber
+	if template.Anonymize {
+		scrubber, _, err = rosterScrubber(client, courseID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		template.SystemPrompt = scrubber.Scrub(template.SystemPrompt)
+		template.SharedContext = scrubber.Scrub(template.SharedContext)
+	}
+
+	generate, ok := feedbackGenerator(c, courseID)
+	if !ok {
+		return
+	}
+
+	state := calibrationJobState{
+		CourseID:     courseID,
+		AssignmentID: assignmentID,
+		Platform:     template.Platform,
+		Model:        template.TextModel,
+		SampleSize:   sampleSize,
+		Seed:         seed,
+	}
+	job, err := jobManager.Create(calibrationJobKind, len(sample), state)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	jobManager.Run(job.ID, func() error {
+		samples := make([]calibration.Sample, 0, len(sample))
+		for i, submission := range sample {
+			if err := jobManager.Progress(job.ID, i, len(sample), fmt.Sprintf("Grading sample %d of %d", i+1, len(sample))); err != nil {
+				return err
+			}
+
+			result, err := replaySubmission(client, assignment, submission, template, scrubber, generate)
+			var quotaErr *usage.QuotaError
+			if errors.As(err, &quotaErr) {
+				return err
+			}
+			samples = append(samples, result)
+		}
+
+		return jobManager.Complete(job.ID, calibration.Evaluate(samples, thresholds))
+	})
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+// replaySubmission grades one human-graded submission and pairs the scores
+func replaySubmission(client *canvas.Client, assignment *canvas.Assignment, submission canvas.Submission, template llm.GradingRequest, scrubber *anonymize.Scrubber, generate llm.Generator) (calibration.Sample, error) {
+	sample := calibration.Sample{
+		SubmissionID:  submission.ID,
+		UserID:        submission.UserID,
+		GraderID:      submission.GraderID,
+		HumanScore:    submission.Score,
+		HumanCriteria: calibration.HumanCriteria(assignment.Rubric, submission.RubricAssessment),
+	}
+
+	studentName := ""
+	if submission.User != nil {
+		studentName = submission.User.Name
+	}
+
+	req := template
+	req.Prompt = calibration.Prompt(assignment, submission, studentName, submissionContent(client, submission))
+	if scrubber != nil {
+		req.Prompt = scrubber.Scrub(req.Prompt)
+	}
+
+	resp, err := generate(req)
+	if err != nil {
+		sample.Error = err.Error()
+		return sample, err
+	}
+
+	if resp.Consensus != nil {
+		// Consensus runs calibrate the aggregated score
+		sample.AIScore = resp.Consensus.Score
+		sample.AICriteria = calibration.MatchCriteria(assignment.Rubric, resp.Consensus.Criteria)
+		sample.Rationale = resp.Feedback
+		return sample, nil
+	}
+
+	if score, ok := llm.ParseStructuredScore(resp.Feedback); ok {
+		sample.AIScore = &score.Score
+		sample.AICriteria = calibration.MatchCriteria(assignment.Rubric, score.Criteria)
+		sample.Rationale = score.Rationale
+	} else {
+		sample.Error = "no score found in the model's feedback"
+		sample.Rationale = resp.Feedback
+	}
+	return sample, nil
+}
+
+// List calibration runs for an assignment, newest first
+func getCalibrations(c *gin.Context) {
+	courseID := c.Param("course_id")
+	assignmentID := c.Param("assignment_id")
+
+	// Initialize as empty slice to ensure JSON returns [] instead of null
+	runs := make([]jobs.Job, 0)
+	for _, job := range jobManager.List(calibrationJobKind) {
+		var state calibrationJobState
+		if err := json.Unmarshal(job.State, &state); err != nil {
+			continue
+		}
+		if state.CourseID == courseID && state.AssignmentID == assignmentID {
+			runs = append(runs, job)
+		}
+	}
+
+	c.JSON(http.StatusOK, runs)
+}
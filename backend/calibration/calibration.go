@@ -0,0 +1,226 @@
+package calibration
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+
+	"auxa/canvas"
+)
+
+// Sample pairs the human grade on a submission with the AI grader's replayed score
+type Sample struct {
+	SubmissionID  int                `json:"submission_id"`
+	UserID        int                `json:"user_id"`
+	GraderID      int                `json:"grader_id"`
+	HumanScore    float64            `json:"human_score"`
+	AIScore       *float64           `json:"ai_score,omitempty"`
+	HumanCriteria map[string]float64 `json:"human_criteria,omitempty"` // Keyed by criterion description
+	AICriteria    map[string]float64 `json:"ai_criteria,omitempty"`
+	Rationale     string             `json:"rationale,omitempty"`
+	Error         string             `json:"error,omitempty"`
+}
+
+// Thresholds decide whether a prompt and model are trustworthy enough for the ungraded set
+type Thresholds struct {
+	Tolerance    float64 `json:"tolerance"`     // Points; an AI score this close to the human score agrees
+	MaxMAE       float64 `json:"max_mae"`       // Points
+	MaxBias      float64 `json:"max_bias"`      // Points, in either direction
+	MinAgreement float64 `json:"min_agreement"` // Fraction of scored samples within tolerance
+	MinSamples   int     `json:"min_samples"`   // Scored samples needed before trusting the metrics
+}
+
+// DefaultThresholds scales the point thresholds to the assignment's points possible
+func DefaultThresholds(pointsPossible float64) Thresholds {
+	if pointsPossible <= 0 {
+		pointsPossible = 100
+	}
+	return Thresholds{
+		Tolerance:    0.1 * pointsPossible,
+		MaxMAE:       0.1 * pointsPossible,
+		MaxBias:      0.05 * pointsPossible,
+		MinAgreement: 0.8,
+		MinSamples:   5,
+	}
+}
+
+// CriterionMetrics are the error metrics for one rubric criterion
+type CriterionMetrics struct {
+	Count int     `json:"count"`
+	MAE   float64 `json:"mae"`
+	Bias  float64 `json:"bias"` // Mean AI minus human; positive means the AI grades leniently
+}
+
+// Metrics summarise how closely the AI grader tracks the human grades
+type Metrics struct {
+	Samples       int                         `json:"samples"`
+	Scored        int                         `json:"scored"` // Samples the AI returned a score for
+	MAE           float64                     `json:"mae"`
+	RMSE          float64                     `json:"rmse"`
+	Bias          float64                     `json:"bias"` // Mean AI minus human
+	AgreementRate float64                     `json:"agreement_rate"`
+	Criteria      map[string]CriterionMetrics `json:"criteria,omitempty"`
+}
+
+// Report is the outcome of a calibration run
+type Report struct {
+	Metrics     Metrics    `json:"metrics"`
+	Thresholds  Thresholds `json:"thresholds"`
+	Trustworthy bool       `json:"trustworthy"`
+	Reasons     []string   `json:"reasons,omitempty"` // Why the run is not trustworthy
+	Samples     []Sample   `json:"samples"`
+}
+
+// HumanGraded reports whether a TA or instructor gave the submission a score. Canvas records
+// automatic grading, e.g. quizzes, with a non-positive grader ID. Excused submissions and
+// those without a grade have a null score, which decodes as 0, so they never count.
+func HumanGraded(submission canvas.Submission) bool {
+	return submission.WorkflowState == "graded" && submission.GraderID > 0 && submission.SubmittedAt != nil &&
+		!submission.Excused && submission.Grade != ""
+}
+
+// SelectSample picks up to size human-graded submissions. The choice is deterministic for
+// a seed so a calibration run can be repeated against the same sample.
+func SelectSample(submissions []canvas.Submission, size int, seed int64) []canvas.Submission {
+	graded := make([]canvas.Submission, 0, len(submissions))
+	for _, submission := range submissions {
+		if HumanGraded(submission) {
+			graded = append(graded, submission)
+		}
+	}
+
+	if size <= 0 || size >= len(graded) {
+		return graded
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+	rng.Shuffle(len(graded), func(i, j int) {
+		graded[i], graded[j] = graded[j], graded[i]
+	})
+	sample := graded[:size]
+	sort.Slice(sample, func(i, j int) bool { return sample[i].ID < sample[j].ID })
+	return sample
+}
+
+// HumanCriteria returns the points a human awarded per rubric criterion, keyed by description
+func HumanCriteria(rubric []canvas.Rubric, assessment canvas.RubricAssessment) map[string]float64 {
+	if len(assessment) == 0 {
+		return nil
+	}
+
+	criteria := make(map[string]float64, len(assessment))
+	for _, criterion := range rubric {
+		if scored, ok := assessment[criterion.ID]; ok && scored.Points != nil {
+			criteria[criterionName(criterion)] = *scored.Points
+		}
+	}
+	return criteria
+}
+
+// MatchCriteria keys the AI's criterion scores by rubric description. Models may name a
+// criterion by its ID or description, in any case; unknown names are dropped.
+func MatchCriteria(rubric []canvas.Rubric, scores map[string]float64) map[string]float64 {
+	if len(scores) == 0 {
+		return nil
+	}
+
+	names := make(map[string]string, len(rubric)*2)
+	for _, criterion := range rubric {
+		name := criterionName(criterion)
+		names[normalize(criterion.ID)] = name
+		names[normalize(criterion.Description)] = name
+	}
+
+	matched := make(map[string]float64, len(scores))
+	for key, points := range scores {
+		if name, ok := names[normalize(key)]; ok {
+			matched[name] = points
+		}
+	}
+	return matched
+}
+
+func criterionName(criterion canvas.Rubric) string {
+	if criterion.Description != "" {
+		return criterion.Description
+	}
+	return criterion.ID
+}
+
+func normalize(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+// Evaluate computes error metrics over the samples and judges them against the thresholds
+func Evaluate(samples []Sample, thresholds Thresholds) Report {
+	metrics := Metrics{Samples: len(samples)}
+
+	var absSum, sqSum, diffSum float64
+	agreed := 0
+	criterionDiffs := make(map[string][]float64)
+	for _, sample := range samples {
+		if sample.AIScore == nil {
+			continue
+		}
+
+		metrics.Scored++
+		diff := *sample.AIScore - sample.HumanScore
+		absSum += math.Abs(diff)
+		sqSum += diff * diff
+		diffSum += diff
+		if math.Abs(diff) <= thresholds.Tolerance {
+			agreed++
+		}
+
+		for name, human := range sample.HumanCriteria {
+			if ai, ok := sample.AICriteria[name]; ok {
+				criterionDiffs[name] = append(criterionDiffs[name], ai-human)
+			}
+		}
+	}
+
+	if metrics.Scored > 0 {
+		n := float64(metrics.Scored)
+		metrics.MAE = absSum / n
+		metrics.RMSE = math.Sqrt(sqSum / n)
+		metrics.Bias = diffSum / n
+		metrics.AgreementRate = float64(agreed) / n
+	}
+
+	if len(criterionDiffs) > 0 {
+		metrics.Criteria = make(map[string]CriterionMetrics, len(criterionDiffs))
+		for name, diffs := range criterionDiffs {
+			var abs, sum float64
+			for _, diff := range diffs {
+				abs += math.Abs(diff)
+				sum += diff
+			}
+			n := float64(len(diffs))
+			metrics.Criteria[name] = CriterionMetrics{Count: len(diffs), MAE: abs / n, Bias: sum / n}
+		}
+	}
+
+	report := Report{Metrics: metrics, Thresholds: thresholds, Samples: samples}
+	if metrics.Scored < thresholds.MinSamples {
+		report.Reasons = append(report.Reasons, fmt.Sprintf("only %d scored samples (need %d)", metrics.Scored, thresholds.MinSamples))
+	}
+	if metrics.Scored > 0 {
+		if metrics.MAE > thresholds.MaxMAE {
+			report.Reasons = append(report.Reasons, fmt.Sprintf("mean absolute error %.2f exceeds %.2f points", metrics.MAE, thresholds.MaxMAE))
+		}
+		if math.Abs(metrics.Bias) > thresholds.MaxBias {
+			direction := "leniently"
+			if metrics.Bias < 0 {
+				direction = "harshly"
+			}
+			report.Reasons = append(report.Reasons, fmt.Sprintf("AI grades %s by %.2f points on average", direction, math.Abs(metrics.Bias)))
+		}
+		if metrics.AgreementRate < thresholds.MinAgreement {
+			report.Reasons = append(report.Reasons, fmt.Sprintf("agreement rate %.0f%% is below %.0f%%", metrics.AgreementRate*100, thresholds.MinAgreement*100))
+		}
+	}
+	report.Trustworthy = len(report.Reasons) == 0
+	return report
+}
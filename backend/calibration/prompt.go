@@ -0,0 +1,87 @@
+package calibration
+
+import (
+	"fmt"
+	"strings"
+
+	"auxa/canvas"
+)
+
+// Prompt builds the grading prompt for a submission. It lays the submission out the way the
+// dashboard does, so calibration measures the prompt TAs will actually run, and adds the
+// rubric with scores requested as JSON so the parser can read a score per criterion.
+func Prompt(assignment *canvas.Assignment, submission canvas.Submission, studentName, content string) string {
+	var b strings.Builder
+
+	if studentName == "" {
+		studentName = "Unknown"
+	}
+	submittedAt := "Not submitted"
+	if submission.SubmittedAt != nil {
+		submittedAt = submission.SubmittedAt.Format("Jan 2, 2006 3:04 PM")
+	}
+	late := "No"
+	if submission.Late {
+		late = "Yes"
+	}
+
+	b.WriteString("ASSIGNMENT INFORMATION:\n")
+	fmt.Fprintf(&b, "Assignment: %s\n", assignment.Name)
+	fmt.Fprintf(&b, "Maximum Points: %g\n", assignment.PointsPossible)
+	fmt.Fprintf(&b, "Student: %s\n", studentName)
+	fmt.Fprintf(&b, "Submission Date: %s\n", submittedAt)
+	fmt.Fprintf(&b, "Late: %s\n\n", late)
+
+	if len(assignment.Rubric) > 0 {
+		b.WriteString("RUBRIC:\n")
+		writeRubric(&b, assignment.Rubric)
+		b.WriteString("\n")
+	}
+
+	fmt.Fprintf(&b, "STUDENT SUBMISSION:\n%s\n\n", content)
+
+	b.WriteString("Please review this student submission and provide:\n")
+	b.WriteString("1. Detailed feedback on the work\n")
+	b.WriteString("2. Strengths and areas for improvement\n")
+	fmt.Fprintf(&b, "3. A suggested grade (out of %g points)\n", assignment.PointsPossible)
+	b.WriteString("4. Specific examples from the submission to support your feedback\n\n")
+
+	b.WriteString("Respond with only a JSON object in this format:\n{\n")
+	b.WriteString(`  "score": <suggested grade>,` + "\n")
+	fmt.Fprintf(&b, `  "max_score": %g,`+"\n", assignment.PointsPossible)
+	if len(assignment.Rubric) > 0 {
+		b.WriteString(`  "criteria": {` + "\n")
+		for i, criterion := range assignment.Rubric {
+			separator := ","
+			if i == len(assignment.Rubric)-1 {
+				separator = ""
+			}
+			fmt.Fprintf(&b, `    %q: <points out of %g>%s`+"\n", criterion.ID, criterion.Points, separator)
+		}
+		b.WriteString("  },\n")
+	}
+	b.WriteString(`  "rationale": "<your detailed feedback>"` + "\n}")
+	if len(assignment.Rubric) > 0 {
+		b.WriteString("\nScore every rubric criterion by its ID; the score should be the sum of the criteria.")
+	}
+
+	return b.String()
+}
+
+// writeRubric lists each criterion with its ID, points and rating levels
+func writeRubric(b *strings.Builder, rubric []canvas.Rubric) {
+	for _, criterion := range rubric {
+		fmt.Fprintf(b, "- [%s] %s (%g points)", criterion.ID, criterion.Description, criterion.Points)
+		if long := strings.TrimSpace(criterion.LongDescription); long != "" {
+			fmt.Fprintf(b, ": %s", long)
+		}
+		b.WriteString("\n")
+		for _, rating := range criterion.Ratings {
+			fmt.Fprintf(b, "    %g points: %s", rating.Points, rating.Description)
+			if long := strings.TrimSpace(rating.LongDescription); long != "" {
+				fmt.Fprintf(b, " (%s)", long)
+			}
+			b.WriteString("\n")
+		}
+	}
+}
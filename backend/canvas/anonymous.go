@@ -0,0 +1,59 @@
+package canvas
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+)
+
+// GetAnonymousAssignmentSubmissions fetches submissions without user or avatar data, for
+// assignments graded anonymously or when the grader opts into anonymized mode
+func (c *Client) GetAnonymousAssignmentSubmissions(courseID, assignmentID string) ([]Submission, error) {
+	params := url.Values{}
+	params.Add("include[]", "submission_history")
+	params.Add("include[]", "submission_comments")
+	params.Add("include[]", "rubric_assessment")
+	params.Add("per_page", "100")
+
+	endpoint := fmt.Sprintf("/courses/%s/assignments/%s/submissions", courseID, assignmentID)
+	pages, err := c.makePagedRequest(endpoint, params)
+	if err != nil {
+		return nil, err
+	}
+
+	var submissions []Submission
+	for _, body := range pages {
+		var page []Submission
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, fmt.Errorf("failed to parse submissions: %w", err)
+		}
+		submissions = append(submissions, page...)
+	}
+
+	return submissions, nil
+}
+
+// GradeAnonymousSubmission posts a grade and optional comment addressed by anonymous ID
+func (c *Client) GradeAnonymousSubmission(courseID, assignmentID, anonymousID string, grade SubmissionGrade) (*Submission, error) {
+	form := url.Values{}
+	form.Set("submission[posted_grade]", grade.PostedGrade)
+	if grade.TextComment != "" {
+		form.Set("comment[text_comment]", grade.TextComment)
+		if grade.GroupComment {
+			form.Set("comment[group_comment]", "true")
+		}
+	}
+
+	endpoint := fmt.Sprintf("/courses/%s/assignments/%s/anonymous_submissions/%s", courseID, assignmentID, anonymousID)
+	body, err := c.makeFormRequest("PUT", endpoint, form)
+	if err != nil {
+		return nil, err
+	}
+
+	var submission Submission
+	if err := json.Unmarshal(body, &submission); err != nil {
+		return nil, fmt.Errorf("failed to parse submission: %w", err)
+	}
+
+	return &submission, nil
+}
@@ -46,25 +46,132 @@ func (c *Client) makeRequest(method, endpoint string, params url.Values) ([]byte
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.Token))
 	req.Header.Set("Content-Type", "application/json")
 
+	return c.do(req)
+}
+
+// makeFormRequest performs an HTTP request to Canvas API with a form-encoded body
+func (c *Client) makeFormRequest(method, endpoint string, form url.Values) ([]byte, error) {
+	urlStr := fmt.Sprintf("%s%s", c.BaseURL, endpoint)
+
+	req, err := http.NewRequest(method, urlStr, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	return c.do(req)
+}
+
+// do authenticates and sends a prepared request, returning the response body
+func (c *Client) do(req *http.Request) ([]byte, error) {
+	body, _, err := c.send(req)
+	return body, err
+}
+
+// send is do that also returns the response headers
+func (c *Client) send(req *http.Request) ([]byte, http.Header, error) {
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.Token))
+
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, nil, fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return nil, nil, fmt.Errorf("failed to read response: %w", err)
 	}
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+		return nil, nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
 	}
 
-	return body, nil
+	return body, resp.Header, nil
+}
+
+// Pages followed for one list request, as a guard against a next link that never ends
+const maxPages = 500
+
+// makePagedRequest GETs every page of a Canvas list endpoint by following the Link header's
+// rel="next" URL, and returns each page's body
+func (c *Client) makePagedRequest(endpoint string, params url.Values) ([][]byte, error) {
+	urlStr := fmt.Sprintf("%s%s", c.BaseURL, endpoint)
+	if len(params) > 0 {
+		urlStr += "?" + params.Encode()
+	}
+
+	var pages [][]byte
+	for urlStr != "" {
+		if len(pages) == maxPages {
+			return nil, fmt.Errorf("%s has more than %d pages", endpoint, maxPages)
+		}
+
+		req, err := http.NewRequest("GET", urlStr, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		body, header, err := c.send(req)
+		if err != nil {
+			return nil, err
+		}
+		pages = append(pages, body)
+
+		if urlStr, err = c.nextPage(header); err != nil {
+			return nil, err
+		}
+	}
+	return pages, nil
+}
+
+// nextPage returns the rel="next" URL of a Link header, or "" on the last page. The URL must
+// point at this Canvas instance, since the token is sent with it.
+func (c *Client) nextPage(header http.Header) (string, error) {
+	for _, link := range header.Values("Link") {
+		for _, part := range strings.Split(link, ",") {
+			target, rels, found := strings.Cut(part, ";")
+			if !found || !isNextRel(rels) {
+				continue
+			}
+
+			next, err := url.Parse(strings.Trim(strings.TrimSpace(target), "<>"))
+			if err != nil {
+				return "", fmt.Errorf("invalid next page link: %w", err)
+			}
+			if err := c.checkHost(next); err != nil {
+				return "", fmt.Errorf("next page link: %w", err)
+			}
+			return next.String(), nil
+		}
+	}
+	return "", nil
+}
+
+// checkHost rejects URLs outside the Canvas instance, so the token is only sent to Canvas
+func (c *Client) checkHost(target *url.URL) error {
+	base, err := url.Parse(c.BaseURL)
+	if err != nil {
+		return err
+	}
+	if target.Scheme != base.Scheme || target.Host != base.Host {
+		return fmt.Errorf("%s points outside %s", target.Redacted(), base.Host)
+	}
+	return nil
+}
+
+func isNextRel(params string) bool {
+	for _, param := range strings.Split(params, ";") {
+		name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+		if strings.TrimSpace(name) == "rel" && strings.Trim(strings.TrimSpace(value), `"`) == "next" {
+			return true
+		}
+	}
+	return false
 }
 
 // GetUserProfile fetches the current user's profile (for connection testing)
@@ -125,6 +232,22 @@ func (c *Client) GetCourseAssignments(courseID string) ([]Assignment, error) {
 	return assignments, nil
 }
 
+// GetAssignment fetches a single assignment
+func (c *Client) GetAssignment(courseID, assignmentID string) (*Assignment, error) {
+	endpoint := fmt.Sprintf("/courses/%s/assignments/%s", courseID, assignmentID)
+	body, err := c.makeRequest("GET", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var assignment Assignment
+	if err := json.Unmarshal(body, &assignment); err != nil {
+		return nil, fmt.Errorf("failed to parse assignment: %w", err)
+	}
+
+	return &assignment, nil
+}
+
 // GetAssignmentSubmissions fetches all submissions for an assignment
 func (c *Client) GetAssignmentSubmissions(courseID, assignmentID string) ([]Submission, error) {
 	params := url.Values{}
@@ -138,14 +261,18 @@ func (c *Client) GetAssignmentSubmissions(courseID, assignmentID string) ([]Subm
 	params.Add("per_page", "100")
 
 	endpoint := fmt.Sprintf("/courses/%s/assignments/%s/submissions", courseID, assignmentID)
-	body, err := c.makeRequest("GET", endpoint, params)
+	pages, err := c.makePagedRequest(endpoint, params)
 	if err != nil {
 		return nil, err
 	}
 
 	var submissions []Submission
-	if err := json.Unmarshal(body, &submissions); err != nil {
-		return nil, fmt.Errorf("failed to parse submissions: %w", err)
+	for _, body := range pages {
+		var page []Submission
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, fmt.Errorf("failed to parse submissions: %w", err)
+		}
+		submissions = append(submissions, page...)
 	}
 
 	return submissions, nil
@@ -228,15 +355,101 @@ func (c *Client) GetCourseEnrollments(courseID string) ([]Enrollment, error) {
 	params.Add("include[]", "avatar_url")
 	params.Add("per_page", "100")
 
-	body, err := c.makeRequest("GET", endpoint, params)
+	pages, err := c.makePagedRequest(endpoint, params)
 	if err != nil {
 		return nil, err
 	}
 
 	var enrollments []Enrollment
-	if err := json.Unmarshal(body, &enrollments); err != nil {
-		return nil, err
+	for _, body := range pages {
+		var page []Enrollment
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, err
+		}
+		enrollments = append(enrollments, page...)
 	}
 
 	return enrollments, nil
 }
+
+// GradeSubmission posts a grade and optional comment for a single student's submission
+func (c *Client) GradeSubmission(courseID, assignmentID, userID string, grade SubmissionGrade) (*Submission, error) {
+	form := url.Values{}
+	form.Set("submission[posted_grade]", grade.PostedGrade)
+	if grade.TextComment != "" {
+		form.Set("comment[text_comment]", grade.TextComment)
+		if grade.GroupComment {
+			form.Set("comment[group_comment]", "true")
+		}
+	}
+
+	endpoint := fmt.Sprintf("/courses/%s/assignments/%s/submissions/%s", courseID, assignmentID, userID)
+	body, err := c.makeFormRequest("PUT", endpoint, form)
+	if err != nil {
+		return nil, err
+	}
+
+	var submission Submission
+	if err := json.Unmarshal(body, &submission); err != nil {
+		return nil, fmt.Errorf("failed to parse submission: %w", err)
+	}
+
+	return &submission, nil
+}
+
+// DownloadFile fetches a file such as a submission attachment, refusing files larger than
+// maxBytes. Attachment URLs are absolute and already carry a download verifier.
+func (c *Client) DownloadFile(fileURL string, maxBytes int64) ([]byte, error) {
+	req, err := http.NewRequest("GET", fileURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.Token))
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("download failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("download failed (status %d)", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read download: %w", err)
+	}
+	if int64(len(body)) > maxBytes {
+		return nil, fmt.Errorf("file exceeds %d bytes", maxBytes)
+	}
+
+	return body, nil
+}
+
+// Large files get longer than the API timeout to stream
+const fileStreamTimeout = 10 * time.Minute
+
+// OpenFile streams a file such as a submission attachment. The caller closes the reader.
+func (c *Client) OpenFile(fileURL string) (io.ReadCloser, error) {
+	req, err := http.NewRequest("GET", fileURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.Token))
+
+	streamer := &http.Client{Transport: c.HTTPClient.Transport, Timeout: fileStreamTimeout}
+	resp, err := streamer.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("download failed: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("download failed (status %d)", resp.StatusCode)
+	}
+
+	return resp.Body, nil
+}
@@ -0,0 +1,125 @@
+package canvas
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// pagedServer serves a list endpoint in pages linked by rel="next", as Canvas does
+func pagedServer(t *testing.T, pages []string, next func(server *httptest.Server, page int) string) (*Client, *httptest.Server) {
+	t.Helper()
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer token" {
+			t.Errorf("Authorization = %q", got)
+		}
+		page := 0
+		fmt.Sscan(r.URL.Query().Get("page"), &page)
+		if page >= len(pages) {
+			http.NotFound(w, r)
+			return
+		}
+		links := []string{fmt.Sprintf(`<%s/api/v1%s?page=0>; rel="first"`, server.URL, r.URL.Path)}
+		if link := next(server, page); link != "" {
+			links = append(links, fmt.Sprintf(`<%s>; rel="next"`, link))
+		}
+		w.Header().Set("Link", strings.Join(links, ","))
+		fmt.Fprint(w, pages[page])
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient("token", "canvas.example.edu")
+	client.BaseURL = server.URL + "/api/v1"
+	return client, server
+}
+
+func TestGetCourseEnrollmentsFollowsPages(t *testing.T) {
+	pages := []string{
+		`[{"user_id":1,"user":{"id":1,"name":"A"}},{"user_id":2,"user":{"id":2,"name":"B"}}]`,
+		`[{"user_id":3,"user":{"id":3,"name":"C"}}]`,
+		`[{"user_id":4,"user":{"id":4,"name":"D"}}]`,
+	}
+	client, _ := pagedServer(t, pages, func(server *httptest.Server, page int) string {
+		if page+1 < len(pages) {
+			return fmt.Sprintf("%s/api/v1/courses/1/enrollments?page=%d&per_page=100", server.URL, page+1)
+		}
+		return ""
+	})
+
+	enrollments, err := client.GetCourseEnrollments("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, enrollment := range enrollments {
+		names = append(names, enrollment.User.Name)
+	}
+	if got := strings.Join(names, ""); got != "ABCD" {
+		t.Errorf("got students %q, want ABCD", got)
+	}
+}
+
+func TestGetGroupCategoryGroupsFollowsPages(t *testing.T) {
+	pages := []string{
+		`[{"id":1,"name":"Team 1","members_count":2,"users":[{"id":10},{"id":11}]}]`,
+		`[{"id":2,"name":"Team 2","members_count":1,"users":[{"id":12}]}]`,
+	}
+	client, _ := pagedServer(t, pages, func(server *httptest.Server, page int) string {
+		if page == 0 {
+			return server.URL + "/api/v1/group_categories/5/groups?include[]=users&page=1"
+		}
+		return ""
+	})
+
+	groups, err := client.GetGroupCategoryGroups("5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 2 || groups[1].Name != "Team 2" || len(groups[0].Users) != 2 {
+		t.Errorf("got groups %+v, want both pages with their members", groups)
+	}
+}
+
+func TestNextPageMustStayOnCanvas(t *testing.T) {
+	client, _ := pagedServer(t, []string{`[]`}, func(*httptest.Server, int) string {
+		return "https://attacker.example.com/steal?page=1"
+	})
+
+	if _, err := client.GetCourseSections("1"); err == nil || !strings.Contains(err.Error(), "outside") {
+		t.Errorf("got error %v, want a rejected next link", err)
+	}
+}
+
+func TestNextPage(t *testing.T) {
+	client := NewClient("token", "canvas.example.edu")
+
+	tests := []struct {
+		name string
+		link string
+		want string
+	}{
+		{name: "no link header", want: ""},
+		{name: "last page", link: `<https://canvas.example.edu/api/v1/x?page=1>; rel="first", <https://canvas.example.edu/api/v1/x?page=3>; rel="last"`, want: ""},
+		{name: "next among others", link: `<https://canvas.example.edu/api/v1/x?page=1>; rel="current",<https://canvas.example.edu/api/v1/x?page=2>; rel="next"`, want: "https://canvas.example.edu/api/v1/x?page=2"},
+		{name: "unquoted rel", link: `<https://canvas.example.edu/api/v1/x?page=2>; rel=next`, want: "https://canvas.example.edu/api/v1/x?page=2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.link != "" {
+				header.Set("Link", tt.link)
+			}
+			got, err := client.nextPage(header)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
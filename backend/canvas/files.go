@@ -0,0 +1,202 @@
+package canvas
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+)
+
+// FileUpload is a file to upload to Canvas
+type FileUpload struct {
+	Name        string
+	ContentType string
+	Size        int64
+	Content     io.Reader
+}
+
+// SubmissionCommentInput is a comment to add to a submission, optionally with uploaded files
+type SubmissionCommentInput struct {
+	TextComment  string `json:"text_comment"`
+	GroupComment bool   `json:"group_comment"`
+	FileIDs      []int  `json:"file_ids"`
+	Attempt      int    `json:"attempt"` // Attempt the comment belongs to; 0 leaves it to Canvas
+}
+
+// uploadSlot is the upload target Canvas hands out in the first step of a file upload
+type uploadSlot struct {
+	UploadURL    string                 `json:"upload_url"`
+	UploadParams map[string]interface{} `json:"upload_params"`
+	FileParam    string                 `json:"file_param"`
+}
+
+// UploadSubmissionCommentFile uploads a file for a submission comment using Canvas's
+// three-step flow: reserve an upload slot, post the file to it, then confirm the upload.
+// The returned attachment's ID is passed to CommentOnSubmission.
+func (c *Client) UploadSubmissionCommentFile(courseID, assignmentID, userID string, file FileUpload) (*Attachment, error) {
+	form := url.Values{}
+	form.Set("name", file.Name)
+	form.Set("size", strconv.FormatInt(file.Size, 10))
+	if file.ContentType != "" {
+		form.Set("content_type", file.ContentType)
+	}
+
+	endpoint := fmt.Sprintf("/courses/%s/assignments/%s/submissions/%s/comments/files", courseID, assignmentID, userID)
+	body, err := c.makeFormRequest("POST", endpoint, form)
+	if err != nil {
+		return nil, err
+	}
+
+	var slot uploadSlot
+	if err := json.Unmarshal(body, &slot); err != nil {
+		return nil, fmt.Errorf("failed to parse upload slot: %w", err)
+	}
+	if slot.UploadURL == "" {
+		return nil, fmt.Errorf("canvas did not return an upload URL")
+	}
+
+	return c.postUpload(slot, file)
+}
+
+// postUpload sends the file to the upload URL and confirms it when Canvas redirects or
+// answers 201 Created with a location
+func (c *Client) postUpload(slot uploadSlot, file FileUpload) (*Attachment, error) {
+	fileParam := slot.FileParam
+	if fileParam == "" {
+		fileParam = "file"
+	}
+
+	// Stream the multipart body; the upload parameters must precede the file
+	reader, writer := io.Pipe()
+	mw := multipart.NewWriter(writer)
+	go func() {
+		writer.CloseWithError(writeUploadForm(mw, slot.UploadParams, fileParam, file))
+	}()
+
+	req, err := http.NewRequest("POST", slot.UploadURL, reader)
+	if err != nil {
+		reader.Close()
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+
+	// The upload URL may be a storage service, so the Canvas token is not sent to it, and
+	// redirects are handled here so the confirmation carries the token instead
+	uploader := &http.Client{
+		Timeout: c.HTTPClient.Timeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := uploader.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("upload failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload response: %w", err)
+	}
+
+	switch {
+	case resp.StatusCode >= 300 && resp.StatusCode < 400,
+		resp.StatusCode == http.StatusCreated && resp.Header.Get("Location") != "":
+		if body, err = c.confirmUpload(resp); err != nil {
+			return nil, err
+		}
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return nil, fmt.Errorf("upload failed (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var attachment Attachment
+	if err := json.Unmarshal(body, &attachment); err != nil {
+		return nil, fmt.Errorf("failed to parse uploaded file: %w", err)
+	}
+	if attachment.ID == 0 {
+		return nil, fmt.Errorf("upload response has no file ID: %s", string(body))
+	}
+
+	return &attachment, nil
+}
+
+// confirmUpload fetches the upload's location with the Canvas token, which Canvas answers
+// with the created file. The location must be on the Canvas host so the token stays there.
+func (c *Client) confirmUpload(resp *http.Response) ([]byte, error) {
+	location, err := resp.Location()
+	if err != nil {
+		return nil, fmt.Errorf("upload response has no location: %w", err)
+	}
+	if err := c.checkHost(location); err != nil {
+		return nil, fmt.Errorf("upload confirmation: %w", err)
+	}
+
+	confirm, err := http.NewRequest("GET", location.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	body, err := c.do(confirm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to confirm upload: %w", err)
+	}
+	return body, nil
+}
+
+func writeUploadForm(mw *multipart.Writer, params map[string]interface{}, fileParam string, file FileUpload) error {
+	// Sort parameter names so the encoded request is deterministic
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if params[name] == nil {
+			continue
+		}
+		if err := mw.WriteField(name, fmt.Sprint(params[name])); err != nil {
+			return err
+		}
+	}
+
+	part, err := mw.CreateFormFile(fileParam, file.Name)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, file.Content); err != nil {
+		return err
+	}
+	return mw.Close()
+}
+
+// CommentOnSubmission adds a comment to a submission without changing its grade
+func (c *Client) CommentOnSubmission(courseID, assignmentID, userID string, comment SubmissionCommentInput) (*Submission, error) {
+	form := url.Values{}
+	form.Set("comment[text_comment]", comment.TextComment)
+	if comment.GroupComment {
+		form.Set("comment[group_comment]", "true")
+	}
+	if comment.Attempt > 0 {
+		form.Set("comment[attempt]", strconv.Itoa(comment.Attempt))
+	}
+	for _, id := range comment.FileIDs {
+		form.Add("comment[file_ids][]", strconv.Itoa(id))
+	}
+
+	endpoint := fmt.Sprintf("/courses/%s/assignments/%s/submissions/%s", courseID, assignmentID, userID)
+	body, err := c.makeFormRequest("PUT", endpoint, form)
+	if err != nil {
+		return nil, err
+	}
+
+	var submission Submission
+	if err := json.Unmarshal(body, &submission); err != nil {
+		return nil, fmt.Errorf("failed to parse submission: %w", err)
+	}
+
+	return &submission, nil
+}
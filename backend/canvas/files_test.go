@@ -0,0 +1,125 @@
+package canvas
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPostUpload(t *testing.T) {
+	canvasServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer token" {
+			t.Errorf("confirmation Authorization = %q", got)
+		}
+		fmt.Fprint(w, `{"id":55,"display_name":"feedback.pdf"}`)
+	}))
+	t.Cleanup(canvasServer.Close)
+	elsewhere := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("request sent outside Canvas with Authorization %q", r.Header.Get("Authorization"))
+	}))
+	t.Cleanup(elsewhere.Close)
+
+	tests := []struct {
+		name    string
+		respond func(w http.ResponseWriter)
+		wantID  int
+		wantErr string
+	}{
+		{
+			name: "file returned directly",
+			respond: func(w http.ResponseWriter) {
+				fmt.Fprint(w, `{"id":42}`)
+			},
+			wantID: 42,
+		},
+		{
+			name: "redirect to Canvas is confirmed with the token",
+			respond: func(w http.ResponseWriter) {
+				w.Header().Set("Location", canvasServer.URL+"/api/v1/files/55/create_success")
+				w.WriteHeader(http.StatusSeeOther)
+			},
+			wantID: 55,
+		},
+		{
+			name: "201 with a location is confirmed",
+			respond: func(w http.ResponseWriter) {
+				w.Header().Set("Location", canvasServer.URL+"/api/v1/files/55")
+				w.WriteHeader(http.StatusCreated)
+				fmt.Fprint(w, `{}`)
+			},
+			wantID: 55,
+		},
+		{
+			name: "redirect outside Canvas is refused",
+			respond: func(w http.ResponseWriter) {
+				w.Header().Set("Location", elsewhere.URL+"/collect")
+				w.WriteHeader(http.StatusFound)
+			},
+			wantErr: "outside",
+		},
+		{
+			name: "response without a file ID",
+			respond: func(w http.ResponseWriter) {
+				fmt.Fprint(w, `{"message":"ok"}`)
+			},
+			wantErr: "no file ID",
+		},
+		{
+			name: "storage error",
+			respond: func(w http.ResponseWriter) {
+				http.Error(w, "denied", http.StatusForbidden)
+			},
+			wantErr: "status 403",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.Header.Get("Authorization"); got != "" {
+					t.Errorf("upload Authorization = %q, want none", got)
+				}
+				if err := r.ParseMultipartForm(1 << 20); err != nil {
+					t.Error(err)
+					return
+				}
+				if got := r.FormValue("key"); got != "uploads/1" {
+					t.Errorf("upload param key = %q", got)
+				}
+				file, _, err := r.FormFile("file")
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if content, _ := io.ReadAll(file); string(content) != "%PDF" {
+					t.Errorf("uploaded %q", content)
+				}
+				tt.respond(w)
+			}))
+			defer storage.Close()
+
+			client := NewClient("token", "canvas.example.edu")
+			client.BaseURL = canvasServer.URL + "/api/v1"
+
+			slot := uploadSlot{UploadURL: storage.URL, UploadParams: map[string]interface{}{"key": "uploads/1"}}
+			file := FileUpload{Name: "feedback.pdf", ContentType: "application/pdf", Size: 4, Content: strings.NewReader("%PDF")}
+
+			attachment, err := client.postUpload(slot, file)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if attachment.ID != tt.wantID {
+				t.Errorf("got file %d, want %d", attachment.ID, tt.wantID)
+			}
+		})
+	}
+}
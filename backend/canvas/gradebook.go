@@ -0,0 +1,82 @@
+package canvas
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+)
+
+// GetCourseSections fetches a course's sections
+func (c *Client) GetCourseSections(courseID string) ([]Section, error) {
+	params := url.Values{}
+	params.Add("per_page", "100")
+
+	endpoint := fmt.Sprintf("/courses/%s/sections", courseID)
+	pages, err := c.makePagedRequest(endpoint, params)
+	if err != nil {
+		return nil, err
+	}
+
+	var sections []Section
+	for _, body := range pages {
+		var page []Section
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, fmt.Errorf("failed to parse sections: %w", err)
+		}
+		sections = append(sections, page...)
+	}
+
+	return sections, nil
+}
+
+// UpdateGrades posts grades for many students on one assignment in a single request. Canvas
+// applies them in the background; poll the returned progress to follow it.
+func (c *Client) UpdateGrades(courseID, assignmentID string, grades map[int]BulkGrade) (*Progress, error) {
+	form := url.Values{}
+
+	// Sort user IDs so the encoded request is deterministic
+	userIDs := make([]int, 0, len(grades))
+	for userID := range grades {
+		userIDs = append(userIDs, userID)
+	}
+	sort.Ints(userIDs)
+
+	for _, userID := range userIDs {
+		key := "grade_data[" + strconv.Itoa(userID) + "]"
+		if grades[userID].Excuse {
+			form.Set(key+"[excuse]", "true")
+		} else {
+			form.Set(key+"[posted_grade]", grades[userID].PostedGrade)
+		}
+	}
+
+	endpoint := fmt.Sprintf("/courses/%s/assignments/%s/submissions/update_grades", courseID, assignmentID)
+	body, err := c.makeFormRequest("POST", endpoint, form)
+	if err != nil {
+		return nil, err
+	}
+
+	var progress Progress
+	if err := json.Unmarshal(body, &progress); err != nil {
+		return nil, fmt.Errorf("failed to parse progress: %w", err)
+	}
+
+	return &progress, nil
+}
+
+// GetProgress fetches the state of an asynchronous operation
+func (c *Client) GetProgress(progressID string) (*Progress, error) {
+	body, err := c.makeRequest("GET", "/progress/"+progressID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var progress Progress
+	if err := json.Unmarshal(body, &progress); err != nil {
+		return nil, fmt.Errorf("failed to parse progress: %w", err)
+	}
+
+	return &progress, nil
+}
@@ -0,0 +1,103 @@
+package canvas
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// graphQL runs a query against Canvas's GraphQL API and decodes its data into out
+func (c *Client) graphQL(query string, variables map[string]interface{}, out interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	if err != nil {
+		return fmt.Errorf("failed to marshal query: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", fmt.Sprintf("https://%s/api/graphql", c.SchoolURL), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	respBody, err := c.do(req)
+	if err != nil {
+		return err
+	}
+
+	// GraphQL reports failures in the body with a 200 status
+	var resp struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return fmt.Errorf("failed to parse GraphQL response: %w", err)
+	}
+	if len(resp.Errors) > 0 {
+		return fmt.Errorf("GraphQL error: %s", resp.Errors[0].Message)
+	}
+	if err := json.Unmarshal(resp.Data, out); err != nil {
+		return fmt.Errorf("failed to parse GraphQL data: %w", err)
+	}
+	return nil
+}
+
+// CommentBankItem is a saved comment from a user's Canvas comment library
+type CommentBankItem struct {
+	ID        string     `json:"_id"`
+	Comment   string     `json:"comment"`
+	CourseID  string     `json:"courseId"`
+	CreatedAt *time.Time `json:"createdAt"`
+	UpdatedAt *time.Time `json:"updatedAt"`
+}
+
+const commentBankQuery = `query CommentBank($userId: ID!, $after: String) {
+  legacyNode(_id: $userId, type: User) {
+    ... on User {
+      commentBankItemsConnection(first: 100, after: $after) {
+        nodes { _id comment courseId createdAt updatedAt }
+        pageInfo { hasNextPage endCursor }
+      }
+    }
+  }
+}`
+
+// GetCommentBankItems fetches every comment in a user's comment library. The library is only
+// exposed through GraphQL.
+func (c *Client) GetCommentBankItems(userID int) ([]CommentBankItem, error) {
+	var items []CommentBankItem
+	variables := map[string]interface{}{"userId": strconv.Itoa(userID)}
+
+	for {
+		var data struct {
+			LegacyNode *struct {
+				CommentBankItemsConnection struct {
+					Nodes    []CommentBankItem `json:"nodes"`
+					PageInfo struct {
+						HasNextPage bool   `json:"hasNextPage"`
+						EndCursor   string `json:"endCursor"`
+					} `json:"pageInfo"`
+				} `json:"commentBankItemsConnection"`
+			} `json:"legacyNode"`
+		}
+		if err := c.graphQL(commentBankQuery, variables, &data); err != nil {
+			return nil, err
+		}
+		if data.LegacyNode == nil {
+			return nil, fmt.Errorf("user %d not found", userID)
+		}
+
+		connection := data.LegacyNode.CommentBankItemsConnection
+		items = append(items, connection.Nodes...)
+		if !connection.PageInfo.HasNextPage || connection.PageInfo.EndCursor == "" {
+			break
+		}
+		variables["after"] = connection.PageInfo.EndCursor
+	}
+
+	return items, nil
+}
@@ -0,0 +1,207 @@
+package canvas
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// GetCourseGroupCategories fetches the group sets defined in a course
+func (c *Client) GetCourseGroupCategories(courseID string) ([]GroupCategory, error) {
+	params := url.Values{}
+	params.Add("per_page", "100")
+
+	endpoint := fmt.Sprintf("/courses/%s/group_categories", courseID)
+	pages, err := c.makePagedRequest(endpoint, params)
+	if err != nil {
+		return nil, err
+	}
+
+	var categories []GroupCategory
+	for _, body := range pages {
+		var page []GroupCategory
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, fmt.Errorf("failed to parse group categories: %w", err)
+		}
+		categories = append(categories, page...)
+	}
+
+	return categories, nil
+}
+
+// GetGroupCategoryGroups fetches the groups within a group set with their members
+func (c *Client) GetGroupCategoryGroups(categoryID string) ([]Group, error) {
+	params := url.Values{}
+	params.Add("include[]", "users")
+	params.Add("per_page", "100")
+
+	endpoint := fmt.Sprintf("/group_categories/%s/groups", categoryID)
+	pages, err := c.makePagedRequest(endpoint, params)
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []Group
+	for _, body := range pages {
+		var page []Group
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, fmt.Errorf("failed to parse groups: %w", err)
+		}
+		groups = append(groups, page...)
+	}
+
+	return groups, nil
+}
+
+// GetGroupUsers fetches the users who belong to a group
+func (c *Client) GetGroupUsers(groupID string) ([]User, error) {
+	params := url.Values{}
+	params.Add("include[]", "avatar_url")
+	params.Add("per_page", "100")
+
+	endpoint := fmt.Sprintf("/groups/%s/users", groupID)
+	pages, err := c.makePagedRequest(endpoint, params)
+	if err != nil {
+		return nil, err
+	}
+
+	var users []User
+	for _, body := range pages {
+		var page []User
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, fmt.Errorf("failed to parse group users: %w", err)
+		}
+		users = append(users, page...)
+	}
+
+	return users, nil
+}
+
+// GetGroupSubmissions fetches submissions for an assignment and collapses group members into
+// one gradeable unit per group. Non-group assignments yield one unit per student.
+func (c *Client) GetGroupSubmissions(courseID, assignmentID string) ([]GroupSubmission, error) {
+	assignment, err := c.GetAssignment(courseID, assignmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	submissions, err := c.GetAssignmentSubmissions(courseID, assignmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Map each student to their group using the assignment's group set
+	groupsByUser := make(map[int]*Group)
+	membersByGroup := make(map[int][]User)
+	var groups []Group
+	if assignment.GroupCategoryID != nil {
+		groups, err = c.GetGroupCategoryGroups(strconv.Itoa(*assignment.GroupCategoryID))
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range groups {
+			group := &groups[i]
+			users := group.Users
+			// Canvas caps the users it embeds in a group, so large groups are fetched in full
+			if len(users) < group.MembersCount {
+				if users, err = c.GetGroupUsers(strconv.Itoa(group.ID)); err != nil {
+					return nil, err
+				}
+			}
+			group.Users = nil
+			membersByGroup[group.ID] = users
+			for _, user := range users {
+				groupsByUser[user.ID] = group
+			}
+		}
+	}
+
+	return CollapseGroupSubmissions(assignment, submissions, groupsByUser, membersByGroup), nil
+}
+
+// CollapseGroupSubmissions folds per-student submissions into one unit per group, preserving
+// the order in which each group's first submission appears. The representative submission is
+// the member's most recent turned-in attempt, falling back to the first member.
+func CollapseGroupSubmissions(assignment *Assignment, submissions []Submission, groupsByUser map[int]*Group, membersByGroup map[int][]User) []GroupSubmission {
+	// Initialize as empty slice to ensure JSON returns [] instead of null
+	units := make([]GroupSubmission, 0)
+	unitIndexByGroup := make(map[int]int)
+
+	for _, submission := range submissions {
+		group := groupsByUser[submission.UserID]
+		if group == nil {
+			unit := GroupSubmission{
+				Submission:        submission,
+				MemberSubmissions: []Submission{submission},
+				GradeIndividually: true,
+			}
+			if submission.User != nil {
+				unit.Members = []User{*submission.User}
+			}
+			units = append(units, unit)
+			continue
+		}
+
+		if idx, ok := unitIndexByGroup[group.ID]; ok {
+			unit := &units[idx]
+			unit.MemberSubmissions = append(unit.MemberSubmissions, submission)
+			if isMoreRecentSubmission(submission, unit.Submission) {
+				unit.Submission = submission
+			}
+			continue
+		}
+
+		unitIndexByGroup[group.ID] = len(units)
+		units = append(units, GroupSubmission{
+			Group:             group,
+			Members:           membersByGroup[group.ID],
+			Submission:        submission,
+			MemberSubmissions: []Submission{submission},
+			GradeIndividually: assignment.GradeGroupStudentsIndividually,
+		})
+	}
+
+	return units
+}
+
+func isMoreRecentSubmission(candidate, current Submission) bool {
+	if candidate.SubmittedAt == nil {
+		return false
+	}
+	if current.SubmittedAt == nil {
+		return true
+	}
+	return candidate.SubmittedAt.After(*current.SubmittedAt)
+}
+
+// GradeGroupSubmission posts one grade for a gradeable unit. When the assignment grades group
+// members individually the grade is posted to every member; otherwise it is posted once and
+// Canvas applies it to the whole group, with the comment shared as a group comment.
+func (c *Client) GradeGroupSubmission(courseID, assignmentID string, unit GroupSubmission, grade SubmissionGrade) ([]Submission, error) {
+	if len(unit.MemberSubmissions) == 0 {
+		return nil, fmt.Errorf("group submission has no members to grade")
+	}
+
+	if unit.Group != nil && !unit.GradeIndividually {
+		grade.GroupComment = true
+		submission, err := c.GradeSubmission(courseID, assignmentID, strconv.Itoa(unit.Submission.UserID), grade)
+		if err != nil {
+			return nil, err
+		}
+		return []Submission{*submission}, nil
+	}
+
+	grade.GroupComment = false
+	graded := make([]Submission, 0, len(unit.MemberSubmissions))
+	for _, member := range unit.MemberSubmissions {
+		submission, err := c.GradeSubmission(courseID, assignmentID, strconv.Itoa(member.UserID), grade)
+		if err != nil {
+			return graded, fmt.Errorf("failed to grade user %d: %w", member.UserID, err)
+		}
+		graded = append(graded, *submission)
+	}
+
+	return graded, nil
+}
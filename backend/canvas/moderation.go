@@ -0,0 +1,115 @@
+package canvas
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+)
+
+// GetProvisionalGradeStatus reports whether a student still has an open grader slot on a
+// moderated assignment, i.e. whether the current user may add a provisional grade
+func (c *Client) GetProvisionalGradeStatus(courseID, assignmentID, studentID string) (*ProvisionalGradeStatus, error) {
+	params := url.Values{}
+	params.Add("student_id", studentID)
+
+	endpoint := fmt.Sprintf("/courses/%s/assignments/%s/provisional_grades/status", courseID, assignmentID)
+	body, err := c.makeRequest("GET", endpoint, params)
+	if err != nil {
+		return nil, err
+	}
+
+	var status ProvisionalGradeStatus
+	if err := json.Unmarshal(body, &status); err != nil {
+		return nil, fmt.Errorf("failed to parse provisional grade status: %w", err)
+	}
+
+	return &status, nil
+}
+
+// SubmitProvisionalGrade records a provisional grade, claiming a grader slot for the student
+func (c *Client) SubmitProvisionalGrade(courseID, assignmentID, userID string, grade ProvisionalGradeInput) (*Submission, error) {
+	form := url.Values{}
+	form.Set("submission[posted_grade]", grade.PostedGrade)
+	form.Set("submission[provisional]", "true")
+	if grade.TextComment != "" {
+		form.Set("comment[text_comment]", grade.TextComment)
+	}
+	addRubricAssessmentParams(form, grade.RubricAssessment)
+
+	endpoint := fmt.Sprintf("/courses/%s/assignments/%s/submissions/%s", courseID, assignmentID, userID)
+	body, err := c.makeFormRequest("PUT", endpoint, form)
+	if err != nil {
+		return nil, err
+	}
+
+	var submission Submission
+	if err := json.Unmarshal(body, &submission); err != nil {
+		return nil, fmt.Errorf("failed to parse submission: %w", err)
+	}
+
+	return &submission, nil
+}
+
+// addRubricAssessmentParams encodes a rubric assessment as Canvas form parameters
+func addRubricAssessmentParams(form url.Values, assessment RubricAssessment) {
+	// Sort criterion IDs so the encoded request is deterministic
+	criterionIDs := make([]string, 0, len(assessment))
+	for id := range assessment {
+		criterionIDs = append(criterionIDs, id)
+	}
+	sort.Strings(criterionIDs)
+
+	for _, id := range criterionIDs {
+		criterion := assessment[id]
+		if criterion.Points != nil {
+			form.Set(fmt.Sprintf("rubric_assessment[%s][points]", id), strconv.FormatFloat(*criterion.Points, 'f', -1, 64))
+		}
+		if criterion.RatingID != "" {
+			form.Set(fmt.Sprintf("rubric_assessment[%s][rating_id]", id), criterion.RatingID)
+		}
+		if criterion.Comments != "" {
+			form.Set(fmt.Sprintf("rubric_assessment[%s][comments]", id), criterion.Comments)
+		}
+	}
+}
+
+// ListProvisionalGrades fetches every gradeable student with the provisional grades each
+// grader has given. Requires moderate permissions on the assignment.
+func (c *Client) ListProvisionalGrades(courseID, assignmentID string) ([]GradeableStudent, error) {
+	params := url.Values{}
+	params.Add("include[]", "provisional_grades")
+	params.Add("per_page", "100")
+
+	endpoint := fmt.Sprintf("/courses/%s/assignments/%s/gradeable_students", courseID, assignmentID)
+	pages, err := c.makePagedRequest(endpoint, params)
+	if err != nil {
+		return nil, err
+	}
+
+	var students []GradeableStudent
+	for _, body := range pages {
+		var page []GradeableStudent
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, fmt.Errorf("failed to parse gradeable students: %w", err)
+		}
+		students = append(students, page...)
+	}
+
+	return students, nil
+}
+
+// SelectProvisionalGrade chooses which grader's provisional grade becomes the final grade
+func (c *Client) SelectProvisionalGrade(courseID, assignmentID, provisionalGradeID string) error {
+	endpoint := fmt.Sprintf("/courses/%s/assignments/%s/provisional_grades/%s/select", courseID, assignmentID, provisionalGradeID)
+	_, err := c.makeFormRequest("PUT", endpoint, url.Values{})
+	return err
+}
+
+// PublishProvisionalGrades publishes the selected provisional grades to students
+func (c *Client) PublishProvisionalGrades(courseID, assignmentID string) error {
+	endpoint := fmt.Sprintf("/courses/%s/assignments/%s/provisional_grades/publish", courseID, assignmentID)
+	_, err := c.makeFormRequest("POST", endpoint, url.Values{})
+	return err
+}
@@ -0,0 +1,107 @@
+package canvas
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// GetCourseRubrics fetches the rubrics defined in a course with their associations
+func (c *Client) GetCourseRubrics(courseID string) ([]RubricDefinition, error) {
+	params := url.Values{}
+	params.Add("include[]", "associations")
+	params.Add("per_page", "100")
+
+	endpoint := fmt.Sprintf("/courses/%s/rubrics", courseID)
+	body, err := c.makeRequest("GET", endpoint, params)
+	if err != nil {
+		return nil, err
+	}
+
+	var rubrics []RubricDefinition
+	if err := json.Unmarshal(body, &rubrics); err != nil {
+		return nil, fmt.Errorf("failed to parse rubrics: %w", err)
+	}
+
+	return rubrics, nil
+}
+
+// GetRubric fetches a single course rubric with its associations
+func (c *Client) GetRubric(courseID, rubricID string) (*RubricDefinition, error) {
+	params := url.Values{}
+	params.Add("include[]", "associations")
+
+	endpoint := fmt.Sprintf("/courses/%s/rubrics/%s", courseID, rubricID)
+	body, err := c.makeRequest("GET", endpoint, params)
+	if err != nil {
+		return nil, err
+	}
+
+	var rubric RubricDefinition
+	if err := json.Unmarshal(body, &rubric); err != nil {
+		return nil, fmt.Errorf("failed to parse rubric: %w", err)
+	}
+
+	return &rubric, nil
+}
+
+// CreateRubric creates a course rubric. When assignmentID is set the rubric is also
+// associated with that assignment, and used for grading if useForGrading is true.
+func (c *Client) CreateRubric(courseID string, rubric RubricDefinition, assignmentID string, useForGrading bool) (*RubricDefinition, *RubricAssociation, error) {
+	endpoint := fmt.Sprintf("/courses/%s/rubrics", courseID)
+	return c.saveRubric("POST", endpoint, courseID, rubric, assignmentID, useForGrading)
+}
+
+// UpdateRubric replaces the criteria of an existing course rubric and its association,
+// as CreateRubric sets them
+func (c *Client) UpdateRubric(courseID, rubricID string, rubric RubricDefinition, assignmentID string, useForGrading bool) (*RubricDefinition, *RubricAssociation, error) {
+	endpoint := fmt.Sprintf("/courses/%s/rubrics/%s", courseID, rubricID)
+	return c.saveRubric("PUT", endpoint, courseID, rubric, assignmentID, useForGrading)
+}
+
+// saveRubric sends a rubric and its association as Canvas form parameters
+func (c *Client) saveRubric(method, endpoint, courseID string, rubric RubricDefinition, assignmentID string, useForGrading bool) (*RubricDefinition, *RubricAssociation, error) {
+	form := url.Values{}
+	form.Set("rubric[title]", rubric.Title)
+	form.Set("rubric[free_form_criterion_comments]", strconv.FormatBool(rubric.FreeFormCriterionComments))
+
+	for i, criterion := range rubric.Data {
+		prefix := fmt.Sprintf("rubric[criteria][%d]", i)
+		form.Set(prefix+"[description]", criterion.Description)
+		form.Set(prefix+"[long_description]", criterion.LongDescription)
+		form.Set(prefix+"[points]", strconv.FormatFloat(criterion.Points, 'f', -1, 64))
+		for j, rating := range criterion.Ratings {
+			ratingPrefix := fmt.Sprintf("%s[ratings][%d]", prefix, j)
+			form.Set(ratingPrefix+"[description]", rating.Description)
+			form.Set(ratingPrefix+"[long_description]", rating.LongDescription)
+			form.Set(ratingPrefix+"[points]", strconv.FormatFloat(rating.Points, 'f', -1, 64))
+		}
+	}
+
+	if assignmentID != "" {
+		form.Set("rubric_association[association_id]", assignmentID)
+		form.Set("rubric_association[association_type]", "Assignment")
+		form.Set("rubric_association[use_for_grading]", strconv.FormatBool(useForGrading))
+		form.Set("rubric_association[purpose]", "grading")
+	} else {
+		form.Set("rubric_association[association_id]", courseID)
+		form.Set("rubric_association[association_type]", "Course")
+		form.Set("rubric_association[purpose]", "bookmark")
+	}
+
+	body, err := c.makeFormRequest(method, endpoint, form)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var created struct {
+		Rubric            RubricDefinition   `json:"rubric"`
+		RubricAssociation *RubricAssociation `json:"rubric_association"`
+	}
+	if err := json.Unmarshal(body, &created); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse saved rubric: %w", err)
+	}
+
+	return &created.Rubric, created.RubricAssociation, nil
+}
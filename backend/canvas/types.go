@@ -11,6 +11,7 @@ type User struct {
 	Email        string `json:"email"`
 	AvatarURL    string `json:"avatar_url"`
 	LoginID      string `json:"login_id"`
+	SISUserID    string `json:"sis_user_id,omitempty"`
 }
 
 // Enrollment represents a Canvas course enrollment
@@ -23,6 +24,7 @@ type Enrollment struct {
 	Role                           string `json:"role"`
 	RoleID                         int    `json:"role_id"`
 	LimitPrivilegesToCourseSection bool   `json:"limit_privileges_to_course_section"`
+	CourseSectionID                int    `json:"course_section_id"`
 	User                           User   `json:"user"`
 	Grades                         *struct {
 		HTMLURL              string  `json:"html_url"`
@@ -70,21 +72,61 @@ type Assignment struct {
 	Rubric                  []Rubric    `json:"rubric"`
 	UseRubricForGrading     bool        `json:"use_rubric_for_grading"`
 	RubricSettings          interface{} `json:"rubric_settings"`
+
+	GroupCategoryID                *int `json:"group_category_id"`
+	GradeGroupStudentsIndividually bool `json:"grade_group_students_individually"`
+
+	AnonymousGrading bool `json:"anonymous_grading"`
+	ModeratedGrading bool `json:"moderated_grading"`
+	GraderCount      int  `json:"grader_count"`
+	FinalGraderID    *int `json:"final_grader_id"`
 }
 
 // Rubric represents a grading rubric criterion
 type Rubric struct {
-	ID              string  `json:"id"`
+	ID              string         `json:"id"`
+	Points          float64        `json:"points"`
+	Description     string         `json:"description"`
+	LongDescription string         `json:"long_description"`
+	Ratings         []RubricRating `json:"ratings,omitempty"`
+}
+
+// RubricRating is one achievement level of a rubric criterion
+type RubricRating struct {
+	ID              string  `json:"id,omitempty"`
 	Points          float64 `json:"points"`
 	Description     string  `json:"description"`
 	LongDescription string  `json:"long_description"`
 }
 
+// RubricDefinition is a course rubric, as listed by the rubrics API
+type RubricDefinition struct {
+	ID                        int                 `json:"id"`
+	Title                     string              `json:"title"`
+	ContextID                 int                 `json:"context_id"`
+	ContextType               string              `json:"context_type"`
+	PointsPossible            float64             `json:"points_possible"`
+	FreeFormCriterionComments bool                `json:"free_form_criterion_comments"`
+	Data                      []Rubric            `json:"data"`
+	Associations              []RubricAssociation `json:"associations,omitempty"` // Present when include[]=associations
+}
+
+// RubricAssociation links a rubric to an assignment or course
+type RubricAssociation struct {
+	ID              int    `json:"id"`
+	RubricID        int    `json:"rubric_id"`
+	AssociationID   int    `json:"association_id"`
+	AssociationType string `json:"association_type"` // "Assignment", "Course" or "Account"
+	UseForGrading   bool   `json:"use_for_grading"`
+	Purpose         string `json:"purpose"`
+}
+
 // Submission represents a Canvas submission
 type Submission struct {
 	ID                 int                 `json:"id"`
 	AssignmentID       int                 `json:"assignment_id"`
 	UserID             int                 `json:"user_id"`
+	AnonymousID        string              `json:"anonymous_id,omitempty"` // Set for anonymously graded assignments
 	SubmittedAt        *time.Time          `json:"submitted_at"`
 	Score              float64             `json:"score"`
 	Grade              string              `json:"grade"`
@@ -101,8 +143,48 @@ type Submission struct {
 	Attempt            int                 `json:"attempt"`
 	Late               bool                `json:"late"`
 	Missing            bool                `json:"missing"`
+	Excused            bool                `json:"excused"`
 	SubmissionComments []SubmissionComment `json:"submission_comments"`
 	User               *User               `json:"user"`
+	RubricAssessment   RubricAssessment    `json:"rubric_assessment"`
+	SubmissionHistory  []SubmissionAttempt `json:"submission_history,omitempty"` // Present when include[]=submission_history
+}
+
+// SubmissionAttempt is one attempt from a submission's history
+type SubmissionAttempt struct {
+	ID             int          `json:"id"`
+	Attempt        int          `json:"attempt"`
+	SubmittedAt    *time.Time   `json:"submitted_at"`
+	Score          *float64     `json:"score"`
+	Grade          string       `json:"grade"`
+	GraderID       int          `json:"grader_id"`
+	GradedAt       *time.Time   `json:"graded_at"`
+	WorkflowState  string       `json:"workflow_state"`
+	SubmissionType string       `json:"submission_type"`
+	Body           string       `json:"body"`
+	URL            string       `json:"url"`
+	Attachments    []Attachment `json:"attachments"`
+	Late           bool         `json:"late"`
+}
+
+// HistoryAttempt returns the attempt with the given number from the submission's history
+func (s Submission) HistoryAttempt(number int) (*SubmissionAttempt, bool) {
+	for i := range s.SubmissionHistory {
+		if s.SubmissionHistory[i].Attempt == number {
+			return &s.SubmissionHistory[i], true
+		}
+	}
+	return nil, false
+}
+
+// RubricAssessment maps rubric criterion IDs to the assessment recorded for each criterion
+type RubricAssessment map[string]RubricCriterionAssessment
+
+// RubricCriterionAssessment is the score and comment recorded against one rubric criterion
+type RubricCriterionAssessment struct {
+	Points   *float64 `json:"points"`
+	RatingID string   `json:"rating_id,omitempty"`
+	Comments string   `json:"comments,omitempty"`
 }
 
 // Attachment represents a file attachment
@@ -127,10 +209,12 @@ type MediaComment struct {
 
 // SubmissionComment represents a comment on a submission
 type SubmissionComment struct {
-	ID        int        `json:"id"`
-	AuthorID  int        `json:"author_id"`
-	Comment   string     `json:"comment"`
-	CreatedAt *time.Time `json:"created_at"`
+	ID          int          `json:"id"`
+	AuthorID    int          `json:"author_id"`
+	Comment     string       `json:"comment"`
+	Attempt     *int         `json:"attempt"` // The attempt the comment was left on, when Canvas records it
+	Attachments []Attachment `json:"attachments,omitempty"`
+	CreatedAt   *time.Time   `json:"created_at"`
 }
 
 // CourseWithStats extends Course with additional statistics
@@ -144,3 +228,96 @@ type AssignmentWithStats struct {
 	Assignment
 	UngradedCount int `json:"ungraded_count"`
 }
+
+// GroupCategory represents a Canvas group set
+type GroupCategory struct {
+	ID         int    `json:"id"`
+	Name       string `json:"name"`
+	Role       string `json:"role"`
+	SelfSignup string `json:"self_signup"`
+	GroupLimit *int   `json:"group_limit"`
+	CourseID   int    `json:"course_id"`
+}
+
+// Group represents a Canvas student group
+type Group struct {
+	ID              int    `json:"id"`
+	Name            string `json:"name"`
+	GroupCategoryID int    `json:"group_category_id"`
+	MembersCount    int    `json:"members_count"`
+	Users           []User `json:"users,omitempty"` // Present when include[]=users
+}
+
+// GroupSubmission collapses the submissions of every group member into one gradeable unit.
+// Students without a group appear as a unit of one with a nil Group.
+type GroupSubmission struct {
+	Group             *Group       `json:"group"`
+	Members           []User       `json:"members"`
+	Submission        Submission   `json:"submission"`         // Representative submission shown to the grader
+	MemberSubmissions []Submission `json:"member_submissions"` // One per member, in member order
+	GradeIndividually bool         `json:"grade_individually"`
+}
+
+// SubmissionGrade is the grade and optional comment posted for a submission
+type SubmissionGrade struct {
+	PostedGrade  string `json:"posted_grade"`
+	TextComment  string `json:"text_comment"`
+	GroupComment bool   `json:"group_comment"`
+}
+
+// ProvisionalGrade is one grader's provisional grade on a moderated assignment
+type ProvisionalGrade struct {
+	ProvisionalGradeID            int        `json:"provisional_grade_id"`
+	Score                         *float64   `json:"score"`
+	Grade                         string     `json:"grade"`
+	ScorerID                      int        `json:"scorer_id"`
+	GradedAt                      *time.Time `json:"graded_at"`
+	Final                         bool       `json:"final"`
+	GradeMatchesCurrentSubmission bool       `json:"grade_matches_current_submission"`
+	SpeedGraderURL                string     `json:"speedgrader_url"`
+}
+
+// GradeableStudent is a student listed for grading, with provisional grades when moderated
+type GradeableStudent struct {
+	ID                         int                `json:"id"`
+	DisplayName                string             `json:"display_name"`
+	AvatarImageURL             string             `json:"avatar_image_url"`
+	InModerationSet            bool               `json:"in_moderation_set"`
+	SelectedProvisionalGradeID *int               `json:"selected_provisional_grade_id"`
+	ProvisionalGrades          []ProvisionalGrade `json:"provisional_grades"`
+}
+
+// ProvisionalGradeStatus reports whether a student still needs a provisional grade
+type ProvisionalGradeStatus struct {
+	NeedsProvisionalGrade bool `json:"needs_provisional_grade"`
+}
+
+// ProvisionalGradeInput is a provisional grade, with optional rubric assessment, to submit
+type ProvisionalGradeInput struct {
+	PostedGrade      string           `json:"posted_grade"`
+	TextComment      string           `json:"text_comment"`
+	RubricAssessment RubricAssessment `json:"rubric_assessment"`
+}
+
+// Section is a course section
+type Section struct {
+	ID           int    `json:"id"`
+	Name         string `json:"name"`
+	SISSectionID string `json:"sis_section_id,omitempty"`
+}
+
+// Progress tracks an asynchronous Canvas operation such as a bulk grade update
+type Progress struct {
+	ID            int     `json:"id"`
+	WorkflowState string  `json:"workflow_state"` // "queued", "running", "completed" or "failed"
+	Completion    float64 `json:"completion"`
+	Message       string  `json:"message"`
+	URL           string  `json:"url"`
+}
+
+// BulkGrade is one student's grade in a bulk update. Excuse marks the assignment excused
+// instead of posting a grade.
+type BulkGrade struct {
+	PostedGrade string `json:"posted_grade"`
+	Excuse      bool   `json:"excuse"`
+}
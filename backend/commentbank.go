@@ -0,0 +1,318 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"auxa/canvas"
+	"auxa/commentbank"
+	"auxa/templates"
+
+	"github.com/gin-gonic/gin"
+)
+
+// commentBank holds the team's reusable comment snippets; initialised in main
+var commentBank *commentbank.Store
+
+// Default number of suggestions for a submission
+const defaultSnippetSuggestions = 5
+
+// List snippets, optionally filtered by course, assignment, criterion, tag and text
+func getSnippets(c *gin.Context) {
+	c.JSON(http.StatusOK, commentBank.List(commentbank.Filter{
+		CourseID:     c.Query("course_id"),
+		AssignmentID: c.Query("assignment_id"),
+		CriterionID:  c.Query("criterion_id"),
+		Tag:          c.Query("tag"),
+		Query:        c.Query("q"),
+	}))
+}
+
+// Get a snippet
+func getSnippet(c *gin.Context) {
+	snippet, found := commentBank.Get(c.Param("snippet_id"))
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Snippet not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, snippet)
+}
+
+// Create a snippet
+func createSnippet(c *gin.Context) {
+	var req commentbank.Snippet
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	created, err := commentBank.Create(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, created)
+}
+
+// Replace a snippet, keeping its usage count
+func updateSnippet(c *gin.Context) {
+	var req commentbank.Snippet
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	id := c.Param("snippet_id")
+	if _, found := commentBank.Get(id); !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Snippet not found"})
+		return
+	}
+
+	updated, err := commentBank.Update(id, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// Delete a snippet
+func deleteSnippet(c *gin.Context) {
+	if err := commentBank.Delete(c.Param("snippet_id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Snippet deleted"})
+}
+
+// snippetRequest names the submission and criterion a snippet is rendered for
+type snippetRequest struct {
+	CourseID     string   `json:"course_id"`
+	AssignmentID string   `json:"assignment_id"`
+	UserID       int      `json:"user_id"`
+	CriterionID  string   `json:"criterion_id"`
+	PointsLost   *float64 `json:"points_lost"`
+}
+
+// snippetContext loads what a snippet's variables need from Canvas. It writes the error
+// response and returns false on failure.
+func snippetContext(c *gin.Context, req snippetRequest) (commentbank.Context, bool) {
+	ctx := commentbank.Context{PointsLost: req.PointsLost}
+	if req.CourseID == "" || req.AssignmentID == "" {
+		if req.CriterionID != "" || req.UserID != 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "course_id and assignment_id are required with user_id or criterion_id"})
+			return ctx, false
+		}
+		return ctx, true
+	}
+
+	client, ok := canvasClientFromRequest(c)
+	if !ok {
+		return ctx, false
+	}
+
+	assignment, err := client.GetAssignment(req.CourseID, req.AssignmentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return ctx, false
+	}
+	ctx.Assignment = assignment
+
+	if req.CriterionID != "" {
+		criterion, found := findCriterion(assignment, req.CourseID, req.AssignmentID, req.CriterionID)
+		if !found {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Criterion not found in the assignment's rubric"})
+			return ctx, false
+		}
+		ctx.Criterion = &criterion
+	}
+
+	if req.UserID != 0 {
+		submission, err := findSubmission(client, req.CourseID, req.AssignmentID, req.UserID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return ctx, false
+		}
+		if submission == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Submission not found"})
+			return ctx, false
+		}
+		ctx.Submission = submission
+		ctx.Content = submissionContent(client, *submission)
+		if submission.User != nil {
+			ctx.StudentName = submission.User.Name
+		}
+	}
+	return ctx, true
+}
+
+// findCriterion looks a criterion up in the assignment's Canvas rubric, then in the team's
+// stored rubric for the assignment
+func findCriterion(assignment *canvas.Assignment, courseID, assignmentID, criterionID string) (canvas.Rubric, bool) {
+	criteria := assignment.Rubric
+	if r, found := rubricStore.ForAssignment(courseID, assignmentID); found {
+		criteria = append(append([]canvas.Rubric{}, criteria...), r.Criteria...)
+	}
+	for _, criterion := range criteria {
+		if criterion.ID == criterionID {
+			return criterion, true
+		}
+	}
+	return canvas.Rubric{}, false
+}
+
+// Preview a snippet filled in for a submission and criterion, without counting a use
+func renderSnippet(c *gin.Context) {
+	respondWithSnippet(c, false)
+}
+
+// Fill in a snippet for a submission and count the use
+func useSnippet(c *gin.Context) {
+	respondWithSnippet(c, true)
+}
+
+func respondWithSnippet(c *gin.Context, recordUse bool) {
+	var req snippetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	snippet, found := commentBank.Get(c.Param("snippet_id"))
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Snippet not found"})
+		return
+	}
+
+	ctx, ok := snippetContext(c, req)
+	if !ok {
+		return
+	}
+
+	vars := commentbank.Variables(ctx)
+	rendered, err := templates.Render(snippet.Text, vars)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "variables": vars})
+		return
+	}
+
+	if recordUse {
+		if snippet, err = commentBank.RecordUse(snippet.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"snippet":   snippet,
+		"rendered":  rendered,
+		"variables": vars,
+	})
+}
+
+// Rank snippets for a student's submission, optionally for one rubric criterion
+func suggestSnippets(c *gin.Context) {
+	req := snippetRequest{
+		CourseID:     c.Param("course_id"),
+		AssignmentID: c.Param("assignment_id"),
+		CriterionID:  c.Query("criterion_id"),
+	}
+
+	var err error
+	if req.UserID, err = strconv.Atoi(c.Param("user_id")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id must be a number"})
+		return
+	}
+
+	limit := defaultSnippetSuggestions
+	if raw := c.Query("limit"); raw != "" {
+		if limit, err = strconv.Atoi(raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a number"})
+			return
+		}
+	}
+	if raw := c.Query("points_lost"); raw != "" {
+		pointsLost, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "points_lost must be a number"})
+			return
+		}
+		req.PointsLost = &pointsLost
+	}
+
+	ctx, ok := snippetContext(c, req)
+	if !ok {
+		return
+	}
+
+	snippets := commentBank.List(commentbank.Filter{
+		CourseID:     req.CourseID,
+		AssignmentID: req.AssignmentID,
+		CriterionID:  req.CriterionID,
+	})
+
+	c.JSON(http.StatusOK, commentbank.Suggest(snippets, ctx, limit))
+}
+
+// Import the connected user's Canvas comment library, optionally only one course's comments.
+// Comments imported before are updated in place.
+func importCanvasCommentBank(c *gin.Context) {
+	courseID := c.Query("course_id")
+
+	client, ok := canvasClientFromRequest(c)
+	if !ok {
+		return
+	}
+
+	user, err := client.GetUserProfile()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	items, err := client.GetCommentBankItems(user.ID)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Initialize as empty slices to ensure JSON returns [] instead of null
+	imported := make([]commentbank.Snippet, 0, len(items))
+	skipped := make([]gin.H, 0)
+	for _, item := range items {
+		if courseID != "" && item.CourseID != courseID {
+			continue
+		}
+
+		// Canvas comments are plain text, so braces in them must not run as template actions
+		text := templates.Escape(item.Comment)
+		snippet := commentbank.Snippet{
+			Text:     text,
+			CourseID: item.CourseID,
+			Source:   commentbank.SourceCanvas,
+			CanvasID: item.ID,
+		}
+		var saved commentbank.Snippet
+		if existing, found := commentBank.FindCanvas(item.ID); found {
+			// Keep the team's tags and scope; only the text comes from Canvas
+			existing.Text = text
+			saved, err = commentBank.Update(existing.ID, existing)
+		} else {
+			saved, err = commentBank.Create(snippet)
+		}
+		if err != nil {
+			skipped = append(skipped, gin.H{"canvas_id": item.ID, "error": err.Error()})
+			continue
+		}
+		imported = append(imported, saved)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"imported": imported,
+		"skipped":  skipped,
+	})
+}
@@ -0,0 +1,272 @@
+// Package commentbank stores the team's reusable feedback snippets. Snippets are templates
+// in the prompt template syntax, so {{student.first_name}} or {{points_lost}} are filled in
+// for each submission.
+package commentbank
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"auxa/store"
+)
+
+// Snippet sources
+const (
+	SourceManual = "manual"
+	SourceCanvas = "canvas" // Imported from a Canvas comment library
+)
+
+// Snippet is a canned comment. An empty course makes it available everywhere, an empty
+// assignment across its course, and no criteria for any criterion.
+type Snippet struct {
+	ID           string     `json:"id"`
+	Title        string     `json:"title,omitempty"`
+	Text         string     `json:"text"`
+	CourseID     string     `json:"course_id,omitempty"`
+	AssignmentID string     `json:"assignment_id,omitempty"`
+	CriterionIDs []string   `json:"criterion_ids,omitempty"`
+	Tags         []string   `json:"tags,omitempty"`
+	Source       string     `json:"source"`
+	CanvasID     string     `json:"canvas_id,omitempty"` // Canvas comment bank item, when imported
+	UsageCount   int        `json:"usage_count"`
+	LastUsedAt   *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// Validate checks the snippet's text, and that its template only uses known variables
+func (s *Snippet) Validate() error {
+	s.Text = strings.TrimSpace(s.Text)
+	if s.Text == "" {
+		return fmt.Errorf("snippet text is required")
+	}
+	if s.AssignmentID != "" && s.CourseID == "" {
+		return fmt.Errorf("course_id is required for assignment snippets")
+	}
+	if s.Source == "" {
+		s.Source = SourceManual
+	}
+	// Every variable is present, empty, without a submission, so rendering catches typos
+	_, err := Render(*s, Context{})
+	return err
+}
+
+// HasCriterion reports whether the snippet is tagged with a criterion
+func (s Snippet) HasCriterion(criterionID string) bool {
+	for _, id := range s.CriterionIDs {
+		if id == criterionID {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter narrows a listing; empty fields match everything
+type Filter struct {
+	CourseID     string
+	AssignmentID string
+	CriterionID  string
+	Tag          string
+	Query        string // Case-insensitive text to find in the title or text
+}
+
+// matches applies the filter. Snippets of broader scopes match narrower filters, so a
+// course-wide snippet is listed for each of the course's assignments.
+func (f Filter) matches(s Snippet) bool {
+	if f.CourseID != "" && s.CourseID != "" && s.CourseID != f.CourseID {
+		return false
+	}
+	if f.AssignmentID != "" && s.AssignmentID != "" && s.AssignmentID != f.AssignmentID {
+		return false
+	}
+	if f.CriterionID != "" && len(s.CriterionIDs) > 0 && !s.HasCriterion(f.CriterionID) {
+		return false
+	}
+	if f.Tag != "" {
+		found := false
+		for _, tag := range s.Tags {
+			if strings.EqualFold(tag, f.Tag) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.Query != "" {
+		query := strings.ToLower(f.Query)
+		if !strings.Contains(strings.ToLower(s.Title), query) && !strings.Contains(strings.ToLower(s.Text), query) {
+			return false
+		}
+	}
+	return true
+}
+
+// Store persists the comment bank
+type Store struct {
+	mu       sync.RWMutex
+	path     string
+	snippets map[string]Snippet
+}
+
+// LoadStore reads snippets from path
+func LoadStore(path string) (*Store, error) {
+	s := &Store{path: path, snippets: make(map[string]Snippet)}
+	if err := store.LoadJSON(path, &s.snippets); err != nil {
+		return nil, err
+	}
+	if s.snippets == nil {
+		s.snippets = make(map[string]Snippet)
+	}
+	return s, nil
+}
+
+func (s *Store) save() error {
+	return store.SaveJSON(s.path, s.snippets)
+}
+
+// List returns matching snippets, most used first
+func (s *Store) List(filter Filter) []Snippet {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// Initialize as empty slice to ensure JSON returns [] instead of null
+	list := make([]Snippet, 0, len(s.snippets))
+	for _, snippet := range s.snippets {
+		if filter.matches(snippet) {
+			list = append(list, snippet)
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].UsageCount != list[j].UsageCount {
+			return list[i].UsageCount > list[j].UsageCount
+		}
+		return list[i].ID < list[j].ID
+	})
+	return list
+}
+
+// Get returns a snippet by ID
+func (s *Store) Get(id string) (Snippet, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snippet, ok := s.snippets[id]
+	return snippet, ok
+}
+
+// FindCanvas returns the snippet imported from a Canvas comment bank item
+func (s *Store) FindCanvas(canvasID string) (Snippet, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, snippet := range s.snippets {
+		if snippet.CanvasID == canvasID {
+			return snippet, true
+		}
+	}
+	return Snippet{}, false
+}
+
+// Create validates and stores a new snippet
+func (s *Store) Create(snippet Snippet) (Snippet, error) {
+	if err := snippet.Validate(); err != nil {
+		return Snippet{}, err
+	}
+
+	idBytes := make([]byte, 8)
+	if _, err := rand.Read(idBytes); err != nil {
+		return Snippet{}, fmt.Errorf("failed to generate snippet ID: %w", err)
+	}
+	snippet.ID = hex.EncodeToString(idBytes)
+	snippet.UsageCount = 0
+	snippet.LastUsedAt = nil
+	snippet.CreatedAt = time.Now()
+	snippet.UpdatedAt = snippet.CreatedAt
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.snippets[snippet.ID] = snippet
+	if err := s.save(); err != nil {
+		delete(s.snippets, snippet.ID)
+		return Snippet{}, err
+	}
+	return snippet, nil
+}
+
+// Update validates and replaces an existing snippet, keeping its usage history
+func (s *Store) Update(id string, snippet Snippet) (Snippet, error) {
+	if err := snippet.Validate(); err != nil {
+		return Snippet{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.snippets[id]
+	if !ok {
+		return Snippet{}, fmt.Errorf("snippet %s not found", id)
+	}
+
+	snippet.ID = id
+	snippet.UsageCount = existing.UsageCount
+	snippet.LastUsedAt = existing.LastUsedAt
+	snippet.CreatedAt = existing.CreatedAt
+	snippet.UpdatedAt = time.Now()
+
+	s.snippets[id] = snippet
+	if err := s.save(); err != nil {
+		s.snippets[id] = existing
+		return Snippet{}, err
+	}
+	return snippet, nil
+}
+
+// Delete removes a snippet
+func (s *Store) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.snippets[id]
+	if !ok {
+		return fmt.Errorf("snippet %s not found", id)
+	}
+
+	delete(s.snippets, id)
+	if err := s.save(); err != nil {
+		s.snippets[id] = existing
+		return err
+	}
+	return nil
+}
+
+// RecordUse counts one use of a snippet
+func (s *Store) RecordUse(id string) (Snippet, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.snippets[id]
+	if !ok {
+		return Snippet{}, fmt.Errorf("snippet %s not found", id)
+	}
+
+	now := time.Now()
+	updated := existing
+	updated.UsageCount++
+	updated.LastUsedAt = &now
+
+	s.snippets[id] = updated
+	if err := s.save(); err != nil {
+		s.snippets[id] = existing
+		return Snippet{}, err
+	}
+	return updated, nil
+}
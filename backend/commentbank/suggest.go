@@ -0,0 +1,179 @@
+package commentbank
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"auxa/canvas"
+	"auxa/llm"
+	"auxa/templates"
+)
+
+// TagPraise marks snippets for work that lost no points
+const TagPraise = "praise"
+
+// Context is the submission, and optionally the criterion, a snippet is used for
+type Context struct {
+	templates.Input
+	Criterion  *canvas.Rubric
+	PointsLost *float64 // Overrides the points lost worked out from the grade
+}
+
+// pointsLost works out the points lost on the criterion, or on the whole assignment when no
+// criterion is given. ok is false when the submission has not been scored.
+func (ctx Context) pointsLost() (score, lost float64, ok bool) {
+	if ctx.Submission == nil {
+		return 0, 0, false
+	}
+	if ctx.Criterion != nil {
+		assessed, found := ctx.Submission.RubricAssessment[ctx.Criterion.ID]
+		if !found || assessed.Points == nil {
+			return 0, 0, false
+		}
+		return *assessed.Points, ctx.Criterion.Points - *assessed.Points, true
+	}
+	if ctx.Assignment == nil || ctx.Submission.Grade == "" {
+		return 0, 0, false
+	}
+	return ctx.Submission.Score, ctx.Assignment.PointsPossible - ctx.Submission.Score, true
+}
+
+// Variables extends the prompt template variables with score, points_lost and criterion.
+// Unknown values are empty so snippets still render for previews.
+func Variables(ctx Context) map[string]interface{} {
+	vars := templates.Variables(ctx.Input)
+
+	var score, lost interface{} = "", ""
+	if s, l, ok := ctx.pointsLost(); ok {
+		score, lost = s, math.Max(0, l)
+	}
+	if ctx.PointsLost != nil {
+		lost = *ctx.PointsLost
+	}
+	vars["score"] = score
+	vars["points_lost"] = lost
+
+	criterion := map[string]interface{}{"id": "", "description": "", "points": 0.0, "score": score, "points_lost": lost}
+	if ctx.Criterion != nil {
+		criterion["id"] = ctx.Criterion.ID
+		criterion["description"] = ctx.Criterion.Description
+		criterion["points"] = ctx.Criterion.Points
+	}
+	vars["criterion"] = criterion
+	return vars
+}
+
+// Render fills a snippet's variables for a context
+func Render(snippet Snippet, ctx Context) (string, error) {
+	return templates.Render(snippet.Text, Variables(ctx))
+}
+
+// Suggestion is a ranked snippet, rendered for the submission
+type Suggestion struct {
+	Snippet  Snippet  `json:"snippet"`
+	Rendered string   `json:"rendered"`
+	Score    float64  `json:"score"`
+	Reasons  []string `json:"reasons"`
+	Error    string   `json:"error,omitempty"` // Why the snippet could not be rendered
+}
+
+var actionPattern = regexp.MustCompile(`\{\{[^}]*\}\}`)
+
+// Ranking weights
+const (
+	weightRelevance  = 0.5  // Wording shared with the submission and criterion
+	weightCriterion  = 0.25 // Tagged with the criterion being graded
+	weightAssignment = 0.15 // Written for this assignment
+	weightUsage      = 0.1  // Used often by the team
+	weightPraise     = 0.1  // Praise matches full marks and is out of place otherwise
+)
+
+// Suggest ranks snippets for a submission. Relevance is measured with the local embedding
+// model, so suggestions need no provider call. At most limit suggestions are returned.
+func Suggest(snippets []Snippet, ctx Context, limit int) []Suggestion {
+	var about strings.Builder
+	about.WriteString(ctx.Content)
+	if ctx.Criterion != nil {
+		about.WriteString("\n" + ctx.Criterion.Description + "\n" + ctx.Criterion.LongDescription)
+	}
+	query := llm.LocalEmbedding(about.String())
+
+	maxUses := 0
+	for _, snippet := range snippets {
+		maxUses = max(maxUses, snippet.UsageCount)
+	}
+	_, lost, scored := ctx.pointsLost()
+	if ctx.PointsLost != nil {
+		lost, scored = *ctx.PointsLost, true
+	}
+
+	vars := Variables(ctx)
+	// Initialize as empty slice to ensure JSON returns [] instead of null
+	suggestions := make([]Suggestion, 0, len(snippets))
+	for _, snippet := range snippets {
+		suggestion := Suggestion{Snippet: snippet, Reasons: []string{}}
+
+		text := actionPattern.ReplaceAllString(snippet.Title+"\n"+snippet.Text+"\n"+strings.Join(snippet.Tags, " "), " ")
+		vector := llm.LocalEmbedding(text)
+		var relevance float64
+		for i := range query {
+			relevance += float64(query[i]) * float64(vector[i])
+		}
+		relevance = math.Max(0, relevance)
+		suggestion.Score += weightRelevance * relevance
+		if relevance >= 0.2 {
+			suggestion.Reasons = append(suggestion.Reasons, "Wording matches the submission")
+		}
+
+		if ctx.Criterion != nil && snippet.HasCriterion(ctx.Criterion.ID) {
+			suggestion.Score += weightCriterion
+			suggestion.Reasons = append(suggestion.Reasons, "Tagged with this criterion")
+		}
+		if ctx.Assignment != nil && snippet.AssignmentID != "" {
+			suggestion.Score += weightAssignment
+			suggestion.Reasons = append(suggestion.Reasons, "Written for this assignment")
+		}
+		if maxUses > 0 && snippet.UsageCount > 0 {
+			suggestion.Score += weightUsage * math.Log1p(float64(snippet.UsageCount)) / math.Log1p(float64(maxUses))
+			suggestion.Reasons = append(suggestion.Reasons, "Frequently used")
+		}
+		if scored && hasTag(snippet, TagPraise) {
+			if lost <= 0 {
+				suggestion.Score += weightPraise
+				suggestion.Reasons = append(suggestion.Reasons, "Praise for full marks")
+			} else {
+				suggestion.Score -= weightPraise
+			}
+		}
+
+		rendered, err := templates.Render(snippet.Text, vars)
+		if err != nil {
+			suggestion.Error = err.Error()
+		}
+		suggestion.Rendered = rendered
+		suggestion.Score = math.Round(suggestion.Score*1000) / 1000
+		suggestions = append(suggestions, suggestion)
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		if suggestions[i].Score != suggestions[j].Score {
+			return suggestions[i].Score > suggestions[j].Score
+		}
+		return suggestions[i].Snippet.UsageCount > suggestions[j].Snippet.UsageCount
+	})
+	if limit > 0 && len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions
+}
+
+func hasTag(snippet Snippet, tag string) bool {
+	for _, t := range snippet.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strconv"
+
+	"auxa/canvas"
+	"auxa/search"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Limits on files attached to submission comments
+const (
+	maxCommentFiles     = 10
+	maxCommentFileBytes = 100 * 1024 * 1024
+)
+
+// Post a submission comment with file attachments. The multipart form carries text_comment,
+// optional group_comment and attempt fields, and one or more files.
+func postSubmissionComment(c *gin.Context) {
+	courseID := c.Param("course_id")
+	assignmentID := c.Param("assignment_id")
+	userID := c.Param("user_id")
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Expected a multipart form: " + err.Error()})
+		return
+	}
+
+	comment := canvas.SubmissionCommentInput{
+		TextComment:  c.PostForm("text_comment"),
+		GroupComment: c.PostForm("group_comment") == "true",
+	}
+	if raw := c.PostForm("attempt"); raw != "" {
+		if comment.Attempt, err = strconv.Atoi(raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "attempt must be a number"})
+			return
+		}
+	}
+
+	files := append(form.File["files"], form.File["file"]...)
+	if len(files) == 0 && comment.TextComment == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A comment or at least one file is required"})
+		return
+	}
+	if len(files) > maxCommentFiles {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("At most %d files can be attached to a comment", maxCommentFiles)})
+		return
+	}
+	for _, header := range files {
+		if header.Size > maxCommentFileBytes {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("%s exceeds %d MB", header.Filename, maxCommentFileBytes/(1024*1024))})
+			return
+		}
+	}
+
+	client, ok := canvasClientFromRequest(c)
+	if !ok {
+		return
+	}
+
+	// Initialize as empty slice to ensure JSON returns [] instead of null
+	uploaded := make([]canvas.Attachment, 0, len(files))
+	for _, header := range files {
+		attachment, err := uploadCommentFile(client, courseID, assignmentID, userID, header)
+		if err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{
+				"error":    fmt.Sprintf("Failed to upload %s: %v", header.Filename, err),
+				"uploaded": uploaded,
+			})
+			return
+		}
+		uploaded = append(uploaded, *attachment)
+		comment.FileIDs = append(comment.FileIDs, attachment.ID)
+	}
+
+	submission, err := client.CommentOnSubmission(courseID, assignmentID, userID, comment)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "uploaded": uploaded})
+		return
+	}
+
+	indexFeedback(client, search.Entry{Kind: search.KindComment, CourseID: courseID, AssignmentID: assignmentID, UserID: submission.UserID, Text: comment.TextComment})
+
+	c.JSON(http.StatusOK, gin.H{
+		"submission": submission,
+		"files":      uploaded,
+	})
+}
+
+// uploadCommentFile streams one multipart file to Canvas
+func uploadCommentFile(client *canvas.Client, courseID, assignmentID, userID string, header *multipart.FileHeader) (*canvas.Attachment, error) {
+	file, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	contentType := header.Header.Get("Content-Type")
+	if contentType == "" || contentType == "application/octet-stream" {
+		if guessed := mime.TypeByExtension(filepath.Ext(header.Filename)); guessed != "" {
+			contentType = guessed
+		}
+	}
+
+	return client.UploadSubmissionCommentFile(courseID, assignmentID, userID, canvas.FileUpload{
+		Name:        filepath.Base(header.Filename),
+		ContentType: contentType,
+		Size:        header.Size,
+		Content:     file,
+	})
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"auxa/canvas"
+	"auxa/plaintext"
+)
+
+const (
+	// Matches the dashboard's OCR_CHARACTER_LIMIT so backend-built prompts look like live ones
+	submissionCharacterLimit = 6000
+	maxAttachmentBytes       = 512 * 1024
+	maxDocumentBytes         = 10 << 20
+)
+
+// Attachments with these extensions are inlined as text; other files are only named
+var textAttachmentExtensions = map[string]bool{
+	".txt": true, ".md": true, ".csv": true, ".json": true, ".html": true, ".xml": true,
+	".py": true, ".java": true, ".c": true, ".cpp": true, ".h": true, ".js": true, ".ts": true,
+	".go": true, ".rb": true, ".rs": true, ".r": true, ".sql": true, ".m": true, ".sh": true,
+}
+
+// Attachments in these formats have their text extracted
+var documentExtractors = map[string]func([]byte) (string, error){
+	".pdf":  plaintext.FromPDF,
+	".docx": plaintext.FromDOCX,
+}
+
+// submissionContent gathers a submission's text for a prompt, truncated like the dashboard's
+func submissionContent(client *canvas.Client, submission canvas.Submission) string {
+	content := submissionText(client, submission.Body, submission.URL, submission.Attachments)
+	if content == "" {
+		return "(No text content)"
+	}
+	return truncateText(content, submissionCharacterLimit)
+}
+
+// attemptText gathers the full text of one attempt from a submission's history
+func attemptText(client *canvas.Client, attempt canvas.SubmissionAttempt) string {
+	return submissionText(client, attempt.Body, attempt.URL, attempt.Attachments)
+}
+
+// submissionText joins the text entry body, the submitted URL, any text attachments and the
+// text of PDF and Word attachments. Other attachments are listed by name only.
+func submissionText(client *canvas.Client, body, url string, attachments []canvas.Attachment) string {
+	var parts []string
+
+	if body != "" {
+		parts = append(parts, plaintext.FromHTML(body))
+	}
+	if url != "" {
+		parts = append(parts, "Submitted URL: "+url)
+	}
+
+	for _, attachment := range attachments {
+		name := attachment.DisplayName
+		if name == "" {
+			name = attachment.Filename
+		}
+
+		ext := strings.ToLower(filepath.Ext(attachment.Filename))
+		extract, isDocument := documentExtractors[ext]
+		if !isDocument && !textAttachmentExtensions[ext] && !strings.HasPrefix(attachment.ContentType, "text/") {
+			parts = append(parts, fmt.Sprintf("[Attachment not included: %s (%s)]", name, attachment.ContentType))
+			continue
+		}
+
+		limit := int64(maxAttachmentBytes)
+		if isDocument {
+			limit = maxDocumentBytes
+		}
+		data, err := client.DownloadFile(attachment.URL, limit)
+		if err != nil {
+			parts = append(parts, fmt.Sprintf("[Attachment %s could not be downloaded: %v]", name, err))
+			continue
+		}
+
+		text := string(data)
+		if isDocument {
+			if text, err = extract(data); err != nil {
+				parts = append(parts, fmt.Sprintf("[Attachment %s has no readable text: %v]", name, err))
+				continue
+			}
+		}
+		parts = append(parts, fmt.Sprintf("--- %s ---\n%s", name, text))
+	}
+
+	return strings.Join(parts, "\n\n")
+}
+
+// truncateText cuts text to limit bytes, marking the cut as the dashboard does
+func truncateText(text string, limit int) string {
+	if len(text) <= limit {
+		return text
+	}
+	return strings.ToValidUTF8(text[:limit], "") + "\n... (content truncated)"
+}
@@ -0,0 +1,229 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Operation kinds
+const (
+	Equal  = "equal"
+	Insert = "insert"
+	Delete = "delete"
+)
+
+// Inputs longer than this many lines are compared as a whole rather than line by line,
+// bounding the O((N+M)·D) comparison time
+const maxLines = 4000
+
+// Line is one line of a diff
+type Line struct {
+	Kind string `json:"kind"`
+	Text string `json:"text"`
+}
+
+// Result is a line diff between two texts
+type Result struct {
+	Lines   []Line `json:"lines"`
+	Added   int    `json:"added"`
+	Removed int    `json:"removed"`
+}
+
+// Changed reports whether the texts differ
+func (r Result) Changed() bool {
+	return r.Added > 0 || r.Removed > 0
+}
+
+// Lines diffs two texts line by line with Myers' O(ND) algorithm, in linear space
+func Lines(before, after string) Result {
+	a := splitLines(before)
+	b := splitLines(after)
+
+	if len(a) > maxLines || len(b) > maxLines {
+		if before == after {
+			return collect(equalLines(a))
+		}
+		var lines []Line
+		for _, text := range a {
+			lines = append(lines, Line{Kind: Delete, Text: text})
+		}
+		for _, text := range b {
+			lines = append(lines, Line{Kind: Insert, Text: text})
+		}
+		return collect(lines)
+	}
+
+	var lines []Line
+	compare(a, b, &lines)
+	return collect(lines)
+}
+
+// compare appends the edits turning a into b, splitting the problem at the middle snake of
+// a shortest edit script so only two diagonals' worth of state is kept
+func compare(a, b []string, lines *[]Line) {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	*lines = append(*lines, equalLines(a[:prefix])...)
+	a, b = a[prefix:], b[prefix:]
+
+	suffix := 0
+	for suffix < len(a) && suffix < len(b) && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	common := equalLines(a[len(a)-suffix:])
+	a, b = a[:len(a)-suffix], b[:len(b)-suffix]
+
+	switch {
+	case len(a) == 0:
+		for _, text := range b {
+			*lines = append(*lines, Line{Kind: Insert, Text: text})
+		}
+	case len(b) == 0:
+		for _, text := range a {
+			*lines = append(*lines, Line{Kind: Delete, Text: text})
+		}
+	default:
+		x, y, u, v := middleSnake(a, b)
+		compare(a[:x], b[:y], lines)
+		*lines = append(*lines, equalLines(a[x:u])...)
+		compare(a[u:], b[v:], lines)
+	}
+
+	*lines = append(*lines, common...)
+}
+
+// middleSnake finds the middle snake of a shortest edit script from a to b, running the
+// search forward from the start and backward from the end until the two meet. The snake
+// runs from (x, y) to (u, v).
+func middleSnake(a, b []string) (x, y, u, v int) {
+	n, m := len(a), len(b)
+	delta := n - m
+	odd := delta%2 != 0
+	max := (n + m + 1) / 2
+
+	// forward[k] is the furthest x reached on diagonal k = x - y from the start; backward[k]
+	// the furthest reached on diagonal k from the end, counting from the end
+	offset := max + 1
+	forward := make([]int, 2*offset+1)
+	backward := make([]int, 2*offset+1)
+
+	for d := 0; d <= max; d++ {
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+			y := x - k
+			startX, startY := x, y
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			forward[offset+k] = x
+
+			if reverse := delta - k; odd && reverse >= -(d-1) && reverse <= d-1 && x+backward[offset+reverse] >= n {
+				return startX, startY, x, y
+			}
+		}
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && backward[offset+k-1] < backward[offset+k+1]) {
+				x = backward[offset+k+1]
+			} else {
+				x = backward[offset+k-1] + 1
+			}
+			y := x - k
+			startX, startY := x, y
+			for x < n && y < m && a[n-1-x] == b[m-1-y] {
+				x++
+				y++
+			}
+			backward[offset+k] = x
+
+			if reverse := delta - k; !odd && reverse >= -d && reverse <= d && x+forward[offset+reverse] >= n {
+				return n - x, m - y, n - startX, m - startY
+			}
+		}
+	}
+
+	// Unreachable: the searches meet by the time d reaches max
+	return 0, 0, 0, 0
+}
+
+func equalLines(texts []string) []Line {
+	lines := make([]Line, len(texts))
+	for i, text := range texts {
+		lines[i] = Line{Kind: Equal, Text: text}
+	}
+	return lines
+}
+
+func collect(lines []Line) Result {
+	// Initialize as empty slice to ensure JSON returns [] instead of null
+	result := Result{Lines: make([]Line, 0, len(lines))}
+	for _, line := range lines {
+		switch line.Kind {
+		case Insert:
+			result.Added++
+		case Delete:
+			result.Removed++
+		}
+		result.Lines = append(result.Lines, line)
+	}
+	return result
+}
+
+func splitLines(text string) []string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// Unified renders the diff with +/- prefixes, keeping context unchanged lines around each
+// change and eliding the rest
+func (r Result) Unified(context int) string {
+	keep := make([]bool, len(r.Lines))
+	for i, line := range r.Lines {
+		if line.Kind == Equal {
+			continue
+		}
+		for j := i - context; j <= i+context; j++ {
+			if j >= 0 && j < len(r.Lines) {
+				keep[j] = true
+			}
+		}
+	}
+
+	var b strings.Builder
+	skipped := 0
+	for i, line := range r.Lines {
+		if !keep[i] {
+			skipped++
+			continue
+		}
+		if skipped > 0 {
+			fmt.Fprintf(&b, "@@ %d unchanged lines @@\n", skipped)
+			skipped = 0
+		}
+
+		prefix := "  "
+		switch line.Kind {
+		case Insert:
+			prefix = "+ "
+		case Delete:
+			prefix = "- "
+		}
+		b.WriteString(prefix + line.Text + "\n")
+	}
+	if skipped > 0 {
+		fmt.Fprintf(&b, "@@ %d unchanged lines @@\n", skipped)
+	}
+	return b.String()
+}
@@ -0,0 +1,146 @@
+package diff
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name          string
+		before, after string
+		want          []Line
+	}{
+		{
+			name:   "identical",
+			before: "a\nb\n",
+			after:  "a\nb",
+			want:   []Line{{Equal, "a"}, {Equal, "b"}},
+		},
+		{
+			name:  "empty before",
+			after: "a\nb",
+			want:  []Line{{Insert, "a"}, {Insert, "b"}},
+		},
+		{
+			name:   "empty after",
+			before: "a",
+			want:   []Line{{Delete, "a"}},
+		},
+		{
+			name: "both empty",
+			want: []Line{},
+		},
+		{
+			name:   "changed line keeps its neighbours",
+			before: "func f() {\n\treturn 1\n}",
+			after:  "func f() {\n\treturn 2\n}",
+			want:   []Line{{Equal, "func f() {"}, {Delete, "\treturn 1"}, {Insert, "\treturn 2"}, {Equal, "}"}},
+		},
+		{
+			name:   "insertion in the middle",
+			before: "a\nb\nc",
+			after:  "a\nb\nx\nc",
+			want:   []Line{{Equal, "a"}, {Equal, "b"}, {Insert, "x"}, {Equal, "c"}},
+		},
+		{
+			name:   "CRLF line endings match LF",
+			before: "a\r\nb\r\n",
+			after:  "a\nb\nc\n",
+			want:   []Line{{Equal, "a"}, {Equal, "b"}, {Insert, "c"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Lines(tt.before, tt.after)
+			if !reflect.DeepEqual(got.Lines, tt.want) {
+				t.Errorf("got %v, want %v", got.Lines, tt.want)
+			}
+		})
+	}
+}
+
+// lcsLength is the textbook dynamic program, to check that diffs are minimal
+func lcsLength(a, b []string) int {
+	prev := make([]int, len(b)+1)
+	for i := range a {
+		cur := make([]int, len(b)+1)
+		for j := range b {
+			switch {
+			case a[i] == b[j]:
+				cur[j+1] = prev[j] + 1
+			case prev[j+1] >= cur[j]:
+				cur[j+1] = prev[j+1]
+			default:
+				cur[j+1] = cur[j]
+			}
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func TestLinesIsMinimalAndReversible(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	alphabet := []string{"a", "b", "c", "d"}
+	random := func() []string {
+		lines := make([]string, rng.Intn(40))
+		for i := range lines {
+			lines[i] = alphabet[rng.Intn(len(alphabet))]
+		}
+		return lines
+	}
+
+	for i := 0; i < 500; i++ {
+		a, b := random(), random()
+		result := Lines(strings.Join(a, "\n"), strings.Join(b, "\n"))
+
+		var before, after []string
+		for _, line := range result.Lines {
+			if line.Kind != Insert {
+				before = append(before, line.Text)
+			}
+			if line.Kind != Delete {
+				after = append(after, line.Text)
+			}
+		}
+		if strings.Join(before, "\n") != strings.Join(a, "\n") || strings.Join(after, "\n") != strings.Join(b, "\n") {
+			t.Fatalf("diff of %v and %v does not reproduce them: %v", a, b, result.Lines)
+		}
+
+		common := lcsLength(a, b)
+		if result.Removed != len(a)-common || result.Added != len(b)-common {
+			t.Fatalf("diff of %v and %v is not minimal: -%d +%d, want -%d +%d", a, b, result.Removed, result.Added, len(a)-common, len(b)-common)
+		}
+	}
+}
+
+func TestLinesOverLimitComparesWhole(t *testing.T) {
+	long := strings.Repeat("line\n", maxLines+1)
+
+	same := Lines(long, long)
+	if same.Changed() {
+		t.Error("identical long texts should not differ")
+	}
+
+	changed := Lines(long, long+"more\n")
+	if changed.Removed != maxLines+1 || changed.Added != maxLines+2 {
+		t.Errorf("got -%d +%d, want the whole texts replaced", changed.Removed, changed.Added)
+	}
+}
+
+func TestUnified(t *testing.T) {
+	result := Lines("1\n2\n3\n4\n5\n6\n7\n8", "1\n2\n3\n4\nfive\n6\n7\n8")
+	want := "@@ 3 unchanged lines @@\n" +
+		"  4\n" +
+		"- 5\n" +
+		"+ five\n" +
+		"  6\n" +
+		"@@ 2 unchanged lines @@\n"
+	if got := result.Unified(1); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"auxa/canvas"
+	"auxa/plaintext"
+
+	"github.com/gin-gonic/gin"
+)
+
+// downloadManifest describes a submissions archive; it is written last, as manifest.json
+type downloadManifest struct {
+	CourseID     string               `json:"course_id"`
+	AssignmentID int                  `json:"assignment_id"`
+	Assignment   string               `json:"assignment"`
+	Points       float64              `json:"points_possible"`
+	DueAt        *time.Time           `json:"due_at"`
+	Anonymous    bool                 `json:"anonymous"`
+	GeneratedAt  time.Time            `json:"generated_at"`
+	Submissions  []downloadSubmission `json:"submissions"`
+}
+
+// downloadSubmission is one student's folder in the archive
+type downloadSubmission struct {
+	Folder         string         `json:"folder"`
+	UserID         int            `json:"user_id,omitempty"`
+	AnonymousID    string         `json:"anonymous_id,omitempty"`
+	Name           string         `json:"name,omitempty"`
+	SISUserID      string         `json:"sis_user_id,omitempty"`
+	Attempt        int            `json:"attempt"`
+	SubmittedAt    *time.Time     `json:"submitted_at"`
+	Late           bool           `json:"late"`
+	WorkflowState  string         `json:"workflow_state"`
+	SubmissionType string         `json:"submission_type"`
+	Score          *float64       `json:"score"`
+	Grade          string         `json:"grade,omitempty"`
+	Files          []downloadFile `json:"files"`
+}
+
+// downloadFile is a file written to a student's folder, or one that could not be
+type downloadFile struct {
+	Path         string `json:"path"`
+	Source       string `json:"source"` // "attachment", "body" or "url"
+	AttachmentID int    `json:"attachment_id,omitempty"`
+	ContentType  string `json:"content_type,omitempty"`
+	Size         int64  `json:"size"`
+	Error        string `json:"error,omitempty"`
+}
+
+// Stream a zip of an assignment's submissions, one folder per student. Anonymous assignments,
+// or anonymous=true, name folders by anonymous ID and leave student details out. Set
+// include_unsubmitted=true to add empty folders for students who have not submitted.
+func downloadSubmissions(c *gin.Context) {
+	courseID := c.Param("course_id")
+	assignmentID := c.Param("assignment_id")
+
+	client, ok := canvasClientFromRequest(c)
+	if !ok {
+		return
+	}
+
+	assignment, err := client.GetAssignment(courseID, assignmentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	anonymous := assignment.AnonymousGrading || c.Query("anonymous") == "true"
+	var submissions []canvas.Submission
+	if anonymous {
+		submissions, err = client.GetAnonymousAssignmentSubmissions(courseID, assignmentID)
+	} else {
+		submissions, err = client.GetAssignmentSubmissions(courseID, assignmentID)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	includeUnsubmitted := c.Query("include_unsubmitted") == "true"
+
+	manifest := downloadManifest{
+		CourseID:     courseID,
+		AssignmentID: assignment.ID,
+		Assignment:   assignment.Name,
+		Points:       assignment.PointsPossible,
+		DueAt:        assignment.DueAt,
+		Anonymous:    anonymous,
+		GeneratedAt:  time.Now(),
+		// Initialize as empty slice to ensure JSON returns [] instead of null
+		Submissions: []downloadSubmission{},
+	}
+
+	// From here on the response is streaming; failures are recorded in the manifest
+	archiveName := strings.Trim(unsafeFileNameChars.ReplaceAllString(assignment.Name, "_"), "_") + "-submissions.zip"
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", archiveName))
+	c.Status(http.StatusOK)
+
+	zw := zip.NewWriter(c.Writer)
+	folders := map[string]bool{}
+	for _, submission := range submissions {
+		if submission.SubmittedAt == nil && !includeUnsubmitted {
+			continue
+		}
+
+		entry := downloadSubmission{
+			Folder:         uniqueName(folders, submissionFolder(submission, anonymous)),
+			Attempt:        submission.Attempt,
+			SubmittedAt:    submission.SubmittedAt,
+			Late:           submission.Late,
+			WorkflowState:  submission.WorkflowState,
+			SubmissionType: submission.SubmissionType,
+			Grade:          submission.Grade,
+			Files:          []downloadFile{},
+		}
+		if submission.Grade != "" {
+			score := submission.Score
+			entry.Score = &score
+		}
+		if anonymous {
+			entry.AnonymousID = submission.AnonymousID
+		} else {
+			entry.UserID = submission.UserID
+			if submission.User != nil {
+				entry.Name = submission.User.Name
+				entry.SISUserID = submission.User.SISUserID
+			}
+		}
+
+		files := map[string]bool{}
+		if submission.Body != "" {
+			title := html.EscapeString(assignment.Name)
+			page := "<!DOCTYPE html>\n<html>\n<head><meta charset=\"utf-8\"><title>" + title + "</title></head>\n<body>\n" + submission.Body + "\n</body>\n</html>\n"
+			entry.Files = append(entry.Files, writeZipText(zw, entry.Folder, uniqueName(files, "submission.html"), "body", page))
+			entry.Files = append(entry.Files, writeZipText(zw, entry.Folder, uniqueName(files, "submission.md"), "body", plaintext.FromHTML(submission.Body)+"\n"))
+		}
+		if submission.URL != "" {
+			shortcut := "[InternetShortcut]\r\nURL=" + submission.URL + "\r\n"
+			entry.Files = append(entry.Files, writeZipText(zw, entry.Folder, uniqueName(files, "submission.url"), "url", shortcut))
+		}
+		for _, attachment := range submission.Attachments {
+			entry.Files = append(entry.Files, writeZipAttachment(zw, client, entry.Folder, uniqueName(files, attachmentFileName(attachment)), attachment))
+		}
+		if len(entry.Files) == 0 {
+			// Keep the folder so every listed student has one
+			if _, err := zw.Create(entry.Folder + "/"); err != nil {
+				break
+			}
+		}
+
+		manifest.Submissions = append(manifest.Submissions, entry)
+	}
+
+	if w, err := zw.Create("manifest.json"); err == nil {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(manifest)
+	}
+	zw.Close()
+}
+
+// submissionFolder names a student's folder: "Lovelace_Ada-42", or the anonymous ID
+func submissionFolder(submission canvas.Submission, anonymous bool) string {
+	name := ""
+	switch {
+	case anonymous && submission.AnonymousID != "":
+		name = submission.AnonymousID
+	case anonymous:
+		name = "student-" + strconv.Itoa(submission.ID)
+	case submission.User != nil && submission.User.SortableName != "":
+		name = submission.User.SortableName + "-" + strconv.Itoa(submission.UserID)
+	case submission.User != nil && submission.User.Name != "":
+		name = submission.User.Name + "-" + strconv.Itoa(submission.UserID)
+	default:
+		name = "user-" + strconv.Itoa(submission.UserID)
+	}
+	return strings.Trim(unsafeFileNameChars.ReplaceAllString(name, "_"), "_")
+}
+
+// attachmentFileName keeps the uploaded file name, made safe for any file system
+func attachmentFileName(attachment canvas.Attachment) string {
+	name := attachment.DisplayName
+	if name == "" {
+		name = attachment.Filename
+	}
+	ext := path.Ext(name)
+	base := strings.Trim(unsafeFileNameChars.ReplaceAllString(strings.TrimSuffix(name, ext), "_"), "_")
+	if base == "" {
+		base = "attachment-" + strconv.Itoa(attachment.ID)
+	}
+	return base + unsafeFileNameChars.ReplaceAllString(ext, "")
+}
+
+// uniqueName returns name, numbered if it is already taken, and marks it taken
+func uniqueName(taken map[string]bool, name string) string {
+	candidate := name
+	ext := path.Ext(name)
+	for i := 2; taken[candidate]; i++ {
+		candidate = fmt.Sprintf("%s-%d%s", strings.TrimSuffix(name, ext), i, ext)
+	}
+	taken[candidate] = true
+	return candidate
+}
+
+func writeZipText(zw *zip.Writer, folder, name, source, content string) downloadFile {
+	file := downloadFile{Path: folder + "/" + name, Source: source, Size: int64(len(content))}
+	w, err := zw.Create(file.Path)
+	if err == nil {
+		_, err = io.WriteString(w, content)
+	}
+	if err != nil {
+		file.Error = err.Error()
+	}
+	return file
+}
+
+func writeZipAttachment(zw *zip.Writer, client *canvas.Client, folder, name string, attachment canvas.Attachment) downloadFile {
+	file := downloadFile{
+		Path:         folder + "/" + name,
+		Source:       "attachment",
+		AttachmentID: attachment.ID,
+		ContentType:  attachment.ContentType,
+	}
+
+	body, err := client.OpenFile(attachment.URL)
+	if err != nil {
+		file.Error = err.Error()
+		return file
+	}
+	defer body.Close()
+
+	header := &zip.FileHeader{Name: file.Path, Method: zip.Deflate, Modified: time.Now()}
+	w, err := zw.CreateHeader(header)
+	if err != nil {
+		file.Error = err.Error()
+		return file
+	}
+	if file.Size, err = io.Copy(w, body); err != nil {
+		file.Error = err.Error()
+	}
+	return file
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"auxa/canvas"
+	"auxa/exemplars"
+	"auxa/llm"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Exemplars are shorter than live submissions so several fit alongside the rubric
+const exemplarCharacterLimit = 2000
+
+// loadExemplars selects graded anchors for an assignment and anonymizes them. The students
+// being graded are excluded along with ref.ExcludeUserIDs, so nobody's own work is shown as
+// an example. Exemplars are always scrubbed against the roster, whether or not the request
+// anonymizes its own prompt.
+func loadExemplars(client *canvas.Client, courseID string, submissions []canvas.Submission, ref llm.ExemplarRequest, gradedUserIDs []int) ([]exemplars.Exemplar, error) {
+	excluded := append(append([]int{}, ref.ExcludeUserIDs...), gradedUserIDs...)
+
+	var anchors []canvas.Submission
+	if len(ref.SubmissionIDs) > 0 {
+		var err error
+		anchors, err = exemplars.SelectByID(submissions, ref.SubmissionIDs, excluded)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		anchors = exemplars.SelectAnchors(submissions, ref.Count, excluded)
+	}
+
+	// Initialize as empty slice to ensure JSON returns [] instead of null
+	selected := make([]exemplars.Exemplar, 0, len(anchors))
+	if len(anchors) == 0 {
+		return selected, nil
+	}
+
+	scrubber, _, err := rosterScrubber(client, courseID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, submission := range anchors {
+		comments := exemplars.GraderComments(submission)
+		for j := range comments {
+			comments[j] = scrubber.Scrub(comments[j])
+		}
+
+		selected = append(selected, exemplars.Exemplar{
+			SubmissionID: submission.ID,
+			Anchor:       exemplars.AnchorLabel(i, len(anchors)),
+			Score:        submission.Score,
+			Content:      scrubber.Scrub(truncateText(submissionContent(client, submission), exemplarCharacterLimit)),
+			Comments:     comments,
+		})
+	}
+
+	return selected, nil
+}
+
+// applyExemplars resolves the request's exemplar references into its shared context, where
+// providers can cache them across the assignment. gradedUserIDs are the students the context
+// is used for, whose work is never picked. It writes an error response and returns false on
+// failure.
+func applyExemplars(c *gin.Context, req *llm.GradingRequest, gradedUserIDs ...int) bool {
+	if req.Exemplars == nil {
+		return true
+	}
+
+	if req.CourseID == "" || req.AssignmentID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "course_id and assignment_id are required for exemplars"})
+		return false
+	}
+
+	missing := len(gradedUserIDs) == 0
+	for _, id := range gradedUserIDs {
+		missing = missing || id <= 0
+	}
+	if missing {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id of the student being graded is required for exemplars"})
+		return false
+	}
+
+	client, ok := canvasClientFromRequest(c)
+	if !ok {
+		return false
+	}
+
+	assignment, err := client.GetAssignment(req.CourseID, req.AssignmentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+
+	submissions, err := client.GetAssignmentSubmissions(req.CourseID, req.AssignmentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+
+	selected, err := loadExemplars(client, req.CourseID, submissions, *req.Exemplars, gradedUserIDs)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to load exemplars: " + err.Error()})
+		return false
+	}
+
+	req.SharedContext = appendContext(req.SharedContext, exemplars.Format(selected, assignment.PointsPossible))
+	req.Exemplars = nil
+	return true
+}
+
+// appendContext adds a block to a request's shared context
+func appendContext(shared, block string) string {
+	if block == "" {
+		return shared
+	}
+	if strings.TrimSpace(shared) == "" {
+		return block
+	}
+	return shared + "\n\n" + block
+}
+
+// Preview the exemplars that would be used for an assignment
+func getExemplars(c *gin.Context) {
+	courseID := c.Param("course_id")
+	assignmentID := c.Param("assignment_id")
+
+	ref := llm.ExemplarRequest{}
+	if count := c.Query("count"); count != "" {
+		n, err := strconv.Atoi(count)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "count must be a number"})
+			return
+		}
+		ref.Count = n
+	}
+	var err error
+	if ref.SubmissionIDs, err = parseIDList(c.Query("submission_ids")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "submission_ids " + err.Error()})
+		return
+	}
+	if ref.ExcludeUserIDs, err = parseIDList(c.Query("exclude_user_ids")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "exclude_user_ids " + err.Error()})
+		return
+	}
+
+	client, ok := canvasClientFromRequest(c)
+	if !ok {
+		return
+	}
+
+	assignment, err := client.GetAssignment(courseID, assignmentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	submissions, err := client.GetAssignmentSubmissions(courseID, assignmentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	selected, err := loadExemplars(client, courseID, submissions, ref, nil)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"exemplars": selected,
+		"context":   exemplars.Format(selected, assignment.PointsPossible),
+	})
+}
+
+// parseIDList parses a comma-separated list of Canvas IDs
+func parseIDList(value string) ([]int, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	var ids []int
+	for _, raw := range strings.Split(value, ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("must be comma-separated numbers")
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
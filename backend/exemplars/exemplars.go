@@ -0,0 +1,143 @@
+package exemplars
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"auxa/calibration"
+	"auxa/canvas"
+)
+
+// Default number of anchors: one high, one medium and one low
+const DefaultCount = 3
+
+// Exemplar is an anonymized, already-graded submission shown to the model as a few-shot example
+type Exemplar struct {
+	SubmissionID int      `json:"submission_id"`
+	Anchor       string   `json:"anchor"` // high, medium or low
+	Score        float64  `json:"score"`
+	Content      string   `json:"content"`
+	Comments     []string `json:"comments,omitempty"` // Grader comments, without author names
+}
+
+// SelectAnchors picks count human-graded submissions spread evenly across the score range,
+// always including the highest and lowest. Submissions by excluded users, e.g. the student
+// being graded, are never chosen. The result is ordered from highest to lowest score.
+func SelectAnchors(submissions []canvas.Submission, count int, excludeUserIDs []int) []canvas.Submission {
+	if count <= 0 {
+		count = DefaultCount
+	}
+
+	excluded := make(map[int]bool, len(excludeUserIDs))
+	for _, id := range excludeUserIDs {
+		excluded[id] = true
+	}
+
+	var graded []canvas.Submission
+	for _, submission := range submissions {
+		if calibration.HumanGraded(submission) && !excluded[submission.UserID] {
+			graded = append(graded, submission)
+		}
+	}
+
+	// Sort by score, breaking ties by ID so the selection is stable across requests
+	sort.Slice(graded, func(i, j int) bool {
+		if graded[i].Score != graded[j].Score {
+			return graded[i].Score > graded[j].Score
+		}
+		return graded[i].ID < graded[j].ID
+	})
+
+	if len(graded) <= count {
+		return graded
+	}
+	if count == 1 {
+		return graded[len(graded)/2 : len(graded)/2+1]
+	}
+
+	anchors := make([]canvas.Submission, 0, count)
+	for i := 0; i < count; i++ {
+		anchors = append(anchors, graded[i*(len(graded)-1)/(count-1)])
+	}
+	return anchors
+}
+
+// SelectByID returns the human-graded submissions with the given IDs, highest score first.
+// Submissions by excluded users are skipped.
+func SelectByID(submissions []canvas.Submission, ids []int, excludeUserIDs []int) ([]canvas.Submission, error) {
+	byID := make(map[int]canvas.Submission, len(submissions))
+	for _, submission := range submissions {
+		byID[submission.ID] = submission
+	}
+
+	excluded := make(map[int]bool, len(excludeUserIDs))
+	for _, id := range excludeUserIDs {
+		excluded[id] = true
+	}
+
+	selected := make([]canvas.Submission, 0, len(ids))
+	for _, id := range ids {
+		submission, ok := byID[id]
+		if !ok {
+			return nil, fmt.Errorf("submission %d not found", id)
+		}
+		if !calibration.HumanGraded(submission) {
+			return nil, fmt.Errorf("submission %d has not been graded by a TA", id)
+		}
+		if !excluded[submission.UserID] {
+			selected = append(selected, submission)
+		}
+	}
+
+	sort.SliceStable(selected, func(i, j int) bool { return selected[i].Score > selected[j].Score })
+	return selected, nil
+}
+
+// AnchorLabel names an anchor by its position in a list ordered from highest to lowest
+func AnchorLabel(index, count int) string {
+	switch {
+	case index == 0 && count > 1:
+		return "high"
+	case index == count-1 && count > 1:
+		return "low"
+	default:
+		return "medium"
+	}
+}
+
+// GraderComments returns the comments on a submission not written by the student
+func GraderComments(submission canvas.Submission) []string {
+	var comments []string
+	for _, comment := range submission.SubmissionComments {
+		if comment.AuthorID != submission.UserID && strings.TrimSpace(comment.Comment) != "" {
+			comments = append(comments, strings.TrimSpace(comment.Comment))
+		}
+	}
+	return comments
+}
+
+// Format renders exemplars as a few-shot block for the shared grading context
+func Format(exemplars []Exemplar, pointsPossible float64) string {
+	if len(exemplars) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("GRADED EXAMPLES:\n")
+	b.WriteString("The following submissions were graded by the course staff. Match their standards, scoring and tone.\n")
+
+	for i, exemplar := range exemplars {
+		fmt.Fprintf(&b, "\nEXAMPLE %d (%s score)\n", i+1, exemplar.Anchor)
+		fmt.Fprintf(&b, "Submission:\n%s\n", exemplar.Content)
+		if len(exemplar.Comments) > 0 {
+			b.WriteString("Grader comments:\n")
+			for _, comment := range exemplar.Comments {
+				fmt.Fprintf(&b, "- %s\n", comment)
+			}
+		}
+		fmt.Fprintf(&b, "SUGGESTED GRADE: %g/%g\n", exemplar.Score, pointsPossible)
+	}
+
+	return b.String()
+}
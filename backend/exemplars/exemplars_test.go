@@ -0,0 +1,96 @@
+package exemplars
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"auxa/canvas"
+)
+
+// submissions decodes Canvas JSON so null scores decode as they do from the API
+func submissions(t *testing.T) []canvas.Submission {
+	t.Helper()
+	const payload = `[
+		{"id": 1, "user_id": 11, "submitted_at": "2026-09-01T10:00:00Z", "workflow_state": "graded", "grader_id": 5, "score": 9, "grade": "9"},
+		{"id": 2, "user_id": 12, "submitted_at": "2026-09-01T10:00:00Z", "workflow_state": "graded", "grader_id": 5, "score": 6, "grade": "6"},
+		{"id": 3, "user_id": 13, "submitted_at": "2026-09-01T10:00:00Z", "workflow_state": "graded", "grader_id": 5, "score": 3, "grade": "3"},
+		{"id": 4, "user_id": 14, "submitted_at": "2026-09-01T10:00:00Z", "workflow_state": "graded", "grader_id": 5, "score": null, "grade": null, "excused": true},
+		{"id": 5, "user_id": 15, "submitted_at": "2026-09-01T10:00:00Z", "workflow_state": "graded", "grader_id": 5, "score": null, "grade": null},
+		{"id": 6, "user_id": 16, "submitted_at": "2026-09-01T10:00:00Z", "workflow_state": "graded", "grader_id": -1, "score": 1, "grade": "1"},
+		{"id": 7, "user_id": 17, "submitted_at": null, "workflow_state": "unsubmitted", "score": null, "grade": null}
+	]`
+	var subs []canvas.Submission
+	if err := json.Unmarshal([]byte(payload), &subs); err != nil {
+		t.Fatal(err)
+	}
+	return subs
+}
+
+func ids(subs []canvas.Submission) []int {
+	result := make([]int, len(subs))
+	for i, submission := range subs {
+		result[i] = submission.ID
+	}
+	return result
+}
+
+func TestSelectAnchorsSkipsUnscoredSubmissions(t *testing.T) {
+	anchors := SelectAnchors(submissions(t), 5, nil)
+
+	got := ids(anchors)
+	want := []int{1, 2, 3}
+	if len(got) != len(want) {
+		t.Fatalf("got anchors %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got anchors %v, want %v", got, want)
+		}
+	}
+}
+
+func TestSelectAnchorsSpreadsAndExcludes(t *testing.T) {
+	subs := submissions(t)
+
+	if got := ids(SelectAnchors(subs, 2, nil)); len(got) != 2 || got[0] != 1 || got[1] != 3 {
+		t.Errorf("two anchors should be the highest and lowest, got %v", got)
+	}
+	if got := ids(SelectAnchors(subs, 1, nil)); len(got) != 1 || got[0] != 2 {
+		t.Errorf("one anchor should be the median, got %v", got)
+	}
+	if got := ids(SelectAnchors(subs, 3, []int{13})); len(got) != 2 || got[1] != 2 {
+		t.Errorf("an excluded student's work was chosen: %v", got)
+	}
+}
+
+func TestSelectByID(t *testing.T) {
+	subs := submissions(t)
+
+	selected, err := SelectByID(subs, []int{3, 1, 2}, []int{12})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(selected); len(got) != 2 || got[0] != 1 || got[1] != 3 {
+		t.Errorf("got %v, want [1 3] with the excluded student skipped", got)
+	}
+
+	tests := []struct {
+		name    string
+		id      int
+		wantErr string
+	}{
+		{name: "excused", id: 4, wantErr: "has not been graded"},
+		{name: "graded without a score", id: 5, wantErr: "has not been graded"},
+		{name: "graded automatically", id: 6, wantErr: "has not been graded"},
+		{name: "missing", id: 99, wantErr: "not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := SelectByID(subs, []int{tt.id}, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("got error %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"auxa/canvas"
+	"auxa/gradebook"
+
+	"github.com/gin-gonic/gin"
+)
+
+// gradebookStudents lists a course's active students as gradebook rows, sorted by name.
+// Students enrolled in several sections list them all, as Canvas does.
+func gradebookStudents(client *canvas.Client, courseID string) ([]gradebook.Student, error) {
+	enrollments, err := client.GetCourseEnrollments(courseID)
+	if err != nil {
+		return nil, err
+	}
+
+	sections, err := client.GetCourseSections(courseID)
+	if err != nil {
+		return nil, err
+	}
+	sectionNames := make(map[int]string, len(sections))
+	for _, section := range sections {
+		sectionNames[section.ID] = section.Name
+	}
+
+	byUser := map[int]*gradebook.Student{}
+	var order []int
+	for _, enrollment := range enrollments {
+		section := sectionNames[enrollment.CourseSectionID]
+		if student, found := byUser[enrollment.UserID]; found {
+			if section != "" && !strings.Contains(student.Section, section) {
+				student.Section += ", " + section
+			}
+			continue
+		}
+
+		name := enrollment.User.SortableName
+		if name == "" {
+			name = enrollment.User.Name
+		}
+		byUser[enrollment.UserID] = &gradebook.Student{
+			UserID:    enrollment.UserID,
+			Name:      name,
+			SISUserID: enrollment.User.SISUserID,
+			LoginID:   enrollment.User.LoginID,
+			Section:   section,
+		}
+		order = append(order, enrollment.UserID)
+	}
+
+	students := make([]gradebook.Student, 0, len(order))
+	for _, userID := range order {
+		students = append(students, *byUser[userID])
+	}
+	sort.SliceStable(students, func(i, j int) bool {
+		return strings.ToLower(students[i].Name) < strings.ToLower(students[j].Name)
+	})
+
+	return students, nil
+}
+
+// gradebookAssignments returns the assignments a gradebook covers: the one named, or every
+// published assignment in the course
+func gradebookAssignments(client *canvas.Client, courseID, assignmentID string) ([]canvas.Assignment, error) {
+	if assignmentID != "" {
+		assignment, err := client.GetAssignment(courseID, assignmentID)
+		if err != nil {
+			return nil, err
+		}
+		return []canvas.Assignment{*assignment}, nil
+	}
+
+	all, err := client.GetCourseAssignments(courseID)
+	if err != nil {
+		return nil, err
+	}
+
+	var published []canvas.Assignment
+	for _, assignment := range all {
+		if assignment.WorkflowState == "published" {
+			published = append(published, assignment)
+		}
+	}
+	return published, nil
+}
+
+// currentGrades fetches existing grades by user ID and assignment ID
+func currentGrades(client *canvas.Client, courseID string, assignments []canvas.Assignment) (map[int]map[int]gradebook.Current, error) {
+	current := map[int]map[int]gradebook.Current{}
+	for _, assignment := range assignments {
+		submissions, err := client.GetAssignmentSubmissions(courseID, strconv.Itoa(assignment.ID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch submissions for %s: %w", assignment.Name, err)
+		}
+
+		for _, submission := range submissions {
+			grade := gradebook.Current{Excused: submission.Excused}
+			// Score decodes null as 0, so only trust it once a grade is recorded
+			if submission.Grade != "" {
+				score := submission.Score
+				grade.Score = &score
+			}
+			if current[submission.UserID] == nil {
+				current[submission.UserID] = map[int]gradebook.Current{}
+			}
+			current[submission.UserID][assignment.ID] = grade
+		}
+	}
+	return current, nil
+}
+
+func gradebookColumn(assignment canvas.Assignment) gradebook.Column {
+	return gradebook.Column{
+		AssignmentID:   assignment.ID,
+		Name:           assignment.Name,
+		PointsPossible: assignment.PointsPossible,
+	}
+}
+
+// Download a Canvas-compatible gradebook CSV for a course, or for one assignment when
+// assignment_id is given
+func exportGradebook(c *gin.Context) {
+	courseID := c.Param("course_id")
+
+	client, ok := canvasClientFromRequest(c)
+	if !ok {
+		return
+	}
+
+	assignments, err := gradebookAssignments(client, courseID, c.Query("assignment_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	students, err := gradebookStudents(client, courseID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	current, err := currentGrades(client, courseID, assignments)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	columns := make([]gradebook.Column, 0, len(assignments))
+	for _, assignment := range assignments {
+		columns = append(columns, gradebookColumn(assignment))
+	}
+
+	cells := map[int]map[int]string{}
+	for userID, grades := range current {
+		cells[userID] = map[int]string{}
+		for assignmentID, grade := range grades {
+			switch {
+			case grade.Excused:
+				cells[userID][assignmentID] = gradebook.Excused
+			case grade.Score != nil:
+				cells[userID][assignmentID] = gradebook.FormatScore(*grade.Score)
+			}
+		}
+	}
+
+	data, err := gradebook.Export(columns, students, cells)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	fileName := fmt.Sprintf("gradebook-%s.csv", courseID)
+	if len(assignments) == 1 && c.Query("assignment_id") != "" {
+		fileName = strings.Trim(unsafeFileNameChars.ReplaceAllString(assignments[0].Name, "_"), "_") + "-grades.csv"
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", data)
+}
+
+// Check a gradebook CSV body against the course and preview the grades it would change.
+// With apply=true the changes are posted to Canvas in bulk, one request per assignment, as
+// long as the check found no errors.
+func importGradebook(c *gin.Context) {
+	courseID := c.Param("course_id")
+	apply := c.Query("apply") == "true"
+
+	sheet, err := gradebook.Parse(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	client, ok := canvasClientFromRequest(c)
+	if !ok {
+		return
+	}
+
+	all, err := client.GetCourseAssignments(courseID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Only fetch grades for the assignments the sheet covers
+	inSheet := map[int]bool{}
+	for _, column := range sheet.Columns {
+		inSheet[column.AssignmentID] = true
+	}
+	assignments := map[int]gradebook.Column{}
+	var covered []canvas.Assignment
+	for _, assignment := range all {
+		assignments[assignment.ID] = gradebookColumn(assignment)
+		if inSheet[assignment.ID] {
+			covered = append(covered, assignment)
+		}
+	}
+
+	roster, err := gradebookStudents(client, courseID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	students := make(map[int]gradebook.Student, len(roster))
+	for _, student := range roster {
+		students[student.UserID] = student
+	}
+
+	current, err := currentGrades(client, courseID, covered)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	preview := gradebook.Plan(sheet, students, assignments, current)
+	if !apply {
+		c.JSON(http.StatusOK, gin.H{"preview": preview, "applied": false})
+		return
+	}
+
+	if len(preview.Errors) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Fix the errors in the CSV before importing", "preview": preview, "applied": false})
+		return
+	}
+
+	byAssignment := map[int]map[int]canvas.BulkGrade{}
+	var assignmentIDs []int
+	for _, change := range preview.Changes {
+		if byAssignment[change.AssignmentID] == nil {
+			byAssignment[change.AssignmentID] = map[int]canvas.BulkGrade{}
+			assignmentIDs = append(assignmentIDs, change.AssignmentID)
+		}
+		grade := canvas.BulkGrade{Excuse: change.Excuse}
+		if change.NewScore != nil {
+			grade.PostedGrade = gradebook.FormatScore(*change.NewScore)
+		}
+		byAssignment[change.AssignmentID][change.UserID] = grade
+	}
+
+	// Initialize as empty slice to ensure JSON returns [] instead of null
+	progress := make([]gin.H, 0, len(assignmentIDs))
+	for _, assignmentID := range assignmentIDs {
+		p, err := client.UpdateGrades(courseID, strconv.Itoa(assignmentID), byAssignment[assignmentID])
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":    fmt.Sprintf("Failed to post grades for %s: %v", assignments[assignmentID].Name, err),
+				"preview":  preview,
+				"progress": progress,
+				"applied":  len(progress) > 0,
+			})
+			return
+		}
+		progress = append(progress, gin.H{"assignment_id": assignmentID, "progress": p})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"preview": preview, "progress": progress, "applied": true})
+}
+
+// Get the state of a Canvas background operation, such as a bulk grade update
+func getCanvasProgress(c *gin.Context) {
+	client, ok := canvasClientFromRequest(c)
+	if !ok {
+		return
+	}
+
+	progress, err := client.GetProgress(c.Param("progress_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, progress)
+}
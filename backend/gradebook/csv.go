@@ -0,0 +1,185 @@
+// Package gradebook reads and writes gradebook spreadsheets in the CSV layout Canvas uses for
+// its own gradebook export and import
+package gradebook
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Leading columns identifying the student, as in Canvas's export
+var studentColumns = []string{"Student", "ID", "SIS User ID", "SIS Login ID", "Section"}
+
+// pointsPossibleLabel starts the row under the header that lists each assignment's points
+const pointsPossibleLabel = "Points Possible"
+
+// Excused is the cell value Canvas uses for an excused assignment
+const Excused = "EX"
+
+// Assignment columns are headed "Name (ID)"
+var assignmentHeaderPattern = regexp.MustCompile(`^(.*)\s+\((\d+)\)$`)
+
+// Column is an assignment column
+type Column struct {
+	AssignmentID   int     `json:"assignment_id"`
+	Name           string  `json:"name"`
+	PointsPossible float64 `json:"points_possible"`
+}
+
+// Student identifies a row's student
+type Student struct {
+	UserID    int    `json:"user_id"`
+	Name      string `json:"name"` // Sortable name, "Last, First", as Canvas exports it
+	SISUserID string `json:"sis_user_id"`
+	LoginID   string `json:"login_id"`
+	Section   string `json:"section"`
+}
+
+// Row is one student's line in a sheet. Cells holds the raw value of each assignment column
+// keyed by assignment ID; blank cells are omitted.
+type Row struct {
+	Line    int            `json:"line"`
+	Student Student        `json:"student"`
+	Cells   map[int]string `json:"cells"`
+}
+
+// Sheet is a parsed gradebook CSV
+type Sheet struct {
+	Columns []Column `json:"columns"`
+	Rows    []Row    `json:"rows"`
+}
+
+// Export writes a gradebook CSV. scores maps user ID to assignment ID to the cell value,
+// which is a score, Excused or blank.
+func Export(columns []Column, students []Student, scores map[int]map[int]string) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+
+	header := append([]string{}, studentColumns...)
+	points := []string{"    " + pointsPossibleLabel, "", "", "", ""}
+	for _, column := range columns {
+		header = append(header, fmt.Sprintf("%s (%d)", column.Name, column.AssignmentID))
+		points = append(points, FormatScore(column.PointsPossible))
+	}
+	if err := w.Write(header); err != nil {
+		return nil, err
+	}
+	if err := w.Write(points); err != nil {
+		return nil, err
+	}
+
+	for _, student := range students {
+		row := []string{student.Name, strconv.Itoa(student.UserID), student.SISUserID, student.LoginID, student.Section}
+		for _, column := range columns {
+			row = append(row, scores[student.UserID][column.AssignmentID])
+		}
+		if err := w.Write(row); err != nil {
+			return nil, err
+		}
+	}
+
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// Parse reads a gradebook CSV exported by Canvas or by Export. Columns that are not
+// assignments, such as Canvas's totals, are ignored.
+func Parse(r io.Reader) (*Sheet, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	idColumn := -1
+	studentIndex := map[string]int{}
+	sheet := &Sheet{}
+	assignmentIndex := map[int]int{} // Column index in the CSV to position in sheet.Columns
+	for i, name := range header {
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+		if match := assignmentHeaderPattern.FindStringSubmatch(name); match != nil {
+			id, _ := strconv.Atoi(match[2])
+			assignmentIndex[i] = len(sheet.Columns)
+			sheet.Columns = append(sheet.Columns, Column{AssignmentID: id, Name: match[1]})
+			continue
+		}
+		studentIndex[name] = i
+		if name == "ID" {
+			idColumn = i
+		}
+	}
+	if idColumn < 0 {
+		return nil, fmt.Errorf("CSV needs an ID column")
+	}
+	if len(sheet.Columns) == 0 {
+		return nil, fmt.Errorf(`CSV has no assignment columns; they are headed "Assignment Name (ID)"`)
+	}
+
+	field := func(record []string, name string) string {
+		if i, ok := studentIndex[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		// Canvas adds rows under the header for points possible and posting policy
+		if first := strings.TrimSpace(record[0]); first == pointsPossibleLabel {
+			for i, position := range assignmentIndex {
+				if i < len(record) {
+					sheet.Columns[position].PointsPossible, _ = strconv.ParseFloat(strings.TrimSpace(record[i]), 64)
+				}
+			}
+			continue
+		}
+		rawID := field(record, "ID")
+		if rawID == "" {
+			continue
+		}
+
+		userID, err := strconv.Atoi(rawID)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: ID %q is not a number", line, rawID)
+		}
+
+		row := Row{
+			Line: line,
+			Student: Student{
+				UserID:    userID,
+				Name:      field(record, "Student"),
+				SISUserID: field(record, "SIS User ID"),
+				LoginID:   field(record, "SIS Login ID"),
+				Section:   field(record, "Section"),
+			},
+			Cells: map[int]string{},
+		}
+		for i, position := range assignmentIndex {
+			if i < len(record) && strings.TrimSpace(record[i]) != "" {
+				row.Cells[sheet.Columns[position].AssignmentID] = strings.TrimSpace(record[i])
+			}
+		}
+		sheet.Rows = append(sheet.Rows, row)
+	}
+
+	return sheet, nil
+}
+
+// FormatScore formats a score as Canvas exports it
+func FormatScore(score float64) string {
+	return strconv.FormatFloat(score, 'f', -1, 64)
+}
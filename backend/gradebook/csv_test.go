@@ -0,0 +1,161 @@
+package gradebook
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		csv     string
+		want    *Sheet
+		wantErr string
+	}{
+		{
+			name: "Canvas export with points possible row and totals",
+			csv: "Student,ID,SIS User ID,SIS Login ID,Section,Essay 1 (101),Lab (Week 2) (102),Current Score\n" +
+				"    Points Possible,,,,,10,5.5,(read only)\n" +
+				"\"Doe, Jane\",7,S1,jdoe,A,8.5,EX,85\n" +
+				"\"Roe, Sam\",9,,sroe,B,, 4 ,40\n",
+			want: &Sheet{
+				Columns: []Column{
+					{AssignmentID: 101, Name: "Essay 1", PointsPossible: 10},
+					{AssignmentID: 102, Name: "Lab (Week 2)", PointsPossible: 5.5},
+				},
+				Rows: []Row{
+					{Line: 3, Student: Student{UserID: 7, Name: "Doe, Jane", SISUserID: "S1", LoginID: "jdoe", Section: "A"}, Cells: map[int]string{101: "8.5", 102: "EX"}},
+					{Line: 4, Student: Student{UserID: 9, Name: "Roe, Sam", LoginID: "sroe", Section: "B"}, Cells: map[int]string{102: "4"}},
+				},
+			},
+		},
+		{
+			name: "byte order mark before the first header",
+			csv:  "\ufeffStudent,ID,Quiz (5)\n\"Doe, Jane\",7,3\n",
+			want: &Sheet{
+				Columns: []Column{{AssignmentID: 5, Name: "Quiz"}},
+				Rows: []Row{
+					{Line: 2, Student: Student{UserID: 7, Name: "Doe, Jane"}, Cells: map[int]string{5: "3"}},
+				},
+			},
+		},
+		{
+			name: "byte order mark on the ID column",
+			csv:  "\ufeffID,Quiz (5)\n7,3\n",
+			want: &Sheet{
+				Columns: []Column{{AssignmentID: 5, Name: "Quiz"}},
+				Rows: []Row{
+					{Line: 2, Student: Student{UserID: 7}, Cells: map[int]string{5: "3"}},
+				},
+			},
+		},
+		{
+			name: "rows without an ID, such as a test student's summary, are skipped",
+			csv:  "Student,ID,Quiz (5)\nTotals,,\n\"Doe, Jane\",7,\n",
+			want: &Sheet{
+				Columns: []Column{{AssignmentID: 5, Name: "Quiz"}},
+				Rows: []Row{
+					{Line: 3, Student: Student{UserID: 7, Name: "Doe, Jane"}, Cells: map[int]string{}},
+				},
+			},
+		},
+		{
+			name: "duplicate rows are kept for Plan to report",
+			csv:  "ID,Quiz (5)\n7,3\n7,4\n",
+			want: &Sheet{
+				Columns: []Column{{AssignmentID: 5, Name: "Quiz"}},
+				Rows: []Row{
+					{Line: 2, Student: Student{UserID: 7}, Cells: map[int]string{5: "3"}},
+					{Line: 3, Student: Student{UserID: 7}, Cells: map[int]string{5: "4"}},
+				},
+			},
+		},
+		{
+			name:    "missing ID column",
+			csv:     "Student,Quiz (5)\nJane,3\n",
+			wantErr: "needs an ID column",
+		},
+		{
+			name:    "no assignment columns",
+			csv:     "Student,ID,Quiz\nJane,7,3\n",
+			wantErr: "no assignment columns",
+		},
+		{
+			name:    "non-numeric ID",
+			csv:     "ID,Quiz (5)\n7,3\nx7,3\n",
+			wantErr: `line 3: ID "x7" is not a number`,
+		},
+		{
+			name:    "empty file",
+			csv:     "",
+			wantErr: "failed to read CSV header",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(strings.NewReader(tt.csv))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got  %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	columns := []Column{
+		{AssignmentID: 101, Name: "Essay, Part 1", PointsPossible: 10},
+		{AssignmentID: 102, Name: "Lab (Week 2)", PointsPossible: 2.5},
+	}
+	students := []Student{
+		{UserID: 7, Name: "Doe, Jane", SISUserID: "S1", LoginID: "jdoe", Section: "A"},
+		{UserID: 9, Name: `O"Neil, Sam`, Section: "B"},
+	}
+	scores := map[int]map[int]string{
+		7: {101: "8.5", 102: Excused},
+		9: {102: "2"},
+	}
+
+	data, err := Export(columns, students, scores)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sheet, err := Parse(strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(sheet.Columns, columns) {
+		t.Errorf("columns: got %+v, want %+v", sheet.Columns, columns)
+	}
+	if len(sheet.Rows) != len(students) {
+		t.Fatalf("got %d rows, want %d", len(sheet.Rows), len(students))
+	}
+	for i, row := range sheet.Rows {
+		if row.Student != students[i] {
+			t.Errorf("row %d student: got %+v, want %+v", i, row.Student, students[i])
+		}
+		if !reflect.DeepEqual(row.Cells, scores[students[i].UserID]) {
+			t.Errorf("row %d cells: got %v, want %v", i, row.Cells, scores[students[i].UserID])
+		}
+	}
+}
+
+func TestFormatScore(t *testing.T) {
+	tests := map[float64]string{0: "0", 10: "10", 8.5: "8.5", 0.1: "0.1", 1e6: "1000000"}
+	for score, want := range tests {
+		if got := FormatScore(score); got != want {
+			t.Errorf("FormatScore(%v) = %q, want %q", score, got, want)
+		}
+	}
+}
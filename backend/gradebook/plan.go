@@ -0,0 +1,138 @@
+package gradebook
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Current is what Canvas holds for one student on one assignment
+type Current struct {
+	Score   *float64
+	Excused bool
+}
+
+// Change is a grade that an import would post
+type Change struct {
+	UserID         int      `json:"user_id"`
+	Student        string   `json:"student"`
+	AssignmentID   int      `json:"assignment_id"`
+	AssignmentName string   `json:"assignment_name"`
+	CurrentScore   *float64 `json:"current_score"`
+	CurrentExcused bool     `json:"current_excused"`
+	NewScore       *float64 `json:"new_score"`
+	Excuse         bool     `json:"excuse"`
+}
+
+// Issue is a problem found in an import, tied to a CSV line where there is one
+type Issue struct {
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message"`
+}
+
+// Preview is the outcome of checking a sheet against the course
+type Preview struct {
+	Changes   []Change `json:"changes"`
+	Unchanged int      `json:"unchanged"`
+	Errors    []Issue  `json:"errors"`
+	Warnings  []Issue  `json:"warnings"`
+}
+
+// Plan compares a sheet with the course. students are the enrolled students by user ID,
+// assignments the course's assignments by ID, and current the existing grades by user ID and
+// assignment ID. Blank cells leave grades alone.
+func Plan(sheet *Sheet, students map[int]Student, assignments map[int]Column, current map[int]map[int]Current) Preview {
+	// Initialize as empty slices to ensure JSON returns [] instead of null
+	preview := Preview{Changes: []Change{}, Errors: []Issue{}, Warnings: []Issue{}}
+
+	known := map[int]bool{}
+	for _, column := range sheet.Columns {
+		assignment, found := assignments[column.AssignmentID]
+		if !found {
+			preview.Errors = append(preview.Errors, Issue{Message: fmt.Sprintf("Assignment %q (%d) is not in this course", column.Name, column.AssignmentID)})
+			continue
+		}
+		known[column.AssignmentID] = true
+		if column.PointsPossible != 0 && column.PointsPossible != assignment.PointsPossible {
+			preview.Warnings = append(preview.Warnings, Issue{Message: fmt.Sprintf("%s is now worth %s points, not %s as in the CSV",
+				assignment.Name, FormatScore(assignment.PointsPossible), FormatScore(column.PointsPossible))})
+		}
+	}
+
+	seen := map[int]int{}
+	for _, row := range sheet.Rows {
+		student, enrolled := students[row.Student.UserID]
+		if !enrolled {
+			preview.Errors = append(preview.Errors, Issue{Line: row.Line, Message: fmt.Sprintf("%s (%d) is not an active student in this course", row.Student.Name, row.Student.UserID)})
+			continue
+		}
+		if previous, duplicate := seen[row.Student.UserID]; duplicate {
+			preview.Errors = append(preview.Errors, Issue{Line: row.Line, Message: fmt.Sprintf("%s already appears on line %d", student.Name, previous)})
+			continue
+		}
+		seen[row.Student.UserID] = row.Line
+		if row.Student.SISUserID != "" && student.SISUserID != "" && row.Student.SISUserID != student.SISUserID {
+			preview.Warnings = append(preview.Warnings, Issue{Line: row.Line, Message: fmt.Sprintf("SIS User ID %s does not match %s's (%s)", row.Student.SISUserID, student.Name, student.SISUserID)})
+		}
+
+		// Walk assignments in a stable order so previews are repeatable
+		ids := make([]int, 0, len(row.Cells))
+		for id := range row.Cells {
+			ids = append(ids, id)
+		}
+		sort.Ints(ids)
+
+		for _, assignmentID := range ids {
+			if !known[assignmentID] {
+				continue
+			}
+			assignment := assignments[assignmentID]
+			existing := current[row.Student.UserID][assignmentID]
+
+			change := Change{
+				UserID:         row.Student.UserID,
+				Student:        student.Name,
+				AssignmentID:   assignmentID,
+				AssignmentName: assignment.Name,
+				CurrentScore:   existing.Score,
+				CurrentExcused: existing.Excused,
+			}
+
+			value := row.Cells[assignmentID]
+			if strings.EqualFold(value, Excused) {
+				if existing.Excused {
+					preview.Unchanged++
+					continue
+				}
+				change.Excuse = true
+				preview.Changes = append(preview.Changes, change)
+				continue
+			}
+
+			score, err := strconv.ParseFloat(value, 64)
+			if err != nil || math.IsNaN(score) || math.IsInf(score, 0) {
+				preview.Errors = append(preview.Errors, Issue{Line: row.Line, Message: fmt.Sprintf("%s: %q is not a score", assignment.Name, value)})
+				continue
+			}
+			if score < 0 {
+				preview.Errors = append(preview.Errors, Issue{Line: row.Line, Message: fmt.Sprintf("%s: score %s is negative", assignment.Name, value)})
+				continue
+			}
+			if score > assignment.PointsPossible {
+				preview.Warnings = append(preview.Warnings, Issue{Line: row.Line, Message: fmt.Sprintf("%s: %s is more than the %s points possible",
+					assignment.Name, FormatScore(score), FormatScore(assignment.PointsPossible))})
+			}
+
+			if !existing.Excused && existing.Score != nil && *existing.Score == score {
+				preview.Unchanged++
+				continue
+			}
+			change.NewScore = &score
+			preview.Changes = append(preview.Changes, change)
+		}
+	}
+
+	return preview
+}
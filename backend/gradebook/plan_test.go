@@ -0,0 +1,162 @@
+package gradebook
+
+import (
+	"reflect"
+	"testing"
+)
+
+func score(v float64) *float64 { return &v }
+
+func TestPlan(t *testing.T) {
+	students := map[int]Student{
+		7: {UserID: 7, Name: "Doe, Jane", SISUserID: "S1"},
+		9: {UserID: 9, Name: "Roe, Sam"},
+	}
+	assignments := map[int]Column{
+		101: {AssignmentID: 101, Name: "Essay", PointsPossible: 10},
+		102: {AssignmentID: 102, Name: "Lab", PointsPossible: 5},
+	}
+	columns := []Column{{AssignmentID: 101, Name: "Essay"}, {AssignmentID: 102, Name: "Lab"}}
+
+	tests := []struct {
+		name         string
+		sheet        *Sheet
+		current      map[int]map[int]Current
+		wantChanges  []Change
+		wantSame     int
+		wantErrors   []Issue
+		wantWarnings []Issue
+	}{
+		{
+			name: "new and changed scores are posted, equal ones are not",
+			sheet: &Sheet{Columns: columns, Rows: []Row{
+				{Line: 3, Student: Student{UserID: 7}, Cells: map[int]string{101: "8", 102: "4"}},
+			}},
+			current: map[int]map[int]Current{7: {101: {Score: score(8)}, 102: {Score: score(3)}}},
+			wantChanges: []Change{
+				{UserID: 7, Student: "Doe, Jane", AssignmentID: 102, AssignmentName: "Lab", CurrentScore: score(3), NewScore: score(4)},
+			},
+			wantSame: 1,
+		},
+		{
+			name: "blank cells leave grades alone",
+			sheet: &Sheet{Columns: columns, Rows: []Row{
+				{Line: 3, Student: Student{UserID: 7}, Cells: map[int]string{}},
+			}},
+			current: map[int]map[int]Current{7: {101: {Score: score(8)}}},
+		},
+		{
+			name: "EX excuses in any case, and is unchanged when already excused",
+			sheet: &Sheet{Columns: columns, Rows: []Row{
+				{Line: 3, Student: Student{UserID: 7}, Cells: map[int]string{101: "ex", 102: "EX"}},
+			}},
+			current: map[int]map[int]Current{7: {101: {Score: score(6)}, 102: {Excused: true}}},
+			wantChanges: []Change{
+				{UserID: 7, Student: "Doe, Jane", AssignmentID: 101, AssignmentName: "Essay", CurrentScore: score(6), Excuse: true},
+			},
+			wantSame: 1,
+		},
+		{
+			name: "a score replaces an excusal even when it matches the old score",
+			sheet: &Sheet{Columns: columns, Rows: []Row{
+				{Line: 3, Student: Student{UserID: 7}, Cells: map[int]string{101: "6"}},
+			}},
+			current: map[int]map[int]Current{7: {101: {Score: score(6), Excused: true}}},
+			wantChanges: []Change{
+				{UserID: 7, Student: "Doe, Jane", AssignmentID: 101, AssignmentName: "Essay", CurrentScore: score(6), CurrentExcused: true, NewScore: score(6)},
+			},
+		},
+		{
+			name: "invalid and negative scores are errors",
+			sheet: &Sheet{Columns: columns, Rows: []Row{
+				{Line: 3, Student: Student{UserID: 7}, Cells: map[int]string{101: "A-", 102: "-1"}},
+				{Line: 4, Student: Student{UserID: 9}, Cells: map[int]string{101: "NaN"}},
+			}},
+			wantErrors: []Issue{
+				{Line: 3, Message: `Essay: "A-" is not a score`},
+				{Line: 3, Message: "Lab: score -1 is negative"},
+				{Line: 4, Message: `Essay: "NaN" is not a score`},
+			},
+		},
+		{
+			name: "scores over points possible are posted with a warning",
+			sheet: &Sheet{Columns: columns, Rows: []Row{
+				{Line: 3, Student: Student{UserID: 9}, Cells: map[int]string{102: "6"}},
+			}},
+			wantChanges: []Change{
+				{UserID: 9, Student: "Roe, Sam", AssignmentID: 102, AssignmentName: "Lab", NewScore: score(6)},
+			},
+			wantWarnings: []Issue{{Line: 3, Message: "Lab: 6 is more than the 5 points possible"}},
+		},
+		{
+			name: "duplicate students are rejected after their first row",
+			sheet: &Sheet{Columns: columns, Rows: []Row{
+				{Line: 3, Student: Student{UserID: 9}, Cells: map[int]string{101: "7"}},
+				{Line: 5, Student: Student{UserID: 9}, Cells: map[int]string{101: "9"}},
+			}},
+			wantChanges: []Change{
+				{UserID: 9, Student: "Roe, Sam", AssignmentID: 101, AssignmentName: "Essay", NewScore: score(7)},
+			},
+			wantErrors: []Issue{{Line: 5, Message: "Roe, Sam already appears on line 3"}},
+		},
+		{
+			name: "students not in the course are errors",
+			sheet: &Sheet{Columns: columns, Rows: []Row{
+				{Line: 3, Student: Student{UserID: 40, Name: "Poe, Al"}, Cells: map[int]string{101: "7"}},
+			}},
+			wantErrors: []Issue{{Line: 3, Message: "Poe, Al (40) is not an active student in this course"}},
+		},
+		{
+			name: "unknown assignments are errors and their cells are skipped",
+			sheet: &Sheet{
+				Columns: []Column{{AssignmentID: 101, Name: "Essay"}, {AssignmentID: 555, Name: "Old quiz"}},
+				Rows: []Row{
+					{Line: 3, Student: Student{UserID: 7}, Cells: map[int]string{555: "3"}},
+				},
+			},
+			wantErrors: []Issue{{Message: `Assignment "Old quiz" (555) is not in this course`}},
+		},
+		{
+			name: "changed points possible and mismatched SIS IDs are warnings",
+			sheet: &Sheet{
+				Columns: []Column{{AssignmentID: 101, Name: "Essay", PointsPossible: 20}},
+				Rows: []Row{
+					{Line: 3, Student: Student{UserID: 7, SISUserID: "S2"}, Cells: map[int]string{}},
+				},
+			},
+			wantWarnings: []Issue{
+				{Message: "Essay is now worth 10 points, not 20 as in the CSV"},
+				{Line: 3, Message: "SIS User ID S2 does not match Doe, Jane's (S1)"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Plan(tt.sheet, students, assignments, tt.current)
+
+			// Compare against empty slices, as Plan returns them for JSON
+			for _, list := range []*[]Issue{&tt.wantErrors, &tt.wantWarnings} {
+				if *list == nil {
+					*list = []Issue{}
+				}
+			}
+			if tt.wantChanges == nil {
+				tt.wantChanges = []Change{}
+			}
+
+			if !reflect.DeepEqual(got.Changes, tt.wantChanges) {
+				t.Errorf("changes:\n got %+v\nwant %+v", got.Changes, tt.wantChanges)
+			}
+			if got.Unchanged != tt.wantSame {
+				t.Errorf("unchanged = %d, want %d", got.Unchanged, tt.wantSame)
+			}
+			if !reflect.DeepEqual(got.Errors, tt.wantErrors) {
+				t.Errorf("errors:\n got %+v\nwant %+v", got.Errors, tt.wantErrors)
+			}
+			if !reflect.DeepEqual(got.Warnings, tt.wantWarnings) {
+				t.Errorf("warnings:\n got %+v\nwant %+v", got.Warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"auxa/canvas"
+	"auxa/search"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Get the group sets defined in a course
+func getCourseGroupCategories(c *gin.Context) {
+	courseID := c.Param("course_id")
+
+	client, ok := canvasClientFromRequest(c)
+	if !ok {
+		return
+	}
+
+	categories, err := client.GetCourseGroupCategories(courseID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, categories)
+}
+
+// Get submissions collapsed into one gradeable unit per group
+func getGroupSubmissions(c *gin.Context) {
+	courseID := c.Param("course_id")
+	assignmentID := c.Param("assignment_id")
+
+	client, ok := canvasClientFromRequest(c)
+	if !ok {
+		return
+	}
+
+	units, err := client.GetGroupSubmissions(courseID, assignmentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, units)
+}
+
+// Post one grade for a group, fanning it out to every member
+func gradeGroupSubmission(c *gin.Context) {
+	courseID := c.Param("course_id")
+	assignmentID := c.Param("assignment_id")
+
+	groupID, err := strconv.Atoi(c.Param("group_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	var req canvas.SubmissionGrade
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	client, ok := canvasClientFromRequest(c)
+	if !ok {
+		return
+	}
+
+	units, err := client.GetGroupSubmissions(courseID, assignmentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var unit *canvas.GroupSubmission
+	for i := range units {
+		if units[i].Group != nil && units[i].Group.ID == groupID {
+			unit = &units[i]
+			break
+		}
+	}
+	if unit == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Group not found for this assignment"})
+		return
+	}
+
+	graded, err := client.GradeGroupSubmission(courseID, assignmentID, *unit, req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":  err.Error(),
+			"graded": graded,
+		})
+		return
+	}
+
+	// Index the comment once per member so searches count how widely it was given. A grade
+	// for the whole group returns one submission, so its members come from the unit.
+	memberIDs := make([]int, 0, len(unit.Members))
+	if unit.Group != nil && !unit.GradeIndividually {
+		for _, member := range unit.Members {
+			memberIDs = append(memberIDs, member.ID)
+		}
+	} else {
+		for _, submission := range graded {
+			memberIDs = append(memberIDs, submission.UserID)
+		}
+	}
+	entries := make([]search.Entry, 0, len(memberIDs))
+	for _, userID := range memberIDs {
+		entries = append(entries, search.Entry{Kind: search.KindComment, CourseID: courseID, AssignmentID: assignmentID, UserID: userID, Text: req.TextComment})
+	}
+	indexFeedback(client, entries...)
+
+	c.JSON(http.StatusOK, gin.H{
+		"group":              unit.Group,
+		"grade_individually": unit.GradeIndividually,
+		"graded":             graded,
+	})
+}
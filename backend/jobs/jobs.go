@@ -0,0 +1,224 @@
+package jobs
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"auxa/store"
+)
+
+// Job statuses
+const (
+	StatusQueued      = "queued"
+	StatusRunning     = "running"
+	StatusCompleted   = "completed"
+	StatusFailed      = "failed"
+	StatusInterrupted = "interrupted" // The backend restarted while the job was running
+)
+
+// Job is a long-running backend task whose progress the renderer polls
+type Job struct {
+	ID        string          `json:"id"`
+	Kind      string          `json:"kind"`
+	Status    string          `json:"status"`
+	Message   string          `json:"message,omitempty"`
+	Done      int             `json:"done"`
+	Total     int             `json:"total"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+	State     json.RawMessage `json:"state,omitempty"`  // Kind-specific state needed to resume
+	Result    json.RawMessage `json:"result,omitempty"` // Kind-specific result once completed
+	Error     string          `json:"error,omitempty"`
+}
+
+// Finished reports whether the job has stopped, successfully or not
+func (j Job) Finished() bool {
+	return j.Status == StatusCompleted || j.Status == StatusFailed
+}
+
+// Manager tracks jobs and persists them so results survive a restart
+type Manager struct {
+	mu   sync.RWMutex
+	path string
+	jobs map[string]*Job
+}
+
+// LoadManager reads jobs from path. Jobs that were running when the backend stopped are
+// marked interrupted so they can be resumed.
+func LoadManager(path string) (*Manager, error) {
+	m := &Manager{path: path, jobs: make(map[string]*Job)}
+	if err := store.LoadJSON(path, &m.jobs); err != nil {
+		return nil, err
+	}
+	if m.jobs == nil {
+		m.jobs = make(map[string]*Job)
+	}
+
+	for _, job := range m.jobs {
+		if job.Status == StatusQueued || job.Status == StatusRunning {
+			job.Status = StatusInterrupted
+			job.Message = "Backend restarted while the job was running"
+		}
+	}
+
+	return m, nil
+}
+
+func (m *Manager) save() error {
+	return store.SaveJSON(m.path, m.jobs)
+}
+
+// Create registers a new queued job
+func (m *Manager) Create(kind string, total int, state interface{}) (Job, error) {
+	idBytes := make([]byte, 8)
+	if _, err := rand.Read(idBytes); err != nil {
+		return Job{}, fmt.Errorf("failed to generate job ID: %w", err)
+	}
+
+	now := time.Now()
+	job := &Job{
+		ID:        hex.EncodeToString(idBytes),
+		Kind:      kind,
+		Status:    StatusQueued,
+		Total:     total,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if state != nil {
+		raw, err := json.Marshal(state)
+		if err != nil {
+			return Job{}, fmt.Errorf("failed to encode job state: %w", err)
+		}
+		job.State = raw
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.jobs[job.ID] = job
+	if err := m.save(); err != nil {
+		delete(m.jobs, job.ID)
+		return Job{}, err
+	}
+
+	return *job, nil
+}
+
+// Get returns a job by ID
+func (m *Manager) Get(id string) (Job, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+// List returns jobs of a kind, or every job when kind is empty, newest first
+func (m *Manager) List(kind string) []Job {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	// Initialize as empty slice to ensure JSON returns [] instead of null
+	jobs := make([]Job, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		if kind == "" || job.Kind == kind {
+			jobs = append(jobs, *job)
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.After(jobs[j].CreatedAt)
+	})
+	return jobs
+}
+
+// Update applies fn to a job and persists the change
+func (m *Manager) Update(id string, fn func(*Job)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return fmt.Errorf("job %s not found", id)
+	}
+	fn(job)
+	job.UpdatedAt = time.Now()
+	return m.save()
+}
+
+// SetState replaces a job's resumable state
+func (m *Manager) SetState(id string, state interface{}) error {
+	raw, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to encode job state: %w", err)
+	}
+	return m.Update(id, func(job *Job) {
+		job.State = raw
+	})
+}
+
+// Progress records how much of a running job is done
+func (m *Manager) Progress(id string, done, total int, message string) error {
+	return m.Update(id, func(job *Job) {
+		job.Status = StatusRunning
+		job.Done = done
+		job.Total = total
+		job.Message = message
+	})
+}
+
+// Complete stores a job's result and marks it completed
+func (m *Manager) Complete(id string, result interface{}) error {
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to encode job result: %w", err)
+	}
+	return m.Update(id, func(job *Job) {
+		job.Status = StatusCompleted
+		job.Done = job.Total
+		job.Message = ""
+		job.Result = raw
+	})
+}
+
+// Fail marks a job failed
+func (m *Manager) Fail(id string, err error) error {
+	return m.Update(id, func(job *Job) {
+		job.Status = StatusFailed
+		job.Error = err.Error()
+	})
+}
+
+// Run executes fn in the background, marking the job running and failing it if fn returns
+// an error or panics. fn is responsible for calling Complete.
+func (m *Manager) Run(id string, fn func() error) {
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				m.logUpdateError(id, m.Fail(id, fmt.Errorf("job panicked: %v", r)))
+			}
+		}()
+
+		m.logUpdateError(id, m.Update(id, func(job *Job) {
+			job.Status = StatusRunning
+			job.Error = ""
+		}))
+
+		if err := fn(); err != nil {
+			m.logUpdateError(id, m.Fail(id, err))
+		}
+	}()
+}
+
+func (m *Manager) logUpdateError(id string, err error) {
+	if err != nil {
+		fmt.Printf("[Jobs] failed to update job %s: %v\n", id, err)
+	}
+}
@@ -0,0 +1,562 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Providers bill batch requests at half the interactive price
+const batchDiscount = 0.5
+
+// Normalized batch statuses shared by every provider
+const (
+	BatchInProgress = "in_progress"
+	BatchCompleted  = "completed"
+	BatchFailed     = "failed"
+	BatchExpired    = "expired"
+	BatchCancelled  = "cancelled"
+)
+
+// BatchItem is one grading request in a batch, identified by a caller-chosen ID.
+// Anthropic limits custom IDs to 64 letters, digits, hyphens and underscores.
+type BatchItem struct {
+	CustomID string         `json:"custom_id"`
+	Request  GradingRequest `json:"request"`
+}
+
+// Batch is the provider-side state of a submitted batch
+type Batch struct {
+	ID        string `json:"id"`
+	Platform  string `json:"platform"`
+	Model     string `json:"model"`
+	Status    string `json:"status"`
+	Total     int    `json:"total"`
+	Completed int    `json:"completed"`
+	Failed    int    `json:"failed"`
+
+	// Provider handles needed to download results
+	OutputFileID string `json:"output_file_id,omitempty"`
+	ErrorFileID  string `json:"error_file_id,omitempty"`
+	ResultsURL   string `json:"results_url,omitempty"`
+}
+
+// Done reports whether the batch has reached a terminal status
+func (b *Batch) Done() bool {
+	return b.Status != BatchInProgress
+}
+
+// BatchResult is the outcome of one batch item
+type BatchResult struct {
+	CustomID string `json:"custom_id"`
+	Feedback string `json:"feedback"`
+	Usage    *Usage `json:"usage,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// SubmitBatch sends grading requests to the provider's batch API. Every item must use the
+// same platform and API key.
+func SubmitBatch(platform, apiKey string, items []BatchItem) (*Batch, error) {
+	if len(items) == 0 {
+		return nil, fmt.Errorf("batch has no items")
+	}
+
+	for i := range items {
+		req := &items[i].Request
+		if req.MaxTokens == 0 {
+			req.MaxTokens = 6000
+		}
+		if req.Temperature == 0 {
+			req.Temperature = 0.7
+		}
+		if req.TextModel == "" {
+			req.TextModel = defaultTextModel(platform)
+		}
+	}
+
+	switch platform {
+	case "openai":
+		return submitOpenAIBatch(apiKey, items)
+	case "anthropic":
+		return submitAnthropicBatch(apiKey, items)
+	default:
+		return nil, fmt.Errorf("batch grading not supported for platform: %s", platform)
+	}
+}
+
+// GetBatch refreshes a batch's status
+func GetBatch(apiKey string, batch *Batch) (*Batch, error) {
+	switch batch.Platform {
+	case "openai":
+		return getOpenAIBatch(apiKey, batch)
+	case "anthropic":
+		return getAnthropicBatch(apiKey, batch)
+	default:
+		return nil, fmt.Errorf("batch grading not supported for platform: %s", batch.Platform)
+	}
+}
+
+// GetBatchResults downloads the results of a finished batch
+func GetBatchResults(apiKey string, batch *Batch) ([]BatchResult, error) {
+	var results []BatchResult
+	var err error
+
+	switch batch.Platform {
+	case "openai":
+		results, err = getOpenAIBatchResults(apiKey, batch)
+	case "anthropic":
+		results, err = getAnthropicBatchResults(apiKey, batch)
+	default:
+		return nil, fmt.Errorf("batch grading not supported for platform: %s", batch.Platform)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range results {
+		if results[i].Usage != nil {
+			results[i].Usage.EstimatedCost = EstimateCost(batch.Model, *results[i].Usage) * batchDiscount
+		}
+	}
+
+	return results, nil
+}
+
+// batchHTTPRequest sends a provider request and returns the body of a successful response
+func batchHTTPRequest(httpReq *http.Request, provider string) ([]byte, error) {
+	client := &http.Client{Timeout: 120 * time.Second}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var errorResp struct {
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		_ = json.Unmarshal(body, &errorResp)
+		if errorResp.Error != nil {
+			return nil, fmt.Errorf("%s batch API error: %s", provider, errorResp.Error.Message)
+		}
+		return nil, fmt.Errorf("%s batch API error: status %d", provider, resp.StatusCode)
+	}
+
+	return body, nil
+}
+
+// forEachJSONLine decodes each non-empty line of a JSON Lines document
+func forEachJSONLine(data []byte, fn func(line []byte) error) error {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if err := fn(line); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// OpenAI Batch API structures
+type openAIBatch struct {
+	ID            string `json:"id"`
+	Status        string `json:"status"`
+	OutputFileID  string `json:"output_file_id"`
+	ErrorFileID   string `json:"error_file_id"`
+	RequestCounts struct {
+		Total     int `json:"total"`
+		Completed int `json:"completed"`
+		Failed    int `json:"failed"`
+	} `json:"request_counts"`
+}
+
+func (b openAIBatch) toBatch(model string) *Batch {
+	status := BatchInProgress
+	switch b.Status {
+	case "completed":
+		status = BatchCompleted
+	case "failed":
+		status = BatchFailed
+	case "expired":
+		status = BatchExpired
+	case "cancelled":
+		status = BatchCancelled
+	}
+
+	return &Batch{
+		ID:           b.ID,
+		Platform:     "openai",
+		Model:        model,
+		Status:       status,
+		Total:        b.RequestCounts.Total,
+		Completed:    b.RequestCounts.Completed,
+		Failed:       b.RequestCounts.Failed,
+		OutputFileID: b.OutputFileID,
+		ErrorFileID:  b.ErrorFileID,
+	}
+}
+
+func submitOpenAIBatch(apiKey string, items []BatchItem) (*Batch, error) {
+	// Build the JSONL input file, one chat completion per line
+	var input bytes.Buffer
+	var model string
+	for _, item := range items {
+		payload, normalizedModel, _, _ := buildOpenAIPayload(item.Request)
+		model = normalizedModel
+
+		line, err := json.Marshal(map[string]interface{}{
+			"custom_id": item.CustomID,
+			"method":    "POST",
+			"url":       "/v1/chat/completions",
+			"body":      payload,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal batch item: %w", err)
+		}
+		input.Write(line)
+		input.WriteByte('\n')
+	}
+
+	// Upload it with purpose=batch
+	var form bytes.Buffer
+	writer := multipart.NewWriter(&form)
+	if err := writer.WriteField("purpose", "batch"); err != nil {
+		return nil, fmt.Errorf("failed to build upload: %w", err)
+	}
+	part, err := writer.CreateFormFile("file", "grading-batch.jsonl")
+	if err != nil {
+		return nil, fmt.Errorf("failed to build upload: %w", err)
+	}
+	if _, err := part.Write(input.Bytes()); err != nil {
+		return nil, fmt.Errorf("failed to build upload: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to build upload: %w", err)
+	}
+
+	httpReq, err := http.NewRequest("POST", "https://api.openai.com/v1/files", &form)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", writer.FormDataContentType())
+	httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+
+	body, err := batchHTTPRequest(httpReq, "OpenAI")
+	if err != nil {
+		return nil, err
+	}
+
+	var file struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(body, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse file upload: %w", err)
+	}
+
+	// Create the batch against the uploaded file
+	bodyBytes, err := json.Marshal(map[string]string{
+		"input_file_id":     file.ID,
+		"endpoint":          "/v1/chat/completions",
+		"completion_window": "24h",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err = http.NewRequest("POST", "https://api.openai.com/v1/batches", bytes.NewBuffer(bodyBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+
+	body, err = batchHTTPRequest(httpReq, "OpenAI")
+	if err != nil {
+		return nil, err
+	}
+
+	var batch openAIBatch
+	if err := json.Unmarshal(body, &batch); err != nil {
+		return nil, fmt.Errorf("failed to parse batch: %w", err)
+	}
+
+	fmt.Printf("[OpenAI Batch] submitted batch=%s model=%s items=%d\n", batch.ID, model, len(items))
+	return batch.toBatch(model), nil
+}
+
+func getOpenAIBatch(apiKey string, current *Batch) (*Batch, error) {
+	httpReq, err := http.NewRequest("GET", "https://api.openai.com/v1/batches/"+current.ID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+
+	body, err := batchHTTPRequest(httpReq, "OpenAI")
+	if err != nil {
+		return nil, err
+	}
+
+	var batch openAIBatch
+	if err := json.Unmarshal(body, &batch); err != nil {
+		return nil, fmt.Errorf("failed to parse batch: %w", err)
+	}
+
+	return batch.toBatch(current.Model), nil
+}
+
+func downloadOpenAIFile(apiKey, fileID string) ([]byte, error) {
+	httpReq, err := http.NewRequest("GET", "https://api.openai.com/v1/files/"+fileID+"/content", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+
+	return batchHTTPRequest(httpReq, "OpenAI")
+}
+
+func getOpenAIBatchResults(apiKey string, batch *Batch) ([]BatchResult, error) {
+	var results []BatchResult
+
+	for _, fileID := range []string{batch.OutputFileID, batch.ErrorFileID} {
+		if fileID == "" {
+			continue
+		}
+
+		data, err := downloadOpenAIFile(apiKey, fileID)
+		if err != nil {
+			return nil, err
+		}
+
+		err = forEachJSONLine(data, func(line []byte) error {
+			var row struct {
+				CustomID string `json:"custom_id"`
+				Response *struct {
+					StatusCode int             `json:"status_code"`
+					Body       json.RawMessage `json:"body"`
+				} `json:"response"`
+				Error *struct {
+					Message string `json:"message"`
+				} `json:"error"`
+			}
+			if err := json.Unmarshal(line, &row); err != nil {
+				return fmt.Errorf("failed to parse batch result: %w", err)
+			}
+
+			result := BatchResult{CustomID: row.CustomID}
+			switch {
+			case row.Error != nil:
+				result.Error = row.Error.Message
+			case row.Response == nil:
+				result.Error = "no response in batch result"
+			default:
+				var completion openAIResponse
+				if err := json.Unmarshal(row.Response.Body, &completion); err != nil {
+					result.Error = fmt.Sprintf("failed to parse response: %v", err)
+				} else if completion.Error != nil {
+					result.Error = completion.Error.Message
+				} else if len(completion.Choices) == 0 {
+					result.Error = "no response from OpenAI"
+				} else {
+					text, err := extractTextFromMessage(completion.Choices[0].Message.Content)
+					if err != nil {
+						result.Error = fmt.Sprintf("failed to extract content: %v", err)
+					} else {
+						usage := completion.usage()
+						result.Feedback = strings.TrimSpace(text)
+						result.Usage = &usage
+					}
+				}
+			}
+
+			results = append(results, result)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return results, nil
+}
+
+// Anthropic Message Batches structures
+type anthropicBatch struct {
+	ID               string `json:"id"`
+	ProcessingStatus string `json:"processing_status"`
+	ResultsURL       string `json:"results_url"`
+	RequestCounts    struct {
+		Processing int `json:"processing"`
+		Succeeded  int `json:"succeeded"`
+		Errored    int `json:"errored"`
+		Canceled   int `json:"canceled"`
+		Expired    int `json:"expired"`
+	} `json:"request_counts"`
+}
+
+func (b anthropicBatch) toBatch(model string) *Batch {
+	counts := b.RequestCounts
+	status := BatchInProgress
+	if b.ProcessingStatus == "ended" {
+		// Individual failures are reported per item; the batch itself only fails if nothing succeeded
+		switch {
+		case counts.Succeeded > 0 || counts.Errored > 0:
+			status = BatchCompleted
+		case counts.Expired > 0:
+			status = BatchExpired
+		default:
+			status = BatchCancelled
+		}
+	}
+
+	return &Batch{
+		ID:         b.ID,
+		Platform:   "anthropic",
+		Model:      model,
+		Status:     status,
+		Total:      counts.Processing + counts.Succeeded + counts.Errored + counts.Canceled + counts.Expired,
+		Completed:  counts.Succeeded,
+		Failed:     counts.Errored + counts.Canceled + counts.Expired,
+		ResultsURL: b.ResultsURL,
+	}
+}
+
+func setAnthropicHeaders(httpReq *http.Request, apiKey string) {
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-api-key", apiKey)
+	httpReq.Header.Set("anthropic-version", "2023-06-01")
+}
+
+func submitAnthropicBatch(apiKey string, items []BatchItem) (*Batch, error) {
+	type batchRequest struct {
+		CustomID string           `json:"custom_id"`
+		Params   anthropicRequest `json:"params"`
+	}
+
+	requests := make([]batchRequest, len(items))
+	var model string
+	for i, item := range items {
+		params := buildAnthropicRequest(item.Request)
+		model = params.Model
+		requests[i] = batchRequest{CustomID: item.CustomID, Params: params}
+	}
+
+	bodyBytes, err := json.Marshal(map[string]interface{}{"requests": requests})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequest("POST", "https://api.anthropic.com/v1/messages/batches", bytes.NewBuffer(bodyBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	setAnthropicHeaders(httpReq, apiKey)
+
+	body, err := batchHTTPRequest(httpReq, "Anthropic")
+	if err != nil {
+		return nil, err
+	}
+
+	var batch anthropicBatch
+	if err := json.Unmarshal(body, &batch); err != nil {
+		return nil, fmt.Errorf("failed to parse batch: %w", err)
+	}
+
+	fmt.Printf("[Anthropic Batch] submitted batch=%s model=%s items=%d\n", batch.ID, model, len(items))
+	return batch.toBatch(model), nil
+}
+
+func getAnthropicBatch(apiKey string, current *Batch) (*Batch, error) {
+	httpReq, err := http.NewRequest("GET", "https://api.anthropic.com/v1/messages/batches/"+current.ID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	setAnthropicHeaders(httpReq, apiKey)
+
+	body, err := batchHTTPRequest(httpReq, "Anthropic")
+	if err != nil {
+		return nil, err
+	}
+
+	var batch anthropicBatch
+	if err := json.Unmarshal(body, &batch); err != nil {
+		return nil, fmt.Errorf("failed to parse batch: %w", err)
+	}
+
+	return batch.toBatch(current.Model), nil
+}
+
+func getAnthropicBatchResults(apiKey string, batch *Batch) ([]BatchResult, error) {
+	if batch.ResultsURL == "" {
+		return nil, fmt.Errorf("batch %s has no results yet", batch.ID)
+	}
+
+	httpReq, err := http.NewRequest("GET", batch.ResultsURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	setAnthropicHeaders(httpReq, apiKey)
+
+	data, err := batchHTTPRequest(httpReq, "Anthropic")
+	if err != nil {
+		return nil, err
+	}
+
+	var results []BatchResult
+	err = forEachJSONLine(data, func(line []byte) error {
+		var row struct {
+			CustomID string `json:"custom_id"`
+			Result   struct {
+				Type    string             `json:"type"`
+				Message *anthropicResponse `json:"message"`
+				Error   *struct {
+					Error *struct {
+						Message string `json:"message"`
+					} `json:"error"`
+				} `json:"error"`
+			} `json:"result"`
+		}
+		if err := json.Unmarshal(line, &row); err != nil {
+			return fmt.Errorf("failed to parse batch result: %w", err)
+		}
+
+		result := BatchResult{CustomID: row.CustomID}
+		switch {
+		case row.Result.Type != "succeeded":
+			result.Error = "request " + row.Result.Type
+			if row.Result.Error != nil && row.Result.Error.Error != nil {
+				result.Error += ": " + row.Result.Error.Error.Message
+			}
+		case row.Result.Message == nil || len(row.Result.Message.Content) == 0:
+			result.Error = "no response from Anthropic"
+		default:
+			usage := row.Result.Message.Usage.usage()
+			result.Feedback = row.Result.Message.Content[0].Text
+			result.Usage = &usage
+		}
+
+		results = append(results, result)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
@@ -0,0 +1,188 @@
+package llm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"auxa/store"
+)
+
+// Cache is a content-addressed, on-disk store of provider responses. Identical prompts to the
+// same model are answered from disk instead of triggering a fresh paid call.
+type Cache struct {
+	dir string
+	ttl time.Duration
+}
+
+// NewCache creates a cache in dir whose entries expire after ttl
+func NewCache(dir string, ttl time.Duration) *Cache {
+	return &Cache{dir: dir, ttl: ttl}
+}
+
+type cacheEntry struct {
+	StoredAt time.Time       `json:"stored_at"`
+	Response json.RawMessage `json:"response"`
+}
+
+// GradingCacheKey hashes everything that determines a grading response
+func GradingCacheKey(req GradingRequest) string {
+	model := req.TextModel
+	if model == "" {
+		model = defaultTextModel(req.Platform)
+	}
+	parts := []string{"grading", req.Platform, model, req.SystemPrompt, req.SharedContext, req.Prompt, fmt.Sprintf("%g", req.Temperature), ""}
+	if req.Consensus != nil {
+		// Members and aggregation change the response; API keys do not
+		config := *req.Consensus
+		config.Members = make([]ConsensusMember, len(req.Consensus.Members))
+		for i, member := range req.Consensus.Members {
+			config.Members[i] = ConsensusMember{Platform: member.Platform, TextModel: member.TextModel}
+		}
+		encoded, _ := json.Marshal(config)
+		parts = append(parts, string(encoded))
+	}
+	return cacheKey(parts...)
+}
+
+// VisionCacheKey hashes everything that determines a vision response, including the image
+func VisionCacheKey(req VisionAnalysisRequest) string {
+	model := req.Model
+	if model == "" {
+		model = defaultVisionModel
+	}
+	imageHash := sha256.Sum256([]byte(strings.TrimSpace(req.ImageBase64)))
+	return cacheKey("vision", req.Platform, model, "", "", req.Prompt, fmt.Sprintf("%g", req.Temperature), hex.EncodeToString(imageHash[:]))
+}
+
+func cacheKey(parts ...string) string {
+	hash := sha256.New()
+	for _, part := range parts {
+		// Length-prefix each part so adjacent fields cannot run together
+		fmt.Fprintf(hash, "%d:%s;", len(part), part)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.dir, key[:2], key+".json")
+}
+
+// Get decodes a fresh cached response for key into v
+func (c *Cache) Get(key string, v interface{}) bool {
+	var entry cacheEntry
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return false
+	}
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return false
+	}
+	if c.ttl > 0 && time.Since(entry.StoredAt) > c.ttl {
+		return false
+	}
+	return json.Unmarshal(entry.Response, v) == nil
+}
+
+// Put stores a response under key
+func (c *Cache) Put(key string, v interface{}) error {
+	response, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode cached response: %w", err)
+	}
+	return store.SaveJSON(c.path(key), cacheEntry{StoredAt: time.Now(), Response: response})
+}
+
+// Purge deletes expired entries and reports how many were removed
+func (c *Cache) Purge() (int, error) {
+	if c.ttl <= 0 {
+		return 0, nil
+	}
+
+	removed := 0
+	err := filepath.Walk(c.dir, func(path string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || filepath.Ext(path) != ".json" {
+			return nil
+		}
+
+		var entry cacheEntry
+		if err := store.LoadJSON(path, &entry); err != nil || time.Since(entry.StoredAt) > c.ttl {
+			if err := os.Remove(path); err == nil {
+				removed++
+			}
+		}
+		return nil
+	})
+	return removed, err
+}
+
+// CacheResponses answers repeated grading requests from cache unless the request bypasses it.
+// Cached responses are marked as such and carry no usage, since nothing was spent.
+func CacheResponses(cache *Cache) Middleware {
+	return func(next Generator) Generator {
+		return func(req GradingRequest) (*GradingResponse, error) {
+			key := GradingCacheKey(req)
+			if !req.BypassCache {
+				var cached GradingResponse
+				if cache.Get(key, &cached) {
+					cached.Cached = true
+					cached.Usage = nil
+					if cached.Consensus != nil {
+						for i := range cached.Consensus.Votes {
+							cached.Consensus.Votes[i].Usage = nil
+						}
+					}
+					return &cached, nil
+				}
+			}
+
+			resp, err := next(req)
+			if err == nil && resp != nil {
+				stored := *resp
+				stored.Warnings = nil
+				if putErr := cache.Put(key, stored); putErr != nil {
+					fmt.Printf("[Cache] failed to store grading response: %v\n", putErr)
+				}
+			}
+			return resp, err
+		}
+	}
+}
+
+// CacheVisionResponses answers repeated vision requests from cache unless the request bypasses it
+func CacheVisionResponses(cache *Cache) VisionMiddleware {
+	return func(next VisionAnalyzer) VisionAnalyzer {
+		return func(req VisionAnalysisRequest) (*VisionAnalysisResponse, error) {
+			key := VisionCacheKey(req)
+			if !req.BypassCache {
+				var cached VisionAnalysisResponse
+				if cache.Get(key, &cached) {
+					cached.Cached = true
+					cached.Usage = nil
+					return &cached, nil
+				}
+			}
+
+			resp, err := next(req)
+			if err == nil && resp != nil {
+				stored := *resp
+				stored.Warnings = nil
+				if putErr := cache.Put(key, stored); putErr != nil {
+					fmt.Printf("[Cache] failed to store vision response: %v\n", putErr)
+				}
+			}
+			return resp, err
+		}
+	}
+}
@@ -12,20 +12,90 @@ import (
 
 // GradingRequest represents a request to generate AI feedback
 type GradingRequest struct {
-	Platform     string  `json:"platform"`
-	APIKey       string  `json:"api_key"`
-	Prompt       string  `json:"prompt"`
-	SystemPrompt string  `json:"system_prompt"`
-	TextModel    string  `json:"text_model"`
-	AudioModel   string  `json:"audio_model"`
-	MaxTokens    int     `json:"max_tokens"`
-	Temperature  float64 `json:"temperature"`
+	Platform     string `json:"platform"`
+	APIKey       string `json:"api_key"`
+	Prompt       string `json:"prompt"`
+	SystemPrompt string `json:"system_prompt"`
+	// Rubric and assignment description shared by every submission of an assignment.
+	// Sent as a separate block so providers can cache it across a batch.
+	SharedContext string  `json:"shared_context"`
+	TextModel     string  `json:"text_model"`
+	AudioModel    string  `json:"audio_model"`
+	MaxTokens     int     `json:"max_tokens"`
+	Temperature   float64 `json:"temperature"`
+
+	// Grade with several provider/model pairs and aggregate their scores
+	Consensus *ConsensusConfig `json:"consensus,omitempty"`
+
+	// Graded submissions to show as few-shot examples. The backend resolves these into
+	// SharedContext before the provider call.
+	Exemplars *ExemplarRequest `json:"exemplars,omitempty"`
+
+	// Grade a resubmission against the student's earlier attempt. The backend adds the prior
+	// feedback and the diff to Prompt before the provider call.
+	Resubmission *ResubmissionRequest `json:"resubmission,omitempty"`
+
+	// Run an autograder test suite on the student's files and add the results to Prompt
+	Autograder *AutograderRequest `json:"autograder,omitempty"`
+
+	// Check the student's Jupyter notebook and add a cell-by-cell summary to Prompt
+	Notebook *NotebookRequest `json:"notebook,omitempty"`
+
+	// Render SystemPrompt and Prompt from the template library instead of sending them
+	Templates *TemplateRequest `json:"templates,omitempty"`
+
+	// Context for the backend; never sent to the provider
+	CourseID     string `json:"course_id,omitempty"`
+	AssignmentID string `json:"assignment_id,omitempty"`
+	UserID       int    `json:"user_id,omitempty"`   // Student being graded; their own work is never an exemplar
+	GraderID     string `json:"-"`                   // Canvas user ID of the TA, resolved from their token for per-TA quotas
+	Anonymize    bool   `json:"anonymize,omitempty"` // Scrub roster names and emails before the provider call
+	BypassCache  bool   `json:"bypass_cache,omitempty"`
 }
 
 // GradingResponse represents the AI feedback response
 type GradingResponse struct {
-	Feedback string `json:"feedback"`
-	Error    string `json:"error,omitempty"`
+	Feedback  string           `json:"feedback"`
+	Model     string           `json:"model,omitempty"`
+	Usage     *Usage           `json:"usage,omitempty"`
+	Consensus *ConsensusResult `json:"consensus,omitempty"`
+	Cached    bool             `json:"cached"`
+	Warnings  []string         `json:"warnings,omitempty"`
+	Error     string           `json:"error,omitempty"`
+}
+
+// ExemplarRequest selects already-graded submissions of the same assignment as few-shot examples
+type ExemplarRequest struct {
+	Count          int   `json:"count"`                      // Anchors chosen automatically across the score range; default 3
+	SubmissionIDs  []int `json:"submission_ids,omitempty"`   // Use these graded submissions instead
+	ExcludeUserIDs []int `json:"exclude_user_ids,omitempty"` // Never use these students' work, e.g. the student being graded
+}
+
+// ResubmissionRequest identifies the attempts to compare when grading a resubmission
+type ResubmissionRequest struct {
+	UserID          int `json:"user_id"`
+	PreviousAttempt int `json:"previous_attempt,omitempty"` // Defaults to the attempt before the current one
+}
+
+// AutograderRequest selects the test suite and student for autograded feedback
+type AutograderRequest struct {
+	SuiteID string `json:"suite_id,omitempty"` // Defaults to the assignment's only suite
+	UserID  int    `json:"user_id"`
+	Rerun   bool   `json:"rerun,omitempty"` // Run again even if the current attempt has a result
+}
+
+// NotebookRequest selects the student and notebook for notebook-checked feedback
+type NotebookRequest struct {
+	UserID       int  `json:"user_id"`
+	AttachmentID int  `json:"attachment_id,omitempty"` // Defaults to the submission's only .ipynb file
+	Execute      bool `json:"execute,omitempty"`       // Re-run the notebook rather than checking saved outputs only
+}
+
+// TemplateRequest names library templates to render for the student being graded. The most
+// specific template of each name visible in the course and assignment is used.
+type TemplateRequest struct {
+	System  string `json:"system,omitempty"`  // System template; replaces SystemPrompt
+	Grading string `json:"grading,omitempty"` // Grading template; replaces Prompt
 }
 
 // VisionAnalysisRequest represents a request to analyse an image with a vision-capable model
@@ -38,16 +108,40 @@ type VisionAnalysisRequest struct {
 	MimeType    string  `json:"mime_type"`
 	MaxTokens   int     `json:"max_tokens"`
 	Temperature float64 `json:"temperature"`
+
+	// Context for the backend; never sent to the provider
+	CourseID     string `json:"course_id,omitempty"`
+	AssignmentID string `json:"assignment_id,omitempty"`
+	GraderID     string `json:"-"`                   // Canvas user ID of the TA, resolved from their token for per-TA quotas
+	Anonymize    bool   `json:"anonymize,omitempty"` // Scrub roster names and emails before the provider call
+	BypassCache  bool   `json:"bypass_cache,omitempty"`
 }
 
 // VisionAnalysisResponse represents the result of a vision analysis request
 type VisionAnalysisResponse struct {
-	Summary string `json:"summary"`
-	Error   string `json:"error,omitempty"`
+	Summary  string   `json:"summary"`
+	Model    string   `json:"model,omitempty"`
+	Usage    *Usage   `json:"usage,omitempty"`
+	Cached   bool     `json:"cached"`
+	Warnings []string `json:"warnings,omitempty"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// Usage reports the tokens a provider call consumed and its estimated cost
+type Usage struct {
+	InputTokens      int     `json:"input_tokens"` // Uncached input tokens
+	OutputTokens     int     `json:"output_tokens"`
+	CacheReadTokens  int     `json:"cache_read_tokens"`  // Input tokens served from the provider's prompt cache
+	CacheWriteTokens int     `json:"cache_write_tokens"` // Input tokens written to the provider's prompt cache
+	EstimatedCost    float64 `json:"estimated_cost"`     // USD, from the configured price table
 }
 
 // GenerateFeedback routes the request to the appropriate LLM provider
 func GenerateFeedback(req GradingRequest) (*GradingResponse, error) {
+	if req.Consensus != nil {
+		return generateConsensus(req)
+	}
+
 	// Set defaults
 	if req.MaxTokens == 0 {
 		req.MaxTokens = 6000
@@ -56,25 +150,31 @@ func GenerateFeedback(req GradingRequest) (*GradingResponse, error) {
 		req.Temperature = 0.7
 	}
 
+	if req.TextModel == "" {
+		req.TextModel = defaultTextModel(req.Platform)
+	}
+
 	var feedback string
+	var usage Usage
 	var err error
 
 	switch req.Platform {
 	case "openai":
-		feedback, err = callOpenAI(req)
+		feedback, usage, err = callOpenAI(req)
 	case "anthropic":
-		feedback, err = callAnthropic(req)
+		feedback, usage, err = callAnthropic(req)
 	case "google":
-		feedback, err = callGoogleGemini(req)
+		feedback, usage, err = callGoogleGemini(req)
 	default:
 		return nil, fmt.Errorf("unsupported platform: %s", req.Platform)
 	}
 
 	if err != nil {
-		return &GradingResponse{Error: err.Error()}, err
+		return &GradingResponse{Model: req.TextModel, Error: err.Error()}, err
 	}
 
-	return &GradingResponse{Feedback: feedback}, nil
+	usage.EstimatedCost = EstimateCost(req.TextModel, usage)
+	return &GradingResponse{Feedback: feedback, Model: req.TextModel, Usage: &usage}, nil
 }
 
 // AnalyzeImage routes vision analysis to the appropriate provider
@@ -86,21 +186,42 @@ func AnalyzeImage(req VisionAnalysisRequest) (*VisionAnalysisResponse, error) {
 		req.Temperature = 0.2
 	}
 
+	if req.Model == "" {
+		req.Model = defaultVisionModel
+	}
+
 	var summary string
+	var usage Usage
 	var err error
 
 	switch req.Platform {
 	case "openai":
-		summary, err = callOpenAIVision(req)
+		summary, usage, err = callOpenAIVision(req)
 	default:
 		return nil, fmt.Errorf("vision analysis not supported for platform: %s", req.Platform)
 	}
 
 	if err != nil {
-		return &VisionAnalysisResponse{Error: err.Error()}, err
+		return &VisionAnalysisResponse{Model: req.Model, Error: err.Error()}, err
 	}
 
-	return &VisionAnalysisResponse{Summary: summary}, nil
+	usage.EstimatedCost = EstimateCost(req.Model, usage)
+	return &VisionAnalysisResponse{Summary: summary, Model: req.Model, Usage: &usage}, nil
+}
+
+// Default models when the request does not name one
+const defaultVisionModel = "gpt-4o-mini"
+
+func defaultTextModel(platform string) string {
+	switch platform {
+	case "openai":
+		return "gpt-4o-mini"
+	case "anthropic":
+		return "claude-sonnet-4-5-20250929"
+	case "google":
+		return "gemini-2.5-pro"
+	}
+	return ""
 }
 
 // OpenAI API structures
@@ -127,11 +248,31 @@ type openAIResponse struct {
 	Choices []struct {
 		Message openAIChoiceMessage `json:"message"`
 	} `json:"choices"`
+	Usage *struct {
+		PromptTokens        int `json:"prompt_tokens"`
+		CompletionTokens    int `json:"completion_tokens"`
+		PromptTokensDetails struct {
+			CachedTokens int `json:"cached_tokens"`
+		} `json:"prompt_tokens_details"`
+	} `json:"usage"`
 	Error *struct {
 		Message string `json:"message"`
 	} `json:"error"`
 }
 
+func (r *openAIResponse) usage() Usage {
+	if r.Usage == nil {
+		return Usage{}
+	}
+	// OpenAI counts cached tokens within prompt_tokens; report them separately
+	cached := r.Usage.PromptTokensDetails.CachedTokens
+	return Usage{
+		InputTokens:     r.Usage.PromptTokens - cached,
+		OutputTokens:    r.Usage.CompletionTokens,
+		CacheReadTokens: cached,
+	}
+}
+
 func usesMaxCompletionTokens(model string) bool {
 	return !isLegacyChatModel(model)
 }
@@ -186,12 +327,17 @@ func extractTextFromMessage(raw json.RawMessage) (string, error) {
 	return "", fmt.Errorf("unsupported message content structure")
 }
 
-// Call OpenAI API
-func callOpenAI(req GradingRequest) (string, error) {
+// buildOpenAIPayload builds the chat completion body for a grading request, returning it
+// with the normalized model, the token parameter used and the effective temperature
+func buildOpenAIPayload(req GradingRequest) (map[string]interface{}, string, string, float64) {
 	systemContent := "You are a teaching assistant helping to grade student assignments. Provide constructive, detailed feedback."
 	if req.SystemPrompt != "" {
 		systemContent = req.SystemPrompt
 	}
+	// OpenAI caches long prompt prefixes automatically, so shared context belongs up front
+	if strings.TrimSpace(req.SharedContext) != "" {
+		systemContent += "\n\n" + req.SharedContext
+	}
 
 	// Use the specified model or default to a chat-compatible baseline
 	model := req.TextModel
@@ -234,6 +380,13 @@ func callOpenAI(req GradingRequest) (string, error) {
 	}
 	payload[paramKey] = req.MaxTokens
 
+	return payload, normalizedModel, paramKey, temperature
+}
+
+// Call OpenAI API
+func callOpenAI(req GradingRequest) (string, Usage, error) {
+	payload, normalizedModel, paramKey, temperature := buildOpenAIPayload(req)
+
 	fmt.Printf("[OpenAI] model=%s param=%s maxTokens=%d temperature=%.2f (explicit=%t)\n",
 		normalizedModel,
 		paramKey,
@@ -276,7 +429,7 @@ func callOpenAI(req GradingRequest) (string, error) {
 
 	body, apiResp, resp, err := tryRequest(payload)
 	if err != nil {
-		return "", err
+		return "", Usage{}, err
 	}
 
 	// Retry once if OpenAI complains about the token parameter
@@ -305,44 +458,44 @@ func callOpenAI(req GradingRequest) (string, error) {
 		if fallback {
 			body, apiResp, resp, err = tryRequest(payload)
 			if err != nil {
-				return "", err
+				return "", Usage{}, err
 			}
 		}
 	}
 
 	if resp.StatusCode != http.StatusOK {
 		if apiResp.Error != nil {
-			return "", fmt.Errorf("OpenAI API error: %s", apiResp.Error.Message)
+			return "", Usage{}, fmt.Errorf("OpenAI API error: %s", apiResp.Error.Message)
 		}
-		return "", fmt.Errorf("OpenAI API error: status %d", resp.StatusCode)
+		return "", Usage{}, fmt.Errorf("OpenAI API error: status %d", resp.StatusCode)
 	}
 
 	var openAIResp openAIResponse
 	if err := json.Unmarshal(body, &openAIResp); err != nil {
-		return "", fmt.Errorf("failed to parse response: %w", err)
+		return "", Usage{}, fmt.Errorf("failed to parse response: %w", err)
 	}
 
 	if len(openAIResp.Choices) == 0 {
-		return "", fmt.Errorf("no response from OpenAI")
+		return "", Usage{}, fmt.Errorf("no response from OpenAI")
 	}
 
 	text, err := extractTextFromMessage(openAIResp.Choices[0].Message.Content)
 	if err != nil {
-		return "", fmt.Errorf("failed to extract content: %w", err)
+		return "", Usage{}, fmt.Errorf("failed to extract content: %w", err)
 	}
 
 	text = strings.TrimSpace(text)
 	if text == "" {
 		if len(openAIResp.Choices[0].Message.ToolCalls) > 0 {
-			return "", fmt.Errorf("model attempted to call a tool, which is not supported in this workflow. Please try again or choose a different model.")
+			return "", Usage{}, fmt.Errorf("model attempted to call a tool, which is not supported in this workflow. Please try again or choose a different model.")
 		}
-		return "", fmt.Errorf("received an empty response from OpenAI. Please try again.")
+		return "", Usage{}, fmt.Errorf("received an empty response from OpenAI. Please try again.")
 	}
 
-	return text, nil
+	return text, openAIResp.usage(), nil
 }
 
-func callOpenAIVision(req VisionAnalysisRequest) (string, error) {
+func callOpenAIVision(req VisionAnalysisRequest) (string, Usage, error) {
 	systemContent := "You help teaching assistants interpret student-uploaded visuals. Provide concise descriptions that highlight elements relevant to grading."
 	userPrompt := strings.TrimSpace(req.Prompt)
 	if userPrompt == "" {
@@ -363,7 +516,7 @@ func callOpenAIVision(req VisionAnalysisRequest) (string, error) {
 
 	imagePayload := strings.TrimSpace(req.ImageBase64)
 	if imagePayload == "" {
-		return "", fmt.Errorf("image payload missing for vision analysis")
+		return "", Usage{}, fmt.Errorf("image payload missing for vision analysis")
 	}
 	imageURL := fmt.Sprintf("data:%s;base64,%s", mimeType, imagePayload)
 
@@ -447,7 +600,7 @@ func callOpenAIVision(req VisionAnalysisRequest) (string, error) {
 
 	body, apiResp, resp, err := tryRequest(payload)
 	if err != nil {
-		return "", err
+		return "", Usage{}, err
 	}
 
 	if resp.StatusCode == http.StatusBadRequest && apiResp.Error != nil {
@@ -469,45 +622,58 @@ func callOpenAIVision(req VisionAnalysisRequest) (string, error) {
 		if fallback {
 			body, apiResp, resp, err = tryRequest(payload)
 			if err != nil {
-				return "", err
+				return "", Usage{}, err
 			}
 		}
 	}
 
 	if resp.StatusCode != http.StatusOK {
 		if apiResp.Error != nil {
-			return "", fmt.Errorf("OpenAI vision API error: %s", apiResp.Error.Message)
+			return "", Usage{}, fmt.Errorf("OpenAI vision API error: %s", apiResp.Error.Message)
 		}
-		return "", fmt.Errorf("OpenAI vision API error: status %d", resp.StatusCode)
+		return "", Usage{}, fmt.Errorf("OpenAI vision API error: status %d", resp.StatusCode)
 	}
 
 	var openAIResp openAIResponse
 	if err := json.Unmarshal(body, &openAIResp); err != nil {
-		return "", fmt.Errorf("failed to parse response: %w", err)
+		return "", Usage{}, fmt.Errorf("failed to parse response: %w", err)
 	}
 
 	if len(openAIResp.Choices) == 0 {
-		return "", fmt.Errorf("no response from OpenAI vision endpoint")
+		return "", Usage{}, fmt.Errorf("no response from OpenAI vision endpoint")
 	}
 
 	text, err := extractTextFromMessage(openAIResp.Choices[0].Message.Content)
 	if err != nil {
-		return "", fmt.Errorf("failed to extract content: %w", err)
+		return "", Usage{}, fmt.Errorf("failed to extract content: %w", err)
 	}
 
 	text = strings.TrimSpace(text)
 	if text == "" {
-		return "", fmt.Errorf("received an empty response from OpenAI vision endpoint")
+		return "", Usage{}, fmt.Errorf("received an empty response from OpenAI vision endpoint")
 	}
 
-	return text, nil
+	return text, openAIResp.usage(), nil
 }
 
 // Anthropic API structures
 type anthropicRequest struct {
-	Model     string             `json:"model"`
-	MaxTokens int                `json:"max_tokens"`
-	Messages  []anthropicMessage `json:"messages"`
+	Model     string                `json:"model"`
+	MaxTokens int                   `json:"max_tokens"`
+	System    []anthropicSystemText `json:"system,omitempty"`
+	Messages  []anthropicMessage    `json:"messages"`
+}
+
+// anthropicSystemText is a system prompt block; a cache_control breakpoint caches the
+// prompt prefix up to and including the block
+type anthropicSystemText struct {
+	Type         string                 `json:"type"`
+	Text         string                 `json:"text"`
+	CacheControl *anthropicCacheControl `json:"cache_control,omitempty"`
+}
+
+type anthropicCacheControl struct {
+	Type string `json:"type"`
 }
 
 type anthropicMessage struct {
@@ -519,42 +685,71 @@ type anthropicResponse struct {
 	Content []struct {
 		Text string `json:"text"`
 	} `json:"content"`
+	Usage anthropicUsage `json:"usage"`
 	Error *struct {
 		Message string `json:"message"`
 	} `json:"error"`
 }
 
-// Call Anthropic Claude API
-func callAnthropic(req GradingRequest) (string, error) {
+type anthropicUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+}
+
+func (u anthropicUsage) usage() Usage {
+	return Usage{
+		InputTokens:      u.InputTokens,
+		OutputTokens:     u.OutputTokens,
+		CacheWriteTokens: u.CacheCreationInputTokens,
+		CacheReadTokens:  u.CacheReadInputTokens,
+	}
+}
+
+// buildAnthropicRequest places the system prompt and shared context in the system field and
+// marks the shared rubric and assignment block as cacheable, so every submission graded
+// against the same rubric reuses the cached prefix
+func buildAnthropicRequest(req GradingRequest) anthropicRequest {
 	systemContent := "You are a teaching assistant helping to grade student assignments. Provide constructive, detailed feedback."
 	if req.SystemPrompt != "" {
 		systemContent = req.SystemPrompt
 	}
 
-	fullPrompt := systemContent + "\n\n" + req.Prompt
-
 	// Use the specified model or default to claude-sonnet-4-5-20250929
 	model := req.TextModel
 	if model == "" {
 		model = "claude-sonnet-4-5-20250929"
 	}
 
-	requestBody := anthropicRequest{
+	system := []anthropicSystemText{{Type: "text", Text: systemContent}}
+	if strings.TrimSpace(req.SharedContext) != "" {
+		system = append(system, anthropicSystemText{Type: "text", Text: req.SharedContext})
+	}
+	system[len(system)-1].CacheControl = &anthropicCacheControl{Type: "ephemeral"}
+
+	return anthropicRequest{
 		Model:     model,
 		MaxTokens: req.MaxTokens,
+		System:    system,
 		Messages: []anthropicMessage{
-			{Role: "user", Content: fullPrompt},
+			{Role: "user", Content: req.Prompt},
 		},
 	}
+}
+
+// Call Anthropic Claude API
+func callAnthropic(req GradingRequest) (string, Usage, error) {
+	requestBody := buildAnthropicRequest(req)
 
 	bodyBytes, err := json.Marshal(requestBody)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+		return "", Usage{}, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	httpReq, err := http.NewRequest("POST", "https://api.anthropic.com/v1/messages", bytes.NewBuffer(bodyBytes))
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return "", Usage{}, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
@@ -564,34 +759,36 @@ func callAnthropic(req GradingRequest) (string, error) {
 	client := &http.Client{Timeout: 60 * time.Second}
 	resp, err := client.Do(httpReq)
 	if err != nil {
-		return "", fmt.Errorf("request failed: %w", err)
+		return "", Usage{}, fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+		return "", Usage{}, fmt.Errorf("failed to read response: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
 		var errorResp anthropicResponse
 		json.Unmarshal(body, &errorResp)
 		if errorResp.Error != nil {
-			return "", fmt.Errorf("Anthropic API error: %s", errorResp.Error.Message)
+			return "", Usage{}, fmt.Errorf("Anthropic API error: %s", errorResp.Error.Message)
 		}
-		return "", fmt.Errorf("Anthropic API error: status %d", resp.StatusCode)
+		return "", Usage{}, fmt.Errorf("Anthropic API error: status %d", resp.StatusCode)
 	}
 
 	var anthropicResp anthropicResponse
 	if err := json.Unmarshal(body, &anthropicResp); err != nil {
-		return "", fmt.Errorf("failed to parse response: %w", err)
+		return "", Usage{}, fmt.Errorf("failed to parse response: %w", err)
 	}
 
 	if len(anthropicResp.Content) == 0 {
-		return "", fmt.Errorf("no response from Anthropic")
+		return "", Usage{}, fmt.Errorf("no response from Anthropic")
 	}
 
-	return anthropicResp.Content[0].Text, nil
+	usage := anthropicResp.Usage.usage()
+	fmt.Printf("[Anthropic] model=%s cacheRead=%d cacheWrite=%d\n", requestBody.Model, usage.CacheReadTokens, usage.CacheWriteTokens)
+	return anthropicResp.Content[0].Text, usage, nil
 }
 
 // Google Gemini API structures
@@ -619,17 +816,26 @@ type geminiResponse struct {
 			Parts []geminiPart `json:"parts"`
 		} `json:"content"`
 	} `json:"candidates"`
+	UsageMetadata struct {
+		PromptTokenCount        int `json:"promptTokenCount"`
+		CandidatesTokenCount    int `json:"candidatesTokenCount"`
+		ThoughtsTokenCount      int `json:"thoughtsTokenCount"`
+		CachedContentTokenCount int `json:"cachedContentTokenCount"`
+	} `json:"usageMetadata"`
 	Error *struct {
 		Message string `json:"message"`
 	} `json:"error"`
 }
 
 // Call Google Gemini API
-func callGoogleGemini(req GradingRequest) (string, error) {
+func callGoogleGemini(req GradingRequest) (string, Usage, error) {
 	systemContent := "You are a teaching assistant helping to grade student assignments. Provide constructive, detailed feedback."
 	if req.SystemPrompt != "" {
 		systemContent = req.SystemPrompt
 	}
+	if strings.TrimSpace(req.SharedContext) != "" {
+		systemContent += "\n\n" + req.SharedContext
+	}
 
 	fullPrompt := systemContent + "\n\n" + req.Prompt
 
@@ -655,13 +861,13 @@ func callGoogleGemini(req GradingRequest) (string, error) {
 
 	bodyBytes, err := json.Marshal(requestBody)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+		return "", Usage{}, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:generateContent?key=%s", model, req.APIKey)
 	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(bodyBytes))
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return "", Usage{}, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
@@ -669,32 +875,39 @@ func callGoogleGemini(req GradingRequest) (string, error) {
 	client := &http.Client{Timeout: 60 * time.Second}
 	resp, err := client.Do(httpReq)
 	if err != nil {
-		return "", fmt.Errorf("request failed: %w", err)
+		return "", Usage{}, fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+		return "", Usage{}, fmt.Errorf("failed to read response: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
 		var errorResp geminiResponse
 		json.Unmarshal(body, &errorResp)
 		if errorResp.Error != nil {
-			return "", fmt.Errorf("Google Gemini API error: %s", errorResp.Error.Message)
+			return "", Usage{}, fmt.Errorf("Google Gemini API error: %s", errorResp.Error.Message)
 		}
-		return "", fmt.Errorf("Google Gemini API error: status %d", resp.StatusCode)
+		return "", Usage{}, fmt.Errorf("Google Gemini API error: status %d", resp.StatusCode)
 	}
 
 	var geminiResp geminiResponse
 	if err := json.Unmarshal(body, &geminiResp); err != nil {
-		return "", fmt.Errorf("failed to parse response: %w", err)
+		return "", Usage{}, fmt.Errorf("failed to parse response: %w", err)
 	}
 
 	if len(geminiResp.Candidates) == 0 || len(geminiResp.Candidates[0].Content.Parts) == 0 {
-		return "", fmt.Errorf("no response from Google Gemini")
+		return "", Usage{}, fmt.Errorf("no response from Google Gemini")
 	}
 
-	return geminiResp.Candidates[0].Content.Parts[0].Text, nil
+	// Thinking tokens are billed as output
+	metadata := geminiResp.UsageMetadata
+	usage := Usage{
+		InputTokens:     metadata.PromptTokenCount - metadata.CachedContentTokenCount,
+		OutputTokens:    metadata.CandidatesTokenCount + metadata.ThoughtsTokenCount,
+		CacheReadTokens: metadata.CachedContentTokenCount,
+	}
+	return geminiResp.Candidates[0].Content.Parts[0].Text, usage, nil
 }
@@ -0,0 +1,393 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Consensus aggregation methods
+const (
+	AggregateMedian = "median"
+	AggregateMean   = "mean"
+)
+
+// Members are flagged when their total scores differ by more than this many points, unless
+// the request sets its own spread
+const defaultMaxSpread = 1.0
+
+// ConsensusConfig asks GenerateFeedback to grade with several provider/model pairs in
+// parallel and aggregate their scores
+type ConsensusConfig struct {
+	Members   []ConsensusMember `json:"members"`
+	Aggregate string            `json:"aggregate"`            // median (default) or mean
+	MaxSpread *float64          `json:"max_spread,omitempty"` // Points, 1 when omitted; wider disagreement is flagged for review
+}
+
+// maxSpread returns the configured spread limit, or the default when it was omitted
+func (c ConsensusConfig) maxSpread() float64 {
+	if c.MaxSpread == nil {
+		return defaultMaxSpread
+	}
+	return *c.MaxSpread
+}
+
+// ConsensusMember is one provider/model pair. An empty API key reuses the request's key when
+// the member is on the request's platform.
+type ConsensusMember struct {
+	Platform  string `json:"platform"`
+	APIKey    string `json:"api_key"`
+	TextModel string `json:"text_model"`
+}
+
+// ConsensusResult reports the aggregated score and each member's vote
+type ConsensusResult struct {
+	Aggregate  string             `json:"aggregate"`
+	Score      *float64           `json:"score,omitempty"`
+	MaxScore   *float64           `json:"max_score,omitempty"`
+	Criteria   map[string]float64 `json:"criteria,omitempty"`
+	Spread     float64            `json:"spread"`
+	Flagged    bool               `json:"flagged"`
+	FlagReason string             `json:"flag_reason,omitempty"`
+	Votes      []ConsensusVote    `json:"votes"`
+}
+
+// ConsensusVote is one member's parsed score, rationale and raw feedback
+type ConsensusVote struct {
+	Platform  string             `json:"platform"`
+	Model     string             `json:"model"`
+	Score     *float64           `json:"score,omitempty"`
+	MaxScore  *float64           `json:"max_score,omitempty"`
+	Criteria  map[string]float64 `json:"criteria,omitempty"`
+	Rationale string             `json:"rationale,omitempty"`
+	Feedback  string             `json:"feedback,omitempty"`
+	Usage     *Usage             `json:"usage,omitempty"`
+	Error     string             `json:"error,omitempty"`
+}
+
+// StructuredScore is the score a model reported in its feedback
+type StructuredScore struct {
+	Score     float64            `json:"score"`
+	MaxScore  *float64           `json:"max_score,omitempty"`
+	Criteria  map[string]float64 `json:"criteria,omitempty"`
+	Rationale string             `json:"rationale,omitempty"`
+}
+
+// ValidateConsensus checks a request's consensus configuration, if any
+func ValidateConsensus(req GradingRequest) error {
+	if req.Consensus == nil {
+		return nil
+	}
+
+	members := req.Consensus.Members
+	if len(members) < 2 || len(members) > 3 {
+		return fmt.Errorf("consensus grading needs two or three members, got %d", len(members))
+	}
+
+	for i, member := range members {
+		if member.Platform == "" {
+			return fmt.Errorf("consensus member %d needs a platform", i)
+		}
+		if member.APIKey == "" && (member.Platform != req.Platform || req.APIKey == "") {
+			return fmt.Errorf("consensus member %d needs an API key for %s", i, member.Platform)
+		}
+	}
+
+	switch req.Consensus.Aggregate {
+	case "", AggregateMedian, AggregateMean:
+	default:
+		return fmt.Errorf("unsupported consensus aggregate: %s", req.Consensus.Aggregate)
+	}
+
+	if req.Consensus.maxSpread() < 0 {
+		return fmt.Errorf("consensus max_spread cannot be negative")
+	}
+
+	return nil
+}
+
+// ProviderCalls reports how many provider calls the request makes
+func (req GradingRequest) ProviderCalls() int {
+	if req.Consensus != nil && len(req.Consensus.Members) > 0 {
+		return len(req.Consensus.Members)
+	}
+	return 1
+}
+
+// generateConsensus sends the request to every member in parallel and aggregates the scores.
+// It fails only when no member returns feedback.
+func generateConsensus(req GradingRequest) (*GradingResponse, error) {
+	if err := ValidateConsensus(req); err != nil {
+		return nil, err
+	}
+
+	config := *req.Consensus
+	if config.Aggregate == "" {
+		config.Aggregate = AggregateMedian
+	}
+
+	votes := make([]ConsensusVote, len(config.Members))
+	var wg sync.WaitGroup
+	for i, member := range config.Members {
+		memberReq := req
+		memberReq.Consensus = nil
+		memberReq.Platform = member.Platform
+		memberReq.TextModel = member.TextModel
+		if member.APIKey != "" {
+			memberReq.APIKey = member.APIKey
+		}
+
+		wg.Add(1)
+		go func(i int, memberReq GradingRequest) {
+			defer wg.Done()
+			votes[i] = castVote(memberReq)
+		}(i, memberReq)
+	}
+	wg.Wait()
+
+	result := aggregateVotes(config, votes)
+
+	var models []string
+	var usage Usage
+	var errs []string
+	for _, vote := range votes {
+		models = append(models, vote.Model)
+		if vote.Usage != nil {
+			usage.InputTokens += vote.Usage.InputTokens
+			usage.OutputTokens += vote.Usage.OutputTokens
+			usage.CacheReadTokens += vote.Usage.CacheReadTokens
+			usage.CacheWriteTokens += vote.Usage.CacheWriteTokens
+			usage.EstimatedCost += vote.Usage.EstimatedCost
+		}
+		if vote.Error != "" {
+			errs = append(errs, fmt.Sprintf("%s: %s", vote.Model, vote.Error))
+		}
+	}
+
+	resp := &GradingResponse{Model: strings.Join(models, "+"), Usage: &usage, Consensus: result}
+	if len(errs) == len(votes) {
+		resp.Error = strings.Join(errs, "; ")
+		return resp, fmt.Errorf("every consensus member failed: %s", resp.Error)
+	}
+
+	// Lead with the feedback of the member closest to the consensus score
+	resp.Feedback = representativeVote(result, votes).Feedback
+	return resp, nil
+}
+
+func castVote(req GradingRequest) ConsensusVote {
+	vote := ConsensusVote{Platform: req.Platform, Model: req.TextModel}
+	if vote.Model == "" {
+		vote.Model = defaultTextModel(req.Platform)
+	}
+
+	resp, err := GenerateFeedback(req)
+	if resp != nil {
+		vote.Usage = resp.Usage
+	}
+	if err != nil {
+		vote.Error = err.Error()
+		return vote
+	}
+
+	vote.Feedback = resp.Feedback
+	if score, ok := ParseStructuredScore(resp.Feedback); ok {
+		vote.Score = &score.Score
+		vote.MaxScore = score.MaxScore
+		vote.Criteria = score.Criteria
+		vote.Rationale = score.Rationale
+	} else {
+		vote.Rationale = strings.TrimSpace(resp.Feedback)
+	}
+	return vote
+}
+
+func aggregateVotes(config ConsensusConfig, votes []ConsensusVote) *ConsensusResult {
+	result := &ConsensusResult{Aggregate: config.Aggregate, Votes: votes}
+	maxSpread := config.maxSpread()
+
+	var scores []float64
+	criterionScores := make(map[string][]float64)
+	for _, vote := range votes {
+		if vote.Score == nil {
+			continue
+		}
+		scores = append(scores, *vote.Score)
+		if vote.MaxScore != nil && result.MaxScore == nil {
+			result.MaxScore = vote.MaxScore
+		}
+		for name, points := range vote.Criteria {
+			criterionScores[name] = append(criterionScores[name], points)
+		}
+	}
+
+	if len(scores) == 0 {
+		result.Flagged = true
+		result.FlagReason = "no model returned a score"
+		return result
+	}
+
+	score := aggregate(config.Aggregate, scores)
+	result.Score = &score
+	result.Spread = spread(scores)
+
+	var reasons []string
+	if len(scores) < 2 {
+		reasons = append(reasons, fmt.Sprintf("only %d of %d models returned a score", len(scores), len(votes)))
+	}
+	if result.Spread > maxSpread {
+		reasons = append(reasons, fmt.Sprintf("scores differ by %g points (limit %g)", result.Spread, maxSpread))
+	}
+
+	if len(criterionScores) > 0 {
+		result.Criteria = make(map[string]float64, len(criterionScores))
+		names := make([]string, 0, len(criterionScores))
+		for name := range criterionScores {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			values := criterionScores[name]
+			result.Criteria[name] = aggregate(config.Aggregate, values)
+			if s := spread(values); s > maxSpread {
+				reasons = append(reasons, fmt.Sprintf("%q differs by %g points", name, s))
+			}
+		}
+	}
+
+	if len(reasons) > 0 {
+		result.Flagged = true
+		result.FlagReason = strings.Join(reasons, "; ")
+	}
+	return result
+}
+
+// representativeVote picks the scored vote nearest the consensus, falling back to the first
+// vote with feedback
+func representativeVote(result *ConsensusResult, votes []ConsensusVote) ConsensusVote {
+	best := -1
+	bestDistance := math.Inf(1)
+	for i, vote := range votes {
+		if vote.Error != "" {
+			continue
+		}
+		if best == -1 {
+			best = i
+		}
+		if result.Score != nil && vote.Score != nil {
+			if distance := math.Abs(*vote.Score - *result.Score); distance < bestDistance {
+				best, bestDistance = i, distance
+			}
+		}
+	}
+	if best == -1 {
+		return ConsensusVote{}
+	}
+	return votes[best]
+}
+
+func aggregate(method string, values []float64) float64 {
+	if method == AggregateMean {
+		var sum float64
+		for _, v := range values {
+			sum += v
+		}
+		return sum / float64(len(values))
+	}
+
+	sorted := append([]float64{}, values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+func spread(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	lowest, highest := values[0], values[0]
+	for _, v := range values[1:] {
+		lowest = math.Min(lowest, v)
+		highest = math.Max(highest, v)
+	}
+	return highest - lowest
+}
+
+var (
+	jsonBlockPattern      = regexp.MustCompile("(?s)```(?:json)?\\s*(\\{.*?\\})\\s*```")
+	suggestedGradePattern = regexp.MustCompile(`(?i)SUGGESTED GRADE:\s*(\d+(?:\.\d+)?)\s*(?:/\s*(\d+(?:\.\d+)?))?`)
+	feedbackPattern       = regexp.MustCompile(`(?is)FEEDBACK:\s*(.*?)\s*(?:SUGGESTED GRADE:|$)`)
+)
+
+// ParseStructuredScore extracts a score from model feedback. It accepts a JSON object with a
+// "score" field, fenced or bare, and falls back to the "SUGGESTED GRADE: X/Y" line the grading
+// prompt asks for.
+func ParseStructuredScore(feedback string) (*StructuredScore, bool) {
+	if score, ok := parseJSONScore(feedback); ok {
+		return score, true
+	}
+
+	match := suggestedGradePattern.FindStringSubmatch(feedback)
+	if match == nil {
+		return nil, false
+	}
+
+	points, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return nil, false
+	}
+	score := &StructuredScore{Score: points}
+	if match[2] != "" {
+		if max, err := strconv.ParseFloat(match[2], 64); err == nil {
+			score.MaxScore = &max
+		}
+	}
+	if rationale := feedbackPattern.FindStringSubmatch(feedback); rationale != nil {
+		score.Rationale = rationale[1]
+	}
+	return score, true
+}
+
+func parseJSONScore(feedback string) (*StructuredScore, bool) {
+	candidates := []string{strings.TrimSpace(feedback)}
+	if match := jsonBlockPattern.FindStringSubmatch(feedback); match != nil {
+		candidates = append([]string{match[1]}, candidates...)
+	}
+	if start, end := strings.Index(feedback, "{"), strings.LastIndex(feedback, "}"); start >= 0 && end > start {
+		candidates = append(candidates, feedback[start:end+1])
+	}
+
+	for _, candidate := range candidates {
+		var raw struct {
+			Score     *float64           `json:"score"`
+			MaxScore  *float64           `json:"max_score"`
+			Criteria  map[string]float64 `json:"criteria"`
+			Rationale string             `json:"rationale"`
+			Feedback  string             `json:"feedback"`
+		}
+		if err := json.Unmarshal([]byte(candidate), &raw); err != nil || raw.Score == nil {
+			continue
+		}
+
+		score := &StructuredScore{
+			Score:     *raw.Score,
+			MaxScore:  raw.MaxScore,
+			Criteria:  raw.Criteria,
+			Rationale: raw.Rationale,
+		}
+		if score.Rationale == "" {
+			score.Rationale = raw.Feedback
+		}
+		return score, true
+	}
+	return nil, false
+}
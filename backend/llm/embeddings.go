@@ -0,0 +1,304 @@
+package llm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// PlatformLocal embeds text on this machine, with no API key or network access
+const PlatformLocal = "local"
+
+// LocalEmbeddingModel names the built-in hashed embedding model
+const LocalEmbeddingModel = "local-hashed-512"
+
+// Dimensions of the local embedding model
+const localDimensions = 512
+
+// Largest number of texts sent to a provider in one embedding call
+const maxEmbeddingBatch = 96
+
+// EmbeddingRequest asks for one vector per text
+type EmbeddingRequest struct {
+	Platform string   `json:"platform"`
+	APIKey   string   `json:"api_key"`
+	Model    string   `json:"model"`
+	Texts    []string `json:"texts"`
+
+	// Context for the backend; never sent to the provider
+	CourseID     string `json:"course_id,omitempty"`
+	AssignmentID string `json:"assignment_id,omitempty"`
+	GraderID     string `json:"-"` // Resolved from the TA's Canvas token
+}
+
+// EmbeddingResponse holds unit-length vectors in the order of the request's texts
+type EmbeddingResponse struct {
+	Vectors [][]float32 `json:"vectors"`
+	Model   string      `json:"model"`
+	Usage   *Usage      `json:"usage,omitempty"`
+}
+
+// DefaultEmbeddingModel is the model used when a request does not name one
+func DefaultEmbeddingModel(platform string) string {
+	switch platform {
+	case "openai":
+		return "text-embedding-3-small"
+	case "google":
+		return "text-embedding-004"
+	case PlatformLocal:
+		return LocalEmbeddingModel
+	}
+	return ""
+}
+
+// Embed routes an embedding request to the provider, or to the local model. Anthropic has
+// no embeddings API, so its users embed locally.
+func Embed(req EmbeddingRequest) (*EmbeddingResponse, error) {
+	if req.Model == "" {
+		req.Model = DefaultEmbeddingModel(req.Platform)
+	}
+
+	resp := &EmbeddingResponse{Vectors: make([][]float32, 0, len(req.Texts)), Model: req.Model, Usage: &Usage{}}
+	for start := 0; start < len(req.Texts); start += maxEmbeddingBatch {
+		batch := req.Texts[start:min(start+maxEmbeddingBatch, len(req.Texts))]
+
+		var vectors [][]float32
+		var usage Usage
+		var err error
+		switch req.Platform {
+		case "openai":
+			vectors, usage, err = embedOpenAI(req.APIKey, req.Model, batch)
+		case "google":
+			vectors, usage, err = embedGemini(req.APIKey, req.Model, batch)
+		case PlatformLocal:
+			if req.Model != LocalEmbeddingModel {
+				return nil, fmt.Errorf("unknown local embedding model: %s", req.Model)
+			}
+			for _, text := range batch {
+				vectors = append(vectors, LocalEmbedding(text))
+			}
+		case "anthropic":
+			return nil, fmt.Errorf("anthropic has no embeddings API; use the %q platform", PlatformLocal)
+		default:
+			return nil, fmt.Errorf("unsupported platform: %s", req.Platform)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(vectors) != len(batch) {
+			return nil, fmt.Errorf("provider returned %d embeddings for %d texts", len(vectors), len(batch))
+		}
+
+		for _, vector := range vectors {
+			resp.Vectors = append(resp.Vectors, normalize(vector))
+		}
+		resp.Usage.InputTokens += usage.InputTokens
+	}
+
+	if req.Platform == PlatformLocal {
+		resp.Usage = nil
+	} else {
+		resp.Usage.EstimatedCost = EstimateCost(req.Model, *resp.Usage)
+	}
+	return resp, nil
+}
+
+func embedOpenAI(apiKey, model string, texts []string) ([][]float32, Usage, error) {
+	bodyBytes, err := json.Marshal(map[string]interface{}{"model": model, "input": texts})
+	if err != nil {
+		return nil, Usage{}, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequest("POST", "https://api.openai.com/v1/embeddings", bytes.NewBuffer(bodyBytes))
+	if err != nil {
+		return nil, Usage{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+
+	body, err := doEmbeddingRequest(httpReq, "OpenAI")
+	if err != nil {
+		return nil, Usage{}, err
+	}
+
+	var parsed struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+		Usage struct {
+			PromptTokens int `json:"prompt_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return nil, Usage{}, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, item := range parsed.Data {
+		if item.Index < 0 || item.Index >= len(vectors) {
+			return nil, Usage{}, fmt.Errorf("OpenAI returned an embedding for unknown input %d", item.Index)
+		}
+		vectors[item.Index] = item.Embedding
+	}
+	return vectors, Usage{InputTokens: parsed.Usage.PromptTokens}, nil
+}
+
+func embedGemini(apiKey, model string, texts []string) ([][]float32, Usage, error) {
+	type part struct {
+		Text string `json:"text"`
+	}
+	type content struct {
+		Parts []part `json:"parts"`
+	}
+	type embedRequest struct {
+		Model   string  `json:"model"`
+		Content content `json:"content"`
+	}
+
+	requests := make([]embedRequest, 0, len(texts))
+	tokens := 0
+	for _, text := range texts {
+		requests = append(requests, embedRequest{Model: "models/" + model, Content: content{Parts: []part{{Text: text}}}})
+		// The batch endpoint reports no usage, so estimate at four characters per token
+		tokens += (len(text) + 3) / 4
+	}
+
+	bodyBytes, err := json.Marshal(map[string]interface{}{"requests": requests})
+	if err != nil {
+		return nil, Usage{}, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:batchEmbedContents?key=%s", model, apiKey)
+	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(bodyBytes))
+	if err != nil {
+		return nil, Usage{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	body, err := doEmbeddingRequest(httpReq, "Google Gemini")
+	if err != nil {
+		return nil, Usage{}, err
+	}
+
+	var parsed struct {
+		Embeddings []struct {
+			Values []float32 `json:"values"`
+		} `json:"embeddings"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return nil, Usage{}, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	vectors := make([][]float32, 0, len(parsed.Embeddings))
+	for _, embedding := range parsed.Embeddings {
+		vectors = append(vectors, embedding.Values)
+	}
+	return vectors, Usage{InputTokens: tokens}, nil
+}
+
+// doEmbeddingRequest sends a provider request and returns the body of a successful response
+func doEmbeddingRequest(httpReq *http.Request, provider string) ([]byte, error) {
+	client := &http.Client{Timeout: 60 * time.Second}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var errorResp struct {
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		json.Unmarshal(body, &errorResp)
+		if errorResp.Error != nil {
+			return nil, fmt.Errorf("%s API error: %s", provider, errorResp.Error.Message)
+		}
+		return nil, fmt.Errorf("%s API error: status %d", provider, resp.StatusCode)
+	}
+	return body, nil
+}
+
+// LocalEmbedding hashes a text's words, word pairs and character trigrams into a fixed
+// number of dimensions and returns a unit-length vector. It captures shared wording rather
+// than meaning, which suits finding feedback on the same mistakes without sending anything
+// to a provider.
+func LocalEmbedding(text string) []float32 {
+	vector := make([]float32, localDimensions)
+	add := func(feature string, weight float32) {
+		h := fnv.New64a()
+		h.Write([]byte(feature))
+		sum := h.Sum64()
+		// The top bit picks a sign so collisions tend to cancel rather than accumulate
+		sign := float32(1)
+		if sum>>63 == 1 {
+			sign = -1
+		}
+		vector[sum%localDimensions] += sign * weight
+	}
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, word := range words {
+		if i > 0 && !(stopWords[words[i-1]] && stopWords[word]) {
+			add("b:"+words[i-1]+" "+word, 0.7)
+		}
+		if stopWords[word] {
+			continue
+		}
+		add("w:"+word, 1)
+		padded := []rune(" " + word + " ")
+		for j := 0; j+3 <= len(padded); j++ {
+			add("c:"+string(padded[j:j+3]), 0.3)
+		}
+	}
+
+	// Damp frequent features so long texts are not dominated by repetition
+	for i, value := range vector {
+		if value != 0 {
+			magnitude := float32(math.Log1p(math.Abs(float64(value))))
+			vector[i] = float32(math.Copysign(float64(magnitude), float64(value)))
+		}
+	}
+	return normalize(vector)
+}
+
+// Common words carry little of what feedback is about, so the local model only uses them in
+// word pairs
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "but": true,
+	"by": true, "for": true, "from": true, "has": true, "have": true, "i": true, "in": true, "is": true,
+	"it": true, "its": true, "of": true, "on": true, "or": true, "so": true, "that": true, "the": true,
+	"this": true, "to": true, "was": true, "were": true, "with": true, "you": true, "your": true,
+}
+
+// normalize scales a vector to unit length so a dot product is its cosine similarity
+func normalize(vector []float32) []float32 {
+	var sum float64
+	for _, value := range vector {
+		sum += float64(value) * float64(value)
+	}
+	if sum == 0 {
+		return vector
+	}
+	scale := float32(1 / math.Sqrt(sum))
+	for i := range vector {
+		vector[i] *= scale
+	}
+	return vector
+}
@@ -0,0 +1,88 @@
+package llm
+
+import (
+	"fmt"
+
+	"auxa/redact"
+)
+
+// Generator produces feedback for a grading request
+type Generator func(GradingRequest) (*GradingResponse, error)
+
+// Middleware wraps a Generator, e.g. to rewrite the request or response around the provider call
+type Middleware func(Generator) Generator
+
+// VisionAnalyzer produces a summary for a vision analysis request
+type VisionAnalyzer func(VisionAnalysisRequest) (*VisionAnalysisResponse, error)
+
+// VisionMiddleware wraps a VisionAnalyzer
+type VisionMiddleware func(VisionAnalyzer) VisionAnalyzer
+
+// Chain wraps generator with middleware; the first middleware is the outermost
+func Chain(generator Generator, middleware ...Middleware) Generator {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		generator = middleware[i](generator)
+	}
+	return generator
+}
+
+// ChainVision wraps analyzer with middleware; the first middleware is the outermost
+func ChainVision(analyzer VisionAnalyzer, middleware ...VisionMiddleware) VisionAnalyzer {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		analyzer = middleware[i](analyzer)
+	}
+	return analyzer
+}
+
+// RedactPII masks personal data in the prompts before the provider call and restores it
+// in the returned feedback
+func RedactPII(redactor *redact.Redactor) Middleware {
+	return func(next Generator) Generator {
+		return func(req GradingRequest) (*GradingResponse, error) {
+			mapping := redact.NewMapping()
+			req.Prompt = redactor.Mask(req.Prompt, mapping)
+			req.SystemPrompt = redactor.Mask(req.SystemPrompt, mapping)
+			req.SharedContext = redactor.Mask(req.SharedContext, mapping)
+			logRedaction("grading", mapping)
+
+			resp, err := next(req)
+			if resp != nil {
+				resp.Feedback = mapping.Unmask(resp.Feedback)
+				resp.Error = mapping.Unmask(resp.Error)
+				if resp.Consensus != nil {
+					for i := range resp.Consensus.Votes {
+						vote := &resp.Consensus.Votes[i]
+						vote.Feedback = mapping.Unmask(vote.Feedback)
+						vote.Rationale = mapping.Unmask(vote.Rationale)
+						vote.Error = mapping.Unmask(vote.Error)
+					}
+				}
+			}
+			return resp, err
+		}
+	}
+}
+
+// RedactVisionPII masks personal data in the vision prompt and restores it in the summary
+func RedactVisionPII(redactor *redact.Redactor) VisionMiddleware {
+	return func(next VisionAnalyzer) VisionAnalyzer {
+		return func(req VisionAnalysisRequest) (*VisionAnalysisResponse, error) {
+			mapping := redact.NewMapping()
+			req.Prompt = redactor.Mask(req.Prompt, mapping)
+			logRedaction("vision", mapping)
+
+			resp, err := next(req)
+			if resp != nil {
+				resp.Summary = mapping.Unmask(resp.Summary)
+				resp.Error = mapping.Unmask(resp.Error)
+			}
+			return resp, err
+		}
+	}
+}
+
+func logRedaction(kind string, mapping *redact.Mapping) {
+	if mapping.Len() > 0 {
+		fmt.Printf("[Redact] %s request masked %v\n", kind, mapping.Summary())
+	}
+}
@@ -0,0 +1,124 @@
+package llm
+
+import (
+	"strings"
+	"sync"
+)
+
+// ModelPrice is the provider list price in USD per million tokens
+type ModelPrice struct {
+	InputPerMillion  float64 `json:"input_per_million"`
+	OutputPerMillion float64 `json:"output_per_million"`
+	// Prompt cache prices; when unset, cached tokens are priced as ordinary input
+	CacheReadPerMillion  float64 `json:"cache_read_per_million,omitempty"`
+	CacheWritePerMillion float64 `json:"cache_write_per_million,omitempty"`
+}
+
+// PriceTable maps model name prefixes to prices. The longest matching prefix wins, so
+// "gpt-4o-mini" is priced separately from "gpt-4o".
+type PriceTable map[string]ModelPrice
+
+// DefaultPrices are list prices at the time of writing; Anthropic cache reads cost 10% of
+// input and five-minute cache writes 125%. Override them with SetPrices when
+// providers change pricing or a department negotiates different rates.
+var DefaultPrices = PriceTable{
+	"gpt-3.5-turbo":     {InputPerMillion: 0.50, OutputPerMillion: 1.50},
+	"gpt-4":             {InputPerMillion: 30, OutputPerMillion: 60},
+	"gpt-4-turbo":       {InputPerMillion: 10, OutputPerMillion: 30},
+	"gpt-4o":            {InputPerMillion: 2.50, OutputPerMillion: 10},
+	"gpt-4o-mini":       {InputPerMillion: 0.15, OutputPerMillion: 0.60},
+	"gpt-4.1":           {InputPerMillion: 2, OutputPerMillion: 8},
+	"gpt-4.1-mini":      {InputPerMillion: 0.40, OutputPerMillion: 1.60},
+	"gpt-4.1-nano":      {InputPerMillion: 0.10, OutputPerMillion: 0.40},
+	"gpt-5":             {InputPerMillion: 1.25, OutputPerMillion: 10},
+	"gpt-5-mini":        {InputPerMillion: 0.25, OutputPerMillion: 2},
+	"gpt-5-nano":        {InputPerMillion: 0.05, OutputPerMillion: 0.40},
+	"o3":                {InputPerMillion: 2, OutputPerMillion: 8},
+	"o4-mini":           {InputPerMillion: 1.10, OutputPerMillion: 4.40},
+	"claude-3-5-haiku":  {InputPerMillion: 0.80, OutputPerMillion: 4, CacheReadPerMillion: 0.08, CacheWritePerMillion: 1},
+	"claude-3-7-sonnet": {InputPerMillion: 3, OutputPerMillion: 15, CacheReadPerMillion: 0.3, CacheWritePerMillion: 3.75},
+	"claude-haiku-4":    {InputPerMillion: 1, OutputPerMillion: 5, CacheReadPerMillion: 0.1, CacheWritePerMillion: 1.25},
+	"claude-sonnet-4":   {InputPerMillion: 3, OutputPerMillion: 15, CacheReadPerMillion: 0.3, CacheWritePerMillion: 3.75},
+	"claude-opus-4":     {InputPerMillion: 15, OutputPerMillion: 75, CacheReadPerMillion: 1.5, CacheWritePerMillion: 18.75},
+	"gemini-2.0-flash":  {InputPerMillion: 0.10, OutputPerMillion: 0.40},
+	"gemini-2.5-flash":  {InputPerMillion: 0.30, OutputPerMillion: 2.50},
+	"gemini-2.5-pro":    {InputPerMillion: 1.25, OutputPerMillion: 10},
+
+	// Embedding models bill input only
+	"text-embedding-3-small": {InputPerMillion: 0.02},
+	"text-embedding-3-large": {InputPerMillion: 0.13},
+	"text-embedding-004":     {InputPerMillion: 0},
+	"gemini-embedding-001":   {InputPerMillion: 0.15},
+}
+
+var (
+	pricesMu sync.RWMutex
+	prices   = DefaultPrices
+)
+
+// SetPrices replaces the default price for each model in overrides
+func SetPrices(overrides PriceTable) {
+	merged := make(PriceTable, len(DefaultPrices)+len(overrides))
+	for model, price := range DefaultPrices {
+		merged[model] = price
+	}
+	for model, price := range overrides {
+		merged[strings.ToLower(model)] = price
+	}
+
+	pricesMu.Lock()
+	prices = merged
+	pricesMu.Unlock()
+}
+
+// Prices returns a copy of the price table in effect
+func Prices() PriceTable {
+	pricesMu.RLock()
+	defer pricesMu.RUnlock()
+
+	table := make(PriceTable, len(prices))
+	for model, price := range prices {
+		table[model] = price
+	}
+	return table
+}
+
+// LookupPrice finds the price for a model by longest prefix match
+func LookupPrice(model string) (ModelPrice, bool) {
+	model = strings.ToLower(strings.TrimSpace(model))
+
+	pricesMu.RLock()
+	defer pricesMu.RUnlock()
+
+	var best string
+	for prefix := range prices {
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(best) {
+			best = prefix
+		}
+	}
+	if best == "" {
+		return ModelPrice{}, false
+	}
+	return prices[best], true
+}
+
+// EstimateCost prices a call's token usage in USD; unknown models cost 0
+func EstimateCost(model string, usage Usage) float64 {
+	price, ok := LookupPrice(model)
+	if !ok {
+		return 0
+	}
+	cacheRead := price.CacheReadPerMillion
+	if cacheRead == 0 {
+		cacheRead = price.InputPerMillion
+	}
+	cacheWrite := price.CacheWritePerMillion
+	if cacheWrite == 0 {
+		cacheWrite = price.InputPerMillion
+	}
+
+	return (float64(usage.InputTokens)*price.InputPerMillion +
+		float64(usage.OutputTokens)*price.OutputPerMillion +
+		float64(usage.CacheReadTokens)*cacheRead +
+		float64(usage.CacheWriteTokens)*cacheWrite) / 1e6
+}
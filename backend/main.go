@@ -1,20 +1,103 @@
 package main
 
 import (
+	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"os"
 	"strings"
 
+	"auxa/anonymize"
+	"auxa/autograder"
 	"auxa/canvas"
+	"auxa/commentbank"
+	"auxa/jobs"
 	"auxa/llm"
+	"auxa/redact"
+	"auxa/rubrics"
+	"auxa/search"
+	"auxa/store"
+	"auxa/templates"
+	"auxa/usage"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
+	// The autograder re-executes the backend to set up its sandbox
+	autograder.RunSandboxHelper()
+
 	gin.SetMode(gin.ReleaseMode)
 
+	var err error
+	pseudonyms, err = anonymize.LoadPseudonymizer(store.Path("pseudonym.key"))
+	if err != nil {
+		log.Fatal("Failed to load pseudonym key:", err)
+	}
+
+	redactionSettings, err = redact.LoadSettings(store.Path("redaction.json"))
+	if err != nil {
+		log.Fatal("Failed to load redaction settings:", err)
+	}
+
+	usageLedger, err = usage.LoadLedger(store.Path("usage.jsonl"))
+	if err != nil {
+		log.Fatal("Failed to load usage ledger:", err)
+	}
+
+	quotas, err = usage.LoadEnforcer(store.Path("limits.json"), usageLedger)
+	if err != nil {
+		log.Fatal("Failed to load spending limits:", err)
+	}
+
+	if err := loadPrices(); err != nil {
+		log.Fatal("Failed to load price table:", err)
+	}
+
+	responseCache = llm.NewCache(store.Path("cache"), responseCacheTTL)
+	if removed, err := responseCache.Purge(); err != nil {
+		log.Println("Failed to purge response cache:", err)
+	} else if removed > 0 {
+		log.Printf("Purged %d expired cached responses", removed)
+	}
+
+	jobManager, err = jobs.LoadManager(store.Path("jobs.json"))
+	if err != nil {
+		log.Fatal("Failed to load jobs:", err)
+	}
+
+	promptTemplates, err = templates.LoadLibrary(store.Path("templates.json"))
+	if err != nil {
+		log.Fatal("Failed to load prompt templates:", err)
+	}
+
+	rubricStore, err = rubrics.LoadStore(store.Path("rubrics.json"))
+	if err != nil {
+		log.Fatal("Failed to load rubrics:", err)
+	}
+
+	autograderSuites, err = autograder.LoadSuiteStore(store.Path("autograder_suites.json"))
+	if err != nil {
+		log.Fatal("Failed to load autograder suites:", err)
+	}
+
+	autograderResults, err = autograder.LoadResultStore(store.Path("autograder_results.json"))
+	if err != nil {
+		log.Fatal("Failed to load autograder results:", err)
+	}
+
+	commentBank, err = commentbank.LoadStore(store.Path("comment_bank.json"))
+	if err != nil {
+		log.Fatal("Failed to load comment bank:", err)
+	}
+
+	feedbackIndex, err = search.LoadIndex(store.Path("feedback_index.json"))
+	if err != nil {
+		log.Fatal("Failed to load feedback index:", err)
+	}
+
 	router := gin.New()
 	router.Use(gin.Recovery())
 
@@ -41,18 +124,169 @@ func main() {
 		api.GET("/courses/:course_id/assignments/:assignment_id/submissions", getAssignmentSubmissions)
 		api.GET("/courses/:course_id/assignments/:assignment_id/ungraded", getUngradedSubmissions)
 		api.GET("/courses/:course_id/enrollments", getCourseEnrollments)
+		api.POST("/courses/:course_id/assignments/:assignment_id/submissions/:user_id/grade", gradeSubmission)
+		api.POST("/courses/:course_id/assignments/:assignment_id/submissions/:user_id/comments", postSubmissionComment)
+
+		// Feedback reports
+		api.GET("/courses/:course_id/assignments/:assignment_id/submissions/:user_id/report", getSubmissionReport)
+		api.POST("/courses/:course_id/assignments/:assignment_id/submissions/:user_id/report/upload", uploadSubmissionReport)
+		api.GET("/courses/:course_id/assignments/:assignment_id/reports", getAssignmentReports)
+		api.GET("/courses/:course_id/assignments/:assignment_id/submissions/download", downloadSubmissions)
+
+		// Offline grading in spreadsheets
+		api.GET("/courses/:course_id/gradebook/export", exportGradebook)
+		api.POST("/courses/:course_id/gradebook/import", importGradebook)
+		api.GET("/progress/:progress_id", getCanvasProgress)
+
+		// Autograder for programming assignments
+		api.GET("/autograder/suites", getAutograderSuites)
+		api.POST("/autograder/suites", createAutograderSuite)
+		api.GET("/autograder/suites/:suite_id", getAutograderSuite)
+		api.PUT("/autograder/suites/:suite_id", updateAutograderSuite)
+		api.DELETE("/autograder/suites/:suite_id", deleteAutograderSuite)
+		api.GET("/courses/:course_id/assignments/:assignment_id/autograde", getAutogradeResults)
+		api.POST("/courses/:course_id/assignments/:assignment_id/autograde", startAutograde)
+		api.POST("/courses/:course_id/assignments/:assignment_id/submissions/:user_id/autograde", autogradeSubmission)
+
+		// Comment bank of reusable snippets
+		api.GET("/comment-bank/snippets", getSnippets)
+		api.POST("/comment-bank/snippets", createSnippet)
+		api.GET("/comment-bank/snippets/:snippet_id", getSnippet)
+		api.PUT("/comment-bank/snippets/:snippet_id", updateSnippet)
+		api.DELETE("/comment-bank/snippets/:snippet_id", deleteSnippet)
+		api.POST("/comment-bank/snippets/:snippet_id/render", renderSnippet)
+		api.POST("/comment-bank/snippets/:snippet_id/use", useSnippet)
+		api.POST("/comment-bank/import/canvas", importCanvasCommentBank)
+		api.GET("/courses/:course_id/assignments/:assignment_id/submissions/:user_id/comment-suggestions", suggestSnippets)
+
+		// Semantic search over past feedback
+		api.GET("/feedback/index", getFeedbackIndex)
+		api.POST("/feedback/index", addFeedbackToIndex)
+		api.DELETE("/feedback/index/:entry_id", deleteFeedbackFromIndex)
+		api.POST("/feedback/search", searchFeedback)
+
+		// Academic integrity
+		api.GET("/courses/:course_id/assignments/:assignment_id/similarity", getSimilarityRuns)
+		api.POST("/courses/:course_id/assignments/:assignment_id/similarity", startSimilarity)
+
+		// Jupyter notebook checks
+		api.POST("/notebooks/analyze", analyzeUploadedNotebook)
+		api.POST("/courses/:course_id/assignments/:assignment_id/submissions/:user_id/notebook", checkSubmissionNotebook)
+
+		// Group assignment routes
+		api.GET("/courses/:course_id/group_categories", getCourseGroupCategories)
+		api.GET("/courses/:course_id/assignments/:assignment_id/groups", getGroupSubmissions)
+		api.POST("/courses/:course_id/assignments/:assignment_id/groups/:group_id/grade", gradeGroupSubmission)
+
+		// Moderated grading routes
+		api.GET("/courses/:course_id/assignments/:assignment_id/provisional_grades", getProvisionalGrades)
+		api.GET("/courses/:course_id/assignments/:assignment_id/provisional_grades/status", getProvisionalGradeStatus)
+		api.POST("/courses/:course_id/assignments/:assignment_id/provisional_grades/publish", publishProvisionalGrades)
+		api.POST("/courses/:course_id/assignments/:assignment_id/provisional_grades/:provisional_grade_id/select", selectProvisionalGrade)
+		api.POST("/courses/:course_id/assignments/:assignment_id/submissions/:user_id/provisional_grade", submitProvisionalGrade)
+		api.POST("/courses/:course_id/assignments/:assignment_id/moderation/compare", compareModeratedGrades)
+
+		// Anonymized grading routes
+		api.GET("/courses/:course_id/assignments/:assignment_id/anonymous/submissions", getAnonymousSubmissions)
+		api.POST("/courses/:course_id/assignments/:assignment_id/anonymous/:pseudonym/grade", gradeAnonymousSubmission)
+
+		api.GET("/courses/:course_id/redaction", getCourseRedaction)
+		api.PUT("/courses/:course_id/redaction", updateCourseRedaction)
+		api.DELETE("/courses/:course_id/redaction", resetCourseRedaction)
+
+		// Calibration of the AI grader against human grades
+		api.GET("/courses/:course_id/assignments/:assignment_id/calibration", getCalibrations)
+		api.POST("/courses/:course_id/assignments/:assignment_id/calibration", startCalibration)
+
+		api.GET("/courses/:course_id/assignments/:assignment_id/exemplars", getExemplars)
+		api.GET("/courses/:course_id/assignments/:assignment_id/submissions/:user_id/diff", getSubmissionDiff)
+
+		// Shared rubric store
+		api.GET("/rubrics", getRubrics)
+		api.POST("/rubrics", createRubric)
+		api.POST("/rubrics/csv", importRubricCSV)
+		api.GET("/rubrics/:rubric_id", getRubric)
+		api.PUT("/rubrics/:rubric_id", updateRubric)
+		api.DELETE("/rubrics/:rubric_id", deleteRubric)
+		api.GET("/rubrics/:rubric_id/csv", exportRubricCSV)
+		api.PUT("/rubrics/:rubric_id/csv", replaceRubricCSV)
+		api.POST("/rubrics/:rubric_id/export", exportRubricToCanvas)
+		api.POST("/courses/:course_id/rubrics/import", importCanvasRubrics)
+		api.POST("/courses/:course_id/assignments/:assignment_id/rubrics/generate", generateRubric)
+
+		// Prompt template library
+		api.GET("/templates", getTemplates)
+		api.POST("/templates", createTemplate)
+		api.POST("/templates/preview", previewTemplate)
+		api.GET("/templates/:template_id", getTemplate)
+		api.PUT("/templates/:template_id", updateTemplate)
+		api.DELETE("/templates/:template_id", deleteTemplate)
+		api.GET("/templates/:template_id/versions", getTemplateVersions)
 
 		// LLM API routes
 		api.POST("/llm/generate-feedback", generateAIFeedback)
 		api.POST("/llm/analyze-image", analyzeImageVisual)
+		api.GET("/llm/batches", getGradingBatches)
+		api.POST("/llm/batches", createGradingBatch)
+		api.POST("/llm/batches/:job_id/resume", resumeGradingBatch)
+
+		// Background job routes
+		api.GET("/jobs", getJobs)
+		api.GET("/jobs/:job_id", getJob)
+
+		// Usage and cost accounting routes
+		api.GET("/usage", getUsageSummary)
+		api.GET("/usage/prices", getPrices)
+		api.PUT("/usage/prices", updatePrices)
+		api.GET("/usage/limits", getUsageLimits)
+		api.PUT("/usage/limits", updateUsageLimits)
 	}
 
 	log.Println(`Starting backend server with "go run main.go"`)
-	if err := router.Run(":3000"); err != nil {
+	if err := serve(router, listenAddresses()); err != nil {
 		log.Fatal("Failed to start server:", err)
 	}
 }
 
+// listenAddresses returns the addresses to serve on. Only local clients may connect by
+// default, since the API runs commands and holds student data; both loopback addresses are
+// used so that localhost works whichever one it resolves to.
+func listenAddresses() []string {
+	if value := os.Getenv(listenAddrEnv); value != "" {
+		return strings.Split(value, ",")
+	}
+	return []string{"127.0.0.1:3000", "[::1]:3000"}
+}
+
+// listenAddrEnv overrides the listen addresses with a comma-separated list
+const listenAddrEnv = "AUXA_LISTEN_ADDR"
+
+// serve runs the router on every address that can be bound and fails only when none can,
+// e.g. so a machine without IPv6 still serves on 127.0.0.1
+func serve(handler http.Handler, addresses []string) error {
+	var listeners []net.Listener
+	for _, address := range addresses {
+		listener, err := net.Listen("tcp", strings.TrimSpace(address))
+		if err != nil {
+			fmt.Printf("[Server] Not listening on %s: %v\n", address, err)
+			continue
+		}
+		fmt.Printf("[Server] Listening on %s\n", listener.Addr())
+		listeners = append(listeners, listener)
+	}
+	if len(listeners) == 0 {
+		return fmt.Errorf("no address in %v could be bound", addresses)
+	}
+
+	errs := make(chan error, len(listeners))
+	for _, listener := range listeners {
+		go func(listener net.Listener) {
+			errs <- http.Serve(listener, handler)
+		}(listener)
+	}
+	return <-errs
+}
+
 // Connect and validate Canvas credentials
 func connectToCanvas(c *gin.Context) {
 	var req struct {
@@ -185,18 +419,49 @@ func generateAIFeedback(c *gin.Context) {
 		return
 	}
 
+	if !applyTemplates(c, &req) {
+		return
+	}
+
 	if req.Prompt == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Prompt is required"})
 		return
 	}
 
+	if err := llm.ValidateConsensus(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !applyExemplars(c, &req, req.UserID) {
+		return
+	}
+
+	if !applyResubmission(c, &req) {
+		return
+	}
+
+	if !applyAutograder(c, &req) {
+		return
+	}
+
+	if !applyNotebook(c, &req) {
+		return
+	}
+
+	if req.Anonymize && !scrubForProvider(c, req.CourseID, &req.Prompt, &req.SystemPrompt, &req.SharedContext) {
+		return
+	}
+
+	generate, ok := feedbackGenerator(c, req.CourseID)
+	if !ok {
+		return
+	}
+
 	// Generate feedback
-	response, err := llm.GenerateFeedback(req)
+	response, err := generate(req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":    err.Error(),
-			"feedback": "",
-		})
+		writeGenerationError(c, err, "feedback")
 		return
 	}
 
@@ -227,12 +492,18 @@ func analyzeImageVisual(c *gin.Context) {
 		return
 	}
 
-	response, err := llm.AnalyzeImage(req)
+	if req.Anonymize && !scrubForProvider(c, req.CourseID, &req.Prompt) {
+		return
+	}
+
+	analyze, ok := visionAnalyzer(c, req.CourseID)
+	if !ok {
+		return
+	}
+
+	response, err := analyze(req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   err.Error(),
-			"summary": "",
-		})
+		writeGenerationError(c, err, "summary")
 		return
 	}
 
@@ -259,3 +530,45 @@ func getCourseEnrollments(c *gin.Context) {
 
 	c.JSON(http.StatusOK, enrollments)
 }
+
+// canvasClientFromRequest builds a Canvas client from the request's credential headers,
+// responding with 400 when they are missing
+func canvasClientFromRequest(c *gin.Context) (*canvas.Client, bool) {
+	token := c.GetHeader("Authorization")
+	schoolURL := c.GetHeader("X-School-URL")
+
+	if token == "" || schoolURL == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing credentials"})
+		return nil, false
+	}
+
+	return canvas.NewClient(token, schoolURL), true
+}
+
+// Post a grade and optional comment for a single student
+func gradeSubmission(c *gin.Context) {
+	courseID := c.Param("course_id")
+	assignmentID := c.Param("assignment_id")
+	userID := c.Param("user_id")
+
+	var req canvas.SubmissionGrade
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	client, ok := canvasClientFromRequest(c)
+	if !ok {
+		return
+	}
+
+	submission, err := client.GradeSubmission(courseID, assignmentID, userID, req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	indexFeedback(client, search.Entry{Kind: search.KindComment, CourseID: courseID, AssignmentID: assignmentID, UserID: submission.UserID, Text: req.TextComment})
+
+	c.JSON(http.StatusOK, submission)
+}
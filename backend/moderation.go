@@ -0,0 +1,182 @@
+package main
+
+import (
+	"net/http"
+
+	"auxa/canvas"
+	"auxa/moderation"
+	"auxa/search"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Default disagreement threshold as a fraction of points possible
+const defaultModerationThresholdRatio = 0.1
+
+// List gradeable students with every grader's provisional grade
+func getProvisionalGrades(c *gin.Context) {
+	courseID := c.Param("course_id")
+	assignmentID := c.Param("assignment_id")
+
+	client, ok := canvasClientFromRequest(c)
+	if !ok {
+		return
+	}
+
+	students, err := client.ListProvisionalGrades(courseID, assignmentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, students)
+}
+
+// Check whether a student still has an open grader slot
+func getProvisionalGradeStatus(c *gin.Context) {
+	courseID := c.Param("course_id")
+	assignmentID := c.Param("assignment_id")
+	studentID := c.Query("student_id")
+
+	if studentID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "student_id is required"})
+		return
+	}
+
+	client, ok := canvasClientFromRequest(c)
+	if !ok {
+		return
+	}
+
+	status, err := client.GetProvisionalGradeStatus(courseID, assignmentID, studentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// Submit a provisional grade with optional rubric assessment
+func submitProvisionalGrade(c *gin.Context) {
+	courseID := c.Param("course_id")
+	assignmentID := c.Param("assignment_id")
+	userID := c.Param("user_id")
+
+	var req canvas.ProvisionalGradeInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	client, ok := canvasClientFromRequest(c)
+	if !ok {
+		return
+	}
+
+	status, err := client.GetProvisionalGradeStatus(courseID, assignmentID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !status.NeedsProvisionalGrade {
+		c.JSON(http.StatusConflict, gin.H{"error": "No grader slot is available for this student"})
+		return
+	}
+
+	submission, err := client.SubmitProvisionalGrade(courseID, assignmentID, userID, req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	indexFeedback(client, append(criterionEntries(courseID, assignmentID, submission.UserID, req.RubricAssessment),
+		search.Entry{Kind: search.KindComment, CourseID: courseID, AssignmentID: assignmentID, UserID: submission.UserID, Text: req.TextComment})...)
+
+	c.JSON(http.StatusOK, submission)
+}
+
+// Select a grader's provisional grade as the final grade
+func selectProvisionalGrade(c *gin.Context) {
+	courseID := c.Param("course_id")
+	assignmentID := c.Param("assignment_id")
+	provisionalGradeID := c.Param("provisional_grade_id")
+
+	client, ok := canvasClientFromRequest(c)
+	if !ok {
+		return
+	}
+
+	if err := client.SelectProvisionalGrade(courseID, assignmentID, provisionalGradeID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Provisional grade selected"})
+}
+
+// Publish the selected provisional grades
+func publishProvisionalGrades(c *gin.Context) {
+	courseID := c.Param("course_id")
+	assignmentID := c.Param("assignment_id")
+
+	client, ok := canvasClientFromRequest(c)
+	if !ok {
+		return
+	}
+
+	if err := client.PublishProvisionalGrades(courseID, assignmentID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Provisional grades published"})
+}
+
+// Compare each grader's provisional score against the AI draft for moderators
+func compareModeratedGrades(c *gin.Context) {
+	courseID := c.Param("course_id")
+	assignmentID := c.Param("assignment_id")
+
+	var req struct {
+		AIDrafts  []moderation.AIDraft `json:"ai_drafts"`
+		Threshold *float64             `json:"threshold"` // Points; 0 flags any difference, omitted uses a tenth of the points
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Threshold != nil && *req.Threshold < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "threshold cannot be negative"})
+		return
+	}
+
+	client, ok := canvasClientFromRequest(c)
+	if !ok {
+		return
+	}
+
+	var threshold float64
+	if req.Threshold != nil {
+		threshold = *req.Threshold
+	} else {
+		assignment, err := client.GetAssignment(courseID, assignmentID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		threshold = assignment.PointsPossible * defaultModerationThresholdRatio
+	}
+
+	students, err := client.ListProvisionalGrades(courseID, assignmentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"threshold":   threshold,
+		"comparisons": moderation.Compare(students, req.AIDrafts, threshold),
+	})
+}
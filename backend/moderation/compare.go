@@ -0,0 +1,95 @@
+package moderation
+
+import (
+	"math"
+
+	"auxa/canvas"
+)
+
+// AIDraft is the score the AI grader drafted for a student
+type AIDraft struct {
+	UserID int     `json:"user_id"`
+	Score  float64 `json:"score"`
+}
+
+// GraderScore lines up one grader's provisional score against the AI draft
+type GraderScore struct {
+	ProvisionalGradeID int      `json:"provisional_grade_id"`
+	ScorerID           int      `json:"scorer_id"`
+	Score              *float64 `json:"score"`
+	Grade              string   `json:"grade"`
+	Selected           bool     `json:"selected"`
+	DeltaFromAI        *float64 `json:"delta_from_ai,omitempty"` // Grader score minus AI score
+	Disagrees          bool     `json:"disagrees"`
+}
+
+// Comparison is one student's row in the moderator comparison view
+type Comparison struct {
+	StudentID    int           `json:"student_id"`
+	DisplayName  string        `json:"display_name"`
+	AIScore      *float64      `json:"ai_score,omitempty"`
+	Graders      []GraderScore `json:"graders"`
+	GraderSpread float64       `json:"grader_spread"` // Max minus min of the graders' scores
+	MaxDeltaAI   float64       `json:"max_delta_from_ai"`
+	Flagged      bool          `json:"flagged"`
+}
+
+// Compare builds the moderator comparison view. A grader disagrees with the AI draft when
+// their scores differ by more than threshold; graders disagreeing with each other by more
+// than threshold also flag the student.
+func Compare(students []canvas.GradeableStudent, drafts []AIDraft, threshold float64) []Comparison {
+	draftByUser := make(map[int]float64, len(drafts))
+	for _, draft := range drafts {
+		draftByUser[draft.UserID] = draft.Score
+	}
+
+	// Initialize as empty slice to ensure JSON returns [] instead of null
+	rows := make([]Comparison, 0, len(students))
+	for _, student := range students {
+		row := Comparison{
+			StudentID:   student.ID,
+			DisplayName: student.DisplayName,
+			Graders:     make([]GraderScore, 0, len(student.ProvisionalGrades)),
+		}
+
+		if score, ok := draftByUser[student.ID]; ok {
+			aiScore := score
+			row.AIScore = &aiScore
+		}
+
+		minScore, maxScore := math.Inf(1), math.Inf(-1)
+		for _, provisional := range student.ProvisionalGrades {
+			grader := GraderScore{
+				ProvisionalGradeID: provisional.ProvisionalGradeID,
+				ScorerID:           provisional.ScorerID,
+				Score:              provisional.Score,
+				Grade:              provisional.Grade,
+				Selected:           student.SelectedProvisionalGradeID != nil && *student.SelectedProvisionalGradeID == provisional.ProvisionalGradeID,
+			}
+
+			if provisional.Score != nil {
+				minScore = math.Min(minScore, *provisional.Score)
+				maxScore = math.Max(maxScore, *provisional.Score)
+
+				if row.AIScore != nil {
+					delta := *provisional.Score - *row.AIScore
+					grader.DeltaFromAI = &delta
+					grader.Disagrees = math.Abs(delta) > threshold
+					row.MaxDeltaAI = math.Max(row.MaxDeltaAI, math.Abs(delta))
+					row.Flagged = row.Flagged || grader.Disagrees
+				}
+			}
+
+			row.Graders = append(row.Graders, grader)
+		}
+
+		if maxScore >= minScore {
+			row.GraderSpread = maxScore - minScore
+			row.Flagged = row.Flagged || row.GraderSpread > threshold
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows
+}
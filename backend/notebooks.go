@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"auxa/canvas"
+	"auxa/llm"
+	"auxa/notebooks"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Size limits for notebooks and the data files re-executed alongside them
+const (
+	maxNotebookBytes     = 20 * 1024 * 1024
+	maxNotebookDataBytes = 50 * 1024 * 1024
+)
+
+// analyzeNotebook parses a notebook and, when execute is set, re-runs it with the given data
+// files. A notebook the sandbox cannot run is still checked against its saved outputs.
+func analyzeNotebook(ctx context.Context, data []byte, files map[string][]byte, execute bool, cellTimeout int) (notebooks.Report, error) {
+	nb, err := notebooks.Parse(data)
+	if err != nil {
+		return notebooks.Report{}, err
+	}
+	if !execute {
+		return notebooks.Check(nb, nil), nil
+	}
+
+	executions, step, err := notebooks.Execute(ctx, nb, notebooks.Options{CellTimeoutSeconds: cellTimeout, Files: files})
+	if errors.Is(err, notebooks.ErrUnsupportedKernel) {
+		report := notebooks.Check(nb, nil)
+		report.Warnings = append(report.Warnings, fmt.Sprintf("Not re-executed: %v (kernel language %q)", err, nb.Language))
+		return report, nil
+	}
+	if err != nil {
+		return notebooks.Report{}, fmt.Errorf("failed to execute notebook: %w", err)
+	}
+
+	report := notebooks.Check(nb, executions)
+	report.Execution = &step
+	if step.TimedOut {
+		report.Warnings = append(report.Warnings, "The notebook ran past its time limit; cells after the last result were not run")
+	} else if len(executions) == 0 && report.CodeCells > 0 {
+		report.Warnings = append(report.Warnings, "The notebook runner failed before any cell ran: "+strings.TrimSpace(step.Stderr))
+	}
+	return report, nil
+}
+
+// submissionNotebook downloads a submission's notebook and its other attachments as data
+// files. attachmentID of 0 picks the only .ipynb attachment.
+func submissionNotebook(client *canvas.Client, submission canvas.Submission, attachmentID int) ([]byte, map[string][]byte, error) {
+	var notebook *canvas.Attachment
+	var candidates []string
+	for i, attachment := range submission.Attachments {
+		name := attachmentName(attachment)
+		isNotebook := strings.EqualFold(path.Ext(name), ".ipynb")
+		if isNotebook {
+			candidates = append(candidates, name)
+		}
+		if (attachmentID != 0 && attachment.ID == attachmentID) || (attachmentID == 0 && isNotebook && notebook == nil) {
+			notebook = &submission.Attachments[i]
+		}
+	}
+	switch {
+	case notebook == nil && attachmentID != 0:
+		return nil, nil, fmt.Errorf("attachment %d is not part of the submission", attachmentID)
+	case notebook == nil:
+		return nil, nil, errors.New("submission has no .ipynb file")
+	case attachmentID == 0 && len(candidates) > 1:
+		return nil, nil, fmt.Errorf("submission has several notebooks (%s); choose one with attachment_id", strings.Join(candidates, ", "))
+	}
+
+	data, err := client.DownloadFile(notebook.URL, maxNotebookBytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to download %s: %w", attachmentName(*notebook), err)
+	}
+
+	files := map[string][]byte{}
+	for _, attachment := range submission.Attachments {
+		if attachment.ID == notebook.ID || attachment.Size > maxNotebookDataBytes {
+			continue
+		}
+		content, err := client.DownloadFile(attachment.URL, maxNotebookDataBytes)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to download %s: %w", attachmentName(attachment), err)
+		}
+		files[attachmentName(attachment)] = content
+	}
+	return data, files, nil
+}
+
+func attachmentName(attachment canvas.Attachment) string {
+	if attachment.Filename != "" {
+		return attachment.Filename
+	}
+	return attachment.DisplayName
+}
+
+// Analyse an uploaded notebook. The multipart form holds the notebook, any data files it
+// reads, execute and cell_timeout_seconds.
+func analyzeUploadedNotebook(c *gin.Context) {
+	form, err := c.MultipartForm()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Expected a multipart form: " + err.Error()})
+		return
+	}
+
+	headers := form.File["notebook"]
+	if len(headers) != 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Exactly one notebook file is required"})
+		return
+	}
+	if headers[0].Size > maxNotebookBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Notebook exceeds %d MB", maxNotebookBytes/(1024*1024))})
+		return
+	}
+
+	cellTimeout := 0
+	if raw := c.PostForm("cell_timeout_seconds"); raw != "" {
+		if cellTimeout, err = strconv.Atoi(raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "cell_timeout_seconds must be a number"})
+			return
+		}
+	}
+
+	data, err := readFormFile(headers[0])
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	files := map[string][]byte{}
+	for _, header := range form.File["files"] {
+		if header.Size > maxNotebookDataBytes {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("%s exceeds %d MB", header.Filename, maxNotebookDataBytes/(1024*1024))})
+			return
+		}
+		if files[header.Filename], err = readFormFile(header); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	report, err := analyzeNotebook(c.Request.Context(), data, files, c.PostForm("execute") == "true", cellTimeout)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"report":  report,
+		"summary": notebooks.Summary(report),
+	})
+}
+
+func readFormFile(header *multipart.FileHeader) ([]byte, error) {
+	f, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// Analyse a student's submitted notebook, re-running it when execute is set
+func checkSubmissionNotebook(c *gin.Context) {
+	courseID := c.Param("course_id")
+	assignmentID := c.Param("assignment_id")
+
+	userID, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id must be a number"})
+		return
+	}
+
+	var req struct {
+		AttachmentID       int  `json:"attachment_id"`
+		Execute            bool `json:"execute"`
+		CellTimeoutSeconds int  `json:"cell_timeout_seconds"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	client, ok := canvasClientFromRequest(c)
+	if !ok {
+		return
+	}
+
+	submission, err := findSubmission(client, courseID, assignmentID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if submission == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Submission not found"})
+		return
+	}
+
+	data, files, err := submissionNotebook(client, *submission, req.AttachmentID)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := analyzeNotebook(c.Request.Context(), data, files, req.Execute, req.CellTimeoutSeconds)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"report":  report,
+		"summary": notebooks.Summary(report),
+	})
+}
+
+// applyNotebook adds the cell-by-cell analysis of the student's notebook to the request's
+// prompt. It writes the error response and returns false when the notebook can't be checked.
+func applyNotebook(c *gin.Context, req *llm.GradingRequest) bool {
+	if req.Notebook == nil {
+		return true
+	}
+
+	if req.CourseID == "" || req.AssignmentID == "" || req.Notebook.UserID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "course_id, assignment_id and notebook.user_id are required for notebook-checked feedback"})
+		return false
+	}
+
+	client, ok := canvasClientFromRequest(c)
+	if !ok {
+		return false
+	}
+
+	submission, err := findSubmission(client, req.CourseID, req.AssignmentID, req.Notebook.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	if submission == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Submission not found"})
+		return false
+	}
+
+	data, files, err := submissionNotebook(client, *submission, req.Notebook.AttachmentID)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return false
+	}
+
+	report, err := analyzeNotebook(c.Request.Context(), data, files, req.Notebook.Execute, 0)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Notebook could not be checked: " + err.Error()})
+		return false
+	}
+
+	req.Prompt = req.Prompt + "\n\n" + notebooks.Summary(report)
+	req.Notebook = nil
+	return true
+}
@@ -0,0 +1,230 @@
+package notebooks
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"auxa/autograder"
+)
+
+// Cell statuses
+const (
+	StatusOK         = "ok"         // Output matches, or the cell was not re-run and shows no problem
+	StatusMismatch   = "mismatch"   // Re-running produced different output
+	StatusError      = "error"      // The cell raised an exception
+	StatusTimedOut   = "timed_out"  // The cell ran past its timeout when re-run
+	StatusNotRun     = "not_run"    // The student never ran the cell
+	StatusMissing    = "missing"    // Re-running produced output the notebook does not contain
+	StatusUnverified = "unverified" // The output has no text form to compare, such as a plot
+	StatusSkipped    = "skipped"    // Markdown, raw and empty cells
+)
+
+// Longest output kept in a check; longer outputs are cut
+const maxOutputBytes = 2000
+
+// CellCheck is the verdict for one cell
+type CellCheck struct {
+	Index          int      `json:"index"`
+	Type           string   `json:"type"`
+	Status         string   `json:"status"`
+	Preview        string   `json:"preview"` // First line of the source
+	ExecutionCount *int     `json:"execution_count,omitempty"`
+	Expected       string   `json:"expected,omitempty"` // Output saved in the notebook
+	Actual         string   `json:"actual,omitempty"`   // Output when re-run
+	Detail         string   `json:"detail,omitempty"`
+	SkippedLines   []string `json:"skipped_lines,omitempty"`
+}
+
+// Report is the cell-by-cell analysis of a notebook
+type Report struct {
+	Language      string                 `json:"language"`
+	CodeCells     int                    `json:"code_cells"`
+	MarkdownCells int                    `json:"markdown_cells"`
+	Executed      bool                   `json:"executed"`
+	Execution     *autograder.StepResult `json:"execution,omitempty"`
+	Counts        map[string]int         `json:"counts"`
+	Cells         []CellCheck            `json:"cells"`
+	Warnings      []string               `json:"warnings"`
+}
+
+// Flagged reports whether any cell needs the grader's attention
+func (r Report) Flagged() bool {
+	return r.Counts[StatusMismatch]+r.Counts[StatusError]+r.Counts[StatusTimedOut]+r.Counts[StatusNotRun]+r.Counts[StatusMissing] > 0
+}
+
+// Check compares each code cell's saved outputs with its re-executed outputs. With no
+// executions, only the saved state of the notebook is checked.
+func Check(nb *Notebook, executions map[int]Execution) Report {
+	report := Report{
+		Language: nb.Language,
+		Executed: executions != nil,
+		Counts:   map[string]int{},
+		// Initialize as empty slice to ensure JSON returns [] instead of null
+		Cells:    make([]CellCheck, 0, len(nb.Cells)),
+		Warnings: []string{},
+	}
+
+	lastCount := 0
+	outOfOrder := false
+	for _, cell := range nb.Cells {
+		check := CellCheck{Index: cell.Index, Type: cell.Type, Preview: preview(cell.Source), ExecutionCount: cell.ExecutionCount}
+
+		switch {
+		case cell.Type == CellMarkdown:
+			report.MarkdownCells++
+			check.Status = StatusSkipped
+		case cell.Type != CellCode || strings.TrimSpace(cell.Source) == "":
+			check.Status = StatusSkipped
+		default:
+			report.CodeCells++
+			if cell.ExecutionCount != nil {
+				if *cell.ExecutionCount <= lastCount {
+					outOfOrder = true
+				}
+				lastCount = *cell.ExecutionCount
+			}
+			execution, ran := executions[cell.Index]
+			if report.Executed && !ran {
+				check.Status = StatusNotRun
+				check.Detail = "Not reached when the notebook was re-run"
+			} else if report.Executed {
+				compareCell(&check, cell, execution)
+			} else {
+				checkSaved(&check, cell)
+			}
+		}
+
+		report.Counts[check.Status]++
+		report.Cells = append(report.Cells, check)
+	}
+
+	if outOfOrder {
+		report.Warnings = append(report.Warnings, "Execution counts are out of order, so cells were run out of sequence or re-run; saved outputs may not match a clean run")
+	}
+	return report
+}
+
+// checkSaved judges a cell from the notebook alone
+func checkSaved(check *CellCheck, cell Cell) {
+	check.Expected = clip(cell.storedText())
+	if out, failed := cell.storedError(); failed {
+		check.Status = StatusError
+		check.Detail = out.EName + ": " + out.EValue
+		return
+	}
+	if cell.ExecutionCount == nil && len(cell.Outputs) == 0 {
+		check.Status = StatusNotRun
+		check.Detail = "The cell was never run"
+		return
+	}
+	check.Status = StatusOK
+}
+
+// compareCell judges a cell by re-running it
+func compareCell(check *CellCheck, cell Cell, execution Execution) {
+	expected := cell.storedText()
+	actual := execution.Text()
+	check.Expected = clip(expected)
+	check.Actual = clip(actual)
+	check.SkippedLines = execution.SkippedLines
+
+	switch {
+	case execution.TimedOut:
+		check.Status = StatusTimedOut
+		check.Detail = "The cell ran out of time"
+		if execution.Error != nil {
+			check.Detail = execution.Error.EValue
+		}
+	case execution.Error != nil:
+		check.Status = StatusError
+		check.Detail = execution.Error.EName + ": " + execution.Error.EValue
+		if out, failed := cell.storedError(); failed {
+			check.Detail += " (the submitted notebook shows " + out.EName + " as well)"
+		}
+	case cell.ExecutionCount == nil && len(cell.Outputs) == 0:
+		check.Status = StatusNotRun
+		check.Detail = "The student never ran the cell"
+		if strings.TrimSpace(actual) != "" {
+			check.Status = StatusMissing
+			check.Detail = "The student never ran the cell; it produces output when run"
+		}
+	case strings.TrimSpace(expected) == "" && cell.hasRichOutput():
+		check.Status = StatusUnverified
+		check.Detail = "Saved output is graphical and cannot be compared"
+	case outputsMatch(expected, actual):
+		check.Status = StatusOK
+		if _, failed := cell.storedError(); failed {
+			check.Detail = "The submitted notebook shows an error but the cell runs cleanly"
+		}
+	case strings.TrimSpace(expected) == "":
+		check.Status = StatusMissing
+		check.Detail = "The cell produces output that is not in the submitted notebook"
+	default:
+		check.Status = StatusMismatch
+		check.Detail = "Re-running the cell produced different output"
+	}
+	if len(execution.SkippedLines) > 0 && check.Status != StatusOK {
+		check.Detail += fmt.Sprintf("; %d magic or shell line(s) were skipped", len(execution.SkippedLines))
+	}
+}
+
+var (
+	addressPattern = regexp.MustCompile(`0x[0-9a-fA-F]{6,}`)
+	spacePattern   = regexp.MustCompile(`[ \t]+`)
+	numberPattern  = regexp.MustCompile(`-?\d+\.\d+(?:[eE][-+]?\d+)?`)
+)
+
+// normalizeOutput removes differences that do not reflect the code: memory addresses,
+// runs of spaces, trailing whitespace and line endings
+func normalizeOutput(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = addressPattern.ReplaceAllString(text, "0x…")
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(spacePattern.ReplaceAllString(line, " "))
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// outputsMatch compares normalised outputs, allowing decimals to differ by a relative
+// tolerance so floating-point noise is not flagged
+func outputsMatch(expected, actual string) bool {
+	a, b := normalizeOutput(expected), normalizeOutput(actual)
+	if a == b {
+		return true
+	}
+	if numberPattern.ReplaceAllString(a, "#") != numberPattern.ReplaceAllString(b, "#") {
+		return false
+	}
+
+	x, y := numberPattern.FindAllString(a, -1), numberPattern.FindAllString(b, -1)
+	for i := range x {
+		p, errP := strconv.ParseFloat(x[i], 64)
+		q, errQ := strconv.ParseFloat(y[i], 64)
+		if errP != nil || errQ != nil {
+			return false
+		}
+		if math.Abs(p-q) > 1e-6*math.Max(1, math.Max(math.Abs(p), math.Abs(q))) {
+			return false
+		}
+	}
+	return true
+}
+
+func preview(source string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(source), "\n")
+	if len(line) > 80 {
+		line = line[:77] + "..."
+	}
+	return line
+}
+
+func clip(text string) string {
+	if len(text) > maxOutputBytes {
+		return text[:maxOutputBytes] + "\n... (truncated)"
+	}
+	return text
+}
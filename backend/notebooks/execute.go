@@ -0,0 +1,210 @@
+package notebooks
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+
+	"auxa/autograder"
+)
+
+// Defaults for re-execution
+const (
+	DefaultCellTimeoutSeconds = 30
+	DefaultTimeSeconds        = 300
+)
+
+// ErrUnsupportedKernel is returned for notebooks that are not written in Python
+var ErrUnsupportedKernel = errors.New("only Python notebooks can be re-executed")
+
+// Options control re-execution
+type Options struct {
+	CellTimeoutSeconds int               `json:"cell_timeout_seconds"`
+	TimeSeconds        int               `json:"time_seconds"` // For the whole notebook
+	MemoryMB           int               `json:"memory_mb"`
+	Files              map[string][]byte `json:"-"` // Data files the notebook reads, by relative path
+}
+
+// Execution is what one code cell produced when re-run
+type Execution struct {
+	Index        int      `json:"index"`
+	Stdout       string   `json:"stdout"`
+	Stderr       string   `json:"stderr"`
+	Result       *string  `json:"result"` // Representation of the cell's final expression
+	Error        *Error   `json:"error"`
+	TimedOut     bool     `json:"timed_out"`
+	Duration     float64  `json:"duration"`
+	SkippedLines []string `json:"skipped_lines"` // IPython magics and shell escapes that were not run
+}
+
+// Error is an exception raised by a cell
+type Error struct {
+	EName     string `json:"ename"`
+	EValue    string `json:"evalue"`
+	Traceback string `json:"traceback,omitempty"`
+}
+
+// Text is what the cell printed to standard output followed by its result
+func (e Execution) Text() string {
+	text := e.Stdout
+	if e.Result != nil {
+		text += *e.Result + "\n"
+	}
+	return text
+}
+
+// Execute runs the notebook's code cells in order in one Python process inside the
+// autograder sandbox, so they share state as in a kernel. Cells keep running after an error
+// so later cells can still be checked. Results are keyed by cell index.
+func Execute(ctx context.Context, nb *Notebook, opts Options) (map[int]Execution, autograder.StepResult, error) {
+	if !nb.IsPython() {
+		return nil, autograder.StepResult{}, ErrUnsupportedKernel
+	}
+
+	cellTimeout := opts.CellTimeoutSeconds
+	if cellTimeout <= 0 {
+		cellTimeout = DefaultCellTimeoutSeconds
+	}
+	total := opts.TimeSeconds
+	if total <= 0 {
+		total = DefaultTimeSeconds
+	}
+
+	type cellInput struct {
+		Index  int    `json:"index"`
+		Source string `json:"source"`
+	}
+	var inputs []cellInput
+	for _, cell := range nb.Cells {
+		if cell.Type == CellCode && strings.TrimSpace(cell.Source) != "" {
+			inputs = append(inputs, cellInput{Index: cell.Index, Source: cell.Source})
+		}
+	}
+	cells, err := json.Marshal(inputs)
+	if err != nil {
+		return nil, autograder.StepResult{}, err
+	}
+
+	// The runner and cells are read-only to the notebook, and records go to a results file
+	// rather than stdout, which cells can write to
+	step, results, err := autograder.RunCommand(ctx, autograder.Command{
+		Language: autograder.LanguagePython,
+		Command:  `python3 -I "$AUXA_HARNESS/` + runnerName + `" ` + strconv.Itoa(cellTimeout),
+		Files:    opts.Files,
+		Harness:  map[string][]byte{runnerName: []byte(runnerSource), cellsFileName: cells},
+		Limits:   autograder.Limits{TimeSeconds: total, CPUSeconds: total, MemoryMB: opts.MemoryMB},
+	})
+	if err != nil {
+		return nil, step, err
+	}
+
+	// Only the first record for each cell that was sent is kept
+	sent := map[int]bool{}
+	for _, input := range inputs {
+		sent[input.Index] = true
+	}
+	executions := map[int]Execution{}
+	scanner := bufio.NewScanner(bytes.NewReader(results[resultsFileName]))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var execution Execution
+		if err := json.Unmarshal(scanner.Bytes(), &execution); err != nil {
+			continue
+		}
+		if _, seen := executions[execution.Index]; sent[execution.Index] && !seen {
+			executions[execution.Index] = execution
+		}
+	}
+
+	return executions, step, nil
+}
+
+const (
+	runnerName      = "notebook_runner.py"
+	cellsFileName   = "notebook_cells.json"
+	resultsFileName = "cells.jsonl"
+)
+
+// runnerSource executes cells like a kernel: one shared namespace, the value of a trailing
+// expression shown as the cell's result, and magics skipped since IPython is not assumed
+const runnerSource = `import ast
+import contextlib
+import io
+import json
+import os
+import signal
+import sys
+import time
+import traceback
+
+CELL_TIMEOUT = int(sys.argv[1])
+OUTPUT_LIMIT = 20000
+
+os.environ.setdefault("MPLBACKEND", "Agg")
+
+
+class CellTimeout(BaseException):
+    pass
+
+
+def on_alarm(signum, frame):
+    raise CellTimeout()
+
+
+signal.signal(signal.SIGALRM, on_alarm)
+
+
+def strip_magics(source):
+    kept, skipped = [], []
+    for line in source.splitlines():
+        stripped = line.lstrip()
+        if stripped.startswith(("%", "!")):
+            skipped.append(stripped)
+            line = line[: len(line) - len(stripped)] + "pass"
+        kept.append(line)
+    return "\n".join(kept), skipped
+
+
+with open(os.path.join(os.environ["AUXA_HARNESS"], "notebook_cells.json")) as f:
+    cells = json.load(f)
+results = open(os.path.join(os.environ["AUXA_RESULTS"], "cells.jsonl"), "w")
+
+# Cells see the workspace as the working directory and import path, but not the runner
+sys.path.insert(0, os.getcwd())
+namespace = {"__name__": "__main__"}
+
+for cell in cells:
+    out, err = io.StringIO(), io.StringIO()
+    record = {"index": cell["index"], "result": None, "error": None, "timed_out": False}
+    source, record["skipped_lines"] = strip_magics(cell["source"])
+    filename = "<cell %d>" % cell["index"]
+    started = time.time()
+    signal.alarm(CELL_TIMEOUT)
+    try:
+        with contextlib.redirect_stdout(out), contextlib.redirect_stderr(err):
+            tree = ast.parse(source, filename=filename)
+            last = None
+            if tree.body and isinstance(tree.body[-1], ast.Expr):
+                last = ast.Expression(tree.body.pop().value)
+            exec(compile(tree, filename, "exec"), namespace)
+            if last is not None:
+                value = eval(compile(last, filename, "eval"), namespace)
+                if value is not None:
+                    record["result"] = repr(value)[:OUTPUT_LIMIT]
+    except CellTimeout:
+        record["timed_out"] = True
+        record["error"] = {"ename": "TimeoutError", "evalue": "cell ran longer than %d seconds" % CELL_TIMEOUT}
+    except BaseException as exc:
+        record["error"] = {"ename": type(exc).__name__, "evalue": str(exc)[:OUTPUT_LIMIT], "traceback": traceback.format_exc(limit=5)[-OUTPUT_LIMIT:]}
+    finally:
+        signal.alarm(0)
+    record["stdout"] = out.getvalue()[:OUTPUT_LIMIT]
+    record["stderr"] = err.getvalue()[:OUTPUT_LIMIT]
+    record["duration"] = round(time.time() - started, 3)
+    results.write(json.dumps(record) + "\n")
+    results.flush()
+`
@@ -0,0 +1,185 @@
+// Package notebooks parses Jupyter notebooks, re-executes their code cells in the sandbox and
+// checks the produced outputs against the ones saved in the notebook
+package notebooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Cell types
+const (
+	CellCode     = "code"
+	CellMarkdown = "markdown"
+	CellRaw      = "raw"
+)
+
+// Notebook is a parsed .ipynb file
+type Notebook struct {
+	Format   int    `json:"nbformat"`
+	Language string `json:"language"`
+	Kernel   string `json:"kernel,omitempty"`
+	Cells    []Cell `json:"cells"`
+}
+
+// Cell is one notebook cell
+type Cell struct {
+	Index          int      `json:"index"`
+	Type           string   `json:"type"`
+	Source         string   `json:"source"`
+	ExecutionCount *int     `json:"execution_count,omitempty"`
+	Outputs        []Output `json:"outputs,omitempty"`
+}
+
+// Output is one saved output of a code cell
+type Output struct {
+	Type      string   `json:"type"`             // "stream", "execute_result", "display_data" or "error"
+	Stream    string   `json:"stream,omitempty"` // "stdout" or "stderr" for stream outputs
+	Text      string   `json:"text,omitempty"`   // Stream text or the text/plain representation
+	MimeTypes []string `json:"mime_types,omitempty"`
+	EName     string   `json:"ename,omitempty"`
+	EValue    string   `json:"evalue,omitempty"`
+}
+
+// multiline is notebook text, stored either as a string or as a list of lines
+type multiline string
+
+func (m *multiline) UnmarshalJSON(data []byte) error {
+	var lines []string
+	if err := json.Unmarshal(data, &lines); err == nil {
+		*m = multiline(strings.Join(lines, ""))
+		return nil
+	}
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+	*m = multiline(text)
+	return nil
+}
+
+type rawNotebook struct {
+	NBFormat int `json:"nbformat"`
+	Metadata struct {
+		KernelSpec struct {
+			Name     string `json:"name"`
+			Language string `json:"language"`
+		} `json:"kernelspec"`
+		LanguageInfo struct {
+			Name string `json:"name"`
+		} `json:"language_info"`
+	} `json:"metadata"`
+	Cells []struct {
+		CellType       string    `json:"cell_type"`
+		Source         multiline `json:"source"`
+		ExecutionCount *int      `json:"execution_count"`
+		Outputs        []struct {
+			OutputType string                     `json:"output_type"`
+			Name       string                     `json:"name"`
+			Text       multiline                  `json:"text"`
+			Data       map[string]json.RawMessage `json:"data"`
+			EName      string                     `json:"ename"`
+			EValue     string                     `json:"evalue"`
+		} `json:"outputs"`
+	} `json:"cells"`
+}
+
+// Parse reads an nbformat 4 notebook
+func Parse(data []byte) (*Notebook, error) {
+	var raw rawNotebook
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("not a valid notebook: %w", err)
+	}
+	if raw.NBFormat != 4 {
+		return nil, fmt.Errorf("unsupported notebook format %d; only nbformat 4 is supported", raw.NBFormat)
+	}
+
+	nb := &Notebook{
+		Format:   raw.NBFormat,
+		Language: strings.ToLower(raw.Metadata.LanguageInfo.Name),
+		Kernel:   raw.Metadata.KernelSpec.Name,
+		Cells:    make([]Cell, 0, len(raw.Cells)),
+	}
+	if nb.Language == "" {
+		nb.Language = strings.ToLower(raw.Metadata.KernelSpec.Language)
+	}
+
+	for i, rc := range raw.Cells {
+		cell := Cell{Index: i + 1, Type: rc.CellType, Source: string(rc.Source), ExecutionCount: rc.ExecutionCount}
+		for _, ro := range rc.Outputs {
+			out := Output{Type: ro.OutputType, Stream: ro.Name, EName: ro.EName, EValue: ro.EValue}
+			switch ro.OutputType {
+			case "stream":
+				out.Text = string(ro.Text)
+			case "execute_result", "display_data":
+				for mime := range ro.Data {
+					out.MimeTypes = append(out.MimeTypes, mime)
+				}
+				sort.Strings(out.MimeTypes)
+				if plain, ok := ro.Data["text/plain"]; ok {
+					var text multiline
+					if err := json.Unmarshal(plain, &text); err == nil {
+						out.Text = string(text)
+					}
+				}
+			}
+			cell.Outputs = append(cell.Outputs, out)
+		}
+		nb.Cells = append(nb.Cells, cell)
+	}
+
+	return nb, nil
+}
+
+// IsPython reports whether the notebook runs on a Python kernel. Notebooks without kernel
+// metadata are assumed to be Python.
+func (nb *Notebook) IsPython() bool {
+	return nb.Language == "" || nb.Language == "python" || strings.HasPrefix(nb.Kernel, "python")
+}
+
+// storedText is what a cell printed or displayed as text, as saved in the notebook. Standard
+// error is left out since warnings vary between environments.
+func (c Cell) storedText() string {
+	var b strings.Builder
+	for _, out := range c.Outputs {
+		switch out.Type {
+		case "stream":
+			if out.Stream != "stderr" {
+				b.WriteString(out.Text)
+			}
+		case "execute_result", "display_data":
+			if out.Text != "" {
+				b.WriteString(out.Text)
+				b.WriteString("\n")
+			}
+		}
+	}
+	return b.String()
+}
+
+// storedError returns the error a cell raised when the student ran it
+func (c Cell) storedError() (Output, bool) {
+	for _, out := range c.Outputs {
+		if out.Type == "error" {
+			return out, true
+		}
+	}
+	return Output{}, false
+}
+
+// hasRichOutput reports whether a cell displayed something with no text form, such as a plot
+func (c Cell) hasRichOutput() bool {
+	for _, out := range c.Outputs {
+		if (out.Type == "execute_result" || out.Type == "display_data") && out.Text == "" && len(out.MimeTypes) > 0 {
+			return true
+		}
+		for _, mime := range out.MimeTypes {
+			if strings.HasPrefix(mime, "image/") {
+				return true
+			}
+		}
+	}
+	return false
+}
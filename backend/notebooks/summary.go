@@ -0,0 +1,58 @@
+package notebooks
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Longest output quoted per cell in the prompt
+const maxPromptOutput = 300
+
+// Summary describes a report cell by cell for a grading prompt
+func Summary(report Report) string {
+	var b strings.Builder
+
+	b.WriteString("NOTEBOOK ANALYSIS:\n")
+	fmt.Fprintf(&b, "The notebook has %d code cells and %d markdown cells.\n", report.CodeCells, report.MarkdownCells)
+	if report.Executed {
+		b.WriteString("It was re-executed from top to bottom in a sandbox and each cell's output was compared with the submitted output.\n")
+	} else {
+		b.WriteString("It was not re-executed; the results below come from the outputs saved in the submission.\n")
+	}
+	fmt.Fprintf(&b, "Cells: %d ok, %d mismatched, %d errors, %d timed out, %d not run, %d missing output, %d unverified\n",
+		report.Counts[StatusOK], report.Counts[StatusMismatch], report.Counts[StatusError], report.Counts[StatusTimedOut],
+		report.Counts[StatusNotRun], report.Counts[StatusMissing], report.Counts[StatusUnverified])
+	for _, warning := range report.Warnings {
+		b.WriteString("Note: " + warning + "\n")
+	}
+
+	b.WriteString("\n")
+	for _, cell := range report.Cells {
+		if cell.Type != CellCode || cell.Status == StatusSkipped {
+			continue
+		}
+		fmt.Fprintf(&b, "- Cell %d [%s]", cell.Index, strings.ToUpper(cell.Status))
+		if cell.Preview != "" {
+			fmt.Fprintf(&b, " `%s`", cell.Preview)
+		}
+		if cell.Detail != "" {
+			fmt.Fprintf(&b, ": %s", cell.Detail)
+		}
+		b.WriteString("\n")
+		if cell.Status == StatusMismatch {
+			fmt.Fprintf(&b, "  Submitted output: %s\n", quoteOutput(cell.Expected))
+			fmt.Fprintf(&b, "  Re-run output: %s\n", quoteOutput(cell.Actual))
+		}
+	}
+
+	b.WriteString("\nTreat mismatched or missing outputs as evidence that the saved results were not produced by the submitted code, and weigh errors and unrun cells against the completeness of the work.")
+	return b.String()
+}
+
+func quoteOutput(text string) string {
+	text = strings.TrimSpace(text)
+	if len(text) > maxPromptOutput {
+		text = text[:maxPromptOutput] + "..."
+	}
+	return fmt.Sprintf("%q", text)
+}
@@ -0,0 +1,161 @@
+// Package pdf writes simple text-and-line PDF documents using the standard Helvetica fonts,
+// so reports can be generated without external dependencies.
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"strings"
+)
+
+// Page sizes in points
+const (
+	LetterWidth  = 612.0
+	LetterHeight = 792.0
+)
+
+// Font selects one of the two built-in fonts
+type Font int
+
+const (
+	Regular Font = iota
+	Bold
+)
+
+// resource names used in content streams
+var fontResources = map[Font]string{Regular: "F1", Bold: "F2"}
+
+// Document is a PDF under construction. Coordinates are in points from the top-left corner
+// of the page, which the writer converts to PDF's bottom-left origin.
+type Document struct {
+	Width  float64
+	Height float64
+
+	pages   []*bytes.Buffer
+	current int
+}
+
+// New creates an empty document with US Letter pages
+func New() *Document {
+	return &Document{Width: LetterWidth, Height: LetterHeight}
+}
+
+// AddPage starts a new page; later drawing goes to it
+func (d *Document) AddPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+	d.current = len(d.pages) - 1
+}
+
+// PageCount returns the number of pages added so far
+func (d *Document) PageCount() int {
+	return len(d.pages)
+}
+
+// SetPage directs drawing to an earlier page, e.g. to add footers once the page count is known
+func (d *Document) SetPage(index int) {
+	if index < 0 || index >= len(d.pages) {
+		return
+	}
+	d.current = index
+}
+
+func (d *Document) page() *bytes.Buffer {
+	if len(d.pages) == 0 {
+		d.AddPage()
+	}
+	return d.pages[d.current]
+}
+
+// Text draws a single line of text with its baseline at y
+func (d *Document) Text(x, y float64, font Font, size float64, text string) {
+	fmt.Fprintf(d.page(), "BT /%s %s Tf %s %s Td (%s) Tj ET\n",
+		fontResources[font], num(size), num(x), num(d.Height-y), escape(encode(text)))
+}
+
+// Line draws a straight line
+func (d *Document) Line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(d.page(), "%s w %s %s m %s %s l S\n", num(width), num(x1), num(d.Height-y1), num(x2), num(d.Height-y2))
+}
+
+// FillRect fills a rectangle with a grey level between 0 (black) and 1 (white)
+func (d *Document) FillRect(x, y, width, height, grey float64) {
+	fmt.Fprintf(d.page(), "q %s g %s %s %s %s re f Q\n", num(grey), num(x), num(d.Height-y-height), num(width), num(height))
+}
+
+// Bytes serializes the document
+func (d *Document) Bytes() ([]byte, error) {
+	if len(d.pages) == 0 {
+		d.AddPage()
+	}
+
+	var out bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// Objects 1-4 are the catalog, page tree and fonts; each page then takes two objects
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+
+	for i, content := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			num(d.Width), num(d.Height), 6+2*i))
+
+		var compressed bytes.Buffer
+		zw := zlib.NewWriter(&compressed)
+		if _, err := zw.Write(content.Bytes()); err != nil {
+			return nil, fmt.Errorf("failed to compress page %d: %w", i+1, err)
+		}
+		if err := zw.Close(); err != nil {
+			return nil, fmt.Errorf("failed to compress page %d: %w", i+1, err)
+		}
+		object(fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", compressed.Len(), compressed.String()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return out.Bytes(), nil
+}
+
+// num formats a coordinate compactly
+func num(v float64) string {
+	s := fmt.Sprintf("%.2f", v)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "" || s == "-0" {
+		return "0"
+	}
+	return s
+}
+
+// escape quotes a string for a PDF literal
+func escape(text string) string {
+	var b strings.Builder
+	for i := 0; i < len(text); i++ {
+		switch ch := text[i]; ch {
+		case '\\', '(', ')':
+			b.WriteByte('\\')
+			b.WriteByte(ch)
+		case '\r', '\n', '\t':
+			b.WriteByte(' ')
+		default:
+			b.WriteByte(ch)
+		}
+	}
+	return b.String()
+}
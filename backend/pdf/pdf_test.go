@@ -0,0 +1,166 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestStringWidth(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		font Font
+		size float64
+		want float64
+	}{
+		{name: "regular", text: "Hello", font: Regular, size: 10, want: 22.78},
+		{name: "bold is wider", text: "Hello", font: Bold, size: 10, want: 24.45},
+		{name: "scales with size", text: "Hello", font: Regular, size: 20, want: 45.56},
+		{name: "Latin-1 and WinAnsi characters use a default width", text: "é—", font: Regular, size: 10, want: 11.12},
+		{name: "empty", text: "", font: Bold, size: 12, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := StringWidth(tt.text, tt.font, tt.size); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("StringWidth(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWrap(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		width float64
+		want  []string
+	}{
+		{name: "fits on one line", text: "Good work", width: 200, want: []string{"Good work"}},
+		{name: "breaks between words", text: "one two three four", width: 50, want: []string{"one two", "three four"}},
+		{name: "keeps line breaks and blank lines", text: "first\r\n\nsecond", width: 200, want: []string{"first", "", "second"}},
+		{name: "collapses spacing", text: "  a   b  ", width: 200, want: []string{"a b"}},
+		{name: "splits words wider than a line", text: "ab supercalifragilistic", width: 30, want: []string{"ab", "super", "califra", "gilistic"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Wrap(tt.text, Regular, 10, tt.width)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			for _, line := range got {
+				if width := StringWidth(line, Regular, 10); width > tt.width {
+					t.Errorf("line %q is %v wide, limit %v", line, width, tt.width)
+				}
+			}
+		})
+	}
+}
+
+func TestEncodeAndEscape(t *testing.T) {
+	tests := []struct {
+		text, want string
+	}{
+		{text: "plain", want: "plain"},
+		{text: `(a\b)`, want: `\(a\\b\)`},
+		{text: "tab\tand\nnewline", want: "tab and newline"},
+		{text: "café “quoted” €5", want: "caf\xe9 \x93quoted\x94 \x805"},
+		{text: "emoji 🙂 and 中文", want: "emoji ? and ??"},
+	}
+	for _, tt := range tests {
+		if got := escape(encode(tt.text)); got != tt.want {
+			t.Errorf("escape(encode(%q)) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestBytesStructure(t *testing.T) {
+	doc := New()
+	doc.Text(72, 100, Bold, 14, "Report (draft)")
+	doc.AddPage()
+	doc.Line(72, 80, 540, 80, 0.5)
+	doc.SetPage(0)
+	doc.FillRect(72, 120, 100, 20, 0.9)
+
+	data, err := doc.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.HasPrefix(data, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(data, []byte("%%EOF\n")) {
+		t.Fatalf("missing header or trailer")
+	}
+	if !bytes.Contains(data, []byte("/Count 2")) {
+		t.Error("page tree should count two pages")
+	}
+
+	// startxref points at the xref table, whose entries point at each object
+	match := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(data)
+	if match == nil {
+		t.Fatal("no startxref")
+	}
+	xref, _ := strconv.Atoi(string(match[1]))
+	if !bytes.HasPrefix(data[xref:], []byte("xref\n0 9\n")) {
+		t.Fatalf("startxref %d does not point at an xref table of 9 entries", xref)
+	}
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(data[xref:], -1)
+	if len(entries) != 8 {
+		t.Fatalf("got %d xref entries, want 8", len(entries))
+	}
+	for i, entry := range entries {
+		offset, _ := strconv.Atoi(string(entry[1]))
+		if want := fmt.Sprintf("%d 0 obj\n", i+1); !bytes.HasPrefix(data[offset:], []byte(want)) {
+			t.Errorf("xref entry %d points at %q", i+1, data[offset:offset+10])
+		}
+	}
+
+	// Content streams flip y to PDF's bottom-left origin
+	pages := pageContents(t, data)
+	if len(pages) != 2 {
+		t.Fatalf("got %d content streams, want 2", len(pages))
+	}
+	for _, want := range []string{"BT /F2 14 Tf 72 692 Td (Report \\(draft\\)) Tj ET", "q 0.9 g 72 652 100 20 re f Q"} {
+		if !strings.Contains(pages[0], want) {
+			t.Errorf("page 1 is missing %q:\n%s", want, pages[0])
+		}
+	}
+	if want := "0.5 w 72 712 m 540 712 l S"; !strings.Contains(pages[1], want) {
+		t.Errorf("page 2 is missing %q:\n%s", want, pages[1])
+	}
+}
+
+func TestBytesWithoutPages(t *testing.T) {
+	data, err := New().Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(data, []byte("/Count 1")) {
+		t.Error("an empty document should still have one page")
+	}
+}
+
+// pageContents inflates every content stream in order
+func pageContents(t *testing.T, data []byte) []string {
+	t.Helper()
+	var pages []string
+	for _, match := range regexp.MustCompile(`(?s)<< /Length (\d+) /Filter /FlateDecode >>\nstream\n`).FindAllSubmatchIndex(data, -1) {
+		length, _ := strconv.Atoi(string(data[match[2]:match[3]]))
+		zr, err := zlib.NewReader(bytes.NewReader(data[match[1] : match[1]+length]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages = append(pages, string(content))
+	}
+	return pages
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"time"
+
+	"auxa/llm"
+
+	"github.com/gin-gonic/gin"
+)
+
+// How long identical prompts are answered from the response cache
+const responseCacheTTL = 7 * 24 * time.Hour
+
+// responseCache stores provider responses on disk; initialised in main
+var responseCache *llm.Cache
+
+// feedbackGenerator assembles the grading pipeline for a course: PII redaction, the response
+// cache and spending caps around the provider call. The cache sits inside redaction so only
+// masked text is written to disk. Calls are metered against the TA behind the request's
+// Canvas token. It writes an error response and returns false on failure.
+func feedbackGenerator(c *gin.Context, courseID string) (llm.Generator, bool) {
+	graderID, ok := graderFromRequest(c)
+	if !ok {
+		return nil, false
+	}
+
+	redactor, ok := redactorForCourse(c, courseID)
+	if !ok {
+		return nil, false
+	}
+
+	var middleware []llm.Middleware
+	if redactor != nil {
+		middleware = append(middleware, llm.RedactPII(redactor))
+	}
+	middleware = append(middleware, llm.CacheResponses(responseCache), meterGrading(graderID))
+
+	return llm.Chain(llm.GenerateFeedback, middleware...), true
+}
+
+// visionAnalyzer assembles the vision pipeline for a course, mirroring feedbackGenerator
+func visionAnalyzer(c *gin.Context, courseID string) (llm.VisionAnalyzer, bool) {
+	graderID, ok := graderFromRequest(c)
+	if !ok {
+		return nil, false
+	}
+
+	redactor, ok := redactorForCourse(c, courseID)
+	if !ok {
+		return nil, false
+	}
+
+	var middleware []llm.VisionMiddleware
+	if redactor != nil {
+		middleware = append(middleware, llm.RedactVisionPII(redactor))
+	}
+	middleware = append(middleware, llm.CacheVisionResponses(responseCache), meterVision(graderID))
+
+	return llm.ChainVision(llm.AnalyzeImage, middleware...), true
+}
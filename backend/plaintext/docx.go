@@ -0,0 +1,77 @@
+package plaintext
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Largest document.xml read from a DOCX, so a zip bomb cannot exhaust memory
+const maxDocumentXMLBytes = 32 << 20
+
+// FromDOCX extracts the text of a Word document's body, one paragraph per line
+func FromDOCX(data []byte) (string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("not a DOCX file: %w", err)
+	}
+
+	var document *zip.File
+	for _, file := range archive.File {
+		if file.Name == "word/document.xml" {
+			document = file
+			break
+		}
+	}
+	if document == nil {
+		return "", fmt.Errorf("not a DOCX file: word/document.xml is missing")
+	}
+
+	r, err := document.Open()
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	decoder := xml.NewDecoder(io.LimitReader(r, maxDocumentXMLBytes))
+	var b strings.Builder
+	inText := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read DOCX text: %w", err)
+		}
+
+		// Element names are matched without their w: namespace
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				b.WriteString("\t")
+			case "br", "cr":
+				b.WriteString("\n")
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				b.WriteString("\n")
+			}
+		case xml.CharData:
+			if inText {
+				b.Write(t)
+			}
+		}
+	}
+
+	return strings.TrimSpace(b.String()), nil
+}
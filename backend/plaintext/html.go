@@ -0,0 +1,23 @@
+package plaintext
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	blockTagPattern = regexp.MustCompile(`(?i)<\s*(br|/p|/div|/li|/h[1-6]|/tr)\s*/?>`)
+	tagPattern      = regexp.MustCompile(`(?s)<[^>]*>`)
+	blankRunPattern = regexp.MustCompile(`\n{3,}`)
+)
+
+// FromHTML converts Canvas HTML, such as a text entry submission body or an assignment
+// description, to plain text
+func FromHTML(body string) string {
+	text := blockTagPattern.ReplaceAllString(body, "\n")
+	text = tagPattern.ReplaceAllString(text, "")
+	text = html.UnescapeString(text)
+	text = blankRunPattern.ReplaceAllString(text, "\n\n")
+	return strings.TrimSpace(text)
+}
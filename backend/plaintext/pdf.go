@@ -0,0 +1,426 @@
+package plaintext
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Most decompressed content read from one PDF
+const maxPDFContentBytes = 32 << 20
+
+// Kerning in a TJ array wider than this, in thousandths of an em, is taken as a word gap
+const tjSpaceThreshold = -200
+
+// ErrNoPDFText is returned for PDFs with no text to extract, such as scans, or whose text
+// cannot be decoded
+var ErrNoPDFText = errors.New("the PDF has no extractable text")
+
+var (
+	streamPattern = regexp.MustCompile(`>>\s*stream\r?\n`)
+	// Streams that hold fonts, images or other objects rather than page content
+	nonContentPattern  = regexp.MustCompile(`/(Subtype\s*/Image|Type\s*/(ObjStm|XRef|XObject|Metadata)|Length[123]\b|Subtype\s*/(Type1C|CIDFontType0C|OpenType|XML))`)
+	otherFilterPattern = regexp.MustCompile(`/(DCTDecode|JPXDecode|CCITTFaxDecode|JBIG2Decode|LZWDecode|RunLengthDecode|ASCII85Decode|ASCIIHexDecode)`)
+)
+
+// FromPDF extracts the text drawn by a PDF's page content streams. It reads fonts with a
+// standard single-byte encoding, which covers most documents exported from word processors;
+// text in fonts with custom encodings may come out garbled, and scans have no text at all.
+func FromPDF(data []byte) (string, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, "\x00\t\n\r "), []byte("%PDF-")) {
+		return "", fmt.Errorf("not a PDF file")
+	}
+
+	var b strings.Builder
+	budget := maxPDFContentBytes
+	for _, match := range streamPattern.FindAllIndex(data, -1) {
+		// The stream's dictionary runs from its object header
+		dictStart := bytes.LastIndex(data[:match[0]], []byte("obj"))
+		if dictStart < 0 {
+			continue
+		}
+		dict := data[dictStart:match[0]]
+		start := match[1]
+		end := bytes.Index(data[start:], []byte("endstream"))
+		if end < 0 {
+			break
+		}
+		raw := data[start : start+end]
+
+		if nonContentPattern.Match(dict) || otherFilterPattern.Match(dict) {
+			continue
+		}
+
+		content := raw
+		if bytes.Contains(dict, []byte("/FlateDecode")) {
+			r, err := zlib.NewReader(bytes.NewReader(raw))
+			if err != nil {
+				continue
+			}
+			content, err = io.ReadAll(io.LimitReader(r, int64(budget)))
+			r.Close()
+			// Truncated streams still yield the text decoded so far
+			if err != nil && len(content) == 0 {
+				continue
+			}
+		}
+		budget -= len(content)
+		if budget <= 0 {
+			break
+		}
+
+		if bytes.Contains(content, []byte("BT")) {
+			writeContentText(&b, content)
+		}
+	}
+
+	text := strings.TrimSpace(collapseBlankLines(b.String()))
+	if text == "" || !readable(text) {
+		return "", ErrNoPDFText
+	}
+	return text, nil
+}
+
+// readable reports whether most of text is letters and digits. Fonts with custom encodings
+// decode to runs of punctuation, which are worse than no text at all.
+func readable(text string) bool {
+	letters, others := 0, 0
+	for _, r := range text {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			letters++
+		case !unicode.IsSpace(r):
+			others++
+		}
+	}
+	return letters >= 2*others
+}
+
+// writeContentText runs the text operators of a content stream
+func writeContentText(b *strings.Builder, content []byte) {
+	s := &contentScanner{data: content}
+	var operands []interface{}
+	inText := false
+
+	newline := func() {
+		if b.Len() > 0 && !strings.HasSuffix(b.String(), "\n") {
+			b.WriteString("\n")
+		}
+	}
+
+	for {
+		token, kind := s.next()
+		switch kind {
+		case tokenEOF:
+			return
+		case tokenString, tokenNumber, tokenArray:
+			operands = append(operands, token)
+			continue
+		}
+
+		op, _ := token.(string)
+		switch op {
+		case "BT":
+			inText = true
+		case "ET":
+			inText = false
+			newline()
+		case "T*", "Tm":
+			newline()
+		case "Td", "TD":
+			if len(operands) == 2 {
+				if ty, ok := operands[1].(float64); ok && ty != 0 {
+					newline()
+				} else if !strings.HasSuffix(b.String(), " ") && !strings.HasSuffix(b.String(), "\n") && b.Len() > 0 {
+					b.WriteString(" ")
+				}
+			}
+		case "Tj":
+			if inText && len(operands) > 0 {
+				writeOperand(b, operands[len(operands)-1])
+			}
+		case "'", "\"":
+			if inText && len(operands) > 0 {
+				newline()
+				writeOperand(b, operands[len(operands)-1])
+			}
+		case "TJ":
+			if inText && len(operands) > 0 {
+				writeOperand(b, operands[len(operands)-1])
+			}
+		}
+		operands = operands[:0]
+	}
+}
+
+func writeOperand(b *strings.Builder, operand interface{}) {
+	switch v := operand.(type) {
+	case []byte:
+		b.WriteString(decodePDFString(v))
+	case []interface{}:
+		for _, item := range v {
+			switch part := item.(type) {
+			case []byte:
+				b.WriteString(decodePDFString(part))
+			case float64:
+				if part < tjSpaceThreshold && !strings.HasSuffix(b.String(), " ") {
+					b.WriteString(" ")
+				}
+			}
+		}
+	}
+}
+
+// decodePDFString reads a string as WinAnsi text, or as UTF-16 when it has a byte order mark
+func decodePDFString(raw []byte) string {
+	if len(raw) >= 2 && raw[0] == 0xfe && raw[1] == 0xff {
+		var runes []rune
+		for i := 2; i+1 < len(raw); i += 2 {
+			runes = append(runes, rune(raw[i])<<8|rune(raw[i+1]))
+		}
+		return string(runes)
+	}
+
+	var b strings.Builder
+	for _, c := range raw {
+		r := rune(c)
+		if mapped, ok := winAnsi[c]; ok {
+			r = mapped
+		}
+		if unicode.IsPrint(r) || r == '\t' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// WinAnsi characters that differ from Latin-1
+var winAnsi = map[byte]rune{
+	0x80: '€', 0x82: '‚', 0x83: 'ƒ', 0x84: '„', 0x85: '…', 0x86: '†', 0x87: '‡', 0x88: 'ˆ',
+	0x89: '‰', 0x8a: 'Š', 0x8b: '‹', 0x8c: 'Œ', 0x8e: 'Ž', 0x91: '‘', 0x92: '’',
+	0x93: '“', 0x94: '”', 0x95: '•', 0x96: '–', 0x97: '—', 0x98: '˜', 0x99: '™', 0x9a: 'š',
+	0x9b: '›', 0x9c: 'œ', 0x9e: 'ž', 0x9f: 'Ÿ',
+}
+
+func collapseBlankLines(text string) string {
+	lines := strings.Split(text, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t")
+		if line == "" && len(kept) > 0 && kept[len(kept)-1] == "" {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return strings.Join(kept, "\n")
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenString
+	tokenNumber
+	tokenArray
+	tokenOperator
+)
+
+// contentScanner splits a content stream into operands and operators
+type contentScanner struct {
+	data []byte
+	pos  int
+}
+
+func (s *contentScanner) next() (interface{}, tokenKind) {
+	for s.pos < len(s.data) {
+		c := s.data[s.pos]
+		switch {
+		case isPDFSpace(c):
+			s.pos++
+		case c == '%':
+			for s.pos < len(s.data) && s.data[s.pos] != '\n' && s.data[s.pos] != '\r' {
+				s.pos++
+			}
+		case c == '(':
+			return s.literal(), tokenString
+		case c == '<' && s.pos+1 < len(s.data) && s.data[s.pos+1] == '<':
+			s.skipDictionary()
+		case c == '<':
+			return s.hex(), tokenString
+		case c == '[':
+			s.pos++
+			var items []interface{}
+			for {
+				token, kind := s.next()
+				if kind == tokenEOF || (kind == tokenOperator && token == "]") {
+					return items, tokenArray
+				}
+				items = append(items, token)
+			}
+		case c == ']':
+			s.pos++
+			return "]", tokenOperator
+		case c == '/':
+			// Names are operands no text operator needs
+			s.pos++
+			for s.pos < len(s.data) && !isPDFSpace(s.data[s.pos]) && !isPDFDelimiter(s.data[s.pos]) {
+				s.pos++
+			}
+			return nil, tokenNumber
+		default:
+			start := s.pos
+			for s.pos < len(s.data) && !isPDFSpace(s.data[s.pos]) && !isPDFDelimiter(s.data[s.pos]) {
+				s.pos++
+			}
+			if s.pos == start {
+				s.pos++
+				continue
+			}
+			word := string(s.data[start:s.pos])
+			if number, err := strconv.ParseFloat(word, 64); err == nil {
+				return number, tokenNumber
+			}
+			if word == "BI" {
+				s.skipInlineImage()
+				continue
+			}
+			return word, tokenOperator
+		}
+	}
+	return nil, tokenEOF
+}
+
+func (s *contentScanner) literal() []byte {
+	s.pos++ // (
+	var out []byte
+	depth := 1
+	for s.pos < len(s.data) {
+		c := s.data[s.pos]
+		s.pos++
+		switch c {
+		case '\\':
+			if s.pos >= len(s.data) {
+				return out
+			}
+			e := s.data[s.pos]
+			s.pos++
+			switch e {
+			case 'n':
+				out = append(out, '\n')
+			case 'r':
+				out = append(out, '\r')
+			case 't':
+				out = append(out, '\t')
+			case 'b':
+				out = append(out, '\b')
+			case 'f':
+				out = append(out, '\f')
+			case '\r':
+				if s.pos < len(s.data) && s.data[s.pos] == '\n' {
+					s.pos++
+				}
+			case '\n':
+			default:
+				if e >= '0' && e <= '7' {
+					value := int(e - '0')
+					for i := 0; i < 2 && s.pos < len(s.data) && s.data[s.pos] >= '0' && s.data[s.pos] <= '7'; i++ {
+						value = value*8 + int(s.data[s.pos]-'0')
+						s.pos++
+					}
+					out = append(out, byte(value))
+				} else {
+					out = append(out, e)
+				}
+			}
+		case '(':
+			depth++
+			out = append(out, c)
+		case ')':
+			depth--
+			if depth == 0 {
+				return out
+			}
+			out = append(out, c)
+		default:
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func (s *contentScanner) hex() []byte {
+	s.pos++ // <
+	var digits []byte
+	for s.pos < len(s.data) && s.data[s.pos] != '>' {
+		if c := s.data[s.pos]; isHexDigit(c) {
+			digits = append(digits, c)
+		}
+		s.pos++
+	}
+	s.pos++ // >
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	out := make([]byte, len(digits)/2)
+	for i := range out {
+		value, _ := strconv.ParseUint(string(digits[2*i:2*i+2]), 16, 8)
+		out[i] = byte(value)
+	}
+	return out
+}
+
+func (s *contentScanner) skipDictionary() {
+	depth := 0
+	for s.pos+1 < len(s.data) {
+		switch {
+		case s.data[s.pos] == '<' && s.data[s.pos+1] == '<':
+			depth++
+			s.pos += 2
+		case s.data[s.pos] == '>' && s.data[s.pos+1] == '>':
+			depth--
+			s.pos += 2
+			if depth == 0 {
+				return
+			}
+		default:
+			s.pos++
+		}
+	}
+	s.pos = len(s.data)
+}
+
+// skipInlineImage skips binary image data up to its EI operator
+func (s *contentScanner) skipInlineImage() {
+	end := bytes.Index(s.data[s.pos:], []byte("EI"))
+	for end >= 0 {
+		after := s.pos + end + 2
+		if s.pos+end > 0 && isPDFSpace(s.data[s.pos+end-1]) && (after == len(s.data) || isPDFSpace(s.data[after])) {
+			s.pos = after
+			return
+		}
+		next := bytes.Index(s.data[after:], []byte("EI"))
+		if next < 0 {
+			break
+		}
+		end = after - s.pos + next
+	}
+	s.pos = len(s.data)
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func isPDFDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
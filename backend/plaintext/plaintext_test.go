@@ -0,0 +1,167 @@
+package plaintext
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"testing"
+
+	"auxa/pdf"
+)
+
+func TestFromHTML(t *testing.T) {
+	tests := []struct {
+		name, html, want string
+	}{
+		{name: "paragraphs", html: "<p>One</p><p>Two</p>", want: "One\nTwo"},
+		{name: "line breaks and entities", html: "a &amp; b<br/>c &lt; d", want: "a & b\nc < d"},
+		{name: "blank runs collapse", html: "<div>x</div><br><br><br><div>y</div>", want: "x\n\ny"},
+		{name: "attributes spanning lines", html: "<a\nhref=\"x\">link</a>", want: "link"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FromHTML(tt.html); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func docx(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestFromDOCX(t *testing.T) {
+	document := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">
+<w:body>
+<w:p><w:r><w:t>Hello </w:t></w:r><w:r><w:rPr><w:b/></w:rPr><w:t>world</w:t></w:r></w:p>
+<w:p><w:r><w:t>a</w:t><w:tab/><w:t>b</w:t><w:br/><w:t>c &amp; d</w:t></w:r></w:p>
+<w:p><w:pPr><w:pStyle w:val="Heading1"/></w:pPr><w:r><w:instrText>PAGE</w:instrText><w:t>Last</w:t></w:r></w:p>
+</w:body>
+</w:document>`
+
+	tests := []struct {
+		name    string
+		data    []byte
+		want    string
+		wantErr bool
+	}{
+		{name: "paragraphs, runs, tabs and breaks", data: docx(t, map[string]string{"word/document.xml": document}), want: "Hello world\na\tb\nc & d\nLast"},
+		{name: "zip without a document", data: docx(t, map[string]string{"content.xml": "<x/>"}), wantErr: true},
+		{name: "not a zip", data: []byte("%PDF-1.4"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FromDOCX(tt.data)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// rawPDF wraps content streams in the minimum FromPDF reads
+func rawPDF(streams ...string) []byte {
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	for i, stream := range streams {
+		var compressed bytes.Buffer
+		zw := zlib.NewWriter(&compressed)
+		zw.Write([]byte(stream))
+		zw.Close()
+		fmt.Fprintf(&b, "%d 0 obj\n<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream\nendobj\n", i+1, compressed.Len(), compressed.String())
+	}
+	b.WriteString("%%EOF\n")
+	return b.Bytes()
+}
+
+func TestFromPDF(t *testing.T) {
+	report := pdf.New()
+	report.Text(72, 72, pdf.Bold, 14, "Feedback for Essay 1")
+	report.Text(72, 96, pdf.Regular, 11, "Strong thesis (see p. 2) — well done")
+	report.AddPage()
+	report.Text(72, 72, pdf.Regular, 11, "Second page")
+	written, err := report.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		want    string
+		wantErr error
+	}{
+		{
+			name: "document from the pdf package",
+			data: written,
+			want: "Feedback for Essay 1\nStrong thesis (see p. 2) — well done\nSecond page",
+		},
+		{
+			name: "TJ kerning, escapes, hex strings and line operators",
+			data: rawPDF("BT /F1 12 Tf 72 700 Td [(Hel) 20 (lo) -300 (world)] TJ T* (a\\(b\\)\\\\c\\101) Tj 0 -14 Td <48692E> Tj ET"),
+			want: "Hello world\na(b)\\cA\nHi.",
+		},
+		{
+			name: "images and fonts are skipped",
+			data: append(rawPDF("BT (Text) Tj ET"), []byte("9 0 obj\n<< /Subtype /Image /Length 7 >>\nstream\nBT (x) Tj ET\nendstream\nendobj\n")...),
+			want: "Text",
+		},
+		{
+			name:    "no text, as in a scan",
+			data:    rawPDF("q 100 0 0 100 0 0 cm /Im1 Do Q"),
+			wantErr: ErrNoPDFText,
+		},
+		{
+			name:    "text in a custom font encoding",
+			data:    rawPDF("BT <0102030405> Tj (!\"#$ %&) Tj ET"),
+			wantErr: ErrNoPDFText,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FromPDF(tt.data)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got %q, %v, want error %v", got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := FromPDF([]byte("PK\x03\x04")); err == nil {
+		t.Error("expected an error for a file that is not a PDF")
+	}
+}
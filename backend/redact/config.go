@@ -0,0 +1,87 @@
+package redact
+
+import (
+	"sync"
+
+	"auxa/store"
+)
+
+// Config selects which kinds of personal data are masked
+type Config struct {
+	Enabled    bool `json:"enabled"`
+	Names      bool `json:"names"`
+	Emails     bool `json:"emails"`
+	StudentIDs bool `json:"student_ids"` // The roster's login and SIS IDs
+	// Also masks numbers matching this pattern, e.g. \b[A-Za-z]?\d{7,9}\b; empty by default
+	StudentIDPattern string `json:"student_id_pattern,omitempty"`
+	Phones           bool   `json:"phones"`
+	Addresses        bool   `json:"addresses"`
+}
+
+// DefaultConfig masks the roster's names, emails and IDs and street addresses. Phone
+// numbers and ID patterns are opt-in, since they also match numbers in ordinary answers.
+func DefaultConfig() Config {
+	return Config{
+		Enabled:    true,
+		Names:      true,
+		Emails:     true,
+		StudentIDs: true,
+		Addresses:  true,
+	}
+}
+
+// Settings persists the redaction config for each course
+type Settings struct {
+	mu   sync.RWMutex
+	path string
+	data settingsFile
+}
+
+type settingsFile struct {
+	Default Config            `json:"default"`
+	Courses map[string]Config `json:"courses"`
+}
+
+// LoadSettings reads course redaction settings from path
+func LoadSettings(path string) (*Settings, error) {
+	s := &Settings{
+		path: path,
+		data: settingsFile{Default: DefaultConfig(), Courses: make(map[string]Config)},
+	}
+	if err := store.LoadJSON(path, &s.data); err != nil {
+		return nil, err
+	}
+	if s.data.Courses == nil {
+		s.data.Courses = make(map[string]Config)
+	}
+	return s, nil
+}
+
+// ForCourse returns the config for a course, falling back to the default
+func (s *Settings) ForCourse(courseID string) Config {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if config, ok := s.data.Courses[courseID]; ok {
+		return config
+	}
+	return s.data.Default
+}
+
+// SetCourse stores the config for a course
+func (s *Settings) SetCourse(courseID string, config Config) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.Courses[courseID] = config
+	return store.SaveJSON(s.path, s.data)
+}
+
+// ResetCourse removes a course override so the default applies again
+func (s *Settings) ResetCourse(courseID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.data.Courses, courseID)
+	return store.SaveJSON(s.path, s.data)
+}
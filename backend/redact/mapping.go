@@ -0,0 +1,95 @@
+package redact
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Mapping records which original value each mask token stands for, so provider output
+// can be de-masked before it is shown to the grader
+type Mapping struct {
+	mu        sync.Mutex
+	tokens    map[interface{}]string // Masked entity (person or literal value) to token
+	originals map[string]string      // Token to restored text
+	counters  map[string]int
+}
+
+// NewMapping creates an empty reversible mapping
+func NewMapping() *Mapping {
+	return &Mapping{
+		tokens:    make(map[interface{}]string),
+		originals: make(map[string]string),
+		counters:  make(map[string]int),
+	}
+}
+
+// token returns the token for entity, allocating the next one of its kind on first sight.
+// A nil mapping returns an anonymous token of the kind.
+func (m *Mapping) token(kind string, entity interface{}, restored string) string {
+	if m == nil {
+		return "[" + kind + "]"
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := struct {
+		kind   string
+		entity interface{}
+	}{kind, entity}
+	if token, ok := m.tokens[key]; ok {
+		return token
+	}
+
+	m.counters[kind]++
+	token := fmt.Sprintf("[%s_%d]", kind, m.counters[kind])
+	m.tokens[key] = token
+	m.originals[token] = restored
+	return token
+}
+
+// Unmask restores masked values in text. Tokens the model echoed without brackets are restored too.
+func (m *Mapping) Unmask(text string) string {
+	if m == nil || text == "" {
+		return text
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return tokenPattern.ReplaceAllStringFunc(text, func(match string) string {
+		parts := tokenPattern.FindStringSubmatch(match)
+		token := "[" + parts[1] + "_" + parts[2] + "]"
+		if original, ok := m.originals[token]; ok {
+			return original
+		}
+		return match
+	})
+}
+
+// Len reports how many distinct values were masked
+func (m *Mapping) Len() int {
+	if m == nil {
+		return 0
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.originals)
+}
+
+// Summary counts masked values by kind, for logging without revealing them
+func (m *Mapping) Summary() map[string]int {
+	summary := make(map[string]int)
+	if m == nil {
+		return summary
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for token := range m.originals {
+		parts := tokenPattern.FindStringSubmatch(token)
+		summary[parts[1]]++
+	}
+	return summary
+}
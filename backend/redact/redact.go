@@ -0,0 +1,257 @@
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"auxa/canvas"
+)
+
+// Minimum length of a single first or last name before it is masked on its own, so that
+// initials and short fragments do not mangle ordinary words
+const minTermLength = 3
+
+// Kinds of personal data the redactor detects; each becomes the prefix of its mask token
+const (
+	KindName      = "NAME"
+	KindEmail     = "EMAIL"
+	KindStudentID = "STUDENT_ID"
+	KindPhone     = "PHONE"
+	KindAddress   = "ADDRESS"
+)
+
+// ambiguousToken stands in for a term shared by more than one person
+const ambiguousToken = "[STUDENT]"
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	phonePattern = regexp.MustCompile(`(?:\+?\d{1,2}[\s.\-]?)?(?:\(\d{3}\)|\d{3})[\s.\-]?\d{3}[\s.\-]\d{4}\b`)
+	// Street line, optionally followed by unit, city, state and ZIP
+	addressPattern = regexp.MustCompile(`\b\d{1,5}\s+(?:[A-Z][A-Za-z]*\.?\s+){1,4}(?:Street|St|Avenue|Ave|Road|Rd|Boulevard|Blvd|Lane|Ln|Drive|Dr|Court|Ct|Way|Place|Pl|Terrace|Circle|Cir|Parkway|Pkwy)\b\.?(?:,?\s+(?:Apt|Apartment|Unit|Suite|Ste|#)\.?\s*[A-Za-z0-9\-]+)?(?:,\s*[A-Z][A-Za-z]+(?:\s[A-Z][A-Za-z]+)*)?(?:,\s*[A-Z]{2})?(?:\s+\d{5}(?:-\d{4})?)?`)
+	tokenPattern   = regexp.MustCompile(`\[?\b(NAME|EMAIL|STUDENT_ID|PHONE|ADDRESS)_(\d+)\b\]?`)
+)
+
+// Person is someone whose identifiers should be masked
+type Person struct {
+	DisplayName string   // Restored in place of the person's mask token
+	Names       []string // Full names and name variants; individual parts are masked too
+	IDs         []string // Login, SIS and other identifiers
+	Emails      []string
+	Alias       string // Optional fixed replacement, e.g. a pseudonym, instead of a reversible token
+}
+
+// FromEnrollments converts a course roster into the people a redactor masks
+func FromEnrollments(enrollments []canvas.Enrollment) []Person {
+	people := make([]Person, len(enrollments))
+	for i, enrollment := range enrollments {
+		user := enrollment.User
+		displayName := user.ShortName
+		if displayName == "" {
+			displayName = user.Name
+		}
+		people[i] = Person{
+			DisplayName: displayName,
+			Names:       []string{user.Name, user.SortableName, user.ShortName},
+			IDs:         []string{user.LoginID, user.SISUserID},
+			Emails:      []string{user.Email},
+		}
+	}
+	return people
+}
+
+// Redactor masks personal data in text before it leaves the machine
+type Redactor struct {
+	config    Config
+	terms     map[string]*term
+	pattern   *regexp.Regexp
+	idPattern *regexp.Regexp
+}
+
+type term struct {
+	kind   string
+	person *Person
+}
+
+// New builds a redactor for a roster according to config
+func New(people []Person, config Config) (*Redactor, error) {
+	r := &Redactor{config: config, terms: make(map[string]*term)}
+
+	// Roster IDs are always masked with the other identifiers; a pattern is only used when the
+	// course sets one, since broad patterns also catch numeric answers
+	if config.StudentIDs && config.StudentIDPattern != "" {
+		idPattern, err := regexp.Compile(config.StudentIDPattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid student ID pattern: %w", err)
+		}
+		r.idPattern = idPattern
+	}
+
+	for i := range people {
+		person := &people[i]
+		if config.Names {
+			for _, name := range person.Names {
+				r.addTerm(name, KindName, person)
+				for _, part := range strings.FieldsFunc(name, isNameSeparator) {
+					r.addTerm(part, KindName, person)
+				}
+			}
+		}
+		if config.StudentIDs {
+			for _, id := range person.IDs {
+				r.addTerm(id, KindStudentID, person)
+			}
+		}
+		if config.Emails {
+			for _, email := range person.Emails {
+				r.addTerm(email, KindEmail, person)
+			}
+		}
+	}
+
+	if len(r.terms) > 0 {
+		// Longest terms first so full names win over their parts
+		keys := make([]string, 0, len(r.terms))
+		for key := range r.terms {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if len(keys[i]) != len(keys[j]) {
+				return len(keys[i]) > len(keys[j])
+			}
+			return keys[i] < keys[j]
+		})
+		for i, key := range keys {
+			keys[i] = termPattern(key, r.terms[key].kind)
+		}
+		r.pattern = regexp.MustCompile(strings.Join(keys, "|"))
+	}
+
+	return r, nil
+}
+
+func (r *Redactor) addTerm(value, kind string, person *Person) {
+	key := strings.ToLower(strings.TrimSpace(value))
+	if utf8.RuneCountInString(key) < minTermLength {
+		return
+	}
+	if existing, ok := r.terms[key]; ok {
+		if existing.person != person {
+			// Shared by two people, e.g. a common first name: mask without attributing it
+			existing.person = nil
+		}
+		return
+	}
+	r.terms[key] = &term{kind: kind, person: person}
+}
+
+// termPattern matches a term regardless of case, except that a single-word name must start
+// with a capital, so a student named Max or Long does not mask max( or long in code and prose
+func termPattern(key, kind string) string {
+	first, size := utf8.DecodeRuneInString(key)
+	if kind != KindName || strings.IndexFunc(key, isNameSeparator) >= 0 || !unicode.IsLetter(first) {
+		return `(?i:` + regexp.QuoteMeta(key) + `)`
+	}
+	capital := regexp.QuoteMeta(string(unicode.ToUpper(first)))
+	if rest := key[size:]; rest != "" {
+		return capital + `(?i:` + regexp.QuoteMeta(rest) + `)`
+	}
+	return capital
+}
+
+func isNameSeparator(r rune) bool {
+	return unicode.IsSpace(r) || r == ','
+}
+
+// Mask replaces personal data in text with tokens recorded in mapping, so the same value
+// always receives the same token. A nil mapping masks irreversibly.
+func (r *Redactor) Mask(text string, mapping *Mapping) string {
+	if r == nil || text == "" {
+		return text
+	}
+
+	if r.config.Emails {
+		text = emailPattern.ReplaceAllStringFunc(text, func(match string) string {
+			if t, ok := r.terms[strings.ToLower(match)]; ok && t.person != nil && t.person.Alias != "" {
+				return t.person.Alias
+			}
+			return mapping.token(KindEmail, match, match)
+		})
+	}
+
+	if r.pattern != nil {
+		text = replaceWholeWords(r.pattern, text, func(match string) string {
+			t := r.terms[strings.ToLower(match)]
+			if t.person == nil {
+				return ambiguousToken
+			}
+			if t.person.Alias != "" {
+				return t.person.Alias
+			}
+			if t.kind == KindName {
+				restored := t.person.DisplayName
+				if restored == "" {
+					restored = match
+				}
+				return mapping.token(KindName, t.person, restored)
+			}
+			return mapping.token(t.kind, match, match)
+		})
+	}
+
+	if r.config.Phones {
+		text = phonePattern.ReplaceAllStringFunc(text, func(match string) string {
+			return mapping.token(KindPhone, match, match)
+		})
+	}
+
+	if r.config.Addresses {
+		text = addressPattern.ReplaceAllStringFunc(text, func(match string) string {
+			return mapping.token(KindAddress, match, match)
+		})
+	}
+
+	if r.idPattern != nil {
+		text = replaceWholeWords(r.idPattern, text, func(match string) string {
+			return mapping.token(KindStudentID, match, match)
+		})
+	}
+
+	return text
+}
+
+// replaceWholeWords replaces pattern matches that are not embedded inside a longer word
+func replaceWholeWords(pattern *regexp.Regexp, text string, replace func(string) string) string {
+	var builder strings.Builder
+	last := 0
+	for _, loc := range pattern.FindAllStringIndex(text, -1) {
+		start, end := loc[0], loc[1]
+		if !isWordBoundary(text, start, end) {
+			continue
+		}
+		builder.WriteString(text[last:start])
+		builder.WriteString(replace(text[start:end]))
+		last = end
+	}
+	builder.WriteString(text[last:])
+	return builder.String()
+}
+
+func isWordBoundary(text string, start, end int) bool {
+	if start > 0 {
+		r, _ := utf8.DecodeLastRuneInString(text[:start])
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			return false
+		}
+	}
+	if end < len(text) {
+		r, _ := utf8.DecodeRuneInString(text[end:])
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			return false
+		}
+	}
+	return true
+}
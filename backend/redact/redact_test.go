@@ -0,0 +1,306 @@
+package redact
+
+import (
+	"path/filepath"
+	"testing"
+
+	"auxa/canvas"
+)
+
+// roster is a synthetic course; none of these people exist
+func roster() []canvas.Enrollment {
+	return []canvas.Enrollment{
+		{User: canvas.User{
+			Name:         "Jane Doe",
+			SortableName: "Doe, Jane",
+			ShortName:    "Jane Doe",
+			LoginID:      "jdoe42",
+			SISUserID:    "S1234567",
+			Email:        "jane.doe@example.edu",
+		}},
+		{User: canvas.User{
+			Name:         "Jane Roe",
+			SortableName: "Roe, Jane",
+			ShortName:    "Jane Roe",
+			LoginID:      "jroe7",
+			Email:        "jroe@example.edu",
+		}},
+		{User: canvas.User{
+			Name:         "Marcus Okafor",
+			SortableName: "Okafor, Marcus",
+			ShortName:    "Marc Okafor",
+			LoginID:      "mokafor",
+			SISUserID:    "S7654321",
+			Email:        "m.okafor@example.edu",
+		}},
+		{User: canvas.User{
+			Name:         "Max Long",
+			SortableName: "Long, Max",
+			ShortName:    "Max Long",
+			LoginID:      "mlong",
+		}},
+	}
+}
+
+func TestMask(t *testing.T) {
+	withPhones := DefaultConfig()
+	withPhones.Phones = true
+	withPattern := DefaultConfig()
+	withPattern.StudentIDPattern = `\b[A-Za-z]?\d{7,9}\b`
+	namesOnly := Config{Enabled: true, Names: true}
+
+	tests := []struct {
+		name   string
+		config Config
+		text   string
+		want   string
+	}{
+		{
+			name:   "full name from the roster",
+			config: DefaultConfig(),
+			text:   "Essay by Marcus Okafor.",
+			want:   "Essay by [NAME_1].",
+		},
+		{
+			name:   "sortable name and name parts share one token",
+			config: DefaultConfig(),
+			text:   "Okafor, Marcus. Later, Okafor argues that Marcus was right.",
+			want:   "[NAME_1]. Later, [NAME_1] argues that [NAME_1] was right.",
+		},
+		{
+			name:   "short name from the roster",
+			config: DefaultConfig(),
+			text:   "Signed, Marc Okafor",
+			want:   "Signed, [NAME_1]",
+		},
+		{
+			name:   "name parts inside longer words are kept",
+			config: DefaultConfig(),
+			text:   "Doesn't Doe-eyed",
+			want:   "Doesn't [NAME_1]-eyed",
+		},
+		{
+			name:   "first name shared by two students is masked without attribution",
+			config: DefaultConfig(),
+			text:   "Jane wrote this",
+			want:   "[STUDENT] wrote this",
+		},
+		{
+			name:   "name parts match only with a capital",
+			config: DefaultConfig(),
+			text:   "Max wrote long total = max(a, b) and MAX LONG signed it",
+			want:   "[NAME_1] wrote long total = max(a, b) and [NAME_1] signed it",
+		},
+		{
+			name:   "roster and other emails",
+			config: DefaultConfig(),
+			text:   "Mail jane.doe@example.edu or tutor@school.org",
+			want:   "Mail [EMAIL_1] or [EMAIL_2]",
+		},
+		{
+			name:   "login and SIS IDs from the roster",
+			config: DefaultConfig(),
+			text:   "Submitted by jdoe42 (S1234567)",
+			want:   "Submitted by [STUDENT_ID_1] ([STUDENT_ID_2])",
+		},
+		{
+			name:   "numbers are left alone without an ID pattern",
+			config: DefaultConfig(),
+			text:   "The answer is 31415926 and S7654321 got it too",
+			want:   "The answer is 31415926 and [STUDENT_ID_1] got it too",
+		},
+		{
+			name:   "ID pattern when the course opts in",
+			config: withPattern,
+			text:   "Student number A01234567, answer 42",
+			want:   "Student number [STUDENT_ID_1], answer 42",
+		},
+		{
+			name:   "phones are left alone by default",
+			config: DefaultConfig(),
+			text:   "Call 555-123-4567",
+			want:   "Call 555-123-4567",
+		},
+		{
+			name:   "phones when the course opts in",
+			config: withPhones,
+			text:   "Call (555) 123-4567 or 555.987.6543",
+			want:   "Call [PHONE_1] or [PHONE_2]",
+		},
+		{
+			name:   "street address with city, state and ZIP",
+			config: DefaultConfig(),
+			text:   "I live at 221 Baker Street, Springfield, IL 62704 now",
+			want:   "I live at [ADDRESS_1] now",
+		},
+		{
+			name:   "disabled kinds are kept",
+			config: namesOnly,
+			text:   "Jane Doe, jdoe42, tutor@school.org, 555-123-4567, 221 Baker Street",
+			want:   "[NAME_1], jdoe42, tutor@school.org, 555-123-4567, 221 Baker Street",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			redactor, err := New(FromEnrollments(roster()), tt.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := redactor.Mask(tt.text, NewMapping()); got != tt.want {
+				t.Errorf("Mask(%q)\n got %q\nwant %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaskWithoutMapping(t *testing.T) {
+	redactor, err := New(FromEnrollments(roster()), DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := redactor.Mask("Jane Doe <jane.doe@example.edu>", nil)
+	if want := "[NAME] <[EMAIL]>"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestMaskUsesAlias(t *testing.T) {
+	people := FromEnrollments(roster())
+	people[2].Alias = "Student Q7"
+	redactor, err := New(people, DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mapping := NewMapping()
+	got := redactor.Mask("Marcus Okafor (m.okafor@example.edu)", mapping)
+	if want := "Student Q7 (Student Q7)"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if mapping.Len() != 0 {
+		t.Errorf("aliases should not be recorded in the mapping, got %d entries", mapping.Len())
+	}
+}
+
+func TestUnmaskRoundTrip(t *testing.T) {
+	config := DefaultConfig()
+	config.Phones = true
+	redactor, err := New(FromEnrollments(roster()), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		text     string
+		response func(masked string) string
+		want     string
+	}{
+		{
+			name:     "masked text restores to the original",
+			text:     "Marcus Okafor (mokafor) lives at 12 Elm Road and answers 555-123-4567.",
+			response: func(masked string) string { return masked },
+			want:     "Marc Okafor (mokafor) lives at 12 Elm Road and answers 555-123-4567.",
+		},
+		{
+			name:     "tokens the model echoed without brackets are restored",
+			text:     "Okafor wrote this",
+			response: func(string) string { return "Great work, NAME_1!" },
+			want:     "Great work, Marc Okafor!",
+		},
+		{
+			name:     "unknown tokens are left as they are",
+			text:     "Okafor wrote this",
+			response: func(string) string { return "[NAME_1] and [NAME_9]" },
+			want:     "Marc Okafor and [NAME_9]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mapping := NewMapping()
+			masked := redactor.Mask(tt.text, mapping)
+			if masked == tt.text {
+				t.Fatalf("nothing was masked in %q", tt.text)
+			}
+			if got := mapping.Unmask(tt.response(masked)); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMappingSummary(t *testing.T) {
+	redactor, err := New(FromEnrollments(roster()), DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mapping := NewMapping()
+	redactor.Mask("Jane Doe, Marcus Okafor, jdoe42, jane.doe@example.edu", mapping)
+	summary := mapping.Summary()
+	want := map[string]int{KindName: 2, KindStudentID: 1, KindEmail: 1}
+	for kind, count := range want {
+		if summary[kind] != count {
+			t.Errorf("summary[%s] = %d, want %d", kind, summary[kind], count)
+		}
+	}
+	if mapping.Len() != 4 {
+		t.Errorf("Len() = %d, want 4", mapping.Len())
+	}
+}
+
+func TestInvalidStudentIDPattern(t *testing.T) {
+	config := DefaultConfig()
+	config.StudentIDPattern = `[`
+	if _, err := New(nil, config); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+}
+
+func TestSettingsPerCourse(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "redaction.json")
+	settings, err := LoadSettings(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := settings.ForCourse("101"); got != DefaultConfig() {
+		t.Errorf("new course got %+v, want the default", got)
+	}
+
+	strict := DefaultConfig()
+	strict.Phones = true
+	strict.StudentIDPattern = `\bU\d{6}\b`
+	if err := settings.SetCourse("101", strict); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := LoadSettings(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := reloaded.ForCourse("101"); got != strict {
+		t.Errorf("course 101 got %+v after reload, want %+v", got, strict)
+	}
+	if got := reloaded.ForCourse("202"); got != DefaultConfig() {
+		t.Errorf("course 202 got %+v, want the default", got)
+	}
+
+	redactor, err := New(nil, reloaded.ForCourse("101"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := redactor.Mask("ID U123456", NewMapping()), "ID [STUDENT_ID_1]"; got != want {
+		t.Errorf("course pattern: got %q, want %q", got, want)
+	}
+
+	if err := reloaded.ResetCourse("101"); err != nil {
+		t.Fatal(err)
+	}
+	if got := reloaded.ForCourse("101"); got != DefaultConfig() {
+		t.Errorf("reset course got %+v, want the default", got)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"auxa/canvas"
+	"auxa/redact"
+
+	"github.com/gin-gonic/gin"
+)
+
+// redactionSettings holds per-course PII redaction config; initialised in main
+var redactionSettings *redact.Settings
+
+// redactorForCourse builds the PII redactor configured for a course, loading the roster
+// when names or IDs are masked. It returns a nil redactor when redaction is disabled and
+// writes an error response and returns false when redaction cannot be guaranteed.
+func redactorForCourse(c *gin.Context, courseID string) (*redact.Redactor, bool) {
+	var client *canvas.Client
+	if needsRoster(courseID) {
+		var ok bool
+		if client, ok = canvasClientFromRequest(c); !ok {
+			return nil, false
+		}
+	}
+
+	redactor, err := courseRedactor(client, courseID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	return redactor, true
+}
+
+// needsRoster reports whether a course's redaction masks roster names or IDs
+func needsRoster(courseID string) bool {
+	config := redactionSettings.ForCourse(courseID)
+	return config.Enabled && courseID != "" && (config.Names || config.StudentIDs || config.Emails)
+}
+
+// courseRedactor builds a course's PII redactor with client, which may be nil when the
+// roster is not needed. It returns a nil redactor when redaction is disabled.
+func courseRedactor(client *canvas.Client, courseID string) (*redact.Redactor, error) {
+	config := redactionSettings.ForCourse(courseID)
+	if !config.Enabled {
+		return nil, nil
+	}
+
+	var people []redact.Person
+	if needsRoster(courseID) {
+		if client == nil {
+			return nil, errors.New("Canvas credentials are needed to load the roster for redaction")
+		}
+		enrollments, err := client.GetCourseEnrollments(courseID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load roster for redaction: %w", err)
+		}
+		people = redact.FromEnrollments(enrollments)
+	}
+
+	return redact.New(people, config)
+}
+
+// Get the PII redaction config for a course
+func getCourseRedaction(c *gin.Context) {
+	c.JSON(http.StatusOK, redactionSettings.ForCourse(c.Param("course_id")))
+}
+
+// Update the PII redaction config for a course
+func updateCourseRedaction(c *gin.Context) {
+	courseID := c.Param("course_id")
+
+	var req redact.Config
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Reject patterns that would fail at grading time
+	if _, err := redact.New(nil, req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := redactionSettings.SetCourse(courseID, req); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, req)
+}
+
+// Restore the default PII redaction config for a course
+func resetCourseRedaction(c *gin.Context) {
+	courseID := c.Param("course_id")
+
+	if err := redactionSettings.ResetCourse(courseID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, redactionSettings.ForCourse(courseID))
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"auxa/canvas"
+	"auxa/reports"
+
+	"github.com/gin-gonic/gin"
+)
+
+var unsafeFileNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// reportFileName names a student's report, e.g. "Essay_1-Ada_Lovelace-42.pdf"
+func reportFileName(assignment *canvas.Assignment, submission canvas.Submission) string {
+	name := fmt.Sprintf("%s-%s-%d", assignment.Name, reports.StudentName(submission), submission.UserID)
+	return strings.Trim(unsafeFileNameChars.ReplaceAllString(name, "_"), "_") + ".pdf"
+}
+
+// loadReportSubmission fetches the assignment and a student's submission for a report. It
+// writes an error response and returns false on failure.
+func loadReportSubmission(c *gin.Context, client *canvas.Client) (*canvas.Assignment, *canvas.Submission, bool) {
+	courseID := c.Param("course_id")
+	assignmentID := c.Param("assignment_id")
+
+	userID, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id must be a number"})
+		return nil, nil, false
+	}
+
+	assignment, err := client.GetAssignment(courseID, assignmentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, nil, false
+	}
+
+	submission, err := findSubmission(client, courseID, assignmentID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, nil, false
+	}
+	if submission == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Submission not found"})
+		return nil, nil, false
+	}
+
+	return assignment, submission, true
+}
+
+// Download a student's feedback report as PDF
+func getSubmissionReport(c *gin.Context) {
+	client, ok := canvasClientFromRequest(c)
+	if !ok {
+		return
+	}
+
+	assignment, submission, ok := loadReportSubmission(c, client)
+	if !ok {
+		return
+	}
+
+	data, err := reports.Render(reports.FromSubmission(assignment, *submission, c.Query("feedback")))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", reportFileName(assignment, *submission)))
+	c.Data(http.StatusOK, "application/pdf", data)
+}
+
+// Download a zip of feedback reports for every submitted or graded submission of an assignment
+func getAssignmentReports(c *gin.Context) {
+	courseID := c.Param("course_id")
+	assignmentID := c.Param("assignment_id")
+	gradedOnly := c.Query("graded_only") == "true"
+
+	client, ok := canvasClientFromRequest(c)
+	if !ok {
+		return
+	}
+
+	assignment, err := client.GetAssignment(courseID, assignmentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	submissions, err := client.GetAssignmentSubmissions(courseID, assignmentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var archive bytes.Buffer
+	zw := zip.NewWriter(&archive)
+	count := 0
+	for _, submission := range submissions {
+		if submission.WorkflowState == "unsubmitted" && submission.GradedAt == nil {
+			continue
+		}
+		if gradedOnly && submission.WorkflowState != "graded" {
+			continue
+		}
+
+		data, err := reports.Render(reports.FromSubmission(assignment, submission, ""))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		w, err := zw.Create(reportFileName(assignment, submission))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if _, err := w.Write(data); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		count++
+	}
+	if err := zw.Close(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if count == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No submissions to report on"})
+		return
+	}
+
+	fileName := strings.Trim(unsafeFileNameChars.ReplaceAllString(assignment.Name, "_"), "_") + "-reports.zip"
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+	c.Data(http.StatusOK, "application/zip", archive.Bytes())
+}
+
+// Render a student's feedback report and attach it to a new submission comment
+func uploadSubmissionReport(c *gin.Context) {
+	var req struct {
+		Feedback     string `json:"feedback"`      // Overall feedback; defaults to the staff comments
+		TextComment  string `json:"text_comment"`  // Text of the comment carrying the report
+		GroupComment bool   `json:"group_comment"` // Send the comment to the whole group
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	client, ok := canvasClientFromRequest(c)
+	if !ok {
+		return
+	}
+
+	assignment, submission, ok := loadReportSubmission(c, client)
+	if !ok {
+		return
+	}
+
+	data, err := reports.Render(reports.FromSubmission(assignment, *submission, req.Feedback))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	courseID := c.Param("course_id")
+	assignmentID := c.Param("assignment_id")
+	userID := c.Param("user_id")
+
+	attachment, err := client.UploadSubmissionCommentFile(courseID, assignmentID, userID, canvas.FileUpload{
+		Name:        reportFileName(assignment, *submission),
+		ContentType: "application/pdf",
+		Size:        int64(len(data)),
+		Content:     bytes.NewReader(data),
+	})
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to upload report: " + err.Error()})
+		return
+	}
+
+	textComment := req.TextComment
+	if textComment == "" {
+		textComment = "Your feedback report is attached."
+	}
+
+	updated, err := client.CommentOnSubmission(courseID, assignmentID, userID, canvas.SubmissionCommentInput{
+		TextComment:  textComment,
+		GroupComment: req.GroupComment,
+		FileIDs:      []int{attachment.ID},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"submission": updated,
+		"file":       attachment,
+	})
+}
@@ -0,0 +1,306 @@
+// Package reports renders per-submission feedback reports as PDF
+package reports
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"auxa/canvas"
+	"auxa/pdf"
+	"auxa/plaintext"
+)
+
+// Report is everything shown in one student's feedback report
+type Report struct {
+	AssignmentName string
+	PointsPossible float64
+	StudentName    string
+	Attempt        int
+	SubmittedAt    *time.Time
+	Late           bool
+	Graded         bool
+	Score          float64
+	Grade          string
+	Criteria       []CriterionResult
+	Feedback       []string // Overall feedback, one entry per comment
+	GeneratedAt    time.Time
+}
+
+// CriterionResult is one rubric row of a report
+type CriterionResult struct {
+	Description string
+	Rating      string
+	Points      *float64
+	MaxPoints   float64
+	Comments    string
+}
+
+// FromSubmission builds a report from a submission's grade, rubric assessment and staff
+// comments. feedback replaces the staff comments as the overall feedback when set.
+func FromSubmission(assignment *canvas.Assignment, submission canvas.Submission, feedback string) Report {
+	r := Report{
+		AssignmentName: assignment.Name,
+		PointsPossible: assignment.PointsPossible,
+		StudentName:    StudentName(submission),
+		Attempt:        submission.Attempt,
+		SubmittedAt:    submission.SubmittedAt,
+		Late:           submission.Late,
+		Graded:         submission.GradedAt != nil || submission.WorkflowState == "graded",
+		Score:          submission.Score,
+		Grade:          submission.Grade,
+		GeneratedAt:    time.Now(),
+	}
+
+	for _, criterion := range assignment.Rubric {
+		result := CriterionResult{
+			Description: criterion.Description,
+			MaxPoints:   criterion.Points,
+		}
+		if assessment, found := submission.RubricAssessment[criterion.ID]; found {
+			result.Points = assessment.Points
+			result.Comments = strings.TrimSpace(assessment.Comments)
+			for _, rating := range criterion.Ratings {
+				if rating.ID != "" && rating.ID == assessment.RatingID {
+					result.Rating = rating.Description
+				}
+			}
+		}
+		r.Criteria = append(r.Criteria, result)
+	}
+
+	if strings.TrimSpace(feedback) != "" {
+		r.Feedback = []string{strings.TrimSpace(feedback)}
+	} else {
+		for _, comment := range submission.SubmissionComments {
+			if comment.AuthorID != submission.UserID && strings.TrimSpace(comment.Comment) != "" {
+				r.Feedback = append(r.Feedback, plaintext.FromHTML(comment.Comment))
+			}
+		}
+	}
+
+	return r
+}
+
+// StudentName names the submission's student, falling back to the anonymous or user ID
+func StudentName(submission canvas.Submission) string {
+	switch {
+	case submission.User != nil && submission.User.Name != "":
+		return submission.User.Name
+	case submission.AnonymousID != "":
+		return "Student " + submission.AnonymousID
+	default:
+		return "Student " + strconv.Itoa(submission.UserID)
+	}
+}
+
+// Page layout in points
+const (
+	margin      = 54.0
+	titleSize   = 18.0
+	headingSize = 13.0
+	bodySize    = 10.0
+	leading     = 13.0
+	cellPadding = 5.0
+)
+
+// Rubric table column widths; the comments column takes the rest of the page
+var columnWidths = []float64{190, 70}
+
+// layout tracks the cursor while content flows down the pages
+type layout struct {
+	doc *pdf.Document
+	y   float64
+}
+
+func (l *layout) bottom() float64 {
+	return l.doc.Height - margin - 2*leading // Room for the footer
+}
+
+// ensure starts a new page unless height fits below the cursor
+func (l *layout) ensure(height float64) bool {
+	if l.doc.PageCount() > 0 && l.y+height <= l.bottom() {
+		return false
+	}
+	l.doc.AddPage()
+	l.y = margin
+	return true
+}
+
+// paragraph writes wrapped text across the page width
+func (l *layout) paragraph(text string, font pdf.Font, size float64) {
+	for _, line := range pdf.Wrap(text, font, size, l.doc.Width-2*margin) {
+		l.ensure(leading)
+		l.y += leading
+		l.doc.Text(margin, l.y, font, size, line)
+	}
+}
+
+func (l *layout) heading(text string) {
+	l.ensure(3 * leading)
+	l.y += leading
+	l.paragraph(text, pdf.Bold, headingSize)
+	l.y += 4
+}
+
+// field writes a bold label followed by its value on one line
+func (l *layout) field(label, value string) {
+	l.ensure(leading)
+	l.y += leading
+	l.doc.Text(margin, l.y, pdf.Bold, bodySize, label)
+	l.doc.Text(margin+110, l.y, pdf.Regular, bodySize, value)
+}
+
+// cellText is a run of wrapped text within a table cell
+type cellText struct {
+	Text string
+	Font pdf.Font
+}
+
+// row draws a table row, moving to a new page and repeating the header when it does not fit
+func (l *layout) row(cells [][]cellText, header bool, repeat func()) {
+	widths := append(append([]float64{}, columnWidths...), l.doc.Width-2*margin-columnWidths[0]-columnWidths[1])
+
+	wrapped := make([][]cellText, len(cells))
+	lines := 0
+	for i, cell := range cells {
+		for _, run := range cell {
+			if run.Text == "" {
+				continue
+			}
+			for _, line := range pdf.Wrap(run.Text, run.Font, bodySize, widths[i]-2*cellPadding) {
+				wrapped[i] = append(wrapped[i], cellText{Text: line, Font: run.Font})
+			}
+		}
+		if len(wrapped[i]) > lines {
+			lines = len(wrapped[i])
+		}
+	}
+
+	// Rows taller than a page are cut short rather than split
+	maxLines := int((l.bottom() - margin - 3*leading) / leading)
+	if lines > maxLines {
+		lines = maxLines
+		for i := range wrapped {
+			if len(wrapped[i]) > maxLines {
+				wrapped[i] = wrapped[i][:maxLines]
+				wrapped[i][maxLines-1].Text += " ..."
+			}
+		}
+	}
+
+	height := float64(lines)*leading + 2*cellPadding
+	if l.ensure(height) && !header && repeat != nil {
+		repeat()
+	}
+
+	if header {
+		l.doc.FillRect(margin, l.y, l.doc.Width-2*margin, height, 0.9)
+	}
+
+	x := margin
+	for i, cell := range wrapped {
+		for j, line := range cell {
+			l.doc.Text(x+cellPadding, l.y+cellPadding+float64(j+1)*leading-3, line.Font, bodySize, line.Text)
+		}
+		l.doc.Line(x, l.y, x, l.y+height, 0.5)
+		x += widths[i]
+	}
+	l.doc.Line(x, l.y, x, l.y+height, 0.5)
+	l.doc.Line(margin, l.y, x, l.y, 0.5)
+	l.doc.Line(margin, l.y+height, x, l.y+height, 0.5)
+
+	l.y += height
+}
+
+// Render lays the report out as a PDF
+func Render(r Report) ([]byte, error) {
+	l := &layout{doc: pdf.New()}
+	l.ensure(0)
+
+	l.y += titleSize
+	l.doc.Text(margin, l.y, pdf.Bold, titleSize, "Feedback Report")
+	l.y += 6
+	l.paragraph(r.AssignmentName, pdf.Regular, headingSize)
+	l.y += leading / 2
+
+	l.field("Student:", r.StudentName)
+	if r.Attempt > 0 {
+		l.field("Attempt:", strconv.Itoa(r.Attempt))
+	}
+	if r.SubmittedAt != nil {
+		l.field("Submitted:", r.SubmittedAt.Local().Format("Jan 2, 2006 3:04 PM"))
+	}
+	l.field("Late:", yesNo(r.Late))
+	l.field("Score:", r.scoreText())
+
+	if len(r.Criteria) > 0 {
+		l.heading("Rubric")
+		header := func() {
+			l.row([][]cellText{
+				{{Text: "Criterion", Font: pdf.Bold}},
+				{{Text: "Points", Font: pdf.Bold}},
+				{{Text: "Comments", Font: pdf.Bold}},
+			}, true, nil)
+		}
+		header()
+
+		for _, criterion := range r.Criteria {
+			points := "-"
+			if criterion.Points != nil {
+				points = formatPoints(*criterion.Points)
+			}
+			l.row([][]cellText{
+				{{Text: criterion.Description, Font: pdf.Bold}, {Text: criterion.Rating, Font: pdf.Regular}},
+				{{Text: points + " / " + formatPoints(criterion.MaxPoints), Font: pdf.Regular}},
+				{{Text: criterion.Comments, Font: pdf.Regular}},
+			}, false, header)
+		}
+	}
+
+	l.heading("Overall Feedback")
+	if len(r.Feedback) == 0 {
+		l.paragraph("No feedback has been left yet.", pdf.Regular, bodySize)
+	}
+	for i, feedback := range r.Feedback {
+		if i > 0 {
+			l.y += leading / 2
+		}
+		l.paragraph(feedback, pdf.Regular, bodySize)
+	}
+
+	// Footers go on once the page count is known
+	generated := "Generated " + r.GeneratedAt.Local().Format("Jan 2, 2006")
+	for i := 0; i < l.doc.PageCount(); i++ {
+		l.doc.SetPage(i)
+		y := l.doc.Height - margin + leading
+		l.doc.Text(margin, y, pdf.Regular, 8, generated)
+		pageLabel := fmt.Sprintf("Page %d of %d", i+1, l.doc.PageCount())
+		l.doc.Text(l.doc.Width-margin-pdf.StringWidth(pageLabel, pdf.Regular, 8), y, pdf.Regular, 8, pageLabel)
+	}
+
+	return l.doc.Bytes()
+}
+
+func (r Report) scoreText() string {
+	if !r.Graded {
+		return "Not graded"
+	}
+	score := formatPoints(r.Score) + " / " + formatPoints(r.PointsPossible)
+	if r.Grade != "" && r.Grade != formatPoints(r.Score) {
+		score += " (" + r.Grade + ")"
+	}
+	return score
+}
+
+func formatPoints(points float64) string {
+	return strconv.FormatFloat(points, 'f', -1, 64)
+}
+
+func yesNo(value bool) string {
+	if value {
+		return "Yes"
+	}
+	return "No"
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"auxa/canvas"
+	"auxa/diff"
+	"auxa/llm"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Unchanged lines kept around each change in diffs shown to the model
+const resubmissionDiffContext = 2
+
+// attemptPair is a resubmission and the earlier attempt it is compared against
+type attemptPair struct {
+	Previous canvas.SubmissionAttempt
+	Current  canvas.SubmissionAttempt
+}
+
+// resolveAttempts picks the attempts to compare. to of 0 means the current attempt; from of 0
+// means the latest attempt before to.
+func resolveAttempts(submission canvas.Submission, from, to int) (*attemptPair, error) {
+	if to == 0 {
+		to = submission.Attempt
+	}
+
+	current, found := submission.HistoryAttempt(to)
+	if !found {
+		if to != submission.Attempt {
+			return nil, fmt.Errorf("attempt %d not found", to)
+		}
+		// Canvas omits history for some submission types; the submission itself is the latest attempt
+		current = &canvas.SubmissionAttempt{
+			ID:             submission.ID,
+			Attempt:        submission.Attempt,
+			SubmittedAt:    submission.SubmittedAt,
+			Grade:          submission.Grade,
+			GraderID:       submission.GraderID,
+			GradedAt:       submission.GradedAt,
+			WorkflowState:  submission.WorkflowState,
+			SubmissionType: submission.SubmissionType,
+			Body:           submission.Body,
+			URL:            submission.URL,
+			Attachments:    submission.Attachments,
+			Late:           submission.Late,
+		}
+	}
+
+	if from == 0 {
+		for _, attempt := range submission.SubmissionHistory {
+			if attempt.Attempt < to && attempt.Attempt > from {
+				from = attempt.Attempt
+			}
+		}
+		if from == 0 {
+			return nil, fmt.Errorf("attempt %d has no earlier attempt to compare against", to)
+		}
+	}
+
+	if from >= to {
+		return nil, fmt.Errorf("from attempt must be earlier than attempt %d", to)
+	}
+
+	previous, found := submission.HistoryAttempt(from)
+	if !found {
+		return nil, fmt.Errorf("attempt %d not found", from)
+	}
+
+	return &attemptPair{Previous: *previous, Current: *current}, nil
+}
+
+// priorFeedback returns the staff comments left on the previous attempt. Comments without an
+// attempt number count when they were made before the current attempt was submitted.
+func priorFeedback(submission canvas.Submission, pair *attemptPair) []string {
+	var comments []string
+	for _, comment := range submission.SubmissionComments {
+		if comment.AuthorID == submission.UserID || strings.TrimSpace(comment.Comment) == "" {
+			continue
+		}
+
+		if comment.Attempt != nil {
+			if *comment.Attempt != pair.Previous.Attempt {
+				continue
+			}
+		} else if comment.CreatedAt == nil || pair.Current.SubmittedAt == nil || !comment.CreatedAt.Before(*pair.Current.SubmittedAt) {
+			continue
+		}
+
+		comments = append(comments, strings.TrimSpace(comment.Comment))
+	}
+	return comments
+}
+
+// formatAttemptScore describes the score an attempt received, if any
+func formatAttemptScore(attempt canvas.SubmissionAttempt, pointsPossible float64) string {
+	if attempt.Score == nil {
+		return "Not graded"
+	}
+	score := fmt.Sprintf("%g/%g", *attempt.Score, pointsPossible)
+	if attempt.Grade != "" && attempt.Grade != strconv.FormatFloat(*attempt.Score, 'f', -1, 64) {
+		score += " (" + attempt.Grade + ")"
+	}
+	return score
+}
+
+// resubmissionContext renders the previous attempt's grade and feedback and the changes since,
+// for appending to a grading prompt
+func resubmissionContext(pair *attemptPair, pointsPossible float64, feedback []string, changes diff.Result) string {
+	var b strings.Builder
+
+	b.WriteString("RESUBMISSION CONTEXT:\n")
+	fmt.Fprintf(&b, "This is attempt %d. The student previously submitted attempt %d.\n", pair.Current.Attempt, pair.Previous.Attempt)
+	fmt.Fprintf(&b, "Previous Score: %s\n", formatAttemptScore(pair.Previous, pointsPossible))
+
+	b.WriteString("\nFEEDBACK ON THE PREVIOUS ATTEMPT:\n")
+	if len(feedback) == 0 {
+		b.WriteString("(No feedback was left on the previous attempt)\n")
+	}
+	for _, comment := range feedback {
+		b.WriteString("- " + comment + "\n")
+	}
+
+	fmt.Fprintf(&b, "\nCHANGES SINCE THE PREVIOUS ATTEMPT (%d lines added, %d removed):\n", changes.Added, changes.Removed)
+	if changes.Changed() {
+		b.WriteString(truncateText(changes.Unified(resubmissionDiffContext), submissionCharacterLimit))
+	} else {
+		b.WriteString("(No changes to the submitted text)\n")
+	}
+
+	b.WriteString("\nGrade the current attempt on its own merits. In your feedback, say which points from the previous feedback were addressed, what improved, and what still needs work.")
+	return b.String()
+}
+
+// applyResubmission adds the previous attempt's feedback and the diff to the request's prompt.
+// It writes an error response and returns false on failure.
+func applyResubmission(c *gin.Context, req *llm.GradingRequest) bool {
+	if req.Resubmission == nil {
+		return true
+	}
+
+	if req.CourseID == "" || req.AssignmentID == "" || req.Resubmission.UserID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "course_id, assignment_id and resubmission.user_id are required for resubmission grading"})
+		return false
+	}
+
+	client, ok := canvasClientFromRequest(c)
+	if !ok {
+		return false
+	}
+
+	assignment, err := client.GetAssignment(req.CourseID, req.AssignmentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+
+	submission, err := findSubmission(client, req.CourseID, req.AssignmentID, req.Resubmission.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	if submission == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Submission not found"})
+		return false
+	}
+
+	pair, err := resolveAttempts(*submission, req.Resubmission.PreviousAttempt, 0)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+
+	changes := diff.Lines(attemptText(client, pair.Previous), attemptText(client, pair.Current))
+	block := resubmissionContext(pair, assignment.PointsPossible, priorFeedback(*submission, pair), changes)
+
+	req.Prompt = strings.TrimRight(req.Prompt, "\n") + "\n\n" + block
+	req.Resubmission = nil
+	return true
+}
+
+// Diff two attempts of a student's submission. from and to default to the previous and
+// current attempts.
+func getSubmissionDiff(c *gin.Context) {
+	courseID := c.Param("course_id")
+	assignmentID := c.Param("assignment_id")
+
+	userID, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id must be a number"})
+		return
+	}
+
+	var from, to int
+	for name, target := range map[string]*int{"from": &from, "to": &to} {
+		if raw := c.Query(name); raw != "" {
+			if *target, err = strconv.Atoi(raw); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": name + " must be a number"})
+				return
+			}
+		}
+	}
+
+	client, ok := canvasClientFromRequest(c)
+	if !ok {
+		return
+	}
+
+	submission, err := findSubmission(client, courseID, assignmentID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if submission == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Submission not found"})
+		return
+	}
+
+	pair, err := resolveAttempts(*submission, from, to)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	changes := diff.Lines(attemptText(client, pair.Previous), attemptText(client, pair.Current))
+
+	feedback := priorFeedback(*submission, pair)
+	if feedback == nil {
+		// Initialize as empty slice to ensure JSON returns [] instead of null
+		feedback = []string{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":           pair.Previous,
+		"to":             pair.Current,
+		"diff":           changes,
+		"unified":        changes.Unified(resubmissionDiffContext),
+		"prior_feedback": feedback,
+	})
+}
@@ -0,0 +1,368 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"auxa/canvas"
+	"auxa/llm"
+	"auxa/plaintext"
+	"auxa/rubrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rubricStore holds the team's shared rubrics; initialised in main
+var rubricStore *rubrics.Store
+
+// List rubrics, optionally for a course and assignment
+func getRubrics(c *gin.Context) {
+	c.JSON(http.StatusOK, rubricStore.List(c.Query("course_id"), c.Query("assignment_id")))
+}
+
+// Get a rubric
+func getRubric(c *gin.Context) {
+	r, found := rubricStore.Get(c.Param("rubric_id"))
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Rubric not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, r)
+}
+
+// Create a rubric
+func createRubric(c *gin.Context) {
+	var req rubrics.Rubric
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	created, err := rubricStore.Create(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, created)
+}
+
+// Replace a rubric
+func updateRubric(c *gin.Context) {
+	var req rubrics.Rubric
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	id := c.Param("rubric_id")
+	if _, found := rubricStore.Get(id); !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Rubric not found"})
+		return
+	}
+
+	updated, err := rubricStore.Update(id, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// Delete a rubric
+func deleteRubric(c *gin.Context) {
+	if err := rubricStore.Delete(c.Param("rubric_id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Rubric deleted"})
+}
+
+// Import a course's Canvas rubrics, updating rubrics imported before
+func importCanvasRubrics(c *gin.Context) {
+	courseID := c.Param("course_id")
+
+	client, ok := canvasClientFromRequest(c)
+	if !ok {
+		return
+	}
+
+	definitions, err := client.GetCourseRubrics(courseID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Initialize as empty slice to ensure JSON returns [] instead of null
+	imported := make([]rubrics.Rubric, 0, len(definitions))
+	for _, definition := range definitions {
+		// The list endpoint may omit associations; fetch them so assignments are linked
+		if len(definition.Associations) == 0 {
+			full, err := client.GetRubric(courseID, strconv.Itoa(definition.ID))
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			definition = *full
+		}
+
+		r := rubrics.FromCanvas(courseID, definition)
+		var saved rubrics.Rubric
+		if existing, found := rubricStore.FindCanvas(courseID, definition.ID); found {
+			r.AssignmentName = existing.AssignmentName
+			saved, err = rubricStore.Update(existing.ID, r)
+		} else {
+			saved, err = rubricStore.Create(r)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to import rubric %q: %v", definition.Title, err)})
+			return
+		}
+		imported = append(imported, saved)
+	}
+
+	c.JSON(http.StatusOK, imported)
+}
+
+// Create or update a structured rubric in Canvas, associated with its assignment when it has one
+func exportRubricToCanvas(c *gin.Context) {
+	var req struct {
+		UseForGrading bool `json:"use_for_grading"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	r, found := rubricStore.Get(c.Param("rubric_id"))
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Rubric not found"})
+		return
+	}
+
+	if r.Type != rubrics.TypeStructured {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only structured rubrics can be exported to Canvas"})
+		return
+	}
+
+	if r.CourseID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Rubric has no course to export to"})
+		return
+	}
+
+	client, ok := canvasClientFromRequest(c)
+	if !ok {
+		return
+	}
+
+	// Rubrics exported before are updated in place rather than duplicated
+	var definition *canvas.RubricDefinition
+	var association *canvas.RubricAssociation
+	var err error
+	if r.CanvasRubricID != 0 {
+		definition, association, err = client.UpdateRubric(r.CourseID, strconv.Itoa(r.CanvasRubricID), r.ToCanvas(), r.AssignmentID, req.UseForGrading)
+	} else {
+		definition, association, err = client.CreateRubric(r.CourseID, r.ToCanvas(), r.AssignmentID, req.UseForGrading)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	r.CanvasRubricID = definition.ID
+	updated, err := rubricStore.Update(r.ID, r)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"rubric":             updated,
+		"canvas_rubric":      definition,
+		"rubric_association": association,
+	})
+}
+
+// Download a structured rubric as CSV
+func exportRubricCSV(c *gin.Context) {
+	r, found := rubricStore.Get(c.Param("rubric_id"))
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Rubric not found"})
+		return
+	}
+
+	if r.Type != rubrics.TypeStructured {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only structured rubrics can be exported as CSV"})
+		return
+	}
+
+	data, err := rubrics.ExportCSV(r.Criteria)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", r.Title+".csv"))
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", data)
+}
+
+// Create a structured rubric from a CSV body. Title, course and assignment come from the
+// query string.
+func importRubricCSV(c *gin.Context) {
+	criteria, err := rubrics.ImportCSV(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	created, err := rubricStore.Create(rubrics.Rubric{
+		Title:        c.Query("title"),
+		Type:         rubrics.TypeStructured,
+		CourseID:     c.Query("course_id"),
+		AssignmentID: c.Query("assignment_id"),
+		Criteria:     criteria,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, created)
+}
+
+// Replace a structured rubric's criteria from a CSV body
+func replaceRubricCSV(c *gin.Context) {
+	r, found := rubricStore.Get(c.Param("rubric_id"))
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Rubric not found"})
+		return
+	}
+
+	criteria, err := rubrics.ImportCSV(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	r.Type = rubrics.TypeStructured
+	r.Content = ""
+	r.FileName = ""
+	r.Criteria = criteria
+	updated, err := rubricStore.Update(r.ID, r)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// Ask the LLM for a structured rubric built from an assignment's description. The rubric is
+// returned for editing and only stored when save is set.
+func generateRubric(c *gin.Context) {
+	courseID := c.Param("course_id")
+	assignmentID := c.Param("assignment_id")
+
+	var req struct {
+		Platform      string  `json:"platform"`
+		APIKey        string  `json:"api_key"`
+		TextModel     string  `json:"text_model"`
+		MaxTokens     int     `json:"max_tokens"`
+		Temperature   float64 `json:"temperature"`
+		CriteriaCount int     `json:"criteria_count"`
+		BypassCache   bool    `json:"bypass_cache"`
+		Save          bool    `json:"save"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Platform == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Platform is required"})
+		return
+	}
+
+	if req.APIKey == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "API key is required"})
+		return
+	}
+
+	client, ok := canvasClientFromRequest(c)
+	if !ok {
+		return
+	}
+
+	assignment, err := client.GetAssignment(courseID, assignmentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if strings.TrimSpace(plaintext.FromHTML(assignment.Description)) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Assignment has no description to build a rubric from"})
+		return
+	}
+
+	generate, ok := feedbackGenerator(c, courseID)
+	if !ok {
+		return
+	}
+
+	resp, err := generate(llm.GradingRequest{
+		Platform:     req.Platform,
+		APIKey:       req.APIKey,
+		TextModel:    req.TextModel,
+		MaxTokens:    req.MaxTokens,
+		Temperature:  req.Temperature,
+		SystemPrompt: rubrics.GenerationSystemPrompt,
+		Prompt:       rubrics.GenerationPrompt(assignment, req.CriteriaCount),
+		CourseID:     courseID,
+		AssignmentID: assignmentID,
+		BypassCache:  req.BypassCache,
+	})
+	if err != nil {
+		writeGenerationError(c, err, "rubric")
+		return
+	}
+
+	title, criteria, err := rubrics.ParseGenerated(resp.Feedback, assignment.PointsPossible)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error(), "raw": resp.Feedback})
+		return
+	}
+	if title == "" {
+		title = assignment.Name + " Rubric"
+	}
+
+	r := rubrics.Rubric{
+		Title:          title,
+		Type:           rubrics.TypeStructured,
+		CourseID:       courseID,
+		AssignmentID:   assignmentID,
+		AssignmentName: assignment.Name,
+		Points:         rubrics.TotalPoints(criteria),
+		Criteria:       criteria,
+	}
+	if req.Save {
+		if r, err = rubricStore.Create(r); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"rubric":   r,
+		"saved":    req.Save,
+		"model":    resp.Model,
+		"usage":    resp.Usage,
+		"cached":   resp.Cached,
+		"warnings": resp.Warnings,
+	})
+}
@@ -0,0 +1,164 @@
+package rubrics
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"auxa/canvas"
+)
+
+// CSV columns: one row per rating, with the criterion repeated on each of its rows. A
+// criterion without ratings has a single row with the rating columns empty.
+var csvHeader = []string{
+	"criterion_id", "criterion", "criterion_long_description", "criterion_points",
+	"rating_id", "rating", "rating_long_description", "rating_points",
+}
+
+// ExportCSV writes structured criteria as CSV
+func ExportCSV(criteria []canvas.Rubric) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+
+	if err := w.Write(csvHeader); err != nil {
+		return nil, err
+	}
+
+	for _, criterion := range criteria {
+		row := []string{criterion.ID, criterion.Description, criterion.LongDescription, formatPoints(criterion.Points)}
+		if len(criterion.Ratings) == 0 {
+			if err := w.Write(append(row, "", "", "", "")); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		for _, rating := range criterion.Ratings {
+			ratingRow := append(append([]string{}, row...), rating.ID, rating.Description, rating.LongDescription, formatPoints(rating.Points))
+			if err := w.Write(ratingRow); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// ImportCSV reads criteria written by ExportCSV. Consecutive rows with the same criterion
+// columns form one criterion; the header row is required so columns can be matched by name.
+// Without a criterion ID, a row with no rating or with a rating the criterion already has
+// starts a new criterion, so criteria sharing a name stay apart.
+func ImportCSV(r io.Reader) ([]canvas.Rubric, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	if _, ok := columns["criterion"]; !ok {
+		return nil, fmt.Errorf("CSV needs a criterion column")
+	}
+
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var criteria []canvas.Rubric
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		id, name := field(record, "criterion_id"), field(record, "criterion")
+		if name == "" {
+			continue
+		}
+
+		points, err := parsePoints(field(record, "criterion_points"))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: criterion_points: %w", line, err)
+		}
+
+		criterion := canvas.Rubric{
+			ID:              id,
+			Description:     name,
+			LongDescription: field(record, "criterion_long_description"),
+			Points:          points,
+		}
+		rating := field(record, "rating")
+
+		last := len(criteria) - 1
+		if last < 0 || !continuesCriterion(criteria[last], criterion, rating) {
+			criteria = append(criteria, criterion)
+			last++
+		}
+
+		if rating != "" {
+			ratingPoints, err := parsePoints(field(record, "rating_points"))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: rating_points: %w", line, err)
+			}
+			criteria[last].Ratings = append(criteria[last].Ratings, canvas.RubricRating{
+				ID:              field(record, "rating_id"),
+				Description:     rating,
+				LongDescription: field(record, "rating_long_description"),
+				Points:          ratingPoints,
+			})
+		}
+	}
+
+	if len(criteria) == 0 {
+		return nil, fmt.Errorf("CSV has no criteria")
+	}
+	return criteria, nil
+}
+
+// continuesCriterion reports whether a row describing next, with the given rating, adds a
+// rating to the criterion before it rather than starting a new one
+func continuesCriterion(previous, next canvas.Rubric, rating string) bool {
+	if previous.ID != next.ID || previous.Description != next.Description ||
+		previous.LongDescription != next.LongDescription || previous.Points != next.Points {
+		return false
+	}
+	if next.ID != "" {
+		return true
+	}
+
+	if rating == "" || len(previous.Ratings) == 0 {
+		return false
+	}
+	for _, existing := range previous.Ratings {
+		if existing.Description == rating {
+			return false
+		}
+	}
+	return true
+}
+
+func formatPoints(points float64) string {
+	return strconv.FormatFloat(points, 'f', -1, 64)
+}
+
+func parsePoints(value string) (float64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.ParseFloat(value, 64)
+}
@@ -0,0 +1,146 @@
+package rubrics
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"auxa/canvas"
+)
+
+func TestCSVRoundTrip(t *testing.T) {
+	criteria := []canvas.Rubric{
+		{
+			ID:              "_101",
+			Description:     "Thesis",
+			LongDescription: "States a clear, arguable claim",
+			Points:          4,
+			Ratings: []canvas.RubricRating{
+				{ID: "r1", Description: "Full marks", LongDescription: "Precise, \"arguable\" claim", Points: 4},
+				{ID: "r2", Description: "Partial", Points: 2.5},
+				{ID: "r3", Description: "No thesis", Points: 0},
+			},
+		},
+		{ID: "_102", Description: "Participation", Points: 1},
+		{
+			Description: "Evidence",
+			Points:      3,
+			Ratings: []canvas.RubricRating{
+				{Description: "Strong", Points: 3},
+				{Description: "Weak", Points: 1},
+			},
+		},
+		{
+			Description: "Evidence",
+			Points:      3,
+			Ratings: []canvas.RubricRating{
+				{Description: "Strong", Points: 3},
+				{Description: "Weak", Points: 1},
+			},
+		},
+	}
+
+	data, err := ExportCSV(criteria)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ImportCSV(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, criteria) {
+		t.Errorf("round trip changed the criteria\n got %+v\nwant %+v\nCSV:\n%s", got, criteria, data)
+	}
+}
+
+func TestImportCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		csv     string
+		want    []canvas.Rubric
+		wantErr string
+	}{
+		{
+			name: "columns are matched by name in any order",
+			csv: "\ufeffRating, Criterion ,Criterion_Points,rating_points\n" +
+				"Good,Style,2,2\n" +
+				"Poor,Style,2,0\n",
+			want: []canvas.Rubric{
+				{Description: "Style", Points: 2, Ratings: []canvas.RubricRating{
+					{Description: "Good", Points: 2},
+					{Description: "Poor", Points: 0},
+				}},
+			},
+		},
+		{
+			name: "rows without a criterion are skipped",
+			csv:  "criterion,criterion_points\n,\nStyle,2\n",
+			want: []canvas.Rubric{{Description: "Style", Points: 2}},
+		},
+		{
+			name: "same-named criteria without IDs and ratings stay apart",
+			csv:  "criterion,criterion_points\nBonus,1\nBonus,1\n",
+			want: []canvas.Rubric{
+				{Description: "Bonus", Points: 1},
+				{Description: "Bonus", Points: 1},
+			},
+		},
+		{
+			name: "different criterion details start a new criterion",
+			csv: "criterion,criterion_points,rating\n" +
+				"Style,2,Good\n" +
+				"Style,3,Great\n",
+			want: []canvas.Rubric{
+				{Description: "Style", Points: 2, Ratings: []canvas.RubricRating{{Description: "Good"}}},
+				{Description: "Style", Points: 3, Ratings: []canvas.RubricRating{{Description: "Great"}}},
+			},
+		},
+		{
+			name: "rows with the same ID share a criterion even if a rating repeats",
+			csv: "criterion_id,criterion,rating\n" +
+				"_1,Style,Good\n" +
+				"_1,Style,Good\n",
+			want: []canvas.Rubric{
+				{ID: "_1", Description: "Style", Ratings: []canvas.RubricRating{
+					{Description: "Good"},
+					{Description: "Good"},
+				}},
+			},
+		},
+		{
+			name:    "criterion column is required",
+			csv:     "title,points\nStyle,2\n",
+			wantErr: "criterion column",
+		},
+		{
+			name:    "invalid points report the line",
+			csv:     "criterion,criterion_points\nStyle,2\nVoice,lots\n",
+			wantErr: "line 3: criterion_points",
+		},
+		{
+			name:    "header only",
+			csv:     "criterion,criterion_points\n",
+			wantErr: "no criteria",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ImportCSV(strings.NewReader(tt.csv))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package rubrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"auxa/canvas"
+	"auxa/plaintext"
+)
+
+// GenerationSystemPrompt instructs the model to answer with a rubric as JSON only
+const GenerationSystemPrompt = `You are an experienced instructor designing grading rubrics. Respond with JSON only, no prose, in this shape:
+{"title": "...", "criteria": [{"description": "...", "long_description": "...", "points": 0, "ratings": [{"description": "...", "long_description": "...", "points": 0}]}]}
+Each criterion's ratings run from full credit down to 0 points; the first rating's points equal the criterion's points. Criterion points must sum exactly to the assignment's total points.`
+
+// GenerationPrompt asks for a rubric for an assignment. criteriaCount of 0 lets the model choose.
+func GenerationPrompt(assignment *canvas.Assignment, criteriaCount int) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Assignment: %s\n", assignment.Name)
+	fmt.Fprintf(&b, "Total Points: %g\n", assignment.PointsPossible)
+	if criteriaCount > 0 {
+		fmt.Fprintf(&b, "Number of Criteria: %d\n", criteriaCount)
+	}
+	fmt.Fprintf(&b, "\nASSIGNMENT DESCRIPTION:\n%s\n\n", plaintext.FromHTML(assignment.Description))
+	b.WriteString("Write a rubric that measures the skills this assignment asks students to demonstrate. ")
+	b.WriteString("Give each criterion three to five ratings with clear, observable descriptions.")
+
+	return b.String()
+}
+
+var jsonObjectPattern = regexp.MustCompile("(?s)```(?:json)?\\s*(\\{.*\\})\\s*```")
+
+// ParseGenerated reads a generated rubric and scales its points to the assignment total.
+// Ratings are sorted from highest to lowest and capped at their criterion's points.
+func ParseGenerated(text string, pointsPossible float64) (string, []canvas.Rubric, error) {
+	raw := strings.TrimSpace(text)
+	if match := jsonObjectPattern.FindStringSubmatch(raw); match != nil {
+		raw = match[1]
+	} else if start, end := strings.Index(raw, "{"), strings.LastIndex(raw, "}"); start >= 0 && end > start {
+		raw = raw[start : end+1]
+	}
+
+	var generated struct {
+		Title    string          `json:"title"`
+		Criteria []canvas.Rubric `json:"criteria"`
+	}
+	if err := json.Unmarshal([]byte(raw), &generated); err != nil {
+		return "", nil, fmt.Errorf("model did not return a JSON rubric: %w", err)
+	}
+
+	criteria := make([]canvas.Rubric, 0, len(generated.Criteria))
+	for _, criterion := range generated.Criteria {
+		if strings.TrimSpace(criterion.Description) == "" || criterion.Points < 0 {
+			continue
+		}
+		criterion.ID = ""
+		criteria = append(criteria, criterion)
+	}
+	if len(criteria) == 0 {
+		return "", nil, fmt.Errorf("model returned a rubric without criteria")
+	}
+
+	scalePoints(criteria, pointsPossible)
+	for i := range criteria {
+		normalizeRatings(&criteria[i])
+	}
+
+	return generated.Title, criteria, nil
+}
+
+// scalePoints rescales criteria so they sum to total, rounding to halves and absorbing the
+// rounding error in the largest criterion
+func scalePoints(criteria []canvas.Rubric, total float64) {
+	sum := TotalPoints(criteria)
+	if total <= 0 || sum == total {
+		return
+	}
+
+	largest := 0
+	for i := range criteria {
+		factor := 1 / float64(len(criteria))
+		if sum > 0 {
+			factor = criteria[i].Points / sum
+		}
+		scaled := math.Round(total*factor*2) / 2
+		for j := range criteria[i].Ratings {
+			if criteria[i].Points > 0 {
+				criteria[i].Ratings[j].Points = math.Round(criteria[i].Ratings[j].Points/criteria[i].Points*scaled*2) / 2
+			}
+		}
+		criteria[i].Points = scaled
+		if criteria[i].Points > criteria[largest].Points {
+			largest = i
+		}
+	}
+
+	remainder := total - TotalPoints(criteria)
+	criteria[largest].Points += remainder
+	if len(criteria[largest].Ratings) > 0 {
+		sort.SliceStable(criteria[largest].Ratings, func(a, b int) bool {
+			return criteria[largest].Ratings[a].Points > criteria[largest].Ratings[b].Points
+		})
+		criteria[largest].Ratings[0].Points = criteria[largest].Points
+	}
+}
+
+// normalizeRatings orders ratings from full credit down and keeps them within the criterion
+func normalizeRatings(criterion *canvas.Rubric) {
+	for i := range criterion.Ratings {
+		criterion.Ratings[i].ID = ""
+		criterion.Ratings[i].Points = math.Max(0, math.Min(criterion.Ratings[i].Points, criterion.Points))
+	}
+	sort.SliceStable(criterion.Ratings, func(a, b int) bool {
+		return criterion.Ratings[a].Points > criterion.Ratings[b].Points
+	})
+	if len(criterion.Ratings) == 0 {
+		criterion.Ratings = []canvas.RubricRating{
+			{Description: "Full Marks", Points: criterion.Points},
+			{Description: "No Marks", Points: 0},
+		}
+	}
+	criterion.Ratings[0].Points = criterion.Points
+}
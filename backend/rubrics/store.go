@@ -0,0 +1,282 @@
+package rubrics
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"auxa/canvas"
+	"auxa/store"
+)
+
+// Rubric types. Text and file rubrics carry free-form content like the dashboard's saved
+// rubrics; structured rubrics carry criteria that round-trip to Canvas and CSV.
+const (
+	TypeText       = "text"
+	TypeFile       = "file"
+	TypeStructured = "structured"
+)
+
+// Rubric is a rubric shared by the TA team
+type Rubric struct {
+	ID               string          `json:"id"`
+	Title            string          `json:"title"`
+	Type             string          `json:"type"`
+	CourseID         string          `json:"course_id,omitempty"`
+	AssignmentID     string          `json:"assignment_id,omitempty"`
+	AssignmentName   string          `json:"assignment_name,omitempty"`
+	Points           float64         `json:"points"`
+	Content          string          `json:"content,omitempty"`   // Text, or a data URL for file rubrics
+	FileName         string          `json:"file_name,omitempty"` // For file rubrics
+	Criteria         []canvas.Rubric `json:"criteria,omitempty"`
+	FreeFormComments bool            `json:"free_form_comments"`
+	CanvasRubricID   int             `json:"canvas_rubric_id,omitempty"` // Set once imported from or exported to Canvas
+	CreatedAt        time.Time       `json:"created_at"`
+	UpdatedAt        time.Time       `json:"updated_at"`
+}
+
+// Validate checks the rubric's type and content, and totals structured points
+func (r *Rubric) Validate() error {
+	if r.Title == "" {
+		return fmt.Errorf("rubric title is required")
+	}
+
+	switch r.Type {
+	case TypeText, TypeFile:
+		if r.Content == "" {
+			return fmt.Errorf("%s rubrics need content", r.Type)
+		}
+	case TypeStructured:
+		if len(r.Criteria) == 0 {
+			return fmt.Errorf("structured rubrics need at least one criterion")
+		}
+		r.Points = TotalPoints(r.Criteria)
+	default:
+		return fmt.Errorf("unsupported rubric type: %q", r.Type)
+	}
+	return nil
+}
+
+// TotalPoints sums the criteria's points
+func TotalPoints(criteria []canvas.Rubric) float64 {
+	var total float64
+	for _, criterion := range criteria {
+		total += criterion.Points
+	}
+	return total
+}
+
+// Text renders the rubric for a grading prompt. File rubrics have no text form.
+func (r Rubric) Text() string {
+	if r.Type == TypeText {
+		return r.Content
+	}
+	if r.Type != TypeStructured {
+		return ""
+	}
+
+	var b strings.Builder
+	for _, criterion := range r.Criteria {
+		fmt.Fprintf(&b, "- %s (%g pts)", criterion.Description, criterion.Points)
+		if criterion.LongDescription != "" {
+			fmt.Fprintf(&b, ": %s", criterion.LongDescription)
+		}
+		b.WriteString("\n")
+		for _, rating := range criterion.Ratings {
+			fmt.Fprintf(&b, "    - %g: %s", rating.Points, rating.Description)
+			if rating.LongDescription != "" {
+				fmt.Fprintf(&b, " (%s)", rating.LongDescription)
+			}
+			b.WriteString("\n")
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// FromCanvas converts a Canvas rubric, taking the assignment from its first assignment association
+func FromCanvas(courseID string, definition canvas.RubricDefinition) Rubric {
+	r := Rubric{
+		Title:            definition.Title,
+		Type:             TypeStructured,
+		CourseID:         courseID,
+		Points:           definition.PointsPossible,
+		Criteria:         definition.Data,
+		FreeFormComments: definition.FreeFormCriterionComments,
+		CanvasRubricID:   definition.ID,
+	}
+	for _, association := range definition.Associations {
+		if association.AssociationType == "Assignment" {
+			r.AssignmentID = strconv.Itoa(association.AssociationID)
+			break
+		}
+	}
+	return r
+}
+
+// ToCanvas converts a structured rubric into a Canvas rubric definition
+func (r Rubric) ToCanvas() canvas.RubricDefinition {
+	return canvas.RubricDefinition{
+		ID:                        r.CanvasRubricID,
+		Title:                     r.Title,
+		PointsPossible:            r.Points,
+		FreeFormCriterionComments: r.FreeFormComments,
+		Data:                      r.Criteria,
+	}
+}
+
+// Store persists the team's rubrics
+type Store struct {
+	mu      sync.RWMutex
+	path    string
+	rubrics map[string]Rubric
+}
+
+// LoadStore reads rubrics from path
+func LoadStore(path string) (*Store, error) {
+	s := &Store{path: path, rubrics: make(map[string]Rubric)}
+	if err := store.LoadJSON(path, &s.rubrics); err != nil {
+		return nil, err
+	}
+	if s.rubrics == nil {
+		s.rubrics = make(map[string]Rubric)
+	}
+	return s, nil
+}
+
+func (s *Store) save() error {
+	return store.SaveJSON(s.path, s.rubrics)
+}
+
+// List returns rubrics, optionally filtered by course and assignment, sorted by title
+func (s *Store) List(courseID, assignmentID string) []Rubric {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// Initialize as empty slice to ensure JSON returns [] instead of null
+	list := make([]Rubric, 0, len(s.rubrics))
+	for _, r := range s.rubrics {
+		if courseID != "" && r.CourseID != courseID {
+			continue
+		}
+		if assignmentID != "" && r.AssignmentID != assignmentID {
+			continue
+		}
+		list = append(list, r)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Title != list[j].Title {
+			return list[i].Title < list[j].Title
+		}
+		return list[i].ID < list[j].ID
+	})
+	return list
+}
+
+// Get returns a rubric by ID
+func (s *Store) Get(id string) (Rubric, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	r, ok := s.rubrics[id]
+	return r, ok
+}
+
+// ForAssignment returns the most recently updated rubric for an assignment
+func (s *Store) ForAssignment(courseID, assignmentID string) (Rubric, bool) {
+	var latest Rubric
+	found := false
+	for _, r := range s.List(courseID, assignmentID) {
+		if !found || r.UpdatedAt.After(latest.UpdatedAt) {
+			latest, found = r, true
+		}
+	}
+	return latest, found
+}
+
+// FindCanvas returns the rubric imported from or exported to a Canvas rubric
+func (s *Store) FindCanvas(courseID string, canvasRubricID int) (Rubric, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, r := range s.rubrics {
+		if r.CourseID == courseID && r.CanvasRubricID == canvasRubricID {
+			return r, true
+		}
+	}
+	return Rubric{}, false
+}
+
+// Create validates and stores a new rubric
+func (s *Store) Create(r Rubric) (Rubric, error) {
+	if err := r.Validate(); err != nil {
+		return Rubric{}, err
+	}
+
+	idBytes := make([]byte, 8)
+	if _, err := rand.Read(idBytes); err != nil {
+		return Rubric{}, fmt.Errorf("failed to generate rubric ID: %w", err)
+	}
+	r.ID = hex.EncodeToString(idBytes)
+	r.CreatedAt = time.Now()
+	r.UpdatedAt = r.CreatedAt
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rubrics[r.ID] = r
+	if err := s.save(); err != nil {
+		delete(s.rubrics, r.ID)
+		return Rubric{}, err
+	}
+	return r, nil
+}
+
+// Update validates and replaces an existing rubric
+func (s *Store) Update(id string, r Rubric) (Rubric, error) {
+	if err := r.Validate(); err != nil {
+		return Rubric{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.rubrics[id]
+	if !ok {
+		return Rubric{}, fmt.Errorf("rubric %s not found", id)
+	}
+
+	r.ID = id
+	r.CreatedAt = existing.CreatedAt
+	r.UpdatedAt = time.Now()
+
+	s.rubrics[id] = r
+	if err := s.save(); err != nil {
+		s.rubrics[id] = existing
+		return Rubric{}, err
+	}
+	return r, nil
+}
+
+// Delete removes a rubric
+func (s *Store) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.rubrics[id]
+	if !ok {
+		return fmt.Errorf("rubric %s not found", id)
+	}
+
+	delete(s.rubrics, id)
+	if err := s.save(); err != nil {
+		s.rubrics[id] = existing
+		return err
+	}
+	return nil
+}
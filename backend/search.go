@@ -0,0 +1,317 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"auxa/canvas"
+	"auxa/llm"
+	"auxa/redact"
+	"auxa/search"
+	"auxa/usage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// feedbackIndex holds feedback posted through Auxa for semantic search; initialised in main
+var feedbackIndex *search.Index
+
+// Defaults for feedback search
+const (
+	defaultSearchLimit    = 10
+	defaultSearchMinScore = 0.2
+)
+
+// indexFeedback adds posted feedback to the search index with the course's PII masked, so
+// neither the index nor an embedding provider holds student names. Failures are logged
+// rather than surfaced, since the feedback has already reached Canvas.
+func indexFeedback(client *canvas.Client, entries ...search.Entry) {
+	if err := redactEntries(client, entries); err != nil {
+		fmt.Printf("[Search] failed to index feedback: %v\n", err)
+		return
+	}
+	if _, err := feedbackIndex.Add(entries...); err != nil {
+		fmt.Printf("[Search] failed to index feedback: %v\n", err)
+	}
+}
+
+// redactEntries masks PII in each entry's text with its course's redactor
+func redactEntries(client *canvas.Client, entries []search.Entry) error {
+	redactors := map[string]*redact.Redactor{}
+	for i := range entries {
+		courseID := entries[i].CourseID
+		redactor, built := redactors[courseID]
+		if !built {
+			var err error
+			if redactor, err = courseRedactor(client, courseID); err != nil {
+				return err
+			}
+			redactors[courseID] = redactor
+		}
+		if redactor != nil {
+			entries[i].Text = redactor.Mask(entries[i].Text, nil)
+		}
+	}
+	return nil
+}
+
+// criterionEntries indexes each criterion comment of a rubric assessment
+func criterionEntries(courseID, assignmentID string, userID int, assessment canvas.RubricAssessment) []search.Entry {
+	var entries []search.Entry
+	for criterionID, criterion := range assessment {
+		entries = append(entries, search.Entry{
+			Kind:         search.KindCriterion,
+			CourseID:     courseID,
+			AssignmentID: assignmentID,
+			UserID:       userID,
+			CriterionID:  criterionID,
+			Text:         criterion.Comments,
+		})
+	}
+	return entries
+}
+
+// embed is the metered embedding call: provider calls are admitted under the spending caps
+// and recorded in the usage ledger like grading calls
+func embed(req llm.EmbeddingRequest) (*llm.EmbeddingResponse, error) {
+	if req.Platform != llm.PlatformLocal {
+		reservation, _, err := quotas.Reserve(req.CourseID, req.GraderID, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		defer reservation.Release()
+	}
+
+	resp, err := llm.Embed(req)
+	if err == nil && resp.Usage != nil {
+		recordUsage(usage.Entry{
+			Kind:          usage.KindEmbedding,
+			CourseID:      req.CourseID,
+			AssignmentID:  req.AssignmentID,
+			GraderID:      req.GraderID,
+			Platform:      req.Platform,
+			Model:         resp.Model,
+			InputTokens:   resp.Usage.InputTokens,
+			EstimatedCost: resp.Usage.EstimatedCost,
+		})
+	}
+	return resp, err
+}
+
+// List indexed feedback, optionally filtered by course, assignment, criterion and kind
+func getFeedbackIndex(c *gin.Context) {
+	c.JSON(http.StatusOK, feedbackIndex.List(search.Filter{
+		CourseID:     c.Query("course_id"),
+		AssignmentID: c.Query("assignment_id"),
+		CriterionID:  c.Query("criterion_id"),
+		Kind:         c.Query("kind"),
+	}))
+}
+
+// Add feedback to the index by hand, such as feedback posted before indexing existed
+func addFeedbackToIndex(c *gin.Context) {
+	var req struct {
+		Entries []search.Entry `json:"entries"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	for _, entry := range req.Entries {
+		if entry.CourseID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Every entry needs a course_id"})
+			return
+		}
+	}
+
+	client, ok := canvasClientFromRequest(c)
+	if !ok {
+		return
+	}
+	if err := redactEntries(client, req.Entries); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	added, err := feedbackIndex.Add(req.Entries...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, added)
+}
+
+// Remove feedback from the index
+func deleteFeedbackFromIndex(c *gin.Context) {
+	if err := feedbackIndex.Delete(c.Param("entry_id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Feedback removed from index"})
+}
+
+// Find past feedback similar to a query, or to a student's submission when user_id is given
+// instead. Feedback not yet embedded with the chosen model is embedded first. Text sent to
+// an embedding provider is redacted like grading prompts, and scrubbed of roster names too
+// when anonymize is set.
+func searchFeedback(c *gin.Context) {
+	var req struct {
+		Platform     string  `json:"platform"`
+		APIKey       string  `json:"api_key"`
+		Model        string  `json:"model"`
+		Query        string  `json:"query"`
+		CourseID     string  `json:"course_id"`
+		AssignmentID string  `json:"assignment_id"`
+		CriterionID  string  `json:"criterion_id"`
+		Kind         string  `json:"kind"`
+		UserID       int     `json:"user_id"`
+		Anonymize    bool    `json:"anonymize"`
+		Limit        int     `json:"limit"`
+		MinScore     float64 `json:"min_score"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Platform == "" {
+		req.Platform = llm.PlatformLocal
+	}
+	if req.Platform != llm.PlatformLocal && req.APIKey == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "API key is required"})
+		return
+	}
+	if req.Model == "" {
+		req.Model = llm.DefaultEmbeddingModel(req.Platform)
+	}
+	if req.Limit <= 0 {
+		req.Limit = defaultSearchLimit
+	}
+	if req.MinScore == 0 {
+		req.MinScore = defaultSearchMinScore
+	}
+
+	var client *canvas.Client
+	query := req.Query
+	if query == "" {
+		if req.UserID == 0 || req.CourseID == "" || req.AssignmentID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A query, or course_id, assignment_id and user_id of a submission, is required"})
+			return
+		}
+
+		var ok bool
+		if client, ok = canvasClientFromRequest(c); !ok {
+			return
+		}
+
+		submission, err := findSubmission(client, req.CourseID, req.AssignmentID, req.UserID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if submission == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Submission not found"})
+			return
+		}
+		query = submissionContent(client, *submission)
+	}
+
+	filter := search.Filter{
+		CourseID:      req.CourseID,
+		AssignmentID:  req.AssignmentID,
+		CriterionID:   req.CriterionID,
+		Kind:          req.Kind,
+		ExcludeUserID: req.UserID, // Feedback the student already received is no help
+	}
+	embedding := llm.EmbeddingRequest{
+		Platform:     req.Platform,
+		APIKey:       req.APIKey,
+		Model:        req.Model,
+		CourseID:     req.CourseID,
+		AssignmentID: req.AssignmentID,
+	}
+
+	pending := feedbackIndex.Unembedded(req.Model, filter)
+	if req.Platform != llm.PlatformLocal {
+		graderID, ok := graderFromRequest(c)
+		if !ok {
+			return
+		}
+		embedding.GraderID = graderID
+
+		if client == nil {
+			if client, ok = canvasClientFromRequest(c); !ok {
+				return
+			}
+		}
+		if !redactForEmbedding(c, client, req.CourseID, req.Anonymize, pending, &query) {
+			return
+		}
+	}
+
+	if len(pending) > 0 {
+		embedding.Texts = make([]string, len(pending))
+		for i, entry := range pending {
+			embedding.Texts[i] = entry.Text
+		}
+		resp, err := embed(embedding)
+		if err != nil {
+			writeGenerationError(c, err, "hits")
+			return
+		}
+
+		vectors := make(map[string][]float32, len(pending))
+		for i, entry := range pending {
+			vectors[entry.ID] = resp.Vectors[i]
+		}
+		if err := feedbackIndex.SetVectors(req.Model, vectors); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	embedding.Texts = []string{query}
+	resp, err := embed(embedding)
+	if err != nil {
+		writeGenerationError(c, err, "hits")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"hits":     feedbackIndex.Search(req.Model, resp.Vectors[0], filter, req.Limit, req.MinScore),
+		"model":    req.Model,
+		"embedded": len(pending),
+	})
+}
+
+// redactForEmbedding masks PII in pending entries and the query before they go to an
+// embedding provider. Entries indexed before redaction are masked here too. It writes an
+// error response and returns false when redaction cannot be guaranteed.
+func redactForEmbedding(c *gin.Context, client *canvas.Client, courseID string, anonymize bool, pending []search.Entry, query *string) bool {
+	if err := redactEntries(client, pending); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	redactor, err := courseRedactor(client, courseID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	if redactor != nil {
+		*query = redactor.Mask(*query, nil)
+	}
+
+	if !anonymize {
+		return true
+	}
+	texts := []*string{query}
+	for i := range pending {
+		texts = append(texts, &pending[i].Text)
+	}
+	return scrubForProvider(c, courseID, texts...)
+}
@@ -0,0 +1,299 @@
+// Package search indexes feedback posted through Auxa so TAs can find and reuse similar
+// past feedback. Entries are embedded lazily, once per embedding model, the first time a
+// search with that model needs them.
+package search
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"auxa/store"
+)
+
+// Entry kinds
+const (
+	KindComment   = "comment"   // A submission comment or the comment posted with a grade
+	KindCriterion = "criterion" // A comment on one rubric criterion
+	KindFeedback  = "feedback"  // Feedback added to the index by hand, e.g. an edited AI draft
+)
+
+// Entry is one piece of indexed feedback
+type Entry struct {
+	ID           string    `json:"id"`
+	Kind         string    `json:"kind"`
+	CourseID     string    `json:"course_id"`
+	AssignmentID string    `json:"assignment_id,omitempty"`
+	UserID       int       `json:"user_id,omitempty"` // The student the feedback was for
+	CriterionID  string    `json:"criterion_id,omitempty"`
+	GraderID     string    `json:"grader_id,omitempty"`
+	Text         string    `json:"text"`
+	CreatedAt    time.Time `json:"created_at"`
+
+	// Unit-length embeddings by model; left out of API responses
+	Vectors map[string][]float32 `json:"vectors,omitempty"`
+}
+
+// Filter narrows entries; empty fields match everything
+type Filter struct {
+	CourseID      string
+	AssignmentID  string
+	CriterionID   string
+	Kind          string
+	ExcludeUserID int // Leave out feedback written for this student
+}
+
+func (f Filter) matches(e *Entry) bool {
+	if f.CourseID != "" && e.CourseID != f.CourseID {
+		return false
+	}
+	if f.AssignmentID != "" && e.AssignmentID != f.AssignmentID {
+		return false
+	}
+	if f.CriterionID != "" && e.CriterionID != f.CriterionID {
+		return false
+	}
+	if f.Kind != "" && e.Kind != f.Kind {
+		return false
+	}
+	if f.ExcludeUserID != 0 && e.UserID == f.ExcludeUserID {
+		return false
+	}
+	return true
+}
+
+// Index persists indexed feedback
+type Index struct {
+	mu      sync.RWMutex
+	path    string
+	entries map[string]*Entry
+}
+
+// LoadIndex reads the index from path
+func LoadIndex(path string) (*Index, error) {
+	idx := &Index{path: path, entries: make(map[string]*Entry)}
+	if err := store.LoadJSON(path, &idx.entries); err != nil {
+		return nil, err
+	}
+	if idx.entries == nil {
+		idx.entries = make(map[string]*Entry)
+	}
+	return idx, nil
+}
+
+func (idx *Index) save() error {
+	return store.SaveJSON(idx.path, idx.entries)
+}
+
+// normalizeText collapses whitespace and case so reposted feedback is recognised
+func normalizeText(text string) string {
+	return strings.ToLower(strings.Join(strings.Fields(text), " "))
+}
+
+// key identifies feedback so posting the same text to the same student twice indexes it once
+func key(e Entry) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		e.CourseID, e.AssignmentID, strconv.Itoa(e.UserID), e.CriterionID, normalizeText(e.Text),
+	}, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// Add stores new entries, skipping blank text and feedback already indexed. It returns the
+// entries that were added.
+func (idx *Index) Add(entries ...Entry) ([]Entry, error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	existing := make(map[string]bool, len(idx.entries))
+	for _, e := range idx.entries {
+		existing[key(*e)] = true
+	}
+
+	added := make([]Entry, 0, len(entries))
+	for _, e := range entries {
+		e.Text = strings.TrimSpace(e.Text)
+		if e.Text == "" || e.CourseID == "" {
+			continue
+		}
+		if e.Kind == "" {
+			e.Kind = KindFeedback
+		}
+		k := key(e)
+		if existing[k] {
+			continue
+		}
+		existing[k] = true
+
+		idBytes := make([]byte, 8)
+		if _, err := rand.Read(idBytes); err != nil {
+			return nil, fmt.Errorf("failed to generate entry ID: %w", err)
+		}
+		e.ID = hex.EncodeToString(idBytes)
+		e.CreatedAt = time.Now()
+		e.Vectors = nil
+		added = append(added, e)
+	}
+	if len(added) == 0 {
+		return added, nil
+	}
+
+	for i := range added {
+		idx.entries[added[i].ID] = &added[i]
+	}
+	if err := idx.save(); err != nil {
+		for _, e := range added {
+			delete(idx.entries, e.ID)
+		}
+		return nil, err
+	}
+
+	return append([]Entry(nil), added...), nil
+}
+
+// List returns matching entries without their vectors, newest first
+func (idx *Index) List(filter Filter) []Entry {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	// Initialize as empty slice to ensure JSON returns [] instead of null
+	list := make([]Entry, 0)
+	for _, e := range idx.entries {
+		if filter.matches(e) {
+			entry := *e
+			entry.Vectors = nil
+			list = append(list, entry)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.After(list[j].CreatedAt)
+	})
+	return list
+}
+
+// Delete removes an entry
+func (idx *Index) Delete(id string) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	existing, ok := idx.entries[id]
+	if !ok {
+		return fmt.Errorf("entry %s not found", id)
+	}
+
+	delete(idx.entries, id)
+	if err := idx.save(); err != nil {
+		idx.entries[id] = existing
+		return err
+	}
+	return nil
+}
+
+// Unembedded returns matching entries with no vector for model, oldest first
+func (idx *Index) Unembedded(model string, filter Filter) []Entry {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var list []Entry
+	for _, e := range idx.entries {
+		if _, ok := e.Vectors[model]; !ok && filter.matches(e) {
+			list = append(list, Entry{ID: e.ID, CourseID: e.CourseID, Text: e.Text, CreatedAt: e.CreatedAt})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.Before(list[j].CreatedAt)
+	})
+	return list
+}
+
+// SetVectors stores model's vectors for entries by ID. Entries deleted meanwhile are skipped.
+func (idx *Index) SetVectors(model string, vectors map[string][]float32) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	var updated []*Entry
+	for id, vector := range vectors {
+		e, ok := idx.entries[id]
+		if !ok {
+			continue
+		}
+		if e.Vectors == nil {
+			e.Vectors = make(map[string][]float32)
+		}
+		e.Vectors[model] = vector
+		updated = append(updated, e)
+	}
+
+	if err := idx.save(); err != nil {
+		for _, e := range updated {
+			delete(e.Vectors, model)
+		}
+		return err
+	}
+	return nil
+}
+
+// Hit is a search result. Identical feedback given to several students is returned once,
+// with Uses counting how often it was given.
+type Hit struct {
+	Entry
+	Score float64 `json:"score"` // Cosine similarity to the query
+	Uses  int     `json:"uses"`
+}
+
+// Search ranks matching entries embedded with model by cosine similarity to query, a
+// unit-length vector from the same model. Hits scoring below minScore are dropped.
+func (idx *Index) Search(model string, query []float32, filter Filter, limit int, minScore float64) []Hit {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	byText := map[string]*Hit{}
+	for _, e := range idx.entries {
+		vector, ok := e.Vectors[model]
+		if !ok || len(vector) != len(query) || !filter.matches(e) {
+			continue
+		}
+
+		var score float64
+		for i := range query {
+			score += float64(query[i]) * float64(vector[i])
+		}
+		if score < minScore {
+			continue
+		}
+
+		text := normalizeText(e.Text)
+		if hit, ok := byText[text]; ok {
+			hit.Uses++
+			if e.CreatedAt.After(hit.CreatedAt) {
+				hit.Entry = *e
+				hit.Entry.Vectors = nil
+			}
+			continue
+		}
+		hit := &Hit{Entry: *e, Score: score, Uses: 1}
+		hit.Entry.Vectors = nil
+		byText[text] = hit
+	}
+
+	// Initialize as empty slice to ensure JSON returns [] instead of null
+	hits := make([]Hit, 0, len(byText))
+	for _, hit := range byText {
+		hits = append(hits, *hit)
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].Uses > hits[j].Uses
+	})
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"auxa/canvas"
+	"auxa/jobs"
+	"auxa/notebooks"
+	"auxa/plaintext"
+	"auxa/similarity"
+
+	"github.com/gin-gonic/gin"
+)
+
+const similarityJobKind = "similarity"
+
+// similarityJobState records which assignment a similarity run covers
+type similarityJobState struct {
+	CourseID     string `json:"course_id"`
+	AssignmentID string `json:"assignment_id"`
+}
+
+// similarityDocument gathers the full text of a submission's body and its text, code and
+// notebook attachments. Other files are counted in skipped.
+func similarityDocument(client *canvas.Client, submission canvas.Submission, anonymous bool) (similarity.Document, int, error) {
+	doc := similarity.Document{ID: submission.UserID, Name: "user-" + strconv.Itoa(submission.UserID)}
+	switch {
+	case anonymous && submission.AnonymousID != "":
+		doc.ID, doc.Name = submission.ID, submission.AnonymousID
+	case anonymous:
+		doc.ID, doc.Name = submission.ID, "student-"+strconv.Itoa(submission.ID)
+	case submission.User != nil && submission.User.Name != "":
+		doc.Name = submission.User.Name
+	}
+
+	if body := strings.TrimSpace(plaintext.FromHTML(submission.Body)); body != "" {
+		doc.Files = append(doc.Files, similarity.File{Name: "text entry", Content: body})
+	}
+
+	skipped := 0
+	for _, attachment := range submission.Attachments {
+		name := attachmentName(attachment)
+		ext := strings.ToLower(filepath.Ext(name))
+		isNotebook := ext == ".ipynb"
+		if !isNotebook && !similarity.IsCode(name) && !textAttachmentExtensions[ext] && !strings.HasPrefix(attachment.ContentType, "text/") {
+			skipped++
+			continue
+		}
+
+		limit := int64(maxAttachmentBytes)
+		if isNotebook {
+			limit = maxNotebookBytes
+		}
+		data, err := client.DownloadFile(attachment.URL, limit)
+		if err != nil {
+			return doc, skipped, fmt.Errorf("failed to download %s: %w", name, err)
+		}
+
+		if isNotebook {
+			doc.Files = append(doc.Files, notebookFiles(name, data)...)
+			continue
+		}
+		doc.Files = append(doc.Files, similarity.File{Name: name, Content: string(data)})
+	}
+	return doc, skipped, nil
+}
+
+// notebookFiles splits a notebook into its code, fingerprinted as Python, and its markdown,
+// fingerprinted as prose, so saved outputs do not count towards similarity
+func notebookFiles(name string, data []byte) []similarity.File {
+	nb, err := notebooks.Parse(data)
+	if err != nil {
+		return []similarity.File{{Name: name, Content: string(data)}}
+	}
+
+	var code, prose []string
+	for _, cell := range nb.Cells {
+		switch cell.Type {
+		case notebooks.CellCode:
+			code = append(code, cell.Source)
+		case notebooks.CellMarkdown:
+			prose = append(prose, cell.Source)
+		}
+	}
+
+	var files []similarity.File
+	if len(code) > 0 {
+		ext := ".py"
+		if !nb.IsPython() {
+			ext = ".txt"
+		}
+		files = append(files, similarity.File{Name: name + " (code)" + ext, Content: strings.Join(code, "\n")})
+	}
+	if len(prose) > 0 {
+		files = append(files, similarity.File{Name: name + " (markdown)", Content: strings.Join(prose, "\n")})
+	}
+	return files
+}
+
+// Start a similarity analysis of an assignment's submissions. Starter code the instructor
+// handed out is given by file name so it is fingerprinted the same way as submissions.
+func startSimilarity(c *gin.Context) {
+	courseID := c.Param("course_id")
+	assignmentID := c.Param("assignment_id")
+
+	var req struct {
+		similarity.Options
+		StarterFiles  map[string]string `json:"starter_files"`  // File name to content
+		UserIDs       []int             `json:"user_ids"`       // Limit the analysis to these students
+		SubmissionIDs []int             `json:"submission_ids"` // Or to these submissions, as anonymous reports identify them
+		AnonymousIDs  []string          `json:"anonymous_ids"`  // Or to these anonymously graded submissions
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := req.Options.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	client, ok := canvasClientFromRequest(c)
+	if !ok {
+		return
+	}
+
+	assignment, err := client.GetAssignment(courseID, assignmentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	anonymous := assignment.AnonymousGrading
+	if anonymous && len(req.UserIDs) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Students of anonymously graded assignments are chosen by submission_ids or anonymous_ids, not user_ids"})
+		return
+	}
+
+	var submissions []canvas.Submission
+	if anonymous {
+		submissions, err = client.GetAnonymousAssignmentSubmissions(courseID, assignmentID)
+	} else {
+		submissions, err = client.GetAssignmentSubmissions(courseID, assignmentID)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	filtered := len(req.UserIDs) > 0 || len(req.SubmissionIDs) > 0 || len(req.AnonymousIDs) > 0
+	wantedUsers, wantedSubmissions, wantedAnonymous := map[int]bool{}, map[int]bool{}, map[string]bool{}
+	for _, userID := range req.UserIDs {
+		wantedUsers[userID] = true
+	}
+	for _, submissionID := range req.SubmissionIDs {
+		wantedSubmissions[submissionID] = true
+	}
+	for _, anonymousID := range req.AnonymousIDs {
+		wantedAnonymous[anonymousID] = true
+	}
+	var targets []canvas.Submission
+	for _, submission := range submissions {
+		if submission.SubmittedAt == nil {
+			continue
+		}
+		if filtered && !wantedUsers[submission.UserID] && !wantedSubmissions[submission.ID] &&
+			!(submission.AnonymousID != "" && wantedAnonymous[submission.AnonymousID]) {
+			continue
+		}
+		targets = append(targets, submission)
+	}
+	if len(targets) < 2 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least two submissions are needed to compare"})
+		return
+	}
+
+	// Initialize as empty slice to ensure JSON returns [] instead of null
+	starter := make([]similarity.File, 0, len(req.StarterFiles))
+	for name, content := range req.StarterFiles {
+		if strings.EqualFold(filepath.Ext(name), ".ipynb") {
+			starter = append(starter, notebookFiles(name, []byte(content))...)
+			continue
+		}
+		starter = append(starter, similarity.File{Name: name, Content: content})
+	}
+
+	job, err := jobManager.Create(similarityJobKind, len(targets), similarityJobState{CourseID: courseID, AssignmentID: assignmentID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	jobManager.Run(job.ID, func() error {
+		docs := make([]similarity.Document, 0, len(targets))
+		var warnings []string
+		skipped := 0
+		for i, submission := range targets {
+			if err := jobManager.Progress(job.ID, i, len(targets), fmt.Sprintf("Fingerprinting submission %d of %d", i+1, len(targets))); err != nil {
+				return err
+			}
+
+			doc, unsupported, err := similarityDocument(client, submission, anonymous)
+			if err != nil {
+				warnings = append(warnings, fmt.Sprintf("%s: %v", doc.Name, err))
+				continue
+			}
+			skipped += unsupported
+			docs = append(docs, doc)
+		}
+
+		if err := jobManager.Progress(job.ID, len(targets), len(targets), "Comparing submissions"); err != nil {
+			return err
+		}
+		report := similarity.Analyze(docs, starter, req.Options)
+		if skipped > 0 {
+			warnings = append(warnings, fmt.Sprintf("%d attachment(s) were not text or code and were left out", skipped))
+		}
+		report.Warnings = append(report.Warnings, warnings...)
+
+		return jobManager.Complete(job.ID, report)
+	})
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+// List similarity runs for an assignment, newest first
+func getSimilarityRuns(c *gin.Context) {
+	courseID := c.Param("course_id")
+	assignmentID := c.Param("assignment_id")
+
+	// Initialize as empty slice to ensure JSON returns [] instead of null
+	runs := make([]jobs.Job, 0)
+	for _, job := range jobManager.List(similarityJobKind) {
+		var state similarityJobState
+		if err := json.Unmarshal(job.State, &state); err != nil {
+			continue
+		}
+		if state.CourseID == courseID && state.AssignmentID == assignmentID {
+			runs = append(runs, job)
+		}
+	}
+
+	c.JSON(http.StatusOK, runs)
+}
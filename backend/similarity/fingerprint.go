@@ -0,0 +1,70 @@
+package similarity
+
+import (
+	"hash/fnv"
+)
+
+// fingerprint is a winnowed k-gram hash and where its k-gram sits
+type fingerprint struct {
+	hash      uint64
+	file      int // Index into the document's files
+	position  int // Index of the k-gram's first token
+	startLine int
+	endLine   int
+}
+
+// kgramHashes hashes every run of k consecutive tokens
+func kgramHashes(tokens []token, k int) []uint64 {
+	if len(tokens) < k {
+		return nil
+	}
+	hashes := make([]uint64, len(tokens)-k+1)
+	for i := range hashes {
+		h := fnv.New64a()
+		for _, t := range tokens[i : i+k] {
+			h.Write([]byte(t.text))
+			h.Write([]byte{0})
+		}
+		hashes[i] = h.Sum64()
+	}
+	return hashes
+}
+
+// winnow keeps the smallest hash of every window of consecutive k-gram hashes, taking the
+// rightmost on ties and recording each selected position once. Any match of at least
+// window+k-1 tokens is guaranteed to share a fingerprint.
+func winnow(tokens []token, file, k, window int) []fingerprint {
+	hashes := kgramHashes(tokens, k)
+	if len(hashes) == 0 {
+		return nil
+	}
+
+	at := func(i int) fingerprint {
+		return fingerprint{hash: hashes[i], file: file, position: i, startLine: tokens[i].line, endLine: tokens[i+k-1].line}
+	}
+	if len(hashes) < window {
+		smallest := 0
+		for i := range hashes {
+			if hashes[i] <= hashes[smallest] {
+				smallest = i
+			}
+		}
+		return []fingerprint{at(smallest)}
+	}
+
+	var prints []fingerprint
+	last := -1
+	for start := 0; start+window <= len(hashes); start++ {
+		smallest := start
+		for i := start; i < start+window; i++ {
+			if hashes[i] <= hashes[smallest] {
+				smallest = i
+			}
+		}
+		if smallest != last {
+			prints = append(prints, at(smallest))
+			last = smallest
+		}
+	}
+	return prints
+}
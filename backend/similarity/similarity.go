@@ -0,0 +1,365 @@
+// Package similarity finds submissions within an assignment that share unusually much text
+// or code. Documents are fingerprinted with winnowed k-gram hashes, the approach used by
+// MOSS, and every pair is compared locally.
+package similarity
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Defaults for Options
+const (
+	DefaultCodeK     = 12 // Code tokens are few and repetitive, so code needs longer k-grams
+	DefaultTextK     = 6
+	DefaultWindow    = 6
+	DefaultThreshold = 0.5
+	DefaultMaxShare  = 0.6
+	DefaultMinPrints = 10
+)
+
+const (
+	maxRegionsPerPair  = 25
+	minDocumentsShared = 4 // Fewer documents than this are too few to call a passage common
+)
+
+// Similarity metrics
+const (
+	MetricAverage = "average" // Shared fingerprints over both documents; flags mutual copying
+	MetricMax     = "max"     // Shared fingerprints over the smaller document; also flags partial copies
+)
+
+// File is one file or text of a submission
+type File struct {
+	Name    string `json:"name"`
+	Content string `json:"-"`
+}
+
+// Document is one student's submission
+type Document struct {
+	ID    int    `json:"id"` // Canvas user ID, or submission ID for anonymously graded assignments
+	Name  string `json:"name"`
+	Files []File `json:"files"`
+}
+
+// Options tune the analysis. Zero values take the defaults.
+type Options struct {
+	CodeK     int     `json:"code_k"`
+	TextK     int     `json:"text_k"`
+	Window    int     `json:"window"`
+	Threshold float64 `json:"threshold"`
+	Metric    string  `json:"metric"`
+	// Fingerprints found in more than this fraction of submissions are treated as common to
+	// the assignment, like a prompt copied into every answer, and ignored; 1 keeps them all
+	MaxShare float64 `json:"max_share"`
+	// Documents with fewer fingerprints than this are too short to compare
+	MinFingerprints int `json:"min_fingerprints"`
+}
+
+func (o Options) withDefaults() Options {
+	if o.CodeK <= 0 {
+		o.CodeK = DefaultCodeK
+	}
+	if o.TextK <= 0 {
+		o.TextK = DefaultTextK
+	}
+	if o.Window <= 0 {
+		o.Window = DefaultWindow
+	}
+	if o.Threshold <= 0 {
+		o.Threshold = DefaultThreshold
+	}
+	if o.Metric == "" {
+		o.Metric = MetricAverage
+	}
+	if o.MaxShare <= 0 {
+		o.MaxShare = DefaultMaxShare
+	}
+	if o.MinFingerprints <= 0 {
+		o.MinFingerprints = DefaultMinPrints
+	}
+	return o
+}
+
+// Validate checks options supplied by a caller
+func (o Options) Validate() error {
+	if o.Threshold < 0 || o.Threshold > 1 {
+		return fmt.Errorf("threshold must be between 0 and 1")
+	}
+	if o.MaxShare < 0 || o.MaxShare > 1 {
+		return fmt.Errorf("max_share must be between 0 and 1")
+	}
+	if o.Metric != "" && o.Metric != MetricAverage && o.Metric != MetricMax {
+		return fmt.Errorf("metric must be %q or %q", MetricAverage, MetricMax)
+	}
+	return nil
+}
+
+// DocumentSummary describes how much of a document was compared
+type DocumentSummary struct {
+	ID           int      `json:"id"`
+	Name         string   `json:"name"`
+	Files        []string `json:"files"`
+	Fingerprints int      `json:"fingerprints"`
+	Starter      int      `json:"starter_fingerprints"` // Removed because they match the starter code
+	Common       int      `json:"common_fingerprints"`  // Removed because most submissions share them
+	Skipped      bool     `json:"skipped"`              // Too short to compare
+}
+
+// Region is a passage two documents share
+type Region struct {
+	FileA      string `json:"file_a"`
+	StartLineA int    `json:"start_line_a"`
+	EndLineA   int    `json:"end_line_a"`
+	FileB      string `json:"file_b"`
+	StartLineB int    `json:"start_line_b"`
+	EndLineB   int    `json:"end_line_b"`
+	Matches    int    `json:"matches"` // Shared fingerprints in the region
+}
+
+// Pair is two documents whose similarity reached the threshold
+type Pair struct {
+	A             int      `json:"a"`
+	AName         string   `json:"a_name"`
+	B             int      `json:"b"`
+	BName         string   `json:"b_name"`
+	Similarity    float64  `json:"similarity"`     // Shared over both documents' fingerprints
+	MaxSimilarity float64  `json:"max_similarity"` // Shared over the smaller document's fingerprints
+	CoverageA     float64  `json:"coverage_a"`     // Share of A found in B
+	CoverageB     float64  `json:"coverage_b"`     // Share of B found in A
+	Shared        int      `json:"shared_fingerprints"`
+	Regions       []Region `json:"regions"`
+}
+
+// Report is the outcome of an analysis
+type Report struct {
+	Options   Options           `json:"options"`
+	Documents []DocumentSummary `json:"documents"`
+	Compared  int               `json:"pairs_compared"`
+	Pairs     []Pair            `json:"pairs"` // Suspicious pairs, most similar first
+	Warnings  []string          `json:"warnings"`
+}
+
+// fingerprinted is a document reduced to its distinct fingerprints
+type fingerprinted struct {
+	doc    Document
+	prints []fingerprint
+	first  map[uint64]fingerprint // First occurrence of each hash
+}
+
+// fingerprintFiles winnows every file with the k suited to its kind
+func fingerprintFiles(files []File, opts Options) []fingerprint {
+	var prints []fingerprint
+	for i, file := range files {
+		if IsCode(file.Name) {
+			prints = append(prints, winnow(tokenizeCode(file.Name, file.Content), i, opts.CodeK, opts.Window)...)
+		} else {
+			prints = append(prints, winnow(tokenizeText(file.Content), i, opts.TextK, opts.Window)...)
+		}
+	}
+	return prints
+}
+
+// starterHashes hashes every k-gram of the starter files, not just the winnowed ones, so no
+// starter passage can survive in a submission
+func starterHashes(files []File, opts Options) map[uint64]bool {
+	hashes := map[uint64]bool{}
+	for _, file := range files {
+		var all []uint64
+		if IsCode(file.Name) {
+			all = kgramHashes(tokenizeCode(file.Name, file.Content), opts.CodeK)
+		} else {
+			all = kgramHashes(tokenizeText(file.Content), opts.TextK)
+		}
+		for _, h := range all {
+			hashes[h] = true
+		}
+	}
+	return hashes
+}
+
+// Analyze compares every pair of documents, ignoring passages from the starter files
+func Analyze(docs []Document, starter []File, opts Options) Report {
+	opts = opts.withDefaults()
+	report := Report{
+		Options: opts,
+		// Initialize as empty slice to ensure JSON returns [] instead of null
+		Documents: make([]DocumentSummary, 0, len(docs)),
+		Pairs:     []Pair{},
+		Warnings:  []string{},
+	}
+
+	excluded := starterHashes(starter, opts)
+
+	// Count the documents each hash appears in, to find passages common to the assignment
+	prepared := make([]fingerprinted, len(docs))
+	seenIn := map[uint64]int{}
+	for i, doc := range docs {
+		prepared[i] = fingerprinted{doc: doc, first: map[uint64]fingerprint{}}
+		for _, fp := range fingerprintFiles(doc.Files, opts) {
+			if _, seen := prepared[i].first[fp.hash]; !seen {
+				prepared[i].first[fp.hash] = fp
+				seenIn[fp.hash]++
+			}
+			prepared[i].prints = append(prepared[i].prints, fp)
+		}
+	}
+	common := map[uint64]bool{}
+	if len(docs) >= minDocumentsShared && opts.MaxShare < 1 {
+		for h, count := range seenIn {
+			if float64(count) > opts.MaxShare*float64(len(docs)) {
+				common[h] = true
+			}
+		}
+	}
+
+	var compared []*fingerprinted
+	for i := range prepared {
+		p := &prepared[i]
+		summary := DocumentSummary{ID: p.doc.ID, Name: p.doc.Name, Files: []string{}}
+		for _, file := range p.doc.Files {
+			summary.Files = append(summary.Files, file.Name)
+		}
+
+		kept := p.prints[:0]
+		for _, fp := range p.prints {
+			switch {
+			case excluded[fp.hash]:
+				summary.Starter++
+			case common[fp.hash]:
+				summary.Common++
+			default:
+				kept = append(kept, fp)
+			}
+		}
+		p.prints = kept
+		for h := range p.first {
+			if excluded[h] || common[h] {
+				delete(p.first, h)
+			}
+		}
+		summary.Fingerprints = len(p.first)
+
+		if summary.Fingerprints < opts.MinFingerprints {
+			summary.Skipped = true
+		} else {
+			compared = append(compared, p)
+		}
+		report.Documents = append(report.Documents, summary)
+	}
+	if skipped := len(docs) - len(compared); skipped > 0 {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("%d submission(s) had too little original text or code to compare", skipped))
+	}
+	if len(common) > 0 {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("%d passage fingerprint(s) shared by more than %.0f%% of submissions were ignored as common to the assignment", len(common), opts.MaxShare*100))
+	}
+
+	for i := 0; i < len(compared); i++ {
+		for j := i + 1; j < len(compared); j++ {
+			report.Compared++
+			if pair, ok := comparePair(compared[i], compared[j], opts); ok {
+				report.Pairs = append(report.Pairs, pair)
+			}
+		}
+	}
+
+	sort.SliceStable(report.Pairs, func(i, j int) bool {
+		return score(report.Pairs[i], opts.Metric) > score(report.Pairs[j], opts.Metric)
+	})
+	return report
+}
+
+func score(pair Pair, metric string) float64 {
+	if metric == MetricMax {
+		return pair.MaxSimilarity
+	}
+	return pair.Similarity
+}
+
+// comparePair measures the overlap of two documents and, when it reaches the threshold,
+// locates the passages they share
+func comparePair(a, b *fingerprinted, opts Options) (Pair, bool) {
+	small, large := a.first, b.first
+	if len(small) > len(large) {
+		small, large = large, small
+	}
+	shared := 0
+	for h := range small {
+		if _, ok := large[h]; ok {
+			shared++
+		}
+	}
+	if shared == 0 {
+		return Pair{}, false
+	}
+
+	pair := Pair{
+		A: a.doc.ID, AName: a.doc.Name,
+		B: b.doc.ID, BName: b.doc.Name,
+		Similarity:    round(2 * float64(shared) / float64(len(a.first)+len(b.first))),
+		MaxSimilarity: round(float64(shared) / float64(len(small))),
+		CoverageA:     round(float64(shared) / float64(len(a.first))),
+		CoverageB:     round(float64(shared) / float64(len(b.first))),
+		Shared:        shared,
+	}
+	if score(pair, opts.Metric) < opts.Threshold {
+		return Pair{}, false
+	}
+
+	pair.Regions = regions(a, b, opts.CodeK+opts.Window)
+	return pair, true
+}
+
+// regions groups shared fingerprints that run in step through both documents into passages.
+// Fingerprints further apart than gap tokens start a new passage.
+func regions(a, b *fingerprinted, gap int) []Region {
+	type run struct {
+		region       Region
+		lastA, lastB fingerprint
+	}
+
+	var runs []run
+	for _, fa := range a.prints {
+		fb, ok := b.first[fa.hash]
+		if !ok {
+			continue
+		}
+		if n := len(runs); n > 0 {
+			current := &runs[n-1]
+			stepA := fa.position - current.lastA.position
+			stepB := fb.position - current.lastB.position
+			if fa.file == current.lastA.file && fb.file == current.lastB.file && stepA >= 0 && stepA <= gap && stepB >= 0 && stepB <= gap {
+				current.region.EndLineA = max(current.region.EndLineA, fa.endLine)
+				current.region.StartLineB = min(current.region.StartLineB, fb.startLine)
+				current.region.EndLineB = max(current.region.EndLineB, fb.endLine)
+				current.region.Matches++
+				current.lastA, current.lastB = fa, fb
+				continue
+			}
+		}
+		runs = append(runs, run{
+			region: Region{
+				FileA: a.doc.Files[fa.file].Name, StartLineA: fa.startLine, EndLineA: fa.endLine,
+				FileB: b.doc.Files[fb.file].Name, StartLineB: fb.startLine, EndLineB: fb.endLine,
+				Matches: 1,
+			},
+			lastA: fa,
+			lastB: fb,
+		})
+	}
+
+	// Initialize as empty slice to ensure JSON returns [] instead of null
+	result := make([]Region, 0, len(runs))
+	for _, r := range runs {
+		result = append(result, r.region)
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Matches > result[j].Matches })
+	if len(result) > maxRegionsPerPair {
+		result = result[:maxRegionsPerPair]
+	}
+	return result
+}
+
+func round(value float64) float64 {
+	return float64(int(value*1000+0.5)) / 1000
+}
@@ -0,0 +1,213 @@
+package similarity
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func randomTokens(rng *rand.Rand, n int) []token {
+	tokens := make([]token, n)
+	for i := range tokens {
+		tokens[i] = token{text: fmt.Sprintf("w%d", rng.Intn(50)), line: i + 1}
+	}
+	return tokens
+}
+
+func TestWinnowGuarantees(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	const k, window = 5, 4
+
+	for trial := 0; trial < 200; trial++ {
+		shared := randomTokens(rng, window+k-1)
+		a := append(append(randomTokens(rng, rng.Intn(60)), shared...), randomTokens(rng, rng.Intn(60))...)
+		b := append(append(randomTokens(rng, rng.Intn(60)), shared...), randomTokens(rng, rng.Intn(60))...)
+
+		printsA := winnow(a, 0, k, window)
+		printsB := winnow(b, 0, k, window)
+
+		// Every window of k-gram hashes contributes a fingerprint, so gaps never exceed it
+		for i := 1; i < len(printsA); i++ {
+			if gap := printsA[i].position - printsA[i-1].position; gap <= 0 || gap > window {
+				t.Fatalf("trial %d: fingerprints at %d and %d are %d apart", trial, printsA[i-1].position, printsA[i].position, gap)
+			}
+		}
+
+		hashes := map[uint64]bool{}
+		for _, fp := range printsA {
+			hashes[fp.hash] = true
+		}
+		found := false
+		for _, fp := range printsB {
+			found = found || hashes[fp.hash]
+		}
+		if !found {
+			t.Fatalf("trial %d: a shared run of %d tokens left no common fingerprint", trial, window+k-1)
+		}
+	}
+}
+
+func TestWinnowShortInput(t *testing.T) {
+	tokens := randomTokens(rand.New(rand.NewSource(2)), 6)
+	if prints := winnow(tokens, 0, 7, 4); prints != nil {
+		t.Errorf("fewer tokens than k should give no fingerprints, got %d", len(prints))
+	}
+	if prints := winnow(tokens, 0, 3, 10); len(prints) != 1 {
+		t.Errorf("fewer k-grams than a window should give one fingerprint, got %d", len(prints))
+	}
+}
+
+func TestTokenizeCodeIgnoresNamesAndComments(t *testing.T) {
+	original := "def total(xs):\n    # add them up\n    s = 0\n    for x in xs:\n        s += x\n    return s\n"
+	renamed := "def sum_all(nums):  # renamed\n    acc = 10\n\n    for v in nums:\n        acc += v\n    return acc\n"
+
+	texts := func(tokens []token) string {
+		var words []string
+		for _, t := range tokens {
+			words = append(words, t.text)
+		}
+		return strings.Join(words, " ")
+	}
+	a, b := tokenizeCode("a.py", original), tokenizeCode("b.py", renamed)
+	if texts(a) != texts(b) {
+		t.Errorf("renamed code tokenized differently\n%s\n%s", texts(a), texts(b))
+	}
+	if last := a[len(a)-1]; last.line != 6 {
+		t.Errorf("last token on line %d, want 6", last.line)
+	}
+
+	tokens := tokenizeCode("a.c", "/* header\n spans lines */ x = \"a // b\"; // tail\ny = 'c';")
+	if got, want := texts(tokens), "id = str ; id = str ;"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if tokens[4].line != 3 {
+		t.Errorf("y on line %d, want 3", tokens[4].line)
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	program := func(name string) string {
+		return fmt.Sprintf(`def %[1]s_mean(values):
+    total = 0
+    count = 0
+    for value in values:
+        if value is not None:
+            total += value
+            count += 1
+    if count == 0:
+        raise ValueError("empty")
+    return total / count
+
+def %[1]s_spread(values):
+    low, high = values[0], values[0]
+    for value in values:
+        if value < low:
+            low = value
+        elif value > high:
+            high = value
+    return high - low
+`, name)
+	}
+	starter := "import sys\n\ndef main():\n    data = [float(line) for line in sys.stdin]\n    print(mean(data), spread(data))\n"
+	original := `class Stack:
+    def __init__(self):
+        self.items = []
+
+    def push(self, item):
+        self.items.append(item)
+
+    def pop(self):
+        if not self.items:
+            raise IndexError("pop from empty stack")
+        return self.items.pop()
+
+    def peek(self):
+        return self.items[-1] if self.items else None
+
+    def __len__(self):
+        return len(self.items)
+`
+
+	docs := []Document{
+		{ID: 1, Name: "copier", Files: []File{{Name: "stats.py", Content: starter + program("stats")}}},
+		{ID: 2, Name: "source", Files: []File{{Name: "main.py", Content: starter + program("calc")}}},
+		{ID: 3, Name: "independent", Files: []File{{Name: "stack.py", Content: starter + original}}},
+		{ID: 4, Name: "brief", Files: []File{{Name: "empty.py", Content: "pass\n"}}},
+	}
+
+	report := Analyze(docs, []File{{Name: "starter.py", Content: starter}}, Options{})
+
+	if len(report.Pairs) != 1 {
+		t.Fatalf("got %d pairs, want 1: %+v", len(report.Pairs), report.Pairs)
+	}
+	pair := report.Pairs[0]
+	if pair.A != 1 || pair.B != 2 {
+		t.Errorf("flagged %s and %s, want copier and source", pair.AName, pair.BName)
+	}
+	if pair.Similarity < 0.9 {
+		t.Errorf("renamed copy scored %v, want at least 0.9", pair.Similarity)
+	}
+	// Only k-grams reaching past the starter's last line, line 5, can match
+	if len(pair.Regions) == 0 || pair.Regions[0].StartLineA < 5 {
+		t.Errorf("regions %+v should not cover the starter code", pair.Regions)
+	}
+
+	if report.Compared != 3 {
+		t.Errorf("compared %d pairs, want 3", report.Compared)
+	}
+	for _, summary := range report.Documents {
+		if summary.ID == 4 && !summary.Skipped {
+			t.Error("a one-line submission should be skipped")
+		}
+		if summary.ID != 4 && summary.Starter == 0 {
+			t.Errorf("%s: starter code fingerprints were not removed", summary.Name)
+		}
+	}
+}
+
+func TestAnalyzeIgnoresCommonPassages(t *testing.T) {
+	prompt := "Explain in your own words why the sky appears blue during the day and red at sunset, citing scattering"
+	answers := []string{
+		"Sunlight contains every colour and the short blue wavelengths bounce off air molecules far more than red ones do",
+		"Rayleigh scattering grows with the inverse fourth power of wavelength which favours violet and blue light overhead",
+		"At dusk light crosses much more atmosphere so most blue is scattered away before reaching our eyes leaving orange",
+		"Our eyes are less sensitive to violet than blue and the sun emits less violet so the daytime sky looks blue to us",
+	}
+
+	var docs []Document
+	for i, answer := range answers {
+		docs = append(docs, Document{ID: i + 1, Name: fmt.Sprintf("student-%d", i+1), Files: []File{{Name: "answer", Content: prompt + "\n" + answer}}})
+	}
+
+	report := Analyze(docs, nil, Options{MinFingerprints: 1})
+	if len(report.Pairs) != 0 {
+		t.Errorf("a prompt copied into every answer should not flag pairs, got %+v", report.Pairs)
+	}
+	for _, summary := range report.Documents {
+		if summary.Common == 0 {
+			t.Errorf("%s: prompt fingerprints were not marked common", summary.Name)
+		}
+	}
+}
+
+func TestOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+		valid   bool
+	}{
+		{"defaults", Options{}, true},
+		{"max metric", Options{Metric: MetricMax, Threshold: 1}, true},
+		{"threshold above one", Options{Threshold: 1.5}, false},
+		{"negative share", Options{MaxShare: -0.1}, false},
+		{"unknown metric", Options{Metric: "jaccard"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.options.Validate(); (err == nil) != tt.valid {
+				t.Errorf("Validate() = %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
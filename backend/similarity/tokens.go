@@ -0,0 +1,169 @@
+package similarity
+
+import (
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// token is one normalised token and the line it came from
+type token struct {
+	text string
+	line int
+}
+
+// commentStyle describes how a language writes comments
+type commentStyle struct {
+	line  []string // Line comment prefixes
+	block bool     // C-style /* */ comments
+}
+
+var (
+	cStyle    = commentStyle{line: []string{"//"}, block: true}
+	hashStyle = commentStyle{line: []string{"#"}}
+)
+
+// Source extensions that are fingerprinted as code; anything else is treated as prose
+var codeComments = map[string]commentStyle{
+	".py": hashStyle, ".r": hashStyle, ".rb": hashStyle, ".sh": hashStyle, ".pl": hashStyle,
+	".java": cStyle, ".c": cStyle, ".h": cStyle, ".cpp": cStyle, ".cc": cStyle, ".hpp": cStyle,
+	".cs": cStyle, ".js": cStyle, ".ts": cStyle, ".go": cStyle, ".rs": cStyle, ".kt": cStyle,
+	".swift": cStyle, ".scala": cStyle, ".php": {line: []string{"//", "#"}, block: true},
+	".sql": {line: []string{"--"}, block: true}, ".m": {line: []string{"%"}},
+	".hs": {line: []string{"--"}}, ".lua": {line: []string{"--"}},
+}
+
+// IsCode reports whether a file name is fingerprinted as source code
+func IsCode(name string) bool {
+	_, ok := codeComments[strings.ToLower(filepath.Ext(name))]
+	return ok
+}
+
+// Keywords of common teaching languages survive normalisation so that the structure of the
+// code is compared rather than its names
+var keywords = toSet(
+	// Python
+	"and", "as", "assert", "async", "await", "break", "class", "continue", "def", "del", "elif",
+	"else", "except", "finally", "for", "from", "global", "if", "import", "in", "is", "lambda",
+	"nonlocal", "not", "or", "pass", "raise", "return", "try", "while", "with", "yield", "none",
+	"true", "false", "print", "range", "len", "self",
+	// C family, Java, JavaScript, Go
+	"abstract", "auto", "bool", "boolean", "case", "catch", "char", "const", "default", "do",
+	"double", "enum", "extends", "extern", "final", "float", "func", "function", "go", "goto",
+	"implements", "instanceof", "int", "interface", "let", "long", "map", "new", "null", "package",
+	"private", "protected", "public", "short", "signed", "sizeof", "static", "struct",
+	"super", "switch", "this", "throw", "throws", "typedef", "union", "unsigned", "var", "void",
+	"volatile", "chan", "defer", "select", "type", "string", "nil", "std", "cout", "cin", "include",
+	"using", "namespace", "template", "typename", "virtual", "delete", "fn", "mut", "impl", "match",
+	"pub", "use", "mod", "loop",
+	// SQL
+	"where", "join", "on", "group", "by", "order", "having", "insert", "into",
+	"values", "update", "set", "create", "table", "primary", "key", "foreign", "references",
+	"distinct", "count", "sum", "avg", "min", "max", "limit", "left", "right", "inner", "outer",
+)
+
+func toSet(words ...string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, word := range words {
+		set[word] = true
+	}
+	return set
+}
+
+// tokenizeCode splits source code into tokens with comments and whitespace dropped.
+// Identifiers become "id", numbers "num" and string literals "str", so renaming variables
+// or changing constants does not hide copied code.
+func tokenizeCode(name, source string) []token {
+	style := codeComments[strings.ToLower(filepath.Ext(name))]
+	runes := []rune(source)
+	var tokens []token
+	line := 1
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		rest := string(runes[i:min(i+2, len(runes))])
+
+		switch {
+		case r == '\n':
+			line++
+			i++
+		case unicode.IsSpace(r):
+			i++
+		case style.block && rest == "/*":
+			i += 2
+			for i < len(runes) && !(runes[i] == '*' && i+1 < len(runes) && runes[i+1] == '/') {
+				if runes[i] == '\n' {
+					line++
+				}
+				i++
+			}
+			i += 2
+		case hasLineComment(style, runes[i:]):
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case r == '"' || r == '\'' || r == '`':
+			start := line
+			quote := r
+			i++
+			for i < len(runes) && runes[i] != quote {
+				if runes[i] == '\\' {
+					i++
+				} else if runes[i] == '\n' {
+					if quote != '`' && style.block {
+						break
+					}
+					line++
+				}
+				i++
+			}
+			i++
+			tokens = append(tokens, token{text: "str", line: start})
+		case unicode.IsLetter(r) || r == '_':
+			j := i
+			for j < len(runes) && (unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j]) || runes[j] == '_') {
+				j++
+			}
+			word := strings.ToLower(string(runes[i:j]))
+			if !keywords[word] {
+				word = "id"
+			}
+			tokens = append(tokens, token{text: word, line: line})
+			i = j
+		case unicode.IsDigit(r):
+			j := i
+			for j < len(runes) && (unicode.IsDigit(runes[j]) || unicode.IsLetter(runes[j]) || runes[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, token{text: "num", line: line})
+			i = j
+		default:
+			tokens = append(tokens, token{text: string(r), line: line})
+			i++
+		}
+	}
+	return tokens
+}
+
+func hasLineComment(style commentStyle, runes []rune) bool {
+	for _, prefix := range style.line {
+		if strings.HasPrefix(string(runes[:min(len(prefix), len(runes))]), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// tokenizeText splits prose into lowercase words, ignoring punctuation and spacing
+func tokenizeText(text string) []token {
+	var tokens []token
+	for i, lineText := range strings.Split(text, "\n") {
+		words := strings.FieldsFunc(lineText, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		for _, word := range words {
+			tokens = append(tokens, token{text: strings.ToLower(word), line: i + 1})
+		}
+	}
+	return tokens
+}
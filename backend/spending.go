@@ -0,0 +1,278 @@
+package main
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"auxa/llm"
+	"auxa/store"
+	"auxa/usage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// usageLedger records every provider call and quotas enforces spending caps against it;
+// both are initialised in main
+var (
+	usageLedger *usage.Ledger
+	quotas      *usage.Enforcer
+)
+
+// graderIDs caches the Canvas user behind each token so a TA is looked up once
+var graderIDs sync.Map
+
+// graderFromRequest identifies the TA making a metered call from their Canvas token, since a
+// grader ID in the request could be left out or forged to dodge the per-TA cap. It writes an
+// error response and returns false when the token cannot be resolved.
+func graderFromRequest(c *gin.Context) (string, bool) {
+	client, ok := canvasClientFromRequest(c)
+	if !ok {
+		return "", false
+	}
+
+	key := sha256.Sum256([]byte(client.SchoolURL + "\x00" + client.Token))
+	if id, ok := graderIDs.Load(key); ok {
+		return id.(string), true
+	}
+
+	user, err := client.GetUserProfile()
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Failed to identify the grader from Canvas: " + err.Error()})
+		return "", false
+	}
+
+	id := strconv.Itoa(user.ID)
+	graderIDs.Store(key, id)
+	return id, true
+}
+
+// meterGrading is the innermost grading middleware: it admits the call under the spending
+// caps for graderID, records its usage in the ledger and attaches any cap warnings to the
+// response
+func meterGrading(graderID string) llm.Middleware {
+	return func(next llm.Generator) llm.Generator {
+		return func(req llm.GradingRequest) (*llm.GradingResponse, error) {
+			req.GraderID = graderID
+
+			// Consensus requests make one provider call per member, each counted against the caps
+			var warnings []string
+			for i := 0; i < req.ProviderCalls(); i++ {
+				reservation, reservationWarnings, err := quotas.Reserve(req.CourseID, req.GraderID, time.Now())
+				if err != nil {
+					return nil, err
+				}
+				defer reservation.Release()
+				if i == 0 {
+					warnings = reservationWarnings
+				}
+			}
+
+			resp, err := next(req)
+			recordGradingUsage(req, resp)
+			if resp != nil {
+				resp.Warnings = append(resp.Warnings, warnings...)
+			}
+			return resp, err
+		}
+	}
+}
+
+// meterVision is the innermost vision middleware, the counterpart of meterGrading
+func meterVision(graderID string) llm.VisionMiddleware {
+	return func(next llm.VisionAnalyzer) llm.VisionAnalyzer {
+		return func(req llm.VisionAnalysisRequest) (*llm.VisionAnalysisResponse, error) {
+			req.GraderID = graderID
+
+			reservation, warnings, err := quotas.Reserve(req.CourseID, req.GraderID, time.Now())
+			if err != nil {
+				return nil, err
+			}
+			defer reservation.Release()
+
+			resp, err := next(req)
+			recordVisionUsage(req, resp)
+			if resp != nil {
+				resp.Warnings = append(resp.Warnings, warnings...)
+			}
+			return resp, err
+		}
+	}
+}
+
+// writeGenerationError responds to a failed provider call, using 429 and the quota error
+// code when a spending cap rejected it
+func writeGenerationError(c *gin.Context, err error, emptyField string) {
+	if errors.Is(err, usage.ErrCourseRequired) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":    err.Error(),
+			emptyField: "",
+		})
+		return
+	}
+
+	var quotaErr *usage.QuotaError
+	if errors.As(err, &quotaErr) {
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error":    quotaErr.Error(),
+			"code":     quotaErr.Code,
+			"quota":    quotaErr,
+			emptyField: "",
+		})
+		return
+	}
+
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error":    err.Error(),
+		emptyField: "",
+	})
+}
+
+// pricesPath stores price overrides on top of llm.DefaultPrices
+func pricesPath() string {
+	return store.Path("prices.json")
+}
+
+// loadPrices applies saved price overrides
+func loadPrices() error {
+	var overrides llm.PriceTable
+	if err := store.LoadJSON(pricesPath(), &overrides); err != nil {
+		return err
+	}
+	llm.SetPrices(overrides)
+	return nil
+}
+
+// recordUsage adds a completed provider call to the ledger. Failures are logged rather than
+// surfaced, since the feedback has already been paid for.
+func recordUsage(entry usage.Entry) {
+	if err := usageLedger.Record(entry); err != nil {
+		fmt.Printf("[Usage] failed to record %s call: %v\n", entry.Kind, err)
+	}
+}
+
+func recordGradingUsage(req llm.GradingRequest, resp *llm.GradingResponse) {
+	if resp == nil || resp.Usage == nil {
+		return
+	}
+
+	// Record each consensus member separately so costs are attributed to the right model
+	if resp.Consensus != nil {
+		for _, vote := range resp.Consensus.Votes {
+			if vote.Usage != nil {
+				recordUsage(gradingEntry(req, vote.Platform, vote.Model, *vote.Usage))
+			}
+		}
+		return
+	}
+
+	recordUsage(gradingEntry(req, req.Platform, resp.Model, *resp.Usage))
+}
+
+func gradingEntry(req llm.GradingRequest, platform, model string, u llm.Usage) usage.Entry {
+	return usage.Entry{
+		Kind:             usage.KindGrading,
+		CourseID:         req.CourseID,
+		AssignmentID:     req.AssignmentID,
+		GraderID:         req.GraderID,
+		Platform:         platform,
+		Model:            model,
+		InputTokens:      u.InputTokens,
+		OutputTokens:     u.OutputTokens,
+		CacheReadTokens:  u.CacheReadTokens,
+		CacheWriteTokens: u.CacheWriteTokens,
+		EstimatedCost:    u.EstimatedCost,
+	}
+}
+
+func recordVisionUsage(req llm.VisionAnalysisRequest, resp *llm.VisionAnalysisResponse) {
+	if resp == nil || resp.Usage == nil {
+		return
+	}
+	recordUsage(usage.Entry{
+		Kind:             usage.KindVision,
+		CourseID:         req.CourseID,
+		AssignmentID:     req.AssignmentID,
+		GraderID:         req.GraderID,
+		Platform:         req.Platform,
+		Model:            resp.Model,
+		InputTokens:      resp.Usage.InputTokens,
+		OutputTokens:     resp.Usage.OutputTokens,
+		CacheReadTokens:  resp.Usage.CacheReadTokens,
+		CacheWriteTokens: resp.Usage.CacheWriteTokens,
+		EstimatedCost:    resp.Usage.EstimatedCost,
+	})
+}
+
+// Get token usage and estimated cost totals, optionally filtered by course, assignment and date
+func getUsageSummary(c *gin.Context) {
+	filter := usage.Filter{
+		CourseID:     c.Query("course_id"),
+		AssignmentID: c.Query("assignment_id"),
+	}
+
+	var err error
+	if since := c.Query("since"); since != "" {
+		if filter.Since, err = time.Parse("2006-01-02", since); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "since must be a date like 2025-01-31"})
+			return
+		}
+	}
+	if until := c.Query("until"); until != "" {
+		if filter.Until, err = time.Parse("2006-01-02", until); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "until must be a date like 2025-01-31"})
+			return
+		}
+		// Include the whole final day
+		filter.Until = filter.Until.AddDate(0, 0, 1)
+	}
+
+	c.JSON(http.StatusOK, usageLedger.Summarize(filter))
+}
+
+// Get the price table used for cost estimates
+func getPrices(c *gin.Context) {
+	c.JSON(http.StatusOK, llm.Prices())
+}
+
+// Override model prices used for cost estimates
+func updatePrices(c *gin.Context) {
+	var req llm.PriceTable
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := store.SaveJSON(pricesPath(), req); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	llm.SetPrices(req)
+
+	c.JSON(http.StatusOK, llm.Prices())
+}
+
+// Get the spending caps for AI grading
+func getUsageLimits(c *gin.Context) {
+	c.JSON(http.StatusOK, quotas.Limits())
+}
+
+// Replace the spending caps for AI grading
+func updateUsageLimits(c *gin.Context) {
+	var req usage.Limits
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := quotas.SetLimits(req); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, quotas.Limits())
+}
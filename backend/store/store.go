@@ -0,0 +1,93 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+var (
+	dataDirOnce sync.Once
+	dataDir     string
+)
+
+// DataDir returns the directory holding Auxa's local state. It honours AUXA_DATA_DIR and
+// otherwise uses an "auxa" folder in the user's config directory.
+func DataDir() string {
+	dataDirOnce.Do(func() {
+		if dir := os.Getenv("AUXA_DATA_DIR"); dir != "" {
+			dataDir = dir
+			return
+		}
+
+		configDir, err := os.UserConfigDir()
+		if err != nil {
+			dataDir = "data"
+			return
+		}
+		dataDir = filepath.Join(configDir, "auxa")
+	})
+	return dataDir
+}
+
+// Path joins name onto the data directory
+func Path(name ...string) string {
+	return filepath.Join(append([]string{DataDir()}, name...)...)
+}
+
+// LoadJSON decodes the JSON file at path into v. A missing file leaves v untouched and is not an error.
+func LoadJSON(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// SaveJSON atomically writes v to path as indented JSON, creating parent directories as needed
+func SaveJSON(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", path, err)
+	}
+
+	return WriteFile(path, data)
+}
+
+// WriteFile atomically replaces the file at path with data, readable only by the current user
+func WriteFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	return nil
+}
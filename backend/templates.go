@@ -0,0 +1,264 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"auxa/canvas"
+	"auxa/llm"
+	"auxa/templates"
+
+	"github.com/gin-gonic/gin"
+)
+
+// promptTemplates holds the shared prompt template library; initialised in main
+var promptTemplates *templates.Library
+
+// List templates visible in a course and assignment
+func getTemplates(c *gin.Context) {
+	c.JSON(http.StatusOK, promptTemplates.List(c.Query("course_id"), c.Query("assignment_id")))
+}
+
+// Create a template at version 1
+func createTemplate(c *gin.Context) {
+	var req templates.Template
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	created, err := promptTemplates.Create(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, created)
+}
+
+// Get a template, optionally at a specific version
+func getTemplate(c *gin.Context) {
+	version := 0
+	if raw := c.Query("version"); raw != "" {
+		var err error
+		if version, err = strconv.Atoi(raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "version must be a number"})
+			return
+		}
+	}
+
+	t, found := promptTemplates.Get(c.Param("template_id"), version)
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, t)
+}
+
+// List every version of a template, newest first
+func getTemplateVersions(c *gin.Context) {
+	versions := promptTemplates.Versions(c.Param("template_id"))
+	if len(versions) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, versions)
+}
+
+// Save a new version of a template
+func updateTemplate(c *gin.Context) {
+	var req templates.Template
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	id := c.Param("template_id")
+	if _, found := promptTemplates.Get(id, 0); !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+		return
+	}
+
+	updated, err := promptTemplates.Update(id, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// Delete a template and its history
+func deleteTemplate(c *gin.Context) {
+	if err := promptTemplates.Delete(c.Param("template_id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Template deleted"})
+}
+
+// Render a saved template or an unsaved body against an assignment and, optionally, a
+// student's submission
+func previewTemplate(c *gin.Context) {
+	var req struct {
+		TemplateID   string `json:"template_id"`
+		Version      int    `json:"version"`
+		Body         string `json:"body"`
+		CourseID     string `json:"course_id"`
+		AssignmentID string `json:"assignment_id"`
+		UserID       int    `json:"user_id"`
+		Rubric       string `json:"rubric"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	body := req.Body
+	if req.TemplateID != "" {
+		t, found := promptTemplates.Get(req.TemplateID, req.Version)
+		if !found {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+			return
+		}
+		body = t.Body
+	}
+
+	in, ok := templateInput(c, req.CourseID, req.AssignmentID, req.UserID, req.Rubric)
+	if !ok {
+		return
+	}
+
+	vars := templates.Variables(in)
+	rendered, err := templates.Render(body, vars)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "variables": vars})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"rendered":  rendered,
+		"variables": vars,
+	})
+}
+
+// templateInput loads what templates render from: the assignment when both IDs are given and
+// the student's submission when userID is too. It writes an error response and returns false
+// on failure.
+func templateInput(c *gin.Context, courseID, assignmentID string, userID int, rubric string) (templates.Input, bool) {
+	in := templates.Input{Rubric: rubric}
+	if courseID == "" || assignmentID == "" {
+		return in, true
+	}
+
+	client, ok := canvasClientFromRequest(c)
+	if !ok {
+		return in, false
+	}
+
+	assignment, err := client.GetAssignment(courseID, assignmentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return in, false
+	}
+	in.Assignment = assignment
+
+	// Prefer the team's stored rubric over the Canvas criteria
+	if in.Rubric == "" {
+		if r, found := rubricStore.ForAssignment(courseID, assignmentID); found {
+			in.Rubric = r.Text()
+		}
+	}
+
+	if userID != 0 {
+		submission, err := findSubmission(client, courseID, assignmentID, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return in, false
+		}
+		if submission == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Submission not found"})
+			return in, false
+		}
+		in.Submission = submission
+		in.Content = submissionContent(client, *submission)
+		if submission.User != nil {
+			in.StudentName = submission.User.Name
+		}
+	}
+
+	return in, true
+}
+
+// applyTemplates renders the request's named library templates for the student being graded
+// into its system prompt and prompt. It writes an error response and returns false on failure.
+func applyTemplates(c *gin.Context, req *llm.GradingRequest) bool {
+	if req.Templates == nil {
+		return true
+	}
+
+	if req.CourseID == "" || req.AssignmentID == "" || req.UserID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "course_id, assignment_id and user_id are required for templates"})
+		return false
+	}
+
+	targets := []struct {
+		kind, name string
+		prompt     *string
+	}{
+		{templates.KindSystem, req.Templates.System, &req.SystemPrompt},
+		{templates.KindGrading, req.Templates.Grading, &req.Prompt},
+	}
+
+	var in templates.Input
+	loaded := false
+	for _, target := range targets {
+		if target.name == "" {
+			continue
+		}
+
+		t, found := promptTemplates.Resolve(target.kind, target.name, req.CourseID, req.AssignmentID)
+		if !found {
+			c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("No %s template named %q", target.kind, target.name)})
+			return false
+		}
+
+		if !loaded {
+			var ok bool
+			if in, ok = templateInput(c, req.CourseID, req.AssignmentID, req.UserID, ""); !ok {
+				return false
+			}
+			loaded = true
+		}
+
+		rendered, err := templates.Render(t.Body, templates.Variables(in))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return false
+		}
+		*target.prompt = rendered
+	}
+
+	req.Templates = nil
+	return true
+}
+
+// findSubmission returns a student's submission for an assignment, or nil when there is none
+func findSubmission(client *canvas.Client, courseID, assignmentID string, userID int) (*canvas.Submission, error) {
+	submissions, err := client.GetAssignmentSubmissions(courseID, assignmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range submissions {
+		if submissions[i].UserID == userID {
+			return &submissions[i], nil
+		}
+	}
+	return nil, nil
+}
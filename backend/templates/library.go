@@ -0,0 +1,251 @@
+package templates
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"auxa/store"
+)
+
+// Template kinds
+const (
+	KindSystem  = "system"  // Rendered into the system prompt
+	KindGrading = "grading" // Rendered into the per-submission prompt
+)
+
+// Template scopes, from least to most specific
+const (
+	ScopeGlobal     = "global"
+	ScopeCourse     = "course"
+	ScopeAssignment = "assignment"
+)
+
+// Template is one version of a named prompt template
+type Template struct {
+	ID           string    `json:"id"`
+	Version      int       `json:"version"`
+	Name         string    `json:"name"`
+	Kind         string    `json:"kind"`
+	Scope        string    `json:"scope"`
+	CourseID     string    `json:"course_id,omitempty"`
+	AssignmentID string    `json:"assignment_id,omitempty"`
+	Body         string    `json:"body"`
+	Author       string    `json:"author,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Validate checks the template's kind, scope and syntax
+func (t Template) Validate() error {
+	if t.Name == "" {
+		return fmt.Errorf("template name is required")
+	}
+
+	switch t.Kind {
+	case KindSystem, KindGrading:
+	default:
+		return fmt.Errorf("unsupported template kind: %q", t.Kind)
+	}
+
+	switch t.Scope {
+	case ScopeGlobal:
+	case ScopeCourse:
+		if t.CourseID == "" {
+			return fmt.Errorf("course_id is required for course templates")
+		}
+	case ScopeAssignment:
+		if t.CourseID == "" || t.AssignmentID == "" {
+			return fmt.Errorf("course_id and assignment_id are required for assignment templates")
+		}
+	default:
+		return fmt.Errorf("unsupported template scope: %q", t.Scope)
+	}
+
+	_, err := Parse(t.Body)
+	return err
+}
+
+// visibleIn reports whether the template applies to a course and assignment
+func (t Template) visibleIn(courseID, assignmentID string) bool {
+	switch t.Scope {
+	case ScopeGlobal:
+		return true
+	case ScopeCourse:
+		return t.CourseID == courseID
+	case ScopeAssignment:
+		return t.CourseID == courseID && t.AssignmentID == assignmentID
+	}
+	return false
+}
+
+func scopeRank(scope string) int {
+	switch scope {
+	case ScopeAssignment:
+		return 2
+	case ScopeCourse:
+		return 1
+	}
+	return 0
+}
+
+// Library stores every version of every template
+type Library struct {
+	mu       sync.RWMutex
+	path     string
+	versions map[string][]Template // Keyed by template ID, oldest version first
+}
+
+// LoadLibrary reads templates from path
+func LoadLibrary(path string) (*Library, error) {
+	l := &Library{path: path, versions: make(map[string][]Template)}
+	if err := store.LoadJSON(path, &l.versions); err != nil {
+		return nil, err
+	}
+	if l.versions == nil {
+		l.versions = make(map[string][]Template)
+	}
+	return l, nil
+}
+
+func (l *Library) save() error {
+	return store.SaveJSON(l.path, l.versions)
+}
+
+// Create adds a new template at version 1
+func (l *Library) Create(t Template) (Template, error) {
+	if err := t.Validate(); err != nil {
+		return Template{}, err
+	}
+
+	idBytes := make([]byte, 8)
+	if _, err := rand.Read(idBytes); err != nil {
+		return Template{}, fmt.Errorf("failed to generate template ID: %w", err)
+	}
+	t.ID = hex.EncodeToString(idBytes)
+	t.Version = 1
+	t.CreatedAt = time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.versions[t.ID] = []Template{t}
+	if err := l.save(); err != nil {
+		delete(l.versions, t.ID)
+		return Template{}, err
+	}
+	return t, nil
+}
+
+// Update saves a new version of an existing template. Earlier versions are kept.
+func (l *Library) Update(id string, t Template) (Template, error) {
+	if err := t.Validate(); err != nil {
+		return Template{}, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	versions, ok := l.versions[id]
+	if !ok {
+		return Template{}, fmt.Errorf("template %s not found", id)
+	}
+
+	t.ID = id
+	t.Version = versions[len(versions)-1].Version + 1
+	t.CreatedAt = time.Now()
+
+	l.versions[id] = append(versions, t)
+	if err := l.save(); err != nil {
+		l.versions[id] = versions
+		return Template{}, err
+	}
+	return t, nil
+}
+
+// Get returns a version of a template, or the latest when version is 0
+func (l *Library) Get(id string, version int) (Template, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	versions := l.versions[id]
+	if len(versions) == 0 {
+		return Template{}, false
+	}
+	if version == 0 {
+		return versions[len(versions)-1], true
+	}
+	for _, t := range versions {
+		if t.Version == version {
+			return t, true
+		}
+	}
+	return Template{}, false
+}
+
+// Versions returns every version of a template, newest first
+func (l *Library) Versions(id string) []Template {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	versions := l.versions[id]
+	history := make([]Template, len(versions))
+	for i, t := range versions {
+		history[len(versions)-1-i] = t
+	}
+	return history
+}
+
+// List returns the latest version of each template visible in a course and assignment,
+// most specific scope first. Empty IDs list only templates of the broader scopes.
+func (l *Library) List(courseID, assignmentID string) []Template {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	// Initialize as empty slice to ensure JSON returns [] instead of null
+	list := make([]Template, 0, len(l.versions))
+	for _, versions := range l.versions {
+		latest := versions[len(versions)-1]
+		if latest.visibleIn(courseID, assignmentID) {
+			list = append(list, latest)
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if scopeRank(list[i].Scope) != scopeRank(list[j].Scope) {
+			return scopeRank(list[i].Scope) > scopeRank(list[j].Scope)
+		}
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// Resolve finds the most specific template of a kind and name for a course and assignment
+func (l *Library) Resolve(kind, name, courseID, assignmentID string) (Template, bool) {
+	for _, t := range l.List(courseID, assignmentID) {
+		if t.Kind == kind && t.Name == name {
+			return t, true
+		}
+	}
+	return Template{}, false
+}
+
+// Delete removes a template and its history
+func (l *Library) Delete(id string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	versions, ok := l.versions[id]
+	if !ok {
+		return fmt.Errorf("template %s not found", id)
+	}
+
+	delete(l.versions, id)
+	if err := l.save(); err != nil {
+		l.versions[id] = versions
+		return err
+	}
+	return nil
+}
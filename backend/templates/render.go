@@ -0,0 +1,161 @@
+package templates
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"auxa/canvas"
+	"auxa/plaintext"
+)
+
+// Bare variable paths such as {{assignment.name}} are rewritten to text/template's
+// {{.assignment.name}}. Actions starting with a keyword or function are left alone.
+var shorthandPattern = regexp.MustCompile(`\{\{(-?\s*)([A-Za-z_]\w*(?:\.[A-Za-z_]\w*)*)(\s*-?)\}\}`)
+
+var reservedWords = map[string]bool{
+	"if": true, "else": true, "end": true, "range": true, "with": true, "template": true,
+	"block": true, "define": true, "break": true, "continue": true, "nil": true,
+	"true": true, "false": true,
+}
+
+func expandShorthand(body string) string {
+	return shorthandPattern.ReplaceAllStringFunc(body, func(action string) string {
+		parts := shorthandPattern.FindStringSubmatch(action)
+		root := strings.SplitN(parts[2], ".", 2)[0]
+		if reservedWords[root] {
+			return action
+		}
+		return "{{" + parts[1] + "." + parts[2] + parts[3] + "}}"
+	})
+}
+
+// Parse compiles a template body. Unknown variables are reported when rendering.
+func Parse(body string) (*template.Template, error) {
+	tmpl, err := template.New("prompt").Option("missingkey=error").Parse(expandShorthand(body))
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// Escape quotes text so it renders as written, for text that was not written as a template
+func Escape(text string) string {
+	return strings.ReplaceAll(text, "{{", `{{"{{"}}`)
+}
+
+// Render fills a template body with variables
+func Render(body string, vars map[string]interface{}) (string, error) {
+	tmpl, err := Parse(body)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, vars); err != nil {
+		return "", fmt.Errorf("failed to render template: %w", err)
+	}
+	return b.String(), nil
+}
+
+// Input is what the backend knows about the submission being graded
+type Input struct {
+	Assignment  *canvas.Assignment
+	Submission  *canvas.Submission
+	StudentName string
+	Rubric      string // Rubric text; defaults to the assignment's Canvas rubric
+	Content     string // Extracted submission text, including attachments
+}
+
+// Variables builds the template variables for an input. Every variable is always present,
+// empty when unknown, so templates render for previews without a submission.
+func Variables(in Input) map[string]interface{} {
+	assignment := map[string]interface{}{
+		"name":            "",
+		"description":     "",
+		"points_possible": 0.0,
+		"due_at":          "",
+	}
+	submission := map[string]interface{}{
+		"body":         "",
+		"content":      in.Content,
+		"url":          "",
+		"submitted_at": "Not submitted",
+		"attempt":      0,
+		"is_late":      false,
+	}
+
+	rubric := in.Rubric
+	pointsPossible := 0.0
+	if in.Assignment != nil {
+		pointsPossible = in.Assignment.PointsPossible
+		assignment["name"] = in.Assignment.Name
+		assignment["description"] = plaintext.FromHTML(in.Assignment.Description)
+		assignment["points_possible"] = pointsPossible
+		assignment["due_at"] = formatTime(in.Assignment.DueAt, "")
+		if rubric == "" {
+			rubric = FormatRubric(in.Assignment.Rubric)
+		}
+	}
+
+	late := "No"
+	if in.Submission != nil {
+		submission["body"] = plaintext.FromHTML(in.Submission.Body)
+		submission["url"] = in.Submission.URL
+		submission["submitted_at"] = formatTime(in.Submission.SubmittedAt, "Not submitted")
+		submission["attempt"] = in.Submission.Attempt
+		submission["is_late"] = in.Submission.Late
+		if in.Submission.Late {
+			late = "Yes"
+		}
+	}
+
+	studentName := in.StudentName
+	if studentName == "" {
+		studentName = "Unknown"
+	}
+
+	return map[string]interface{}{
+		"assignment":      assignment,
+		"submission":      submission,
+		"student":         map[string]interface{}{"name": studentName, "first_name": FirstName(studentName)},
+		"rubric":          rubric,
+		"points_possible": pointsPossible,
+		"late":            late,
+	}
+}
+
+// FirstName takes the given name from a display name or a sortable "Last, First" name
+func FirstName(name string) string {
+	if last, first, found := strings.Cut(name, ","); found && strings.TrimSpace(first) != "" {
+		name = first
+	} else {
+		name = last
+	}
+	if fields := strings.Fields(name); len(fields) > 0 {
+		return fields[0]
+	}
+	return name
+}
+
+// FormatRubric renders Canvas rubric criteria as text for a prompt
+func FormatRubric(criteria []canvas.Rubric) string {
+	var b strings.Builder
+	for _, criterion := range criteria {
+		fmt.Fprintf(&b, "- %s (%g pts)", criterion.Description, criterion.Points)
+		if criterion.LongDescription != "" {
+			fmt.Fprintf(&b, ": %s", criterion.LongDescription)
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func formatTime(t *time.Time, fallback string) string {
+	if t == nil {
+		return fallback
+	}
+	return t.Format("Jan 2, 2006 3:04 PM")
+}
@@ -0,0 +1,206 @@
+package usage
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Kinds of provider call
+const (
+	KindGrading   = "grading"
+	KindVision    = "vision"
+	KindEmbedding = "embedding"
+	KindBatch     = "batch" // One item of a provider batch job, billed at the batch rate
+)
+
+// Entry records one provider call
+type Entry struct {
+	Time             time.Time `json:"time"`
+	Kind             string    `json:"kind"` // One of the Kind constants
+	CourseID         string    `json:"course_id,omitempty"`
+	AssignmentID     string    `json:"assignment_id,omitempty"`
+	GraderID         string    `json:"grader_id,omitempty"`
+	Platform         string    `json:"platform"`
+	Model            string    `json:"model"`
+	InputTokens      int       `json:"input_tokens"`
+	OutputTokens     int       `json:"output_tokens"`
+	CacheReadTokens  int       `json:"cache_read_tokens,omitempty"`
+	CacheWriteTokens int       `json:"cache_write_tokens,omitempty"`
+	EstimatedCost    float64   `json:"estimated_cost"`
+}
+
+// Totals aggregates calls, tokens and cost
+type Totals struct {
+	Calls            int     `json:"calls"`
+	InputTokens      int     `json:"input_tokens"`
+	OutputTokens     int     `json:"output_tokens"`
+	CacheReadTokens  int     `json:"cache_read_tokens"`
+	CacheWriteTokens int     `json:"cache_write_tokens"`
+	EstimatedCost    float64 `json:"estimated_cost"`
+}
+
+func (t *Totals) add(entry Entry) {
+	t.Calls++
+	t.InputTokens += entry.InputTokens
+	t.OutputTokens += entry.OutputTokens
+	t.CacheReadTokens += entry.CacheReadTokens
+	t.CacheWriteTokens += entry.CacheWriteTokens
+	t.EstimatedCost += entry.EstimatedCost
+}
+
+// Tokens is the total of every token processed, cached or not
+func (t Totals) Tokens() int {
+	return t.InputTokens + t.OutputTokens + t.CacheReadTokens + t.CacheWriteTokens
+}
+
+// Filter narrows a summary; empty fields match everything
+type Filter struct {
+	CourseID     string
+	AssignmentID string
+	GraderID     string
+	Since        time.Time
+	Until        time.Time
+}
+
+func (f Filter) matches(entry Entry) bool {
+	if f.CourseID != "" && entry.CourseID != f.CourseID {
+		return false
+	}
+	if f.AssignmentID != "" && entry.AssignmentID != f.AssignmentID {
+		return false
+	}
+	if f.GraderID != "" && entry.GraderID != f.GraderID {
+		return false
+	}
+	if !f.Since.IsZero() && entry.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !entry.Time.Before(f.Until) {
+		return false
+	}
+	return true
+}
+
+// Summary breaks down spend by course, assignment and model
+type Summary struct {
+	Total        Totals                       `json:"total"`
+	ByCourse     map[string]Totals            `json:"by_course"`
+	ByAssignment map[string]map[string]Totals `json:"by_assignment"` // Course ID, then assignment ID
+	ByModel      map[string]Totals            `json:"by_model"`
+}
+
+// Ledger is an append-only, on-disk record of provider calls
+type Ledger struct {
+	mu      sync.RWMutex
+	path    string
+	entries []Entry
+}
+
+// LoadLedger reads the JSON Lines ledger at path
+func LoadLedger(path string) (*Ledger, error) {
+	ledger := &Ledger{path: path}
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ledger, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open usage ledger: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// Skip a line torn by a crash mid-write rather than losing the whole ledger
+			fmt.Printf("[Usage] skipping unreadable ledger line: %v\n", err)
+			continue
+		}
+		ledger.entries = append(ledger.entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read usage ledger: %w", err)
+	}
+
+	return ledger, nil
+}
+
+// Record appends an entry and persists it
+func (l *Ledger) Record(entry Entry) error {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode usage entry: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o700); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+	file, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open usage ledger: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write usage ledger: %w", err)
+	}
+
+	l.entries = append(l.entries, entry)
+	return nil
+}
+
+// Summarize totals the entries matching filter
+func (l *Ledger) Summarize(filter Filter) Summary {
+	summary := Summary{
+		ByCourse:     make(map[string]Totals),
+		ByAssignment: make(map[string]map[string]Totals),
+		ByModel:      make(map[string]Totals),
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	for _, entry := range l.entries {
+		if !filter.matches(entry) {
+			continue
+		}
+
+		summary.Total.add(entry)
+
+		course := summary.ByCourse[entry.CourseID]
+		course.add(entry)
+		summary.ByCourse[entry.CourseID] = course
+
+		assignments, ok := summary.ByAssignment[entry.CourseID]
+		if !ok {
+			assignments = make(map[string]Totals)
+			summary.ByAssignment[entry.CourseID] = assignments
+		}
+		assignment := assignments[entry.AssignmentID]
+		assignment.add(entry)
+		assignments[entry.AssignmentID] = assignment
+
+		model := summary.ByModel[entry.Model]
+		model.add(entry)
+		summary.ByModel[entry.Model] = model
+	}
+
+	return summary
+}
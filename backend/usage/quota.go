@@ -0,0 +1,246 @@
+package usage
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"auxa/store"
+)
+
+// Cap periods
+const (
+	PeriodDay   = "day"
+	PeriodTotal = "total"
+)
+
+// Cap limits calls and tokens within a period. Zero values mean unlimited.
+type Cap struct {
+	MaxCalls  int    `json:"max_calls"`
+	MaxTokens int    `json:"max_tokens"`
+	Period    string `json:"period,omitempty"` // "day" (default) or "total"
+}
+
+func (c Cap) unlimited() bool {
+	return c.MaxCalls == 0 && c.MaxTokens == 0
+}
+
+// Limits configures spending caps for AI grading
+type Limits struct {
+	Daily     Cap            `json:"daily"`      // Across every course and TA
+	PerCourse Cap            `json:"per_course"` // Default cap for each course
+	Courses   map[string]Cap `json:"courses"`    // Per-course overrides of PerCourse
+	PerTA     Cap            `json:"per_ta"`     // Cap for each TA
+	WarnAt    []float64      `json:"warn_at"`    // Fractions of a cap that trigger a warning, e.g. 0.8
+}
+
+// coursesCapped reports whether any course has a cap
+func (l Limits) coursesCapped() bool {
+	if !l.PerCourse.unlimited() {
+		return true
+	}
+	for _, c := range l.Courses {
+		if !c.unlimited() {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultLimits leaves every cap unlimited and warns at 80% and 95%
+func DefaultLimits() Limits {
+	return Limits{
+		Courses: make(map[string]Cap),
+		WarnAt:  []float64{0.8, 0.95},
+	}
+}
+
+// Quota error codes returned to the renderer
+const (
+	CodeQuotaExceeded = "quota_exceeded"
+)
+
+// ErrCourseRequired rejects calls that name no course while course caps are configured, so
+// leaving the course out cannot bypass them
+var ErrCourseRequired = errors.New("a course_id is required while per-course spending caps are configured")
+
+// QuotaError reports a call rejected by a cap
+type QuotaError struct {
+	Code  string `json:"code"`
+	Scope string `json:"scope"` // "daily", "course" or "ta"
+	Key   string `json:"key,omitempty"`
+	Limit string `json:"limit"` // "calls" or "tokens"
+	Used  int    `json:"used"`
+	Max   int    `json:"max"`
+}
+
+func (e *QuotaError) Error() string {
+	scope := e.Scope
+	if e.Key != "" {
+		scope += " " + e.Key
+	}
+	return fmt.Sprintf("AI grading %s cap reached for %s (%d of %d used)", e.Limit, scope, e.Used, e.Max)
+}
+
+// Enforcer checks calls against the configured limits before they reach a provider
+type Enforcer struct {
+	mu      sync.Mutex
+	path    string
+	ledger  *Ledger
+	limits  Limits
+	pending map[string]int // In-flight calls per scope, so parallel batches cannot overshoot
+}
+
+// LoadEnforcer reads limits from path and enforces them against ledger
+func LoadEnforcer(path string, ledger *Ledger) (*Enforcer, error) {
+	e := &Enforcer{
+		path:    path,
+		ledger:  ledger,
+		limits:  DefaultLimits(),
+		pending: make(map[string]int),
+	}
+	if err := store.LoadJSON(path, &e.limits); err != nil {
+		return nil, err
+	}
+	if e.limits.Courses == nil {
+		e.limits.Courses = make(map[string]Cap)
+	}
+	return e, nil
+}
+
+// Limits returns the limits in effect
+func (e *Enforcer) Limits() Limits {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.limits
+}
+
+// SetLimits replaces and persists the limits
+func (e *Enforcer) SetLimits(limits Limits) error {
+	if limits.Courses == nil {
+		limits.Courses = make(map[string]Cap)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err := store.SaveJSON(e.path, limits); err != nil {
+		return err
+	}
+	e.limits = limits
+	return nil
+}
+
+// Reservation holds a slot for an in-flight call until it is released
+type Reservation struct {
+	enforcer *Enforcer
+	keys     []string
+	once     sync.Once
+}
+
+// Release frees the reservation once the call has been recorded or has failed
+func (r *Reservation) Release() {
+	r.once.Do(func() {
+		r.enforcer.mu.Lock()
+		defer r.enforcer.mu.Unlock()
+		for _, key := range r.keys {
+			r.enforcer.pending[key]--
+			if r.enforcer.pending[key] <= 0 {
+				delete(r.enforcer.pending, key)
+			}
+		}
+	})
+}
+
+type scopedCap struct {
+	scope  string
+	key    string
+	cap    Cap
+	filter Filter
+}
+
+// Reserve admits a call for a course and TA, returning warnings for caps nearing their
+// limit. It returns a *QuotaError when any cap is already reached.
+func (e *Enforcer) Reserve(courseID, graderID string, now time.Time) (*Reservation, []string, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if courseID == "" && e.limits.coursesCapped() {
+		return nil, nil, ErrCourseRequired
+	}
+
+	caps := []scopedCap{{scope: "daily", cap: e.limits.Daily}}
+	if courseID != "" {
+		courseCap, ok := e.limits.Courses[courseID]
+		if !ok {
+			courseCap = e.limits.PerCourse
+		}
+		caps = append(caps, scopedCap{scope: "course", key: courseID, cap: courseCap, filter: Filter{CourseID: courseID}})
+	}
+	if graderID != "" {
+		caps = append(caps, scopedCap{scope: "ta", key: graderID, cap: e.limits.PerTA, filter: Filter{GraderID: graderID}})
+	}
+
+	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	var warnings []string
+	keys := make([]string, 0, len(caps))
+	for _, sc := range caps {
+		if sc.cap.unlimited() {
+			continue
+		}
+		if sc.cap.Period != PeriodTotal {
+			sc.filter.Since = startOfDay
+		}
+
+		pendingKey := sc.scope + ":" + sc.key
+		totals := e.ledger.Summarize(sc.filter).Total
+		calls := totals.Calls + e.pending[pendingKey]
+
+		if sc.cap.MaxCalls > 0 && calls+1 > sc.cap.MaxCalls {
+			return nil, nil, &QuotaError{Code: CodeQuotaExceeded, Scope: sc.scope, Key: sc.key, Limit: "calls", Used: calls, Max: sc.cap.MaxCalls}
+		}
+		if sc.cap.MaxTokens > 0 && totals.Tokens() >= sc.cap.MaxTokens {
+			return nil, nil, &QuotaError{Code: CodeQuotaExceeded, Scope: sc.scope, Key: sc.key, Limit: "tokens", Used: totals.Tokens(), Max: sc.cap.MaxTokens}
+		}
+
+		warnings = append(warnings, e.warnings(sc, calls+1, totals.Tokens())...)
+		keys = append(keys, pendingKey)
+	}
+
+	for _, key := range keys {
+		e.pending[key]++
+	}
+
+	return &Reservation{enforcer: e, keys: keys}, warnings, nil
+}
+
+// warnings reports the highest warning threshold crossed by calls or tokens
+func (e *Enforcer) warnings(sc scopedCap, calls, tokens int) []string {
+	scope := sc.scope
+	if sc.key != "" {
+		scope += " " + sc.key
+	}
+
+	var warnings []string
+	check := func(limit string, used, max int) {
+		if max == 0 {
+			return
+		}
+		ratio := float64(used) / float64(max)
+		threshold := 0.0
+		for _, warnAt := range e.limits.WarnAt {
+			if ratio >= warnAt && warnAt > threshold {
+				threshold = warnAt
+			}
+		}
+		if threshold > 0 {
+			warnings = append(warnings, fmt.Sprintf("%s %s usage at %.0f%% of cap (%d of %d)", scope, limit, ratio*100, used, max))
+		}
+	}
+	check("calls", calls, sc.cap.MaxCalls)
+	check("tokens", tokens, sc.cap.MaxTokens)
+
+	return warnings
+}
@@ -2104,6 +2104,8 @@ async function describeImageWithVision({ dataUrl, mimeType, sourceLabel, aiConfi
     prompt: buildVisionPrompt(sourceLabel),
     mime_type: mimeType || 'image/png',
     image_base64: base64,
+    course_id: currentGradingContext ? String(currentGradingContext.courseId) : '',
+    assignment_id: currentGradingContext ? String(currentGradingContext.assignmentId) : '',
     max_tokens: 480,
     temperature: 0.2
   };
@@ -2111,7 +2113,9 @@ async function describeImageWithVision({ dataUrl, mimeType, sourceLabel, aiConfi
   const response = await fetch('http://localhost:3000/api/llm/analyze-image', {
     method: 'POST',
     headers: {
-      'Content-Type': 'application/json'
+      'Content-Type': 'application/json',
+      'Authorization': userCredentials.token,
+      'X-School-URL': userCredentials.school
     },
     body: JSON.stringify(body)
   });
@@ -2407,9 +2411,14 @@ async function callBackendLLM(platform, prompt, apiKey, systemPrompt, textModel,
   const response = await fetch('http://localhost:3000/api/llm/generate-feedback', {
     method: 'POST',
     headers: {
-      'Content-Type': 'application/json'
+      'Content-Type': 'application/json',
+      'Authorization': userCredentials.token,
+      'X-School-URL': userCredentials.school
     },
     body: JSON.stringify({
+      course_id: currentGradingContext ? String(currentGradingContext.courseId) : '',
+      assignment_id: currentGradingContext ? String(currentGradingContext.assignmentId) : '',
+      user_id: currentGradingContext ? currentGradingContext.userId : 0,
       platform: platform,
       api_key: apiKey,
       prompt: prompt,